@@ -41,12 +41,26 @@ func ComputeEpochAttesterData(ctx context.Context, spec *common.Spec, epc *commo
 		},
 		CurrEpochUnslashedTargetStake: 0,
 	}
-	for i := common.ValidatorIndex(0); i < common.ValidatorIndex(len(flats)); i++ {
-		flat := &flats[i]
-		// eligibility check
-		if flat.IsActive(prevEpoch) || (flat.Slashed && prevEpoch+1 < flat.WithdrawableEpoch) {
-			out.EligibleIndices = append(out.EligibleIndices, i)
+	eligibleRanges := spec.Parallelism.SplitRanges(uint64(len(flats)))
+	eligible := make([][]common.ValidatorIndex, len(eligibleRanges))
+	err = common.ForEachRange(ctx, eligibleRanges, func(ctx context.Context, ri int, r common.IndexRange) error {
+		indices := make([]common.ValidatorIndex, 0, r.End-r.Start)
+		for i := common.ValidatorIndex(r.Start); i < common.ValidatorIndex(r.End); i++ {
+			flat := &flats[i]
+			// eligibility check
+			if flat.IsActive(prevEpoch) || (flat.Slashed && prevEpoch+1 < flat.WithdrawableEpoch) {
+				indices = append(indices, i)
+			}
 		}
+		eligible[ri] = indices
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// merge in range order, to keep the eligible indices sorted
+	for _, indices := range eligible {
+		out.EligibleIndices = append(out.EligibleIndices, indices...)
 	}
 	prevEpochParticipationView, err := state.PreviousEpochParticipation()
 	if err != nil {
@@ -66,24 +80,41 @@ func ComputeEpochAttesterData(ctx context.Context, spec *common.Spec, epc *commo
 		return nil, err
 	}
 	out.CurrParticipation = currEpochParticipation
-	for _, vi := range epc.PreviousEpoch.ActiveIndices {
-		if flats[vi].Slashed {
-			continue
-		}
-		effBal := flats[vi].EffectiveBalance
-		prevFlag := prevEpochParticipation[vi]
-		if prevFlag&TIMELY_SOURCE_FLAG != 0 {
-			out.PrevEpochUnslashedStake.SourceStake += effBal
-		}
-		if prevFlag&TIMELY_TARGET_FLAG != 0 {
-			out.PrevEpochUnslashedStake.TargetStake += effBal
-		}
-		if prevFlag&TIMELY_HEAD_FLAG != 0 {
-			out.PrevEpochUnslashedStake.HeadStake += effBal
-		}
-		if currEpochParticipation[vi]&TIMELY_TARGET_FLAG != 0 {
-			out.CurrEpochUnslashedTargetStake += effBal
+	activeIndices := epc.PreviousEpoch.ActiveIndices
+	stakeRanges := spec.Parallelism.SplitRanges(uint64(len(activeIndices)))
+	prevStakes := make([]EpochStakeSummary, len(stakeRanges))
+	currStakes := make([]common.Gwei, len(stakeRanges))
+	err = common.ForEachRange(ctx, stakeRanges, func(ctx context.Context, ri int, r common.IndexRange) error {
+		prevStake := &prevStakes[ri]
+		for _, vi := range activeIndices[r.Start:r.End] {
+			if flats[vi].Slashed {
+				continue
+			}
+			effBal := flats[vi].EffectiveBalance
+			prevFlag := prevEpochParticipation[vi]
+			if prevFlag&TIMELY_SOURCE_FLAG != 0 {
+				prevStake.SourceStake += effBal
+			}
+			if prevFlag&TIMELY_TARGET_FLAG != 0 {
+				prevStake.TargetStake += effBal
+			}
+			if prevFlag&TIMELY_HEAD_FLAG != 0 {
+				prevStake.HeadStake += effBal
+			}
+			if currEpochParticipation[vi]&TIMELY_TARGET_FLAG != 0 {
+				currStakes[ri] += effBal
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for ri := range stakeRanges {
+		out.PrevEpochUnslashedStake.SourceStake += prevStakes[ri].SourceStake
+		out.PrevEpochUnslashedStake.TargetStake += prevStakes[ri].TargetStake
+		out.PrevEpochUnslashedStake.HeadStake += prevStakes[ri].HeadStake
+		out.CurrEpochUnslashedTargetStake += currStakes[ri]
 	}
 	if out.PrevEpochUnslashedStake.SourceStake < spec.EFFECTIVE_BALANCE_INCREMENT {
 		out.PrevEpochUnslashedStake.SourceStake = spec.EFFECTIVE_BALANCE_INCREMENT
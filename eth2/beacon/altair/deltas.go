@@ -12,11 +12,23 @@ func ComputeFlagDeltas(ctx context.Context, spec *common.Spec, epc *common.Epoch
 	valCount := uint64(len(attesterData.Flats))
 	out := common.NewDeltas(valCount)
 
-	unslashedParticipatingTotalBalance := common.Gwei(0)
-	for _, vi := range epc.PreviousEpoch.ActiveIndices {
-		if !attesterData.Flats[vi].Slashed && (attesterData.PrevParticipation[vi]&flag != 0) {
-			unslashedParticipatingTotalBalance += attesterData.Flats[vi].EffectiveBalance
+	activeIndices := epc.PreviousEpoch.ActiveIndices
+	stakeRanges := spec.Parallelism.SplitRanges(uint64(len(activeIndices)))
+	stakes := make([]common.Gwei, len(stakeRanges))
+	err := common.ForEachRange(ctx, stakeRanges, func(ctx context.Context, ri int, r common.IndexRange) error {
+		for _, vi := range activeIndices[r.Start:r.End] {
+			if !attesterData.Flats[vi].Slashed && (attesterData.PrevParticipation[vi]&flag != 0) {
+				stakes[ri] += attesterData.Flats[vi].EffectiveBalance
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	unslashedParticipatingTotalBalance := common.Gwei(0)
+	for _, stake := range stakes {
+		unslashedParticipatingTotalBalance += stake
 	}
 	// get_total_balance makes it 1 increment minimum
 	if unslashedParticipatingTotalBalance < spec.EFFECTIVE_BALANCE_INCREMENT {
@@ -27,23 +39,37 @@ func ComputeFlagDeltas(ctx context.Context, spec *common.Spec, epc *common.Epoch
 	activeIncrements := epc.TotalActiveStake / spec.EFFECTIVE_BALANCE_INCREMENT
 
 	baseRewardPerIncrement := (spec.EFFECTIVE_BALANCE_INCREMENT * common.Gwei(spec.BASE_REWARD_FACTOR)) / epc.TotalActiveStakeSqRoot
-	for _, vi := range attesterData.EligibleIndices {
-		effBal := attesterData.Flats[vi].EffectiveBalance
-		increments := effBal / spec.EFFECTIVE_BALANCE_INCREMENT
-		baseReward := increments * baseRewardPerIncrement
-		prevEpochParticipation := attesterData.PrevParticipation[vi]
-		flagParticipation := prevEpochParticipation&flag != 0
-
-		slashed := attesterData.Flats[vi].Slashed
-		if !slashed && flagParticipation {
-			if !isInactivityLeak {
-				rewardNumerator := (baseReward * weight) * unslashedParticipatingIncrements
-				rewardDenominator := activeIncrements * WEIGHT_DENOMINATOR
-				out.Rewards[vi] += rewardNumerator / rewardDenominator
+	eligibleIndices := attesterData.EligibleIndices
+	// Every worker writes to the deltas of a distinct set of validators.
+	err = spec.Parallelism.ParallelRanges(ctx, uint64(len(eligibleIndices)), func(ctx context.Context, _ int, r common.IndexRange) error {
+		for i, vi := range eligibleIndices[r.Start:r.End] {
+			// every 1024 validators, check if the context is done.
+			if i&((1<<10)-1) == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			effBal := attesterData.Flats[vi].EffectiveBalance
+			increments := effBal / spec.EFFECTIVE_BALANCE_INCREMENT
+			baseReward := increments * baseRewardPerIncrement
+			prevEpochParticipation := attesterData.PrevParticipation[vi]
+			flagParticipation := prevEpochParticipation&flag != 0
+
+			slashed := attesterData.Flats[vi].Slashed
+			if !slashed && flagParticipation {
+				if !isInactivityLeak {
+					rewardNumerator := (baseReward * weight) * unslashedParticipatingIncrements
+					rewardDenominator := activeIncrements * WEIGHT_DENOMINATOR
+					out.Rewards[vi] += rewardNumerator / rewardDenominator
+				}
+			} else if flag != TIMELY_HEAD_FLAG {
+				out.Penalties[vi] += (baseReward * weight) / WEIGHT_DENOMINATOR
 			}
-		} else if flag != TIMELY_HEAD_FLAG {
-			out.Penalties[vi] += (baseReward * weight) / WEIGHT_DENOMINATOR
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return out, nil
 }
@@ -52,16 +78,30 @@ func ComputeInactivityPenaltyDeltas(ctx context.Context, spec *common.Spec, epc
 	attesterData *EpochAttesterData, inactivityScores *InactivityScoresView, inactivityPenaltyQuotient uint64) (*common.Deltas, error) {
 	out := common.NewDeltas(uint64(len(attesterData.Flats)))
 	penaltyDenominator := common.Gwei(uint64(spec.INACTIVITY_SCORE_BIAS) * inactivityPenaltyQuotient)
-	for _, vi := range attesterData.EligibleIndices {
-		if !(!attesterData.Flats[vi].Slashed && (attesterData.PrevParticipation[vi]&TIMELY_TARGET_FLAG != 0)) {
-			score, err := inactivityScores.GetScore(vi)
-			if err != nil {
-				return nil, err
+	eligibleIndices := attesterData.EligibleIndices
+	// The scores tree is only read, which is safe to do concurrently.
+	err := spec.Parallelism.ParallelRanges(ctx, uint64(len(eligibleIndices)), func(ctx context.Context, _ int, r common.IndexRange) error {
+		for i, vi := range eligibleIndices[r.Start:r.End] {
+			// every 1024 validators, check if the context is done.
+			if i&((1<<10)-1) == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			if !(!attesterData.Flats[vi].Slashed && (attesterData.PrevParticipation[vi]&TIMELY_TARGET_FLAG != 0)) {
+				score, err := inactivityScores.GetScore(vi)
+				if err != nil {
+					return err
+				}
+				effBal := attesterData.Flats[vi].EffectiveBalance
+				penaltyNumerator := effBal * common.Gwei(score)
+				out.Penalties[vi] += penaltyNumerator / penaltyDenominator
 			}
-			effBal := attesterData.Flats[vi].EffectiveBalance
-			penaltyNumerator := effBal * common.Gwei(score)
-			out.Penalties[vi] += penaltyNumerator / penaltyDenominator
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return out, nil
 }
@@ -0,0 +1,235 @@
+package altair
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+
+	kbls "github.com/kilic/bls12-381"
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+// testValidators creates validators with cheap, but valid and distinct, pubkeys.
+func testValidators(count uint64, balance common.Gwei) []phase0.KickstartValidatorData {
+	out := make([]phase0.KickstartValidatorData, 0, count)
+	g1 := kbls.NewG1()
+	for i := uint64(0); i < count; i++ {
+		var pub kbls.PointG1
+		g1.MulScalarBig(&pub, g1.One(), big.NewInt(int64(i+1)))
+		withdrawalCred := common.Root{0xbb}
+		binary.LittleEndian.PutUint64(withdrawalCred[1:], i)
+		out = append(out, phase0.KickstartValidatorData{
+			Pubkey:                common.BLSPubkey((*blsu.Pubkey)(&pub).Serialize()),
+			WithdrawalCredentials: withdrawalCred,
+			Balance:               balance,
+		})
+	}
+	return out
+}
+
+// testEpochStates creates a phase0 state, and its altair upgrade, at the last slot of epoch 2,
+// ready for an epoch transition. The altair state has a mix of participation flags to reward and penalize.
+func testEpochStates(t *testing.T, spec *common.Spec, validatorCount uint64) (*phase0.BeaconStateView, *BeaconStateView) {
+	pre, _, err := phase0.KickStartState(spec, common.Root{123}, 1564000000, testValidators(validatorCount, spec.MAX_EFFECTIVE_BALANCE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pre.SetSlot(spec.SLOTS_PER_EPOCH*3 - 1); err != nil {
+		t.Fatal(err)
+	}
+	epc, err := common.NewEpochsContext(spec, pre)
+	if err != nil {
+		t.Fatal(err)
+	}
+	preCopy, err := pre.CopyState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := UpgradeToAltair(spec, epc, preCopy.(*phase0.BeaconStateView))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevParticipation, err := state.PreviousEpochParticipation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := common.ValidatorIndex(0); i < common.ValidatorIndex(validatorCount); i++ {
+		if flags := ParticipationFlags(i % 8); flags != 0 {
+			if err := prevParticipation.SetFlags(i, flags); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return pre, state
+}
+
+func processEpochRoot(t *testing.T, spec *common.Spec, state common.BeaconState) common.Root {
+	s, err := state.CopyState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	epc, err := common.NewEpochsContext(spec, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ProcessEpoch(context.Background(), spec, epc); err != nil {
+		t.Fatal(err)
+	}
+	return s.HashTreeRoot(tree.GetHashFn())
+}
+
+func TestParallelEpochProcessingDeterministic(t *testing.T) {
+	spec := *configs.Mainnet
+	spec.Parallelism = common.Parallelism{MaxWorkers: 1}
+	phase0State, altairState := testEpochStates(t, &spec, 1000)
+	expectedPhase0 := processEpochRoot(t, &spec, phase0State)
+	expectedAltair := processEpochRoot(t, &spec, altairState)
+	// small uneven ranges, to test the range boundaries
+	for _, workers := range []int{2, 3, 7, 16} {
+		spec.Parallelism = common.Parallelism{MaxWorkers: workers, MinRangeSize: 10}
+		if got := processEpochRoot(t, &spec, phase0State); got != expectedPhase0 {
+			t.Errorf("phase0: %d workers: got state root %s, expected %s", workers, got, expectedPhase0)
+		}
+		if got := processEpochRoot(t, &spec, altairState); got != expectedAltair {
+			t.Errorf("altair: %d workers: got state root %s, expected %s", workers, got, expectedAltair)
+		}
+	}
+}
+
+func TestParallelEpochProcessingCanceled(t *testing.T) {
+	spec := *configs.Mainnet
+	spec.Parallelism = common.Parallelism{MaxWorkers: 4, MinRangeSize: 10}
+	_, state := testEpochStates(t, &spec, 1000)
+	epc, err := common.NewEpochsContext(&spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := state.ProcessEpoch(ctx, &spec, epc); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context cancellation error, got: %v", err)
+	}
+}
+
+// altairOnly keeps the state in altair during slot processing.
+type altairOnly struct {
+	*BeaconStateView
+}
+
+func (s altairOnly) UpgradeMaybe(ctx context.Context, spec *common.Spec, epc *common.EpochsContext) error {
+	return nil
+}
+
+func TestEpochsContextCarriedForward(t *testing.T) {
+	spec := configs.Mainnet
+	_, state := testEpochStates(t, spec, 1000)
+	epc, err := common.NewEpochsContext(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bals, err := state.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// lower balances, for effective balance updates
+	for i := common.ValidatorIndex(0); i < 1000; i += 7 {
+		if err := bals.SetBalance(i, spec.MAX_EFFECTIVE_BALANCE/2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// new validators, for registry updates
+	for i, v := range testValidators(1010, spec.MAX_EFFECTIVE_BALANCE)[1000:] {
+		if err := state.AddValidator(spec, v.Pubkey, v.WithdrawalCredentials, v.Balance); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := epc.ValidatorPubkeyCache.AddValidator(common.ValidatorIndex(1000+i), v.Pubkey); err != nil {
+			t.Fatal(err)
+		}
+		if err := epc.AppendEffectiveBalance(vals, common.ValidatorIndex(1000+i), spec.MAX_EFFECTIVE_BALANCE); err != nil {
+			t.Fatal(err)
+		}
+	}
+	slot, err := state.Slot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// through 2 epoch transitions, the second with the registry changes of the first
+	if err := common.ProcessSlots(context.Background(), spec, epc, altairOnly{state}, slot+1+spec.SLOTS_PER_EPOCH); err != nil {
+		t.Fatal(err)
+	}
+	if eff := epc.EffectiveBalances[0]; eff > spec.MAX_EFFECTIVE_BALANCE/2 {
+		t.Fatalf("expected effective balance update, got %d", eff)
+	}
+	expected, err := common.NewEpochsContext(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epc.TotalActiveStake != expected.TotalActiveStake {
+		t.Fatalf("total active stake: got %d, expected %d", epc.TotalActiveStake, expected.TotalActiveStake)
+	}
+	if len(epc.EffectiveBalances) != len(expected.EffectiveBalances) {
+		t.Fatalf("effective balances count: got %d, expected %d", len(epc.EffectiveBalances), len(expected.EffectiveBalances))
+	}
+	for i := range expected.EffectiveBalances {
+		if epc.EffectiveBalances[i] != expected.EffectiveBalances[i] {
+			t.Fatalf("effective balance %d: got %d, expected %d", i, epc.EffectiveBalances[i], expected.EffectiveBalances[i])
+		}
+	}
+	if a, b := len(epc.NextEpoch.ActiveIndices), len(expected.NextEpoch.ActiveIndices); a != b {
+		t.Fatalf("next epoch active indices: got %d, expected %d", a, b)
+	}
+	for i := range expected.NextEpoch.Shuffling {
+		if epc.NextEpoch.Shuffling[i] != expected.NextEpoch.Shuffling[i] {
+			t.Fatalf("next epoch shuffling differs at %d", i)
+		}
+	}
+}
+
+func TestAppendEffectiveBalanceOutdated(t *testing.T) {
+	spec := configs.Mainnet
+	_, state := testEpochStates(t, spec, 64)
+	epc, err := common.NewEpochsContext(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// validators added without tracking them in the epochs context
+	for _, v := range testValidators(67, spec.MAX_EFFECTIVE_BALANCE/2)[64:] {
+		if err := state.AddValidator(spec, v.Pubkey, v.WithdrawalCredentials, v.Balance); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := epc.AppendEffectiveBalance(vals, 66, spec.MAX_EFFECTIVE_BALANCE/2); err != nil {
+		t.Fatalf("expected the effective balances to be reloaded, got: %v", err)
+	}
+	if got := len(epc.EffectiveBalances); got != 67 {
+		t.Fatalf("expected 67 effective balances, got %d", got)
+	}
+	for i := common.ValidatorIndex(0); i < 67; i++ {
+		val, err := vals.Validator(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eff, err := val.EffectiveBalance()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if epc.EffectiveBalances[i] != eff {
+			t.Fatalf("effective balance %d: got %d, expected %d", i, epc.EffectiveBalances[i], eff)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package altair
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// GenesisFromEth1 builds a genesis state that starts at Altair, like phase0.GenesisFromEth1.
+func GenesisFromEth1(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, deps []common.Deposit, ignoreSignaturesAndProofs bool) (*BeaconStateView, *common.EpochsContext, error) {
+	state := NewBeaconStateView(spec)
+	settings := &phase0.GenesisSettings{
+		ForkVersion:   spec.ALTAIR_FORK_VERSION,
+		EmptyBodyRoot: BeaconBlockBodyType(spec).New().HashTreeRoot(tree.GetHashFn()),
+		MaxEffectiveBalance: func(withdrawalCreds common.Root) common.Gwei {
+			return spec.MAX_EFFECTIVE_BALANCE
+		},
+		ActivationBalance: spec.MAX_EFFECTIVE_BALANCE,
+	}
+	epc, err := phase0.InitGenesisState(spec, state, settings, eth1BlockHash, time, deps, ignoreSignaturesAndProofs)
+	if err != nil {
+		return nil, nil, err
+	}
+	committee, err := common.ComputeNextSyncCommittee(spec, epc, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := SetGenesisSyncCommittees(spec, epc, state, committee); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
+
+// SetGenesisSyncCommittees sets both the current and next sync committee of a genesis state to the given committee,
+// and loads them into the epochs context.
+func SetGenesisSyncCommittees(spec *common.Spec, epc *common.EpochsContext, state common.SyncCommitteeBeaconState, committee *common.SyncCommittee) error {
+	currentSyncCommitteeView, err := committee.View(spec)
+	if err != nil {
+		return err
+	}
+	nextSyncCommitteeView, err := committee.View(spec)
+	if err != nil {
+		return err
+	}
+	if err := state.SetCurrentSyncCommittee(currentSyncCommitteeView); err != nil {
+		return err
+	}
+	if err := state.SetNextSyncCommittee(nextSyncCommitteeView); err != nil {
+		return err
+	}
+	return epc.LoadSyncCommittees(state)
+}
+
+// To build an Altair genesis state without Eth 1.0 deposits, i.e. directly from a sequence of minimal validator data.
+func KickStartState(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, validators []phase0.KickstartValidatorData) (*BeaconStateView, *common.EpochsContext, error) {
+	state, epc, err := GenesisFromEth1(spec, eth1BlockHash, 0, phase0.KickstartDeposits(validators), true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetGenesisTime(time); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
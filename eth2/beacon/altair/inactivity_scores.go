@@ -3,6 +3,7 @@ package altair
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/ztyp/codec"
@@ -41,6 +42,13 @@ func (li InactivityScores) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) comm
 	}, length, uint64(spec.VALIDATOR_REGISTRY_LIMIT))
 }
 
+func (li InactivityScores) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]Uint64View{}) // encode as empty list, not null
+	}
+	return json.Marshal([]Uint64View(li))
+}
+
 func (li InactivityScores) View(spec *common.Spec) (*ParticipationRegistryView, error) {
 	typ := InactivityScoresType(spec)
 	var buf bytes.Buffer
@@ -93,34 +101,62 @@ func ProcessInactivityUpdates(ctx context.Context, spec *common.Spec, attesterDa
 	finalityDelay := attesterData.PrevEpoch - finalized.Epoch
 	isInactivityLeak := finalityDelay > spec.MIN_EPOCHS_TO_INACTIVITY_PENALTY
 
-	for _, vi := range attesterData.EligibleIndices {
-		score, err := inactivityScores.GetScore(vi)
-		if err != nil {
-			return err
-		}
-		newScore := score
-
-		// Increase the inactivity score of inactive validators
-		if !attesterData.Flats[vi].Slashed && (attesterData.PrevParticipation[vi]&TIMELY_TARGET_FLAG != 0) {
-			if newScore > 0 {
-				newScore -= 1
+	type scoreUpdate struct {
+		index common.ValidatorIndex
+		score uint64
+	}
+	eligibleIndices := attesterData.EligibleIndices
+	ranges := spec.Parallelism.SplitRanges(uint64(len(eligibleIndices)))
+	updates := make([][]scoreUpdate, len(ranges))
+	// The new scores are computed in parallel, the tree is only read by the workers.
+	err = common.ForEachRange(ctx, ranges, func(ctx context.Context, ri int, r common.IndexRange) error {
+		var out []scoreUpdate
+		for i, vi := range eligibleIndices[r.Start:r.End] {
+			// every 1024 validators, check if the context is done.
+			if i&((1<<10)-1) == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
 			}
-		} else {
-			newScore += uint64(spec.INACTIVITY_SCORE_BIAS)
-		}
+			score, err := inactivityScores.GetScore(vi)
+			if err != nil {
+				return err
+			}
+			newScore := score
 
-		// Decrease the inactivity score of all eligible validators during a leak-free epoch
-		if !isInactivityLeak {
-			if newScore < uint64(spec.INACTIVITY_SCORE_RECOVERY_RATE) {
-				newScore = 0
+			// Increase the inactivity score of inactive validators
+			if !attesterData.Flats[vi].Slashed && (attesterData.PrevParticipation[vi]&TIMELY_TARGET_FLAG != 0) {
+				if newScore > 0 {
+					newScore -= 1
+				}
 			} else {
-				newScore -= uint64(spec.INACTIVITY_SCORE_RECOVERY_RATE)
+				newScore += uint64(spec.INACTIVITY_SCORE_BIAS)
+			}
+
+			// Decrease the inactivity score of all eligible validators during a leak-free epoch
+			if !isInactivityLeak {
+				if newScore < uint64(spec.INACTIVITY_SCORE_RECOVERY_RATE) {
+					newScore = 0
+				} else {
+					newScore -= uint64(spec.INACTIVITY_SCORE_RECOVERY_RATE)
+				}
 			}
-		}
 
-		// if there was any change, update the state.
-		if newScore != score {
-			if err := inactivityScores.SetScore(vi, newScore); err != nil {
+			// if there was any change, remember to update the state.
+			if newScore != score {
+				out = append(out, scoreUpdate{index: vi, score: newScore})
+			}
+		}
+		updates[ri] = out
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// The tree is modified sequentially, in validator index order.
+	for _, rangeUpdates := range updates {
+		for _, u := range rangeUpdates {
+			if err := inactivityScores.SetScore(u.index, u.score); err != nil {
 				return err
 			}
 		}
@@ -89,6 +89,9 @@ func (r *ParticipationRegistry) UnmarshalJSON(data []byte) error {
 }
 
 func (r ParticipationRegistry) MarshalJSON() ([]byte, error) {
+	if r == nil {
+		return json.Marshal([]ParticipationFlags{}) // encode as empty list, not null
+	}
 	return json.Marshal([]ParticipationFlags(r))
 }
 
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 
-	blsu "github.com/protolambda/bls12-381-util"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/ztyp/bitfields"
 	"github.com/protolambda/ztyp/codec"
@@ -83,15 +82,10 @@ func ProcessSyncAggregate(ctx context.Context, spec *common.Spec, epc *common.Ep
 		return fmt.Errorf("missing current sync committee info in EPC")
 	}
 
-	participantPubkeys := make([]*blsu.Pubkey, 0, spec.SYNC_COMMITTEE_SIZE)
-	for i := uint64(0); i < uint64(spec.SYNC_COMMITTEE_SIZE); i++ {
-		if agg.SyncCommitteeBits.GetBit(i) {
-			pub, err := epc.CurrentSyncCommittee.CachedPubkeys[i].Pubkey()
-			if err != nil {
-				return fmt.Errorf("failed to decode cached pubkey in sync-committee: %v", err)
-			}
-			participantPubkeys = append(participantPubkeys, pub)
-		}
+	// Aggregated with the help of the cached aggregate of the full sync committee
+	aggPub, err := epc.CurrentSyncCommittee.AggregateParticipants(agg.SyncCommitteeBits.GetBit)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate sync committee participant pubkeys: %v", err)
 	}
 
 	prevSlot := currentSlot.Previous()
@@ -108,7 +102,12 @@ func ProcessSyncAggregate(ctx context.Context, spec *common.Spec, epc *common.Ep
 	if err != nil {
 		return fmt.Errorf("failed to decode and sub-group check sync committee signature: %v", err)
 	}
-	if !blsu.Eth2FastAggregateVerify(participantPubkeys, signingRoot[:], sig) {
+	if aggPub == nil {
+		// no participants, only the G2 point at infinity is valid
+		if !common.VerifyAggregateSignature(ctx, "sync aggregate", nil, signingRoot, sig) {
+			return errors.New("invalid sync committee signature")
+		}
+	} else if !common.VerifySignature(ctx, "sync aggregate", aggPub, signingRoot, sig) {
 		return errors.New("invalid sync committee signature")
 	}
 
@@ -127,9 +126,11 @@ func ProcessSyncAggregate(ctx context.Context, spec *common.Spec, epc *common.Ep
 	}
 	// Note: the minimum effective balance of the proposer is sufficient
 	// to not result in differences from spec operations
+	participantCount := uint64(0)
 	for i := uint64(0); i < uint64(spec.SYNC_COMMITTEE_SIZE); i++ {
 		validatorIndex := epc.CurrentSyncCommittee.Indices[i]
 		if agg.SyncCommitteeBits.GetBit(i) {
+			participantCount += 1
 			if err := common.IncreaseBalance(bals, validatorIndex, participantReward); err != nil {
 				return err
 			}
@@ -144,7 +145,7 @@ func ProcessSyncAggregate(ctx context.Context, spec *common.Spec, epc *common.Ep
 	if err != nil {
 		return err
 	}
-	proposerRewardSum := proposerReward * common.Gwei(len(participantPubkeys))
+	proposerRewardSum := proposerReward * common.Gwei(participantCount)
 	if err := common.IncreaseBalance(bals, proposer, proposerRewardSum); err != nil {
 		return err
 	}
@@ -111,6 +111,32 @@ func (sc *SyncCommitteeContribution) VerifySignature(spec *common.Spec, subcommi
 	return nil
 }
 
+// VerifySignatureAggregated is like VerifySignature, but aggregates the subcommittee pubkeys
+// with the help of the cached aggregate pubkey of the subcommittee.
+func (sc *SyncCommitteeContribution) VerifySignatureAggregated(spec *common.Spec, syncCommittee *common.IndexedSyncCommittee, domFn common.BLSDomainFn) error {
+	aggPub, err := syncCommittee.AggregateSubcommitteeParticipants(spec, uint64(sc.SubcommitteeIndex), sc.AggregationBits.GetBit)
+	if err != nil {
+		return err
+	}
+	dom, err := domFn(common.DOMAIN_SYNC_COMMITTEE, spec.SlotToEpoch(sc.Slot))
+	if err != nil {
+		return err
+	}
+	signingRoot := common.ComputeSigningRoot(sc.BeaconBlockRoot, dom)
+	sig, err := sc.Signature.Signature()
+	if err != nil {
+		return fmt.Errorf("failed to deserialize and sub-group check sync committee contribution signature: %v", err)
+	}
+	if aggPub == nil {
+		if !blsu.Eth2FastAggregateVerify(nil, signingRoot[:], sig) {
+			return errors.New("could not verify BLS signature for sync committee contribution")
+		}
+	} else if !blsu.Verify(aggPub, signingRoot[:], sig) {
+		return errors.New("could not verify BLS signature for sync committee contribution")
+	}
+	return nil
+}
+
 type SyncCommitteeContributionView struct {
 	*ContainerView
 }
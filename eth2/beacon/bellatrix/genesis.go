@@ -0,0 +1,73 @@
+package bellatrix
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// GenesisFromEth1 builds a genesis state that starts at Bellatrix, like phase0.GenesisFromEth1.
+// The header is the latest execution payload header of the genesis state.
+// If nil, the header only refers to the EL genesis block by eth1BlockHash, with empty transactions.
+// To start before the merge transition, an empty (not nil) header can be used instead.
+func GenesisFromEth1(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, deps []common.Deposit, ignoreSignaturesAndProofs bool, header *ExecutionPayloadHeader) (*BeaconStateView, *common.EpochsContext, error) {
+	state := NewBeaconStateView(spec)
+	hFn := tree.GetHashFn()
+	emptyBodyRoot := BeaconBlockBodyType(spec).New().HashTreeRoot(hFn)
+	epc, err := InitGenesisState(spec, state, spec.BELLATRIX_FORK_VERSION, emptyBodyRoot, eth1BlockHash, time, deps, ignoreSignaturesAndProofs, func() error {
+		if header == nil {
+			header = &ExecutionPayloadHeader{
+				BlockHash:        eth1BlockHash,
+				TransactionsRoot: common.PayloadTransactions{}.HashTreeRoot(spec, hFn),
+			}
+		}
+		return state.SetLatestExecutionPayloadHeader(header)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
+
+// InitGenesisState initializes the empty state of Bellatrix or a later fork as genesis state,
+// with the validators and sync committees like altair.GenesisFromEth1, and the given fork version and empty block body root.
+// The upgrade step then sets the fields of the fork, like the latest execution payload header.
+func InitGenesisState(spec *common.Spec, state common.SyncCommitteeBeaconState, forkVersion common.Version, emptyBodyRoot common.Root,
+	eth1BlockHash common.Root, time common.Timestamp, deps []common.Deposit, ignoreSignaturesAndProofs bool, upgrade func() error) (*common.EpochsContext, error) {
+	settings := &phase0.GenesisSettings{
+		ForkVersion:   forkVersion,
+		EmptyBodyRoot: emptyBodyRoot,
+		MaxEffectiveBalance: func(withdrawalCreds common.Root) common.Gwei {
+			return spec.MAX_EFFECTIVE_BALANCE
+		},
+		ActivationBalance: spec.MAX_EFFECTIVE_BALANCE,
+	}
+	epc, err := phase0.InitGenesisState(spec, state, settings, eth1BlockHash, time, deps, ignoreSignaturesAndProofs)
+	if err != nil {
+		return nil, err
+	}
+	committee, err := common.ComputeNextSyncCommittee(spec, epc, state)
+	if err != nil {
+		return nil, err
+	}
+	if err := altair.SetGenesisSyncCommittees(spec, epc, state, committee); err != nil {
+		return nil, err
+	}
+	if err := upgrade(); err != nil {
+		return nil, err
+	}
+	return epc, nil
+}
+
+// To build a Bellatrix genesis state without Eth 1.0 deposits, i.e. directly from a sequence of minimal validator data.
+func KickStartState(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, validators []phase0.KickstartValidatorData, header *ExecutionPayloadHeader) (*BeaconStateView, *common.EpochsContext, error) {
+	state, epc, err := GenesisFromEth1(spec, eth1BlockHash, 0, phase0.KickstartDeposits(validators), true, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetGenesisTime(time); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
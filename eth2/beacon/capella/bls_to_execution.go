@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 
-	blsu "github.com/protolambda/bls12-381-util"
 	"github.com/protolambda/ztyp/tree"
 
 	"github.com/protolambda/zrnt/eth2/beacon/common"
@@ -72,7 +71,7 @@ func ProcessBLSToExecutionChange(ctx context.Context, spec *common.Spec, epc *co
 		return err
 	}
 
-	if !blsu.Verify(pubKey, sigRoot[:], signature) {
+	if !common.VerifySignature(ctx, fmt.Sprintf("bls to execution change of %d", addressChange.ValidatorIndex), pubKey, sigRoot, signature) {
 		return fmt.Errorf("invalid bls to execution change signature")
 	}
 	var newWithdrawalCredentials tree.Root
@@ -1,6 +1,7 @@
 package capella
 
 import (
+	"encoding/json"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
 	. "github.com/protolambda/ztyp/view"
@@ -80,6 +81,13 @@ func (li HistoricalSummaries) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) c
 	}, length, uint64(spec.HISTORICAL_ROOTS_LIMIT))
 }
 
+func (li HistoricalSummaries) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]HistoricalSummary{}) // encode as empty list, not null
+	}
+	return json.Marshal([]HistoricalSummary(li))
+}
+
 func HistoricalSummariesType(spec *common.Spec) ListTypeDef {
 	return ListType(HistoricalSummaryType, uint64(spec.HISTORICAL_ROOTS_LIMIT))
 }
@@ -95,3 +103,19 @@ func (h *HistoricalSummariesView) Append(summary HistoricalSummary) error {
 	v := summary.View()
 	return h.ComplexListView.Append(v)
 }
+
+func (h *HistoricalSummariesView) Summary(i uint64) (HistoricalSummary, error) {
+	c, err := AsContainer(h.Get(i))
+	if err != nil {
+		return HistoricalSummary{}, err
+	}
+	blockSummaryRoot, err := AsRoot(c.Get(0))
+	if err != nil {
+		return HistoricalSummary{}, err
+	}
+	stateSummaryRoot, err := AsRoot(c.Get(1))
+	if err != nil {
+		return HistoricalSummary{}, err
+	}
+	return HistoricalSummary{BlockSummaryRoot: blockSummaryRoot, StateSummaryRoot: stateSummaryRoot}, nil
+}
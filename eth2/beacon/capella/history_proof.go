@@ -0,0 +1,119 @@
+package capella
+
+import (
+	"fmt"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/util/merkle"
+)
+
+// HistoricalBlockRootProof proves that a block root is part of the canonical history,
+// as accumulated in the historical_summaries, or the historical_roots for periods before Capella.
+type HistoricalBlockRootProof struct {
+	// Slot of the block root. The period of the slot must be accumulated already.
+	Slot      common.Slot `json:"slot" yaml:"slot"`
+	BlockRoot common.Root `json:"block_root" yaml:"block_root"`
+	// Branch of the block root in the block roots of the period, from the bottom up.
+	Branch []common.Root `json:"branch" yaml:"branch"`
+	// Root of the state roots of the period, to compute the HistoricalBatch root of periods before Capella.
+	StateRootsRoot common.Root `json:"state_roots_root" yaml:"state_roots_root"`
+}
+
+// NewHistoricalBlockRootProof proves the block root at the given slot, with the block roots and state roots
+// of the state at the end of the period, i.e. the state at the first slot of the next period.
+func NewHistoricalBlockRootProof(spec *common.Spec, blockRoots common.BatchRoots, stateRoots common.BatchRoots,
+	slot common.Slot) (*HistoricalBlockRootProof, error) {
+	backed, ok := blockRoots.(interface{ Backing() tree.Node })
+	if !ok {
+		return nil, fmt.Errorf("block roots of type %T are not backed by a tree", blockRoots)
+	}
+	blockRoot, err := blockRoots.GetRoot(slot)
+	if err != nil {
+		return nil, err
+	}
+	depth := tree.CoverDepth(uint64(spec.SLOTS_PER_HISTORICAL_ROOT))
+	gindex := tree.Gindex64(uint64(1)<<depth | uint64(slot%spec.SLOTS_PER_HISTORICAL_ROOT))
+	hFn := tree.GetHashFn()
+	branch, err := merkle.ProveBranch(backed.Backing(), gindex, hFn)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoricalBlockRootProof{
+		Slot:           slot,
+		BlockRoot:      blockRoot,
+		Branch:         branch,
+		StateRootsRoot: stateRoots.HashTreeRoot(hFn),
+	}, nil
+}
+
+// BlockSummaryRoot computes the root of the block roots of the period, from the block root and its branch.
+func (p *HistoricalBlockRootProof) BlockSummaryRoot(spec *common.Spec) (common.Root, error) {
+	depth := uint64(tree.CoverDepth(uint64(spec.SLOTS_PER_HISTORICAL_ROOT)))
+	if uint64(len(p.Branch)) != depth {
+		return common.Root{}, fmt.Errorf("expected branch of %d roots, got %d", depth, len(p.Branch))
+	}
+	index := uint64(p.Slot % spec.SLOTS_PER_HISTORICAL_ROOT)
+	value := p.BlockRoot
+	for i, sibling := range p.Branch {
+		if (index>>i)&1 == 1 {
+			value = tree.Hash(sibling, value)
+		} else {
+			value = tree.Hash(value, sibling)
+		}
+	}
+	return value, nil
+}
+
+// Verify checks the proof against the historical_roots of the state, or the historical_summaries since Capella.
+// States of forks before Capella only have historical_roots.
+func (p *HistoricalBlockRootProof) Verify(spec *common.Spec, state common.BeaconState) error {
+	blockSummaryRoot, err := p.BlockSummaryRoot(spec)
+	if err != nil {
+		return err
+	}
+	period := uint64(p.Slot / spec.SLOTS_PER_HISTORICAL_ROOT)
+	histRoots, err := state.HistoricalRoots()
+	if err != nil {
+		return err
+	}
+	count, err := histRoots.Length()
+	if err != nil {
+		return err
+	}
+	if period < count {
+		expected, err := histRoots.Root(period)
+		if err != nil {
+			return err
+		}
+		if root := tree.Hash(blockSummaryRoot, p.StateRootsRoot); root != expected {
+			return fmt.Errorf("historical batch root %s does not match historical root %s of period %d", root, expected, period)
+		}
+		return nil
+	}
+	summariesState, ok := state.(HistoricalSummariesBeaconState)
+	if !ok {
+		return fmt.Errorf("period %d of slot %d is not accumulated in the %d historical roots", period, p.Slot, count)
+	}
+	summaries, err := summariesState.HistoricalSummaries()
+	if err != nil {
+		return err
+	}
+	summaryCount, err := summaries.Length()
+	if err != nil {
+		return err
+	}
+	if period-count >= summaryCount {
+		return fmt.Errorf("period %d of slot %d is not accumulated in the %d historical summaries", period, p.Slot, summaryCount)
+	}
+	summary, err := summaries.Summary(period - count)
+	if err != nil {
+		return err
+	}
+	if summary.BlockSummaryRoot != blockSummaryRoot {
+		return fmt.Errorf("block summary root %s does not match historical summary %s of period %d",
+			blockSummaryRoot, summary.BlockSummaryRoot, period)
+	}
+	return nil
+}
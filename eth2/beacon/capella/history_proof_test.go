@@ -0,0 +1,88 @@
+package capella
+
+import (
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func fillPeriod(t *testing.T, spec *common.Spec, state *BeaconStateView, period uint64) {
+	start := common.Slot(period) * spec.SLOTS_PER_HISTORICAL_ROOT
+	for slot := start; slot < start+spec.SLOTS_PER_HISTORICAL_ROOT; slot++ {
+		if err := common.SetRecentRoots(spec, state, slot, common.Root{byte(slot), 0xaa}, common.Root{byte(slot), 0xbb}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func proveSlot(t *testing.T, spec *common.Spec, state *BeaconStateView, slot common.Slot) *HistoricalBlockRootProof {
+	blockRoots, err := state.BlockRoots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stateRoots, err := state.StateRoots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := NewHistoricalBlockRootProof(spec, blockRoots, stateRoots, slot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.BlockRoot != (common.Root{byte(slot), 0xaa}) {
+		t.Fatalf("unexpected block root %s at slot %d", proof.BlockRoot, slot)
+	}
+	return proof
+}
+
+func TestHistoricalBlockRootProof(t *testing.T) {
+	spec := configs.Minimal
+	state := NewBeaconStateView(spec)
+
+	// the first period is accumulated in the historical roots, as before Capella
+	fillPeriod(t, spec, state, 0)
+	rootsProof := proveSlot(t, spec, state, 11)
+	if err := common.UpdateHistoricalRoots(state); err != nil {
+		t.Fatal(err)
+	}
+	// the second period is accumulated in the historical summaries
+	fillPeriod(t, spec, state, 1)
+	summaryProof := proveSlot(t, spec, state, spec.SLOTS_PER_HISTORICAL_ROOT+37)
+	if err := UpdateHistoricalSummaries(state); err != nil {
+		t.Fatal(err)
+	}
+	// the third period is not accumulated yet
+	fillPeriod(t, spec, state, 2)
+	pendingProof := proveSlot(t, spec, state, 2*spec.SLOTS_PER_HISTORICAL_ROOT+5)
+
+	if err := rootsProof.Verify(spec, state); err != nil {
+		t.Fatalf("historical roots proof: %v", err)
+	}
+	if err := summaryProof.Verify(spec, state); err != nil {
+		t.Fatalf("historical summaries proof: %v", err)
+	}
+	if err := pendingProof.Verify(spec, state); err == nil {
+		t.Fatal("expected error for period that is not accumulated")
+	}
+
+	tampered := *summaryProof
+	tampered.BlockRoot = common.Root{0xcc}
+	if err := tampered.Verify(spec, state); err == nil {
+		t.Fatal("expected error for tampered block root")
+	}
+	tampered = *rootsProof
+	tampered.StateRootsRoot = common.Root{0xcc}
+	if err := tampered.Verify(spec, state); err == nil {
+		t.Fatal("expected error for tampered state roots root")
+	}
+	tampered = *summaryProof
+	tampered.Slot += 1
+	if err := tampered.Verify(spec, state); err == nil {
+		t.Fatal("expected error for proof at the wrong slot")
+	}
+	tampered = *summaryProof
+	tampered.Branch = tampered.Branch[1:]
+	if err := tampered.Verify(spec, state); err == nil {
+		t.Fatal("expected error for short branch")
+	}
+}
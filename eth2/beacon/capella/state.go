@@ -546,6 +546,8 @@ func (state *BeaconStateView) SetNextWithdrawalValidatorIndex(nextValidator comm
 
 type HistoricalSummariesList interface {
 	Append(summary HistoricalSummary) error
+	Length() (uint64, error)
+	Summary(i uint64) (HistoricalSummary, error)
 }
 
 func (state *BeaconStateView) HistoricalSummaries() (HistoricalSummariesList, error) {
@@ -2,8 +2,7 @@ package common
 
 import (
 	"bytes"
-
-	blsu "github.com/protolambda/bls12-381-util"
+	"fmt"
 )
 
 type BeaconBlockEnvelope struct {
@@ -28,25 +27,40 @@ func (b *BeaconBlockEnvelope) VerifySignature(spec *Spec, genesisValidatorsRoot
 }
 
 func (b *BeaconBlockEnvelope) VerifySignatureVersioned(spec *Spec, version Version, genesisValidatorsRoot Root, proposer ValidatorIndex, cachedPub *CachedPubkey) bool {
-	if b.ProposerIndex != proposer {
+	set, err := b.SignatureSetVersioned(spec, version, genesisValidatorsRoot, proposer, cachedPub)
+	if err != nil {
 		return false
 	}
+	return set.Verify()
+}
+
+// SignatureSetVersioned prepares the proposer signature check of the block,
+// and errors if the block does not match the proposer, version or fork digest, or if the signature is malformed.
+func (b *BeaconBlockEnvelope) SignatureSetVersioned(spec *Spec, version Version, genesisValidatorsRoot Root, proposer ValidatorIndex, cachedPub *CachedPubkey) (*SignatureSet, error) {
+	if b.ProposerIndex != proposer {
+		return nil, fmt.Errorf("expected proposer %d, but block has proposer %d", proposer, b.ProposerIndex)
+	}
 	forkRoot := ComputeForkDataRoot(version, genesisValidatorsRoot)
 	// Sanity check fork digest
 	if !bytes.Equal(forkRoot[0:4], b.ForkDigest[:]) {
-		return false
+		return nil, fmt.Errorf("fork digest %s does not match version %s", b.ForkDigest, version)
 	}
 	pub, err := cachedPub.Pubkey()
 	if err != nil {
-		return false
+		return nil, fmt.Errorf("failed to deserialize proposer pubkey: %v", err)
 	}
 	dom := ComputeDomain(DOMAIN_BEACON_PROPOSER, version, genesisValidatorsRoot)
 	signingRoot := ComputeSigningRoot(b.BlockRoot, dom)
 	sig, err := b.Signature.Signature()
 	if err != nil {
-		return false
+		return nil, fmt.Errorf("failed to deserialize and sub-group check block signature: %v", err)
 	}
-	return blsu.Verify(pub, signingRoot[:], sig)
+	return &SignatureSet{
+		Description: fmt.Sprintf("block %s proposer %d", b.BlockRoot, proposer),
+		Pubkey:      pub,
+		SigningRoot: signingRoot,
+		Signature:   sig,
+	}, nil
 }
 
 type EnvelopeBuilder interface {
@@ -291,7 +291,8 @@ func ComputeSigningRoot(msgRoot Root, dom BLSDomain) Root {
 	return withDomain.HashTreeRoot(tree.GetHashFn())
 }
 
-// For pubkeys/signatures in state, a tree-representation is used. (TODO: cache optimized deserialized/parsed bls points)
+// For pubkeys/signatures in state, a tree-representation is used.
+// Deserialized pubkeys are cached with CachedPubkey, and aggregates of committees in the EpochsContext.
 
 type BLSPubkeyView struct {
 	*BasicVectorView
@@ -0,0 +1,200 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	kbls "github.com/kilic/bls12-381"
+	blsu "github.com/protolambda/bls12-381-util"
+)
+
+// AggregateCachedPubkeys aggregates all the given pubkeys. At least 1 pubkey is required.
+func AggregateCachedPubkeys(pubs []*CachedPubkey) (*blsu.Pubkey, error) {
+	blsPubs := make([]*blsu.Pubkey, len(pubs), len(pubs))
+	for i, p := range pubs {
+		pub, err := p.Pubkey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode cached pubkey %d (%s): %w", i, p.Compressed, err)
+		}
+		blsPubs[i] = pub
+	}
+	return blsu.AggregatePubkeys(blsPubs)
+}
+
+// AggregateParticipantPubkeys aggregates the pubkeys of the participants of a group.
+// The aggregate of the full group, if not nil, is used to subtract the non-participants from,
+// if there are fewer non-participants than participants.
+// Returns a nil pubkey if there are no participants.
+func AggregateParticipantPubkeys(groupAggregate *blsu.Pubkey, pubs []*CachedPubkey, participating func(i uint64) bool) (*blsu.Pubkey, error) {
+	count := uint64(len(pubs))
+	participants := uint64(0)
+	for i := uint64(0); i < count; i++ {
+		if participating(i) {
+			participants += 1
+		}
+	}
+	if participants == 0 {
+		return nil, nil
+	}
+	if groupAggregate == nil || participants*2 <= count {
+		blsPubs := make([]*blsu.Pubkey, 0, participants)
+		for i := uint64(0); i < count; i++ {
+			if participating(i) {
+				pub, err := pubs[i].Pubkey()
+				if err != nil {
+					return nil, fmt.Errorf("failed to decode cached pubkey %d (%s): %w", i, pubs[i].Compressed, err)
+				}
+				blsPubs = append(blsPubs, pub)
+			}
+		}
+		return blsu.AggregatePubkeys(blsPubs)
+	}
+	// Note: the group aggregate is only available if none of the group pubkeys is the identity point,
+	// so subtracting gives exactly the same result as adding up the participants.
+	g1 := kbls.NewG1()
+	out := (kbls.PointG1)(*groupAggregate)
+	for i := uint64(0); i < count; i++ {
+		if !participating(i) {
+			pub, err := pubs[i].Pubkey()
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode cached pubkey %d (%s): %w", i, pubs[i].Compressed, err)
+			}
+			g1.Sub(&out, &out, (*kbls.PointG1)(pub))
+		}
+	}
+	return (*blsu.Pubkey)(&out), nil
+}
+
+// AggregatePubkeyCache lazily computes and caches the aggregate pubkeys of groups of validators.
+// It is safe for concurrent use.
+type AggregatePubkeyCache struct {
+	lock       sync.Mutex
+	aggregates map[aggregateKey]*blsu.Pubkey
+}
+
+type aggregateKey struct {
+	slot  Slot
+	index uint64
+}
+
+// Aggregate returns the cached aggregate of the group identified by the slot and index,
+// or computes and caches it with the given group pubkeys.
+// Nil is returned if the group cannot be pre-aggregated, e.g. due to an identity pubkey.
+// The aggregate pubkey must not be modified.
+func (c *AggregatePubkeyCache) Aggregate(slot Slot, index uint64, pubs []*CachedPubkey) *blsu.Pubkey {
+	key := aggregateKey{slot: slot, index: index}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if agg, ok := c.aggregates[key]; ok {
+		return agg
+	}
+	// Errors are not fatal: the participants will just be aggregated without the help of the group aggregate.
+	agg, err := AggregateCachedPubkeys(pubs)
+	if err != nil {
+		agg = nil
+	}
+	if c.aggregates == nil {
+		c.aggregates = make(map[aggregateKey]*blsu.Pubkey)
+	}
+	c.aggregates[key] = agg
+	return agg
+}
+
+// Len returns the number of cached aggregates.
+func (c *AggregatePubkeyCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.aggregates)
+}
+
+// CommitteePubkeys looks up the pubkeys of the committee members.
+func CommitteePubkeys(pubCache *PubkeyCache, committee []ValidatorIndex) ([]*CachedPubkey, error) {
+	out := make([]*CachedPubkey, len(committee), len(committee))
+	for i, vi := range committee {
+		pub, ok := pubCache.Pubkey(vi)
+		if !ok {
+			return nil, fmt.Errorf("could not find pubkey for index %d", vi)
+		}
+		out[i] = pub
+	}
+	return out, nil
+}
+
+// AggregateCommitteeParticipants aggregates the pubkeys of the participating members of the beacon committee,
+// with the help of the pre-aggregated committee pubkey, which is cached in the shuffling of the epoch.
+// The participating function is called with the position of the member in the committee.
+// Returns an error if there are no participants.
+func (epc *EpochsContext) AggregateCommitteeParticipants(slot Slot, index CommitteeIndex, participating func(i uint64) bool) (*blsu.Pubkey, error) {
+	committee, err := epc.GetBeaconCommittee(slot, index)
+	if err != nil {
+		return nil, err
+	}
+	shuf, err := epc.getShufflingEpoch(epc.Spec.SlotToEpoch(slot))
+	if err != nil {
+		return nil, err
+	}
+	pubs, err := CommitteePubkeys(epc.ValidatorPubkeyCache, committee)
+	if err != nil {
+		return nil, err
+	}
+	groupAggregate := shuf.Aggregates.Aggregate(slot, uint64(index), pubs)
+	aggPub, err := AggregateParticipantPubkeys(groupAggregate, pubs, participating)
+	if err != nil {
+		return nil, err
+	}
+	if aggPub == nil {
+		return nil, errors.New("no committee participants to aggregate")
+	}
+	return aggPub, nil
+}
+
+// syncCommitteeAggregates caches the aggregate pubkeys of a sync committee and its subcommittees.
+type syncCommitteeAggregates struct {
+	once          sync.Once
+	full          *blsu.Pubkey
+	subcommittees [SYNC_COMMITTEE_SUBNET_COUNT]*blsu.Pubkey
+}
+
+func (isc *IndexedSyncCommittee) loadAggregates() *syncCommitteeAggregates {
+	isc.aggregates.once.Do(func() {
+		// Errors are not fatal: the participants will just be aggregated without the help of the cached aggregates.
+		subComSize := uint64(len(isc.CachedPubkeys)) / SYNC_COMMITTEE_SUBNET_COUNT
+		full := kbls.NewG1().Zero()
+		g1 := kbls.NewG1()
+		for i := uint64(0); i < SYNC_COMMITTEE_SUBNET_COUNT; i++ {
+			agg, err := AggregateCachedPubkeys(isc.CachedPubkeys[i*subComSize : (i+1)*subComSize])
+			if err != nil {
+				full = nil
+				continue
+			}
+			isc.aggregates.subcommittees[i] = agg
+			if full != nil {
+				g1.Add(full, full, (*kbls.PointG1)(agg))
+			}
+		}
+		if full != nil && subComSize > 0 && subComSize*SYNC_COMMITTEE_SUBNET_COUNT == uint64(len(isc.CachedPubkeys)) {
+			isc.aggregates.full = (*blsu.Pubkey)(full)
+		}
+	})
+	return &isc.aggregates
+}
+
+// AggregateParticipants aggregates the pubkeys of the participating sync committee members,
+// with the help of the cached pre-aggregated sync committee pubkey.
+// Returns a nil pubkey if there are no participants.
+func (isc *IndexedSyncCommittee) AggregateParticipants(participating func(i uint64) bool) (*blsu.Pubkey, error) {
+	return AggregateParticipantPubkeys(isc.loadAggregates().full, isc.CachedPubkeys, participating)
+}
+
+// AggregateSubcommitteeParticipants aggregates the pubkeys of the participating members of a sync subcommittee,
+// with the help of the cached pre-aggregated subcommittee pubkey.
+// The participating function is called with the position of the member in the subcommittee.
+// Returns a nil pubkey if there are no participants.
+func (isc *IndexedSyncCommittee) AggregateSubcommitteeParticipants(spec *Spec, subnet uint64, participating func(i uint64) bool) (*blsu.Pubkey, error) {
+	pubs, _, err := isc.Subcommittee(spec, subnet)
+	if err != nil {
+		return nil, err
+	}
+	return AggregateParticipantPubkeys(isc.loadAggregates().subcommittees[subnet], pubs, participating)
+}
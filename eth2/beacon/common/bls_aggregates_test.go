@@ -0,0 +1,120 @@
+package common
+
+import (
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+)
+
+func testCachedPubkeys(t testing.TB, n int) []*CachedPubkey {
+	sets := testSignatureSets(t, n)
+	out := make([]*CachedPubkey, n, n)
+	for i := range sets {
+		out[i] = &CachedPubkey{Compressed: sets[i].Pubkey.Serialize()}
+	}
+	return out
+}
+
+func naiveAggregate(t testing.TB, pubs []*CachedPubkey, participating func(i uint64) bool) *blsu.Pubkey {
+	var participants []*CachedPubkey
+	for i, p := range pubs {
+		if participating(uint64(i)) {
+			participants = append(participants, p)
+		}
+	}
+	if len(participants) == 0 {
+		return nil
+	}
+	agg, err := AggregateCachedPubkeys(participants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return agg
+}
+
+func TestAggregateParticipantPubkeys(t *testing.T) {
+	pubs := testCachedPubkeys(t, 16)
+	groupAggregate, err := AggregateCachedPubkeys(pubs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, participating := range map[string]func(i uint64) bool{
+		"none":   func(i uint64) bool { return false },
+		"one":    func(i uint64) bool { return i == 3 },
+		"few":    func(i uint64) bool { return i%4 == 0 },
+		"half":   func(i uint64) bool { return i%2 == 0 },
+		"most":   func(i uint64) bool { return i%4 != 0 },
+		"allbut": func(i uint64) bool { return i != 7 },
+		"all":    func(i uint64) bool { return true },
+	} {
+		t.Run(name, func(t *testing.T) {
+			expected := naiveAggregate(t, pubs, participating)
+			for _, group := range []*blsu.Pubkey{nil, groupAggregate} {
+				got, err := AggregateParticipantPubkeys(group, pubs, participating)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expected == nil || got == nil {
+					if expected != got {
+						t.Fatalf("expected %v, got %v", expected, got)
+					}
+					continue
+				}
+				if got.Serialize() != expected.Serialize() {
+					t.Fatal("aggregate pubkey does not match")
+				}
+			}
+		})
+	}
+	// the group aggregate must not be modified by the subtraction
+	if groupAggregate.Serialize() != naiveAggregate(t, pubs, func(i uint64) bool { return true }).Serialize() {
+		t.Fatal("group aggregate was modified")
+	}
+}
+
+func TestSyncCommitteeAggregates(t *testing.T) {
+	spec := &Spec{}
+	spec.SYNC_COMMITTEE_SIZE = 32
+	pubs := testCachedPubkeys(t, 32)
+	isc := &IndexedSyncCommittee{CachedPubkeys: pubs, Indices: make([]ValidatorIndex, 32)}
+	mostly := func(i uint64) bool { return i%5 != 0 }
+	got, err := isc.AggregateParticipants(mostly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Serialize() != naiveAggregate(t, pubs, mostly).Serialize() {
+		t.Fatal("sync committee aggregate does not match")
+	}
+	for subnet := uint64(0); subnet < SYNC_COMMITTEE_SUBNET_COUNT; subnet++ {
+		subPubs, _, err := isc.Subcommittee(spec, subnet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := isc.AggregateSubcommitteeParticipants(spec, subnet, mostly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Serialize() != naiveAggregate(t, subPubs, mostly).Serialize() {
+			t.Fatalf("sync subcommittee %d aggregate does not match", subnet)
+		}
+	}
+}
+
+func TestAggregatePubkeyCache(t *testing.T) {
+	pubs := testCachedPubkeys(t, 8)
+	var c AggregatePubkeyCache
+	a := c.Aggregate(10, 2, pubs)
+	if a == nil {
+		t.Fatal("expected aggregate")
+	}
+	// cached by slot and index, the pubkeys are not used again
+	if b := c.Aggregate(10, 2, nil); b != a {
+		t.Fatal("expected cached aggregate")
+	}
+	if b := c.Aggregate(10, 3, pubs[:4]); b == a {
+		t.Fatal("expected different aggregate for different committee")
+	}
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 cached aggregates, got %d", c.Len())
+	}
+}
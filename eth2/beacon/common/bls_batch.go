@@ -0,0 +1,121 @@
+package common
+
+import (
+	"context"
+	"fmt"
+
+	kbls "github.com/kilic/bls12-381"
+	blsu "github.com/protolambda/bls12-381-util"
+)
+
+// SignatureSet is a single signature check: a (possibly aggregated) pubkey, the signing root, and the signature.
+type SignatureSet struct {
+	// Description of what was signed, to identify the offending set if a batch fails.
+	Description string
+	Pubkey      *blsu.Pubkey
+	SigningRoot Root
+	Signature   *blsu.Signature
+}
+
+// Verify checks the signature set by itself.
+func (s *SignatureSet) Verify() bool {
+	return blsu.Verify(s.Pubkey, s.SigningRoot[:], s.Signature)
+}
+
+// SignatureBatch collects signature sets, to verify them all at once with a randomized batch verification.
+// The batch is not safe for concurrent use.
+type SignatureBatch struct {
+	Sets []SignatureSet
+}
+
+func NewSignatureBatch() *SignatureBatch {
+	return &SignatureBatch{}
+}
+
+func (b *SignatureBatch) Add(description string, pub *blsu.Pubkey, signingRoot Root, sig *blsu.Signature) {
+	b.Sets = append(b.Sets, SignatureSet{
+		Description: description,
+		Pubkey:      pub,
+		SigningRoot: signingRoot,
+		Signature:   sig,
+	})
+}
+
+// Verify checks all collected signature sets with a single randomized batch verification.
+// If the batch is invalid, the sets are checked one by one, and the first invalid set is reported.
+// An empty batch is valid.
+func (b *SignatureBatch) Verify() error {
+	n := len(b.Sets)
+	if n == 0 {
+		return nil
+	}
+	pubs := make([]*blsu.Pubkey, n, n)
+	msgs := make([][]byte, n, n)
+	sigs := make([]*blsu.Signature, n, n)
+	// The batch equation does not reject the identity signature like a regular verification does,
+	// so any such signature skips straight to the individual checks.
+	batchable := true
+	for i := range b.Sets {
+		s := &b.Sets[i]
+		if (*kbls.G2)(nil).IsZero((*kbls.PointG2)(s.Signature)) {
+			batchable = false
+			break
+		}
+		pubs[i] = s.Pubkey
+		msgs[i] = s.SigningRoot[:]
+		sigs[i] = s.Signature
+	}
+	if batchable {
+		if valid, err := blsu.SignatureSetVerify(pubs, msgs, sigs); err != nil {
+			return fmt.Errorf("failed to batch-verify %d signatures: %w", n, err)
+		} else if valid {
+			return nil
+		}
+	}
+	for i := range b.Sets {
+		if s := &b.Sets[i]; !s.Verify() {
+			return fmt.Errorf("invalid signature %d of %d: %s", i, n, s.Description)
+		}
+	}
+	return fmt.Errorf("batch of %d signatures is invalid, but no individual invalid signature was found", n)
+}
+
+type signatureBatchKey struct{}
+
+// WithSignatureBatch returns a context that makes VerifySignature and VerifyAggregateSignature
+// collect the signatures into the batch, instead of verifying them immediately.
+// The batch is scoped to the processing that uses the context: see StateTransitionBatchVerified.
+func WithSignatureBatch(ctx context.Context, batch *SignatureBatch) context.Context {
+	return context.WithValue(ctx, signatureBatchKey{}, batch)
+}
+
+// SignatureBatchFromContext returns the batch of the context, or nil if signatures are verified immediately.
+func SignatureBatchFromContext(ctx context.Context) *SignatureBatch {
+	batch, _ := ctx.Value(signatureBatchKey{}).(*SignatureBatch)
+	return batch
+}
+
+// VerifySignature checks the signature, or defers the check to the signature batch of the context if there is one.
+// The description is used to identify the signature if the deferred check fails.
+func VerifySignature(ctx context.Context, description string, pub *blsu.Pubkey, signingRoot Root, sig *blsu.Signature) bool {
+	if batch := SignatureBatchFromContext(ctx); batch != nil {
+		batch.Add(description, pub, signingRoot, sig)
+		return true
+	}
+	return blsu.Verify(pub, signingRoot[:], sig)
+}
+
+// VerifyAggregateSignature is like VerifySignature, but with the eth2_fast_aggregate_verify rules:
+// the pubkeys are aggregated, and the G2 point at infinity is valid when there are no pubkeys.
+func VerifyAggregateSignature(ctx context.Context, description string, pubs []*blsu.Pubkey, signingRoot Root, sig *blsu.Signature) bool {
+	batch := SignatureBatchFromContext(ctx)
+	if batch == nil || len(pubs) == 0 {
+		return blsu.Eth2FastAggregateVerify(pubs, signingRoot[:], sig)
+	}
+	aggPub, err := blsu.AggregatePubkeys(pubs)
+	if err != nil {
+		return false
+	}
+	batch.Add(description, aggPub, signingRoot, sig)
+	return true
+}
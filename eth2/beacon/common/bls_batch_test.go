@@ -0,0 +1,103 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+)
+
+func testSignatureSets(t testing.TB, n int) []SignatureSet {
+	out := make([]SignatureSet, 0, n)
+	for i := 0; i < n; i++ {
+		var skBytes [32]byte
+		skBytes[31] = byte(i + 1)
+		var sk blsu.SecretKey
+		if err := sk.Deserialize(&skBytes); err != nil {
+			t.Fatal(err)
+		}
+		pub, err := blsu.SkToPk(&sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := Root{byte(i)}
+		out = append(out, SignatureSet{
+			Description: fmt.Sprintf("set %d", i),
+			Pubkey:      pub,
+			SigningRoot: msg,
+			Signature:   blsu.Sign(&sk, msg[:]),
+		})
+	}
+	return out
+}
+
+func TestSignatureBatch(t *testing.T) {
+	sets := testSignatureSets(t, 10)
+	batch := NewSignatureBatch()
+	if err := batch.Verify(); err != nil {
+		t.Fatalf("empty batch must be valid: %v", err)
+	}
+	for _, s := range sets {
+		batch.Add(s.Description, s.Pubkey, s.SigningRoot, s.Signature)
+	}
+	if err := batch.Verify(); err != nil {
+		t.Fatalf("expected valid batch: %v", err)
+	}
+	// swap the signatures of two sets, the first set with a mismatch is the offender
+	batch.Sets[3].Signature, batch.Sets[7].Signature = batch.Sets[7].Signature, batch.Sets[3].Signature
+	err := batch.Verify()
+	if err == nil {
+		t.Fatal("expected invalid batch")
+	}
+	if !strings.Contains(err.Error(), "set 3") {
+		t.Fatalf("expected set 3 to be reported, got: %v", err)
+	}
+}
+
+func TestDeferredSignature(t *testing.T) {
+	sets := testSignatureSets(t, 2)
+	batch := NewSignatureBatch()
+	ctx := WithSignatureBatch(context.Background(), batch)
+	// the wrong signature is accepted for now, but caught by the batch
+	if !VerifySignature(ctx, sets[0].Description, sets[0].Pubkey, sets[0].SigningRoot, sets[1].Signature) {
+		t.Fatal("expected deferred signature check")
+	}
+	if len(batch.Sets) != 1 {
+		t.Fatalf("expected 1 deferred set, got %d", len(batch.Sets))
+	}
+	if err := batch.Verify(); err == nil {
+		t.Fatal("expected invalid batch")
+	}
+	// the batch is scoped to the context: other checks are immediate
+	if VerifySignature(context.Background(), sets[0].Description, sets[0].Pubkey, sets[0].SigningRoot, sets[1].Signature) {
+		t.Fatal("expected immediate signature check to fail")
+	}
+	if len(batch.Sets) != 1 {
+		t.Fatalf("expected signature check without batch context to not be deferred, got %d sets", len(batch.Sets))
+	}
+}
+
+func BenchmarkSignatureBatch(b *testing.B) {
+	for _, n := range []int{1, 16, 128} {
+		sets := testSignatureSets(b, n)
+		b.Run(fmt.Sprintf("individual_%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for j := range sets {
+					if !sets[j].Verify() {
+						b.Fatal("invalid signature")
+					}
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("batch_%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				batch := &SignatureBatch{Sets: sets}
+				if err := batch.Verify(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
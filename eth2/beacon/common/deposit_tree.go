@@ -0,0 +1,408 @@
+package common
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	. "github.com/protolambda/ztyp/view"
+)
+
+// DepositTreeFinalized are the roots of the finalized subtrees of a deposit tree, from left to right.
+// There is at most one finalized subtree per level of the tree.
+type DepositTreeFinalized []Root
+
+func (a *DepositTreeFinalized) Deserialize(dr *codec.DecodingReader) error {
+	return dr.List(func() codec.Deserializable {
+		i := len(*a)
+		*a = append(*a, Root{})
+		return &((*a)[i])
+	}, RootType.TypeByteLength(), DEPOSIT_CONTRACT_TREE_DEPTH)
+}
+
+func (a DepositTreeFinalized) Serialize(w *codec.EncodingWriter) error {
+	return w.List(func(i uint64) codec.Serializable {
+		return &a[i]
+	}, RootType.TypeByteLength(), uint64(len(a)))
+}
+
+func (a DepositTreeFinalized) ByteLength() uint64 {
+	return RootType.TypeByteLength() * uint64(len(a))
+}
+
+func (DepositTreeFinalized) FixedLength() uint64 {
+	return 0
+}
+
+func (a DepositTreeFinalized) HashTreeRoot(hFn tree.HashFn) Root {
+	length := uint64(len(a))
+	return hFn.Mixin(hFn.ChunksHTR(func(i uint64) tree.Root {
+		return a[i]
+	}, length, DEPOSIT_CONTRACT_TREE_DEPTH), length)
+}
+
+func (a DepositTreeFinalized) MarshalJSON() ([]byte, error) {
+	if a == nil {
+		return json.Marshal([]Root{}) // encode as empty list, not null
+	}
+	return json.Marshal([]Root(a))
+}
+
+var DepositTreeSnapshotType = ContainerType("DepositTreeSnapshot", []FieldDef{
+	{"finalized", ListType(RootType, DEPOSIT_CONTRACT_TREE_DEPTH)},
+	{"deposit_root", RootType},
+	{"deposit_count", Uint64Type},
+	{"execution_block_hash", RootType},
+	{"execution_block_height", Uint64Type},
+})
+
+// DepositTreeSnapshot is the EIP-4881 snapshot of the finalized part of a deposit tree,
+// to initialize a DepositTree without all the deposits before it.
+type DepositTreeSnapshot struct {
+	Finalized            DepositTreeFinalized `json:"finalized" yaml:"finalized"`
+	DepositRoot          Root                 `json:"deposit_root" yaml:"deposit_root"`
+	DepositCount         DepositIndex         `json:"deposit_count" yaml:"deposit_count"`
+	ExecutionBlockHash   Root                 `json:"execution_block_hash" yaml:"execution_block_hash"`
+	ExecutionBlockHeight Uint64View           `json:"execution_block_height" yaml:"execution_block_height"`
+}
+
+func (s *DepositTreeSnapshot) Deserialize(dr *codec.DecodingReader) error {
+	return dr.Container(&s.Finalized, &s.DepositRoot, &s.DepositCount, &s.ExecutionBlockHash, &s.ExecutionBlockHeight)
+}
+
+func (s *DepositTreeSnapshot) Serialize(w *codec.EncodingWriter) error {
+	return w.Container(s.Finalized, &s.DepositRoot, s.DepositCount, &s.ExecutionBlockHash, s.ExecutionBlockHeight)
+}
+
+func (s *DepositTreeSnapshot) ByteLength() uint64 {
+	return codec.ContainerLength(s.Finalized, &s.DepositRoot, s.DepositCount, &s.ExecutionBlockHash, s.ExecutionBlockHeight)
+}
+
+func (*DepositTreeSnapshot) FixedLength() uint64 {
+	return 0
+}
+
+func (s *DepositTreeSnapshot) HashTreeRoot(hFn tree.HashFn) Root {
+	return hFn.HashTreeRoot(s.Finalized, &s.DepositRoot, s.DepositCount, &s.ExecutionBlockHash, s.ExecutionBlockHeight)
+}
+
+// CalculateRoot computes the deposit root from the finalized subtrees,
+// to check the snapshot against its DepositRoot.
+func (s *DepositTreeSnapshot) CalculateRoot() Root {
+	size := uint64(s.DepositCount)
+	index := len(s.Finalized)
+	root := tree.ZeroHashes[0]
+	for level := 0; level < DEPOSIT_CONTRACT_TREE_DEPTH; level++ {
+		if size&1 == 1 {
+			if index == 0 {
+				// not enough finalized roots for the deposit count, the root will not match
+				return Root{}
+			}
+			index--
+			root = tree.Hash(s.Finalized[index], root)
+		} else {
+			root = tree.Hash(root, tree.ZeroHashes[level])
+		}
+		size >>= 1
+	}
+	return tree.GetHashFn().Mixin(root, uint64(s.DepositCount))
+}
+
+// depositTreeNode is a node of the deposit tree, as specified in EIP-4881.
+// Nodes are replaced when finalized, so the finalized parts of the tree can be pruned.
+type depositTreeNode interface {
+	root() Root
+	isFull() bool
+	// pushLeaf adds the leaf to the subtree of the given level, and returns the updated subtree.
+	pushLeaf(leaf Root, level uint8) (depositTreeNode, error)
+	// finalize prunes the first count deposits of the subtree of the given level, and returns the updated subtree.
+	finalize(count uint64, level uint8) depositTreeNode
+	// getFinalized appends the roots of the finalized subtrees, and returns the number of finalized deposits.
+	getFinalized(out *DepositTreeFinalized) uint64
+}
+
+// depositTreeFinalizedNode is a pruned subtree of only finalized deposits.
+type depositTreeFinalizedNode struct {
+	count uint64
+	hash  Root
+}
+
+func (n *depositTreeFinalizedNode) root() Root {
+	return n.hash
+}
+
+func (n *depositTreeFinalizedNode) isFull() bool {
+	return true
+}
+
+func (n *depositTreeFinalizedNode) pushLeaf(leaf Root, level uint8) (depositTreeNode, error) {
+	return nil, errors.New("cannot push a leaf into a finalized subtree")
+}
+
+func (n *depositTreeFinalizedNode) finalize(count uint64, level uint8) depositTreeNode {
+	return n
+}
+
+func (n *depositTreeFinalizedNode) getFinalized(out *DepositTreeFinalized) uint64 {
+	*out = append(*out, n.hash)
+	return n.count
+}
+
+type depositTreeLeaf Root
+
+func (n *depositTreeLeaf) root() Root {
+	return Root(*n)
+}
+
+func (n *depositTreeLeaf) isFull() bool {
+	return true
+}
+
+func (n *depositTreeLeaf) pushLeaf(leaf Root, level uint8) (depositTreeNode, error) {
+	return nil, errors.New("cannot push a leaf into a leaf")
+}
+
+func (n *depositTreeLeaf) finalize(count uint64, level uint8) depositTreeNode {
+	return &depositTreeFinalizedNode{count: 1, hash: Root(*n)}
+}
+
+func (n *depositTreeLeaf) getFinalized(out *DepositTreeFinalized) uint64 {
+	return 0
+}
+
+type depositTreePair struct {
+	left, right depositTreeNode
+	// cached root, reset when a leaf is pushed into the subtree
+	hash   Root
+	hashed bool
+}
+
+func (n *depositTreePair) root() Root {
+	if !n.hashed {
+		n.hash = tree.Hash(n.left.root(), n.right.root())
+		n.hashed = true
+	}
+	return n.hash
+}
+
+func (n *depositTreePair) isFull() bool {
+	return n.right.isFull()
+}
+
+func (n *depositTreePair) pushLeaf(leaf Root, level uint8) (depositTreeNode, error) {
+	var err error
+	n.hashed = false
+	if !n.left.isFull() {
+		n.left, err = n.left.pushLeaf(leaf, level-1)
+	} else {
+		n.right, err = n.right.pushLeaf(leaf, level-1)
+	}
+	return n, err
+}
+
+func (n *depositTreePair) finalize(count uint64, level uint8) depositTreeNode {
+	deposits := uint64(1) << level
+	if deposits <= count {
+		return &depositTreeFinalizedNode{count: deposits, hash: n.root()}
+	}
+	n.left = n.left.finalize(count, level-1)
+	if count > deposits/2 {
+		n.right = n.right.finalize(count-deposits/2, level-1)
+	}
+	return n
+}
+
+func (n *depositTreePair) getFinalized(out *DepositTreeFinalized) uint64 {
+	return n.left.getFinalized(out) + n.right.getFinalized(out)
+}
+
+// depositTreeZero is an empty subtree of the given level.
+type depositTreeZero uint8
+
+func (n depositTreeZero) root() Root {
+	return tree.ZeroHashes[n]
+}
+
+func (n depositTreeZero) isFull() bool {
+	return false
+}
+
+func (n depositTreeZero) pushLeaf(leaf Root, level uint8) (depositTreeNode, error) {
+	return newDepositSubtree(leaf, level), nil
+}
+
+func (n depositTreeZero) finalize(count uint64, level uint8) depositTreeNode {
+	// there are no deposits to finalize in an empty subtree
+	return n
+}
+
+func (n depositTreeZero) getFinalized(out *DepositTreeFinalized) uint64 {
+	return 0
+}
+
+// newDepositSubtree creates a subtree of the given level, with the leaf as first and only deposit.
+func newDepositSubtree(leaf Root, level uint8) depositTreeNode {
+	if level == 0 {
+		l := depositTreeLeaf(leaf)
+		return &l
+	}
+	return &depositTreePair{left: newDepositSubtree(leaf, level-1), right: depositTreeZero(level - 1)}
+}
+
+// depositTreeFromSnapshot reconstructs the subtree of the given level, from the finalized roots and deposit count.
+func depositTreeFromSnapshot(finalized []Root, count uint64, level uint8) depositTreeNode {
+	if len(finalized) == 0 || count == 0 {
+		return depositTreeZero(level)
+	}
+	if count == uint64(1)<<level {
+		return &depositTreeFinalizedNode{count: count, hash: finalized[0]}
+	}
+	if level == 0 {
+		return depositTreeZero(0)
+	}
+	leftSize := uint64(1) << (level - 1)
+	if count <= leftSize {
+		return &depositTreePair{
+			left:  depositTreeFromSnapshot(finalized, count, level-1),
+			right: depositTreeZero(level - 1),
+		}
+	}
+	return &depositTreePair{
+		left:  &depositTreeFinalizedNode{count: leftSize, hash: finalized[0]},
+		right: depositTreeFromSnapshot(finalized[1:], count-leftSize, level-1),
+	}
+}
+
+// DepositTree is the EIP-4881 deposit contract tree. Deposits are inserted incrementally,
+// and finalized deposits are pruned, so only the deposits that still need proofs are kept in memory.
+type DepositTree struct {
+	tree        depositTreeNode
+	mixInLength uint64
+	// finalized deposits, and the execution block that finalized them
+	finalizedCount       uint64
+	finalizedBlockHash   Root
+	finalizedBlockHeight uint64
+	finalizedSet         bool
+}
+
+// NewDepositTree creates an empty deposit tree.
+func NewDepositTree() *DepositTree {
+	return &DepositTree{tree: depositTreeZero(DEPOSIT_CONTRACT_TREE_DEPTH)}
+}
+
+// NewDepositTreeFromSnapshot creates a deposit tree from a snapshot of the finalized deposits.
+func NewDepositTreeFromSnapshot(snapshot *DepositTreeSnapshot) (*DepositTree, error) {
+	if len(snapshot.Finalized) > DEPOSIT_CONTRACT_TREE_DEPTH {
+		return nil, fmt.Errorf("too many finalized roots: %d", len(snapshot.Finalized))
+	}
+	if root := snapshot.CalculateRoot(); root != snapshot.DepositRoot {
+		return nil, fmt.Errorf("snapshot deposit root %s does not match computed root %s", snapshot.DepositRoot, root)
+	}
+	count := uint64(snapshot.DepositCount)
+	return &DepositTree{
+		tree:                 depositTreeFromSnapshot(snapshot.Finalized, count, DEPOSIT_CONTRACT_TREE_DEPTH),
+		mixInLength:          count,
+		finalizedCount:       count,
+		finalizedBlockHash:   snapshot.ExecutionBlockHash,
+		finalizedBlockHeight: uint64(snapshot.ExecutionBlockHeight),
+		finalizedSet:         true,
+	}, nil
+}
+
+// DepositCount is the number of deposits in the tree, including finalized deposits.
+func (t *DepositTree) DepositCount() DepositIndex {
+	return DepositIndex(t.mixInLength)
+}
+
+// Root is the deposit root, including the length mix-in, as in Eth1Data.DepositRoot.
+func (t *DepositTree) Root() Root {
+	return tree.GetHashFn().Mixin(t.tree.root(), t.mixInLength)
+}
+
+// PushLeaf adds the root of the next deposit data to the tree.
+func (t *DepositTree) PushLeaf(leaf Root) error {
+	if t.mixInLength >= uint64(1)<<DEPOSIT_CONTRACT_TREE_DEPTH {
+		return errors.New("deposit tree is full")
+	}
+	node, err := t.tree.pushLeaf(leaf, DEPOSIT_CONTRACT_TREE_DEPTH)
+	if err != nil {
+		return err
+	}
+	t.tree = node
+	t.mixInLength += 1
+	return nil
+}
+
+// PushDeposit adds the next deposit data to the tree.
+func (t *DepositTree) PushDeposit(data *DepositData) error {
+	return t.PushLeaf(data.HashTreeRoot(tree.GetHashFn()))
+}
+
+// Finalize prunes the deposits up to the deposit count of the finalized eth1 data,
+// from the execution block at the given height.
+func (t *DepositTree) Finalize(eth1Data Eth1Data, executionBlockHeight uint64) error {
+	count := uint64(eth1Data.DepositCount)
+	if count > t.mixInLength {
+		return fmt.Errorf("cannot finalize %d deposits, tree only has %d", count, t.mixInLength)
+	}
+	if count < t.finalizedCount {
+		return fmt.Errorf("cannot finalize %d deposits, already finalized %d", count, t.finalizedCount)
+	}
+	t.tree = t.tree.finalize(count, DEPOSIT_CONTRACT_TREE_DEPTH)
+	t.finalizedCount = count
+	t.finalizedBlockHash = eth1Data.BlockHash
+	t.finalizedBlockHeight = executionBlockHeight
+	t.finalizedSet = true
+	return nil
+}
+
+// Snapshot creates a snapshot of the finalized deposits.
+// The tree must have been finalized at least once.
+func (t *DepositTree) Snapshot() (*DepositTreeSnapshot, error) {
+	if !t.finalizedSet {
+		return nil, errors.New("deposit tree is not finalized, no snapshot available")
+	}
+	var finalized DepositTreeFinalized
+	count := t.tree.getFinalized(&finalized)
+	snapshot := &DepositTreeSnapshot{
+		Finalized:            finalized,
+		DepositCount:         DepositIndex(count),
+		ExecutionBlockHash:   t.finalizedBlockHash,
+		ExecutionBlockHeight: Uint64View(t.finalizedBlockHeight),
+	}
+	snapshot.DepositRoot = snapshot.CalculateRoot()
+	return snapshot, nil
+}
+
+// Proof creates the proof of the deposit at the given index, against the current deposit root.
+// Finalized deposits cannot be proven anymore.
+func (t *DepositTree) Proof(index DepositIndex) (leaf Root, proof DepositProof, err error) {
+	if uint64(index) >= t.mixInLength {
+		return Root{}, DepositProof{}, fmt.Errorf("deposit %d is not in the tree of %d deposits", index, t.mixInLength)
+	}
+	if uint64(index) < t.finalizedCount {
+		return Root{}, DepositProof{}, fmt.Errorf("deposit %d is finalized and cannot be proven", index)
+	}
+	node := t.tree
+	for depth := DEPOSIT_CONTRACT_TREE_DEPTH; depth > 0; depth-- {
+		pair, ok := node.(*depositTreePair)
+		if !ok {
+			return Root{}, DepositProof{}, fmt.Errorf("deposit %d is pruned and cannot be proven", index)
+		}
+		if (uint64(index)>>(depth-1))&1 == 1 {
+			proof[depth-1] = pair.left.root()
+			node = pair.right
+		} else {
+			proof[depth-1] = pair.right.root()
+			node = pair.left
+		}
+	}
+	if _, ok := node.(*depositTreeLeaf); !ok {
+		return Root{}, DepositProof{}, fmt.Errorf("deposit %d is pruned and cannot be proven", index)
+	}
+	binary.LittleEndian.PutUint64(proof[DEPOSIT_CONTRACT_TREE_DEPTH][:8], t.mixInLength)
+	return node.root(), proof, nil
+}
@@ -0,0 +1,135 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	. "github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/util/merkle"
+)
+
+func checkDepositProofs(t *testing.T, dt *DepositTree, leaves []Root, from uint64) {
+	root := dt.Root()
+	for i := from; i < uint64(dt.DepositCount()); i++ {
+		leaf, proof, err := dt.Proof(DepositIndex(i))
+		if err != nil {
+			t.Fatalf("deposit %d: %v", i, err)
+		}
+		if leaf != leaves[i] {
+			t.Fatalf("deposit %d: unexpected leaf %s", i, leaf)
+		}
+		if !merkle.VerifyMerkleBranch(leaf, proof[:], DEPOSIT_CONTRACT_TREE_DEPTH+1, i, root) {
+			t.Fatalf("deposit %d: invalid proof", i)
+		}
+	}
+}
+
+func TestDepositTree(t *testing.T) {
+	hFn := tree.GetHashFn()
+	// the deposit roots as list, like the genesis deposit roots
+	expected := ComplexListType(RootType, 1<<DEPOSIT_CONTRACT_TREE_DEPTH).New()
+	dt := NewDepositTree()
+	if dt.Root() != expected.HashTreeRoot(hFn) {
+		t.Fatal("empty deposit tree root does not match")
+	}
+	var leaves []Root
+	for i := 0; i < 20; i++ {
+		data := DepositData{Amount: Gwei(i), Signature: BLSSignature{byte(i)}}
+		leaf := data.HashTreeRoot(hFn)
+		leaves = append(leaves, leaf)
+		if err := dt.PushDeposit(&data); err != nil {
+			t.Fatal(err)
+		}
+		rv := RootView(leaf)
+		if err := expected.Append(&rv); err != nil {
+			t.Fatal(err)
+		}
+		if dt.Root() != expected.HashTreeRoot(hFn) {
+			t.Fatalf("deposit root does not match after %d deposits", i+1)
+		}
+	}
+	checkDepositProofs(t, dt, leaves, 0)
+
+	if _, err := dt.Snapshot(); err == nil {
+		t.Fatal("expected error for snapshot before finalization")
+	}
+	root := dt.Root()
+	if err := dt.Finalize(Eth1Data{DepositRoot: root, DepositCount: 11, BlockHash: Root{0xaa}}, 1234); err != nil {
+		t.Fatal(err)
+	}
+	if dt.Root() != root {
+		t.Fatal("finalization changed the deposit root")
+	}
+	if _, _, err := dt.Proof(10); err == nil {
+		t.Fatal("expected error for proof of finalized deposit")
+	}
+	checkDepositProofs(t, dt, leaves, 11)
+	if err := dt.Finalize(Eth1Data{DepositCount: 10}, 1233); err == nil {
+		t.Fatal("expected error for finalizing less deposits")
+	}
+	if err := dt.Finalize(Eth1Data{DepositCount: 21}, 1300); err == nil {
+		t.Fatal("expected error for finalizing more deposits than in the tree")
+	}
+
+	snapshot, err := dt.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 11 deposits: a subtree of 8, of 2 and of 1
+	if snapshot.DepositCount != 11 || len(snapshot.Finalized) != 3 || snapshot.ExecutionBlockHeight != 1234 {
+		t.Fatalf("unexpected snapshot: %d deposits, %d finalized roots", snapshot.DepositCount, len(snapshot.Finalized))
+	}
+
+	var buf bytes.Buffer
+	if err := snapshot.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if uint64(buf.Len()) != snapshot.ByteLength() {
+		t.Fatalf("serialized %d bytes, expected %d", buf.Len(), snapshot.ByteLength())
+	}
+	var decoded DepositTreeSnapshot
+	if err := decoded.Deserialize(codec.NewDecodingReader(bytes.NewReader(buf.Bytes()), uint64(buf.Len()))); err != nil {
+		t.Fatal(err)
+	}
+	sszView, err := DepositTreeSnapshotType.Deserialize(codec.NewDecodingReader(bytes.NewReader(buf.Bytes()), uint64(buf.Len())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.HashTreeRoot(hFn) != snapshot.HashTreeRoot(hFn) || sszView.HashTreeRoot(hFn) != snapshot.HashTreeRoot(hFn) {
+		t.Fatal("snapshot does not match after SSZ round trip")
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON DepositTreeSnapshot
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if fromJSON.HashTreeRoot(hFn) != snapshot.HashTreeRoot(hFn) {
+		t.Fatal("snapshot does not match after JSON round trip")
+	}
+
+	restored, err := NewDepositTreeFromSnapshot(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaf := range leaves[11:] {
+		if err := restored.PushLeaf(leaf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if restored.Root() != root {
+		t.Fatal("restored deposit tree root does not match")
+	}
+	checkDepositProofs(t, restored, leaves, 11)
+
+	decoded.DepositRoot = Root{1}
+	if _, err := NewDepositTreeFromSnapshot(&decoded); err == nil {
+		t.Fatal("expected error for snapshot with wrong deposit root")
+	}
+}
@@ -9,6 +9,9 @@ import (
 type IndexedSyncCommittee struct {
 	CachedPubkeys []*CachedPubkey
 	Indices       []ValidatorIndex
+
+	// lazily computed aggregate pubkeys of the committee and subcommittees
+	aggregates syncCommitteeAggregates
 }
 
 func (isc *IndexedSyncCommittee) Subcommittee(spec *Spec, subnet uint64) (pubs []*CachedPubkey, indices []ValidatorIndex, err error) {
@@ -57,14 +60,21 @@ type EpochsContext struct {
 	CurrentSyncCommittee *IndexedSyncCommittee
 	NextSyncCommittee    *IndexedSyncCommittee
 
-	// TODO: track active effective balances
-	// TODO: track total active stake
-	// Effective balances of all validators at the start of the epoch.
+	// Effective balances of all validators at the start of the epoch, extended with the validators added by deposits.
+	// Shared between clones: it must not be modified, only appended to with AppendEffectiveBalance.
 	EffectiveBalances []Gwei
 	// Total effective balance of the active validators at the start of the epoch.
 	TotalActiveStake Gwei
 	// cached integer square root of TotalActiveStake
 	TotalActiveStakeSqRoot Gwei
+
+	// The clone that may append to the EffectiveBalances backing array without copying it first.
+	effectiveBalancesOwner *EpochsContext
+
+	// Validator data of the epoch transition, updated with the changes of the transition,
+	// to rotate to the next epoch without reading the validator registry from the state.
+	transitionFlats      []FlatValidator
+	transitionFlatsEpoch Epoch
 }
 
 // NewEpochsContext constructs a new context for the processing of the current epoch.
@@ -101,7 +111,7 @@ func (epc *EpochsContext) LoadShuffling(state BeaconState) error {
 	if err != nil {
 		return err
 	}
-	indicesBounded, err := LoadBoundedIndices(vals)
+	indicesBounded, effBalances, err := loadValidators(vals)
 	if err != nil {
 		return err
 	}
@@ -114,9 +124,7 @@ func (epc *EpochsContext) LoadShuffling(state BeaconState) error {
 	if err != nil {
 		return err
 	}
-	if err := epc.loadCurrentStake(state, indicesBounded); err != nil {
-		return err
-	}
+	epc.setCurrentStake(indicesBounded, effBalances)
 
 	prevEpoch := currentEpoch.Previous()
 	if prevEpoch == currentEpoch { // in case of genesis
@@ -134,36 +142,99 @@ func (epc *EpochsContext) LoadShuffling(state BeaconState) error {
 	return nil
 }
 
-func (epc *EpochsContext) loadCurrentStake(state BeaconState, indicesBounded []BoundedIndex) error {
-	epc.EffectiveBalances = make([]Gwei, len(indicesBounded), len(indicesBounded))
-	epc.TotalActiveStake = 0
-	vals, err := state.Validators()
+// loadValidators reads the activation and exit epochs and the effective balances of all validators,
+// in a single pass over the validator registry.
+func loadValidators(vals ValidatorRegistry) (indicesBounded []BoundedIndex, effBalances []Gwei, err error) {
+	valCount, err := vals.ValidatorCount()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	currentEpoch := epc.CurrentEpoch.Epoch
-	for i, v := range indicesBounded {
-		// TODO: optimize effective balance retrieval
-		val, err := vals.Validator(ValidatorIndex(i))
+	indicesBounded = make([]BoundedIndex, 0, valCount)
+	effBalances = make([]Gwei, 0, valCount)
+	valIterNext := vals.Iter()
+	for i := ValidatorIndex(0); true; i++ {
+		val, ok, err := valIterNext()
 		if err != nil {
-			return err
+			return nil, nil, err
+		}
+		if !ok {
+			break
+		}
+		actiEp, err := val.ActivationEpoch()
+		if err != nil {
+			return nil, nil, err
+		}
+		exitEp, err := val.ExitEpoch()
+		if err != nil {
+			return nil, nil, err
 		}
 		eff, err := val.EffectiveBalance()
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
-		epc.EffectiveBalances[i] = eff
+		indicesBounded = append(indicesBounded, BoundedIndex{
+			Index:      i,
+			Activation: actiEp,
+			Exit:       exitEp,
+		})
+		effBalances = append(effBalances, eff)
+	}
+	return indicesBounded, effBalances, nil
+}
+
+// setCurrentStake takes ownership of the effective balances, and sums up the active stake of the current epoch.
+func (epc *EpochsContext) setCurrentStake(indicesBounded []BoundedIndex, effBalances []Gwei) {
+	epc.EffectiveBalances = effBalances
+	epc.effectiveBalancesOwner = epc
+	epc.TotalActiveStake = 0
+	currentEpoch := epc.CurrentEpoch.Epoch
+	for i, v := range indicesBounded {
 		if v.Activation <= currentEpoch && currentEpoch < v.Exit {
-			epc.TotalActiveStake += eff
+			epc.TotalActiveStake += effBalances[i]
 		}
 	}
 	if epc.TotalActiveStake < epc.Spec.EFFECTIVE_BALANCE_INCREMENT {
 		epc.TotalActiveStake = epc.Spec.EFFECTIVE_BALANCE_INCREMENT
 	}
 	epc.TotalActiveStakeSqRoot = Gwei(math.IntegerSquareroot(uint64(epc.TotalActiveStake)))
+}
+
+// AppendEffectiveBalance tracks the effective balance of a validator that was just added to the registry.
+// New validators are not active yet, the total active stake is not affected.
+// If the tracked balances do not line up with the registry, e.g. when the context was created
+// before other validators were added, the effective balances are reloaded from the registry instead.
+func (epc *EpochsContext) AppendEffectiveBalance(vals ValidatorRegistry, index ValidatorIndex, eff Gwei) error {
+	if count := ValidatorIndex(len(epc.EffectiveBalances)); index != count {
+		_, effBalances, err := loadValidators(vals)
+		if err != nil {
+			return err
+		}
+		if ValidatorIndex(len(effBalances)) != index+1 {
+			return fmt.Errorf("cannot track effective balance of validator %d, registry has %d validators", index, len(effBalances))
+		}
+		epc.EffectiveBalances = effBalances
+		epc.effectiveBalancesOwner = epc
+		return nil
+	}
+	if epc.effectiveBalancesOwner != epc {
+		// Another clone may append to the same backing array, copy it first.
+		effBalances := make([]Gwei, len(epc.EffectiveBalances), len(epc.EffectiveBalances)+64)
+		copy(effBalances, epc.EffectiveBalances)
+		epc.EffectiveBalances = effBalances
+		epc.effectiveBalancesOwner = epc
+	}
+	epc.EffectiveBalances = append(epc.EffectiveBalances, eff)
 	return nil
 }
 
+// TrackTransitionFlats registers the validator data of the epoch transition of the current epoch.
+// The epoch transition must keep the data up to date with the registry and effective balance changes it makes,
+// so that RotateEpochs can use it instead of reading all validators from the state.
+func (epc *EpochsContext) TrackTransitionFlats(flats []FlatValidator) {
+	epc.transitionFlats = flats
+	epc.transitionFlatsEpoch = epc.CurrentEpoch.Epoch
+}
+
 func (epc *EpochsContext) LoadProposers(state BeaconState) error {
 	// prerequisite to load shuffling: the list of active indices, same as in the shuffling. So load the shuffling first.
 	if epc.CurrentEpoch == nil {
@@ -209,15 +280,37 @@ func (epc *EpochsContext) RotateEpochs(state BeaconState) error {
 	epc.PreviousEpoch = epc.CurrentEpoch
 	epc.CurrentEpoch = epc.NextEpoch
 	nextEpoch := epc.CurrentEpoch.Epoch + 1
+	var indicesBounded []BoundedIndex
+	var effBalances []Gwei
+	flats := epc.transitionFlats
+	epc.transitionFlats = nil
 	vals, err := state.Validators()
 	if err != nil {
 		return err
 	}
-	// TODO: could use epoch-transition processing validator data to not read state here
-	indicesBounded, err := LoadBoundedIndices(vals)
+	valCount, err := vals.ValidatorCount()
 	if err != nil {
 		return err
 	}
+	if flats != nil && epc.transitionFlatsEpoch == epc.PreviousEpoch.Epoch && uint64(len(flats)) == valCount {
+		// Carry forward the validator data of the epoch transition
+		indicesBounded = make([]BoundedIndex, len(flats), len(flats))
+		effBalances = make([]Gwei, len(flats), len(flats))
+		for i := range flats {
+			flat := &flats[i]
+			indicesBounded[i] = BoundedIndex{
+				Index:      ValidatorIndex(i),
+				Activation: flat.ActivationEpoch,
+				Exit:       flat.ExitEpoch,
+			}
+			effBalances[i] = flat.EffectiveBalance
+		}
+	} else {
+		indicesBounded, effBalances, err = loadValidators(vals)
+		if err != nil {
+			return err
+		}
+	}
 	epc.NextEpoch, err = ComputeShufflingEpoch(epc.Spec, state, indicesBounded, nextEpoch)
 	if err != nil {
 		return err
@@ -225,9 +318,7 @@ func (epc *EpochsContext) RotateEpochs(state BeaconState) error {
 	if err := epc.LoadProposers(state); err != nil {
 		return err
 	}
-	if err := epc.loadCurrentStake(state, indicesBounded); err != nil {
-		return err
-	}
+	epc.setCurrentStake(indicesBounded, effBalances)
 	if syncState, ok := state.(SyncCommitteeBeaconState); ok {
 		// if the state has a list of sync committee pubkeys, we want to cache the indices of that sync committee
 		if epc.CurrentEpoch.Epoch%epc.Spec.EPOCHS_PER_SYNC_COMMITTEE_PERIOD == 0 {
@@ -296,18 +387,26 @@ func (epc *EpochsContext) getSlotComms(slot Slot) ([][]ValidatorIndex, error) {
 	return comms[epochSlot], nil
 }
 
-func (epc *EpochsContext) getEpochComms(epoch Epoch) ([][][]ValidatorIndex, error) {
+func (epc *EpochsContext) getShufflingEpoch(epoch Epoch) (*ShufflingEpoch, error) {
 	if epoch == epc.PreviousEpoch.Epoch {
-		return epc.PreviousEpoch.Committees, nil
+		return epc.PreviousEpoch, nil
 	} else if epoch == epc.CurrentEpoch.Epoch {
-		return epc.CurrentEpoch.Committees, nil
+		return epc.CurrentEpoch, nil
 	} else if epoch == epc.NextEpoch.Epoch {
-		return epc.NextEpoch.Committees, nil
+		return epc.NextEpoch, nil
 	} else {
 		return nil, fmt.Errorf("beacon committee retrieval: out of range epoch: %d", epoch)
 	}
 }
 
+func (epc *EpochsContext) getEpochComms(epoch Epoch) ([][][]ValidatorIndex, error) {
+	shuf, err := epc.getShufflingEpoch(epoch)
+	if err != nil {
+		return nil, err
+	}
+	return shuf.Committees, nil
+}
+
 // Return the beacon committee at slot for index.
 func (epc *EpochsContext) GetBeaconCommittee(slot Slot, index CommitteeIndex) ([]ValidatorIndex, error) {
 	if index >= CommitteeIndex(epc.Spec.MAX_COMMITTEES_PER_SLOT) {
@@ -327,7 +426,10 @@ func (epc *EpochsContext) GetBeaconCommittee(slot Slot, index CommitteeIndex) ([
 
 func (epc *EpochsContext) GetCommitteeCountPerSlot(epoch Epoch) (uint64, error) {
 	epochComms, err := epc.getEpochComms(epoch)
-	return uint64(len(epochComms[0])), err
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(epochComms[0])), nil
 }
 
 func (epc *EpochsContext) GetBeaconProposer(slot Slot) (ValidatorIndex, error) {
@@ -0,0 +1,334 @@
+package common
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/protolambda/zrnt/eth2/util/math"
+)
+
+// ErrStaleSnapshot is returned when an EpochsContext snapshot does not match the state it is loaded for.
+// The context should then be computed from the state with NewEpochsContext instead.
+var ErrStaleSnapshot = errors.New("epochs context snapshot does not match the state")
+
+var epochsContextSnapshotMagic = [4]byte{'e', 'p', 'c', 's'}
+
+// epochsContextSnapshotVersion is incremented on every incompatible change to the snapshot format.
+const epochsContextSnapshotVersion = 1
+
+// WriteSnapshot encodes the shufflings, proposers, sync committees and stake of the context,
+// in a compact binary format, to be restored with LoadEpochsContextSnapshot.
+// Validator indices and balances are varint encoded, and the active indices as deltas.
+// The pubkey cache is not part of the snapshot, see PubkeyCache.WritePubkeys to persist it.
+func (epc *EpochsContext) WriteSnapshot(w io.Writer) error {
+	sw := snapshotWriter{w: bufio.NewWriter(w)}
+	sw.write(epochsContextSnapshotMagic[:])
+	sw.uvarint(epochsContextSnapshotVersion)
+	sw.uvarint(uint64(len(epc.EffectiveBalances)))
+	for _, shuf := range []*ShufflingEpoch{epc.PreviousEpoch, epc.CurrentEpoch, epc.NextEpoch} {
+		sw.uvarint(uint64(shuf.Epoch))
+		sw.write(shuf.Seed[:])
+		sw.uvarint(uint64(len(shuf.ActiveIndices)))
+		prev := ValidatorIndex(0)
+		for _, vi := range shuf.ActiveIndices {
+			sw.uvarint(uint64(vi - prev))
+			prev = vi
+		}
+		for _, vi := range shuf.Shuffling {
+			sw.uvarint(uint64(vi))
+		}
+	}
+	sw.uvarint(uint64(epc.Proposers.Epoch))
+	sw.write(epc.Proposers.Seed[:])
+	sw.uvarint(epc.Proposers.CommitteesPerSlot)
+	sw.indices(epc.Proposers.Proposers)
+	if epc.CurrentSyncCommittee != nil && epc.NextSyncCommittee != nil {
+		sw.uvarint(1)
+		sw.indices(epc.CurrentSyncCommittee.Indices)
+		sw.indices(epc.NextSyncCommittee.Indices)
+	} else {
+		sw.uvarint(0)
+	}
+	for _, eff := range epc.EffectiveBalances {
+		sw.uvarint(uint64(eff))
+	}
+	sw.uvarint(uint64(epc.TotalActiveStake))
+	if sw.err != nil {
+		return sw.err
+	}
+	return sw.w.Flush()
+}
+
+// LoadEpochsContextSnapshot decodes a snapshot written with WriteSnapshot, for the processing of the given state.
+// The snapshot is checked to be consistent with the state cheaply: the epoch, the validator count,
+// the seeds of the shufflings and proposers, derived from the randao mixes of the state,
+// and the sync committee pubkeys must all match. If not, an error wrapping ErrStaleSnapshot is returned.
+// The pubkey cache must contain all validators of the state, see PubkeyCache.SyncValidators.
+func LoadEpochsContextSnapshot(spec *Spec, state BeaconState, pc *PubkeyCache, r io.Reader) (*EpochsContext, error) {
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	valCount, err := vals.ValidatorCount()
+	if err != nil {
+		return nil, err
+	}
+	epc, err := readEpochsContextSnapshot(spec, pc, valCount, bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode epochs context snapshot: %w", err)
+	}
+	if err := epc.checkSnapshot(state); err != nil {
+		return nil, err
+	}
+	return epc, nil
+}
+
+// readEpochsContextSnapshot decodes a snapshot of a state with the given number of validators.
+// The validator count is checked before anything is allocated, the snapshot may be corrupt.
+func readEpochsContextSnapshot(spec *Spec, pc *PubkeyCache, stateValCount uint64, r *bufio.Reader) (*EpochsContext, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, err
+	}
+	if magic != epochsContextSnapshotMagic {
+		return nil, errors.New("not an epochs context snapshot")
+	}
+	sr := snapshotReader{r: r}
+	if version := sr.uvarint(); sr.err == nil && version != epochsContextSnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected %d", version, epochsContextSnapshotVersion)
+	}
+	valCount := sr.uvarint()
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	if valCount != stateValCount {
+		return nil, fmt.Errorf("%w: snapshot with %d validators, state with %d", ErrStaleSnapshot, valCount, stateValCount)
+	}
+	epc := &EpochsContext{
+		Spec:                 spec,
+		ValidatorPubkeyCache: pc,
+	}
+	var shufs [3]*ShufflingEpoch
+	for i := range shufs {
+		shuf := &ShufflingEpoch{Epoch: Epoch(sr.uvarint())}
+		sr.read(shuf.Seed[:])
+		activeCount := sr.uvarint()
+		if sr.err != nil {
+			return nil, sr.err
+		}
+		if activeCount > valCount {
+			return nil, fmt.Errorf("active validator count %d of epoch %d exceeds validator count %d", activeCount, shuf.Epoch, valCount)
+		}
+		shuf.ActiveIndices = make([]ValidatorIndex, activeCount, activeCount)
+		prev := uint64(0)
+		for j := range shuf.ActiveIndices {
+			prev += sr.uvarint()
+			shuf.ActiveIndices[j] = ValidatorIndex(prev)
+		}
+		shuf.Shuffling = make([]ValidatorIndex, activeCount, activeCount)
+		for j := range shuf.Shuffling {
+			shuf.Shuffling[j] = ValidatorIndex(sr.uvarint())
+		}
+		shuf.sliceCommittees(spec)
+		shufs[i] = shuf
+	}
+	epc.PreviousEpoch, epc.CurrentEpoch, epc.NextEpoch = shufs[0], shufs[1], shufs[2]
+	if epc.PreviousEpoch.Epoch == epc.CurrentEpoch.Epoch { // in case of genesis
+		epc.PreviousEpoch = epc.CurrentEpoch
+	}
+	epc.Proposers = &ProposersEpoch{Spec: spec, Epoch: Epoch(sr.uvarint())}
+	sr.read(epc.Proposers.Seed[:])
+	epc.Proposers.CommitteesPerSlot = sr.uvarint()
+	epc.Proposers.Proposers = sr.indices(uint64(spec.SLOTS_PER_EPOCH))
+	if hasSyncCommittees := sr.uvarint(); hasSyncCommittees != 0 {
+		var err error
+		epc.CurrentSyncCommittee, err = epc.indexedSyncCommittee(sr.indices(uint64(spec.SYNC_COMMITTEE_SIZE)))
+		if err != nil {
+			return nil, err
+		}
+		epc.NextSyncCommittee, err = epc.indexedSyncCommittee(sr.indices(uint64(spec.SYNC_COMMITTEE_SIZE)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	effBalances := make([]Gwei, valCount, valCount)
+	for i := range effBalances {
+		effBalances[i] = Gwei(sr.uvarint())
+	}
+	epc.EffectiveBalances = effBalances
+	epc.effectiveBalancesOwner = epc
+	epc.TotalActiveStake = Gwei(sr.uvarint())
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	epc.TotalActiveStakeSqRoot = Gwei(math.IntegerSquareroot(uint64(epc.TotalActiveStake)))
+	return epc, nil
+}
+
+// indexedSyncCommittee looks up the pubkeys of the sync committee members.
+// Indices are checked against the validator count of the snapshot later on, pubkeys must be known already.
+func (epc *EpochsContext) indexedSyncCommittee(indices []ValidatorIndex) (*IndexedSyncCommittee, error) {
+	cachedPubs := make([]*CachedPubkey, len(indices), len(indices))
+	for i, idx := range indices {
+		pub, ok := epc.ValidatorPubkeyCache.Pubkey(idx)
+		if !ok {
+			return nil, fmt.Errorf("missing pubkey of sync committee member %d, validator %d", i, idx)
+		}
+		cachedPubs[i] = pub
+	}
+	return &IndexedSyncCommittee{
+		CachedPubkeys: cachedPubs,
+		Indices:       indices,
+	}, nil
+}
+
+func (epc *EpochsContext) checkSnapshot(state BeaconState) error {
+	slot, err := state.Slot()
+	if err != nil {
+		return err
+	}
+	if epoch := epc.Spec.SlotToEpoch(slot); epoch != epc.CurrentEpoch.Epoch || epoch != epc.Proposers.Epoch {
+		return fmt.Errorf("%w: snapshot of epoch %d, state at epoch %d", ErrStaleSnapshot, epc.CurrentEpoch.Epoch, epoch)
+	}
+	// the validator count is checked against the state while reading the snapshot
+	valCount := uint64(len(epc.EffectiveBalances))
+	mixes, err := state.RandaoMixes()
+	if err != nil {
+		return err
+	}
+	for _, shuf := range []*ShufflingEpoch{epc.PreviousEpoch, epc.CurrentEpoch, epc.NextEpoch} {
+		seed, err := GetSeed(epc.Spec, mixes, shuf.Epoch, DOMAIN_BEACON_ATTESTER)
+		if err != nil {
+			return err
+		}
+		if seed != shuf.Seed {
+			return fmt.Errorf("%w: attester seed of epoch %d differs", ErrStaleSnapshot, shuf.Epoch)
+		}
+		for _, vi := range shuf.Shuffling {
+			if uint64(vi) >= valCount {
+				return fmt.Errorf("%w: shuffling of epoch %d has unknown validator %d", ErrStaleSnapshot, shuf.Epoch, vi)
+			}
+		}
+	}
+	seed, err := GetSeed(epc.Spec, mixes, epc.Proposers.Epoch, DOMAIN_BEACON_PROPOSER)
+	if err != nil {
+		return err
+	}
+	if seed != epc.Proposers.Seed {
+		return fmt.Errorf("%w: proposer seed of epoch %d differs", ErrStaleSnapshot, epc.Proposers.Epoch)
+	}
+	for _, vi := range epc.Proposers.Proposers {
+		if uint64(vi) >= valCount {
+			return fmt.Errorf("%w: proposers of epoch %d have unknown validator %d", ErrStaleSnapshot, epc.Proposers.Epoch, vi)
+		}
+	}
+	syncState, ok := state.(SyncCommitteeBeaconState)
+	if ok != (epc.CurrentSyncCommittee != nil) {
+		return fmt.Errorf("%w: sync committees do not match the fork of the state", ErrStaleSnapshot)
+	}
+	if ok {
+		current, err := syncState.CurrentSyncCommittee()
+		if err != nil {
+			return err
+		}
+		if err := checkSnapshotSyncCommittee(current, epc.CurrentSyncCommittee); err != nil {
+			return fmt.Errorf("current sync committee: %w", err)
+		}
+		next, err := syncState.NextSyncCommittee()
+		if err != nil {
+			return err
+		}
+		if err := checkSnapshotSyncCommittee(next, epc.NextSyncCommittee); err != nil {
+			return fmt.Errorf("next sync committee: %w", err)
+		}
+	}
+	return nil
+}
+
+func checkSnapshotSyncCommittee(view *SyncCommitteeView, isc *IndexedSyncCommittee) error {
+	pubsView, err := view.Pubkeys()
+	if err != nil {
+		return err
+	}
+	pubs, err := pubsView.Flatten()
+	if err != nil {
+		return err
+	}
+	if len(pubs) != len(isc.CachedPubkeys) {
+		return fmt.Errorf("%w: sync committee size %d, expected %d", ErrStaleSnapshot, len(isc.CachedPubkeys), len(pubs))
+	}
+	for i, pub := range pubs {
+		if isc.CachedPubkeys[i].Compressed != pub {
+			return fmt.Errorf("%w: sync committee member %d differs", ErrStaleSnapshot, i)
+		}
+	}
+	return nil
+}
+
+// snapshotWriter keeps the first write error, to check once at the end.
+type snapshotWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (sw *snapshotWriter) write(data []byte) {
+	if sw.err == nil {
+		_, sw.err = sw.w.Write(data)
+	}
+}
+
+func (sw *snapshotWriter) uvarint(v uint64) {
+	sw.write(sw.buf[:binary.PutUvarint(sw.buf[:], v)])
+}
+
+func (sw *snapshotWriter) indices(indices []ValidatorIndex) {
+	sw.uvarint(uint64(len(indices)))
+	for _, vi := range indices {
+		sw.uvarint(uint64(vi))
+	}
+}
+
+// snapshotReader keeps the first read error, to check once in a while. Values read after an error are zero.
+type snapshotReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (sr *snapshotReader) read(dst []byte) {
+	if sr.err == nil {
+		_, sr.err = io.ReadFull(sr.r, dst)
+	}
+}
+
+func (sr *snapshotReader) uvarint() uint64 {
+	if sr.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(sr.r)
+	sr.err = err
+	return v
+}
+
+// indices reads a list of validator indices, of exactly the expected length.
+func (sr *snapshotReader) indices(expected uint64) []ValidatorIndex {
+	count := sr.uvarint()
+	if sr.err != nil {
+		return nil
+	}
+	if count != expected {
+		sr.err = fmt.Errorf("expected %d validator indices, got %d", expected, count)
+		return nil
+	}
+	out := make([]ValidatorIndex, count, count)
+	for i := range out {
+		out[i] = ValidatorIndex(sr.uvarint())
+	}
+	return out
+}
@@ -0,0 +1,210 @@
+package common_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+
+	kbls "github.com/kilic/bls12-381"
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+// testValidators creates validators with cheap, but valid and distinct, pubkeys.
+func testValidators(count uint64, balance common.Gwei) []phase0.KickstartValidatorData {
+	out := make([]phase0.KickstartValidatorData, 0, count)
+	g1 := kbls.NewG1()
+	for i := uint64(0); i < count; i++ {
+		var pub kbls.PointG1
+		g1.MulScalarBig(&pub, g1.One(), big.NewInt(int64(i+1)))
+		withdrawalCred := common.Root{0xbb}
+		binary.LittleEndian.PutUint64(withdrawalCred[1:], i)
+		out = append(out, phase0.KickstartValidatorData{
+			Pubkey:                common.BLSPubkey((*blsu.Pubkey)(&pub).Serialize()),
+			WithdrawalCredentials: withdrawalCred,
+			Balance:               balance,
+		})
+	}
+	return out
+}
+
+// testEpochStates creates a phase0 state, and its altair upgrade, at the last slot of epoch 2.
+func testEpochStates(t *testing.T, spec *common.Spec, validatorCount uint64) (*phase0.BeaconStateView, *altair.BeaconStateView) {
+	pre, _, err := phase0.KickStartState(spec, common.Root{123}, 1564000000, testValidators(validatorCount, spec.MAX_EFFECTIVE_BALANCE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pre.SetSlot(spec.SLOTS_PER_EPOCH*3 - 1); err != nil {
+		t.Fatal(err)
+	}
+	epc, err := common.NewEpochsContext(spec, pre)
+	if err != nil {
+		t.Fatal(err)
+	}
+	preCopy, err := pre.CopyState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := altair.UpgradeToAltair(spec, epc, preCopy.(*phase0.BeaconStateView))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pre, state
+}
+
+func checkEpochsContextEqual(t *testing.T, got *common.EpochsContext, expected *common.EpochsContext) {
+	t.Helper()
+	for i, pair := range [][2]*common.ShufflingEpoch{
+		{got.PreviousEpoch, expected.PreviousEpoch},
+		{got.CurrentEpoch, expected.CurrentEpoch},
+		{got.NextEpoch, expected.NextEpoch},
+	} {
+		a, b := pair[0], pair[1]
+		if a.Epoch != b.Epoch || a.Seed != b.Seed {
+			t.Fatalf("shuffling %d: got epoch %d (seed %s), expected epoch %d (seed %s)", i, a.Epoch, a.Seed, b.Epoch, b.Seed)
+		}
+		if len(a.ActiveIndices) != len(b.ActiveIndices) || len(a.Shuffling) != len(b.Shuffling) {
+			t.Fatalf("shuffling %d: different number of active validators", i)
+		}
+		for j := range b.ActiveIndices {
+			if a.ActiveIndices[j] != b.ActiveIndices[j] || a.Shuffling[j] != b.Shuffling[j] {
+				t.Fatalf("shuffling %d: differs at %d", i, j)
+			}
+		}
+		for slot := range b.Committees {
+			if len(a.Committees[slot]) != len(b.Committees[slot]) {
+				t.Fatalf("shuffling %d: different committee count at slot %d", i, slot)
+			}
+		}
+	}
+	if got.Proposers.Epoch != expected.Proposers.Epoch || got.Proposers.Seed != expected.Proposers.Seed ||
+		got.Proposers.CommitteesPerSlot != expected.Proposers.CommitteesPerSlot {
+		t.Fatal("proposers epoch data differs")
+	}
+	for i := range expected.Proposers.Proposers {
+		if got.Proposers.Proposers[i] != expected.Proposers.Proposers[i] {
+			t.Fatalf("proposer %d differs", i)
+		}
+	}
+	for name, pair := range map[string][2]*common.IndexedSyncCommittee{
+		"current": {got.CurrentSyncCommittee, expected.CurrentSyncCommittee},
+		"next":    {got.NextSyncCommittee, expected.NextSyncCommittee},
+	} {
+		a, b := pair[0], pair[1]
+		if (a == nil) != (b == nil) {
+			t.Fatalf("%s sync committee presence differs", name)
+		}
+		if b == nil {
+			continue
+		}
+		for i := range b.Indices {
+			if a.Indices[i] != b.Indices[i] || a.CachedPubkeys[i].Compressed != b.CachedPubkeys[i].Compressed {
+				t.Fatalf("%s sync committee member %d differs", name, i)
+			}
+		}
+	}
+	if len(got.EffectiveBalances) != len(expected.EffectiveBalances) {
+		t.Fatal("effective balances count differs")
+	}
+	for i := range expected.EffectiveBalances {
+		if got.EffectiveBalances[i] != expected.EffectiveBalances[i] {
+			t.Fatalf("effective balance %d differs", i)
+		}
+	}
+	if got.TotalActiveStake != expected.TotalActiveStake || got.TotalActiveStakeSqRoot != expected.TotalActiveStakeSqRoot {
+		t.Fatal("total active stake differs")
+	}
+}
+
+func TestEpochsContextSnapshot(t *testing.T) {
+	spec := configs.Mainnet
+	phase0State, altairState := testEpochStates(t, spec, 1000)
+	for name, state := range map[string]common.BeaconState{
+		"phase0": phase0State,
+		"altair": altairState,
+	} {
+		t.Run(name, func(t *testing.T) {
+			epc, err := common.NewEpochsContext(spec, state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := epc.WriteSnapshot(&buf); err != nil {
+				t.Fatal(err)
+			}
+			snapshot := buf.Bytes()
+			got, err := common.LoadEpochsContextSnapshot(spec, state, epc.ValidatorPubkeyCache, bytes.NewReader(snapshot))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEpochsContextEqual(t, got, epc)
+
+			// truncated snapshots must not decode
+			for _, size := range []int{0, 3, len(snapshot) / 2, len(snapshot) - 1} {
+				if _, err := common.LoadEpochsContextSnapshot(spec, state, epc.ValidatorPubkeyCache, bytes.NewReader(snapshot[:size])); err == nil {
+					t.Fatalf("expected error for snapshot truncated to %d bytes", size)
+				}
+			}
+
+			// a different randao history changes the seeds
+			s, err := state.CopyState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			mixes, err := s.RandaoMixes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for epoch := common.Epoch(0); epoch < 4; epoch++ {
+				if err := mixes.SetRandomMix(epoch, common.Root{0xaa}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := common.LoadEpochsContextSnapshot(spec, s, epc.ValidatorPubkeyCache, bytes.NewReader(snapshot)); !errors.Is(err, common.ErrStaleSnapshot) {
+				t.Fatalf("expected stale snapshot after randao change, got: %v", err)
+			}
+
+			// a different validator count
+			s, err = state.CopyState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := testValidators(1001, spec.MAX_EFFECTIVE_BALANCE)[1000]
+			if err := s.AddValidator(spec, v.Pubkey, v.WithdrawalCredentials, v.Balance); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := common.LoadEpochsContextSnapshot(spec, s, epc.ValidatorPubkeyCache, bytes.NewReader(snapshot)); !errors.Is(err, common.ErrStaleSnapshot) {
+				t.Fatalf("expected stale snapshot after validator change, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestEpochsContextSnapshotCorrupt(t *testing.T) {
+	spec := configs.Mainnet
+	state, _ := testEpochStates(t, spec, 100)
+	epc, err := common.NewEpochsContext(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := append([]byte("epcs"), 1)
+	// a validator count up to the registry limit, that must not be allocated for
+	hugeCount := binary.AppendUvarint(header, uint64(spec.VALIDATOR_REGISTRY_LIMIT))
+	if _, err := common.LoadEpochsContextSnapshot(spec, state, epc.ValidatorPubkeyCache, bytes.NewReader(hugeCount)); !errors.Is(err, common.ErrStaleSnapshot) {
+		t.Fatalf("expected stale snapshot for huge validator count, got: %v", err)
+	}
+	// the right validator count, but more active validators than that
+	hugeActive := binary.AppendUvarint(header, 100)
+	hugeActive = binary.AppendUvarint(hugeActive, 0)
+	hugeActive = append(hugeActive, make([]byte, 32)...)
+	hugeActive = binary.AppendUvarint(hugeActive, uint64(spec.VALIDATOR_REGISTRY_LIMIT))
+	if _, err := common.LoadEpochsContextSnapshot(spec, state, epc.ValidatorPubkeyCache, bytes.NewReader(hugeActive)); err == nil {
+		t.Fatal("expected error for huge active validator count")
+	}
+}
@@ -0,0 +1,24 @@
+package common
+
+import "testing"
+
+func TestAppendEffectiveBalanceClones(t *testing.T) {
+	epc := &EpochsContext{}
+	epc.EffectiveBalances = make([]Gwei, 2, 10)
+	epc.effectiveBalancesOwner = epc
+	a := epc.Clone()
+	b := epc.Clone()
+	if err := a.AppendEffectiveBalance(nil, 2, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AppendEffectiveBalance(nil, 2, 200); err != nil {
+		t.Fatal(err)
+	}
+	if err := epc.AppendEffectiveBalance(nil, 2, 300); err != nil {
+		t.Fatal(err)
+	}
+	if a.EffectiveBalances[2] != 100 || b.EffectiveBalances[2] != 200 || epc.EffectiveBalances[2] != 300 {
+		t.Fatalf("clones affected each other: %d, %d, %d",
+			a.EffectiveBalances[2], b.EffectiveBalances[2], epc.EffectiveBalances[2])
+	}
+}
@@ -1,5 +1,7 @@
 package common
 
+import "context"
+
 type FlatValidator struct {
 	EffectiveBalance           Gwei
 	Slashed                    bool
@@ -14,23 +16,60 @@ func (v *FlatValidator) IsActive(epoch Epoch) bool {
 }
 
 func FlattenValidators(vals ValidatorRegistry) ([]FlatValidator, error) {
+	return FlattenValidatorsContext(context.Background(), Parallelism{}, vals)
+}
+
+// FlattenValidatorsContext flattens the validator registry, splitting the registry in ranges across workers.
+func FlattenValidatorsContext(ctx context.Context, p Parallelism, vals ValidatorRegistry) ([]FlatValidator, error) {
 	count, err := vals.ValidatorCount()
 	if err != nil {
 		return nil, err
 	}
 	out := make([]FlatValidator, count, count)
-	next := vals.Iter()
-	for i := uint64(0); i < count; i++ {
-		v, ok, err := next()
-		if err != nil {
-			return nil, err
-		}
-		if !ok {
-			break
+	ranges := p.SplitRanges(count)
+	if len(ranges) == 1 {
+		// Iterating is cheaper than looking up every validator separately
+		next := vals.Iter()
+		for i := uint64(0); i < count; i++ {
+			// every 1024 validators, check if the context is done.
+			if i&((1<<10)-1) == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+			v, ok, err := next()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+			if err := v.Flatten(&out[i]); err != nil {
+				return nil, err
+			}
 		}
-		if err := v.Flatten(&out[i]); err != nil {
-			return nil, err
+		return out, nil
+	}
+	// Reading the validators tree concurrently is safe, as long as it is not modified in the meantime.
+	err = ForEachRange(ctx, ranges, func(ctx context.Context, _ int, r IndexRange) error {
+		for i := r.Start; i < r.End; i++ {
+			if (i-r.Start)&((1<<10)-1) == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			v, err := vals.Validator(ValidatorIndex(i))
+			if err != nil {
+				return err
+			}
+			if err := v.Flatten(&out[i]); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return out, nil
 }
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"encoding/json"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
 	. "github.com/protolambda/ztyp/view"
@@ -36,6 +37,13 @@ func (p CommitteeIndices) HashTreeRoot(spec *Spec, hFn tree.HashFn) Root {
 	}, uint64(len(p)), uint64(spec.MAX_VALIDATORS_PER_COMMITTEE))
 }
 
+func (p CommitteeIndices) MarshalJSON() ([]byte, error) {
+	if p == nil {
+		return json.Marshal([]ValidatorIndex{}) // encode as empty list, not null
+	}
+	return json.Marshal([]ValidatorIndex(p))
+}
+
 func (c *Phase0Preset) CommitteeIndices() ListTypeDef {
 	return ListType(ValidatorIndexType, uint64(c.MAX_VALIDATORS_PER_COMMITTEE))
 }
@@ -70,6 +78,13 @@ func (p SlotCommitteeIndices) HashTreeRoot(spec *Spec, hFn tree.HashFn) Root {
 	}, uint64(len(p)), uint64(spec.MAX_VALIDATORS_PER_COMMITTEE*spec.MAX_COMMITTEES_PER_SLOT))
 }
 
+func (p SlotCommitteeIndices) MarshalJSON() ([]byte, error) {
+	if p == nil {
+		return json.Marshal([]ValidatorIndex{}) // encode as empty list, not null
+	}
+	return json.Marshal([]ValidatorIndex(p))
+}
+
 func SlotCommitteeIndicesType(c *Spec) ListTypeDef {
 	return ListType(ValidatorIndexType, uint64(c.MAX_VALIDATORS_PER_COMMITTEE*c.MAX_COMMITTEES_PER_SLOT))
 }
@@ -2,6 +2,7 @@ package common
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -45,6 +46,25 @@ type AttnetBits [attnetByteLen]byte
 func (ab *AttnetBits) BitLen() uint64 {
 	return ATTESTATION_SUBNET_COUNT
 }
+
+func (p *AttnetBits) GetBit(i uint64) bool {
+	if i >= ATTESTATION_SUBNET_COUNT {
+		return false
+	}
+	return p[i>>3]&(1<<(i&7)) != 0
+}
+
+// SetBit sets the bit of the subnet, out of range subnets are ignored.
+func (p *AttnetBits) SetBit(i uint64, v bool) {
+	if i >= ATTESTATION_SUBNET_COUNT {
+		return
+	}
+	if v {
+		p[i>>3] |= 1 << (i & 7)
+	} else {
+		p[i>>3] &^= 1 << (i & 7)
+	}
+}
 func (p *AttnetBits) Deserialize(dr *codec.DecodingReader) error {
 	if p == nil {
 		return errors.New("nil attnet bits")
@@ -100,6 +120,25 @@ func (ab *SyncnetBits) BitLen() uint64 {
 	return SYNC_COMMITTEE_SUBNET_COUNT
 }
 
+func (p *SyncnetBits) GetBit(i uint64) bool {
+	if i >= SYNC_COMMITTEE_SUBNET_COUNT {
+		return false
+	}
+	return p[i>>3]&(1<<(i&7)) != 0
+}
+
+// SetBit sets the bit of the subnet, out of range subnets are ignored.
+func (p *SyncnetBits) SetBit(i uint64, v bool) {
+	if i >= SYNC_COMMITTEE_SUBNET_COUNT {
+		return
+	}
+	if v {
+		p[i>>3] |= 1 << (i & 7)
+	} else {
+		p[i>>3] &^= 1 << (i & 7)
+	}
+}
+
 func (p *SyncnetBits) Deserialize(dr *codec.DecodingReader) error {
 	if p == nil {
 		return errors.New("nil syncnet bits")
@@ -367,3 +406,107 @@ func (i *Goodbye) UnmarshalJSON(b []byte) error {
 func (i Goodbye) String() string {
 	return Uint64View(i).String()
 }
+
+type BeaconBlocksByRangeRequest struct {
+	StartSlot Slot       `json:"start_slot" yaml:"start_slot"`
+	Count     Uint64View `json:"count" yaml:"count"`
+	// Deprecated, must be 1
+	Step Uint64View `json:"step" yaml:"step"`
+}
+
+func (d *BeaconBlocksByRangeRequest) Deserialize(dr *codec.DecodingReader) error {
+	return dr.FixedLenContainer(&d.StartSlot, &d.Count, &d.Step)
+}
+
+func (d *BeaconBlocksByRangeRequest) Serialize(w *codec.EncodingWriter) error {
+	return w.FixedLenContainer(&d.StartSlot, &d.Count, &d.Step)
+}
+
+const BeaconBlocksByRangeRequestByteLen = 8 + 8 + 8
+
+func (d BeaconBlocksByRangeRequest) ByteLength() uint64 {
+	return BeaconBlocksByRangeRequestByteLen
+}
+
+func (*BeaconBlocksByRangeRequest) FixedLength() uint64 {
+	return BeaconBlocksByRangeRequestByteLen
+}
+
+func (d *BeaconBlocksByRangeRequest) HashTreeRoot(hFn tree.HashFn) Root {
+	return hFn.HashTreeRoot(&d.StartSlot, &d.Count, &d.Step)
+}
+
+func (r *BeaconBlocksByRangeRequest) String() string {
+	return fmt.Sprintf("BeaconBlocksByRangeRequest(start_slot: %d, count: %d, step: %d)", r.StartSlot, r.Count, r.Step)
+}
+
+// BeaconBlocksByRootRequest is a list of block roots, up to MAX_REQUEST_BLOCKS.
+type BeaconBlocksByRootRequest []Root
+
+func (r *BeaconBlocksByRootRequest) Deserialize(spec *Spec, dr *codec.DecodingReader) error {
+	return tree.ReadRootsLimited(dr, (*[]Root)(r), uint64(spec.MAX_REQUEST_BLOCKS))
+}
+
+func (r BeaconBlocksByRootRequest) Serialize(spec *Spec, w *codec.EncodingWriter) error {
+	return tree.WriteRoots(w, r)
+}
+
+func (r BeaconBlocksByRootRequest) ByteLength(spec *Spec) uint64 {
+	return uint64(len(r)) * 32
+}
+
+func (r *BeaconBlocksByRootRequest) FixedLength(spec *Spec) uint64 {
+	return 0 // it's a list, no fixed length
+}
+
+func (r BeaconBlocksByRootRequest) HashTreeRoot(spec *Spec, hFn tree.HashFn) Root {
+	length := uint64(len(r))
+	return hFn.ComplexListHTR(func(i uint64) tree.HTR {
+		if i < length {
+			return &r[i]
+		}
+		return nil
+	}, length, uint64(spec.MAX_REQUEST_BLOCKS))
+}
+
+func (r BeaconBlocksByRootRequest) MarshalJSON() ([]byte, error) {
+	if r == nil {
+		return json.Marshal([]Root{}) // encode as empty list, not null
+	}
+	return json.Marshal([]Root(r))
+}
+
+func (r BeaconBlocksByRootRequest) String() string {
+	return fmt.Sprintf("BeaconBlocksByRootRequest(roots: %d)", len(r))
+}
+
+type BlobSidecarsByRangeRequest struct {
+	StartSlot Slot       `json:"start_slot" yaml:"start_slot"`
+	Count     Uint64View `json:"count" yaml:"count"`
+}
+
+func (d *BlobSidecarsByRangeRequest) Deserialize(dr *codec.DecodingReader) error {
+	return dr.FixedLenContainer(&d.StartSlot, &d.Count)
+}
+
+func (d *BlobSidecarsByRangeRequest) Serialize(w *codec.EncodingWriter) error {
+	return w.FixedLenContainer(&d.StartSlot, &d.Count)
+}
+
+const BlobSidecarsByRangeRequestByteLen = 8 + 8
+
+func (d BlobSidecarsByRangeRequest) ByteLength() uint64 {
+	return BlobSidecarsByRangeRequestByteLen
+}
+
+func (*BlobSidecarsByRangeRequest) FixedLength() uint64 {
+	return BlobSidecarsByRangeRequestByteLen
+}
+
+func (d *BlobSidecarsByRangeRequest) HashTreeRoot(hFn tree.HashFn) Root {
+	return hFn.HashTreeRoot(&d.StartSlot, &d.Count)
+}
+
+func (r *BlobSidecarsByRangeRequest) String() string {
+	return fmt.Sprintf("BlobSidecarsByRangeRequest(start_slot: %d, count: %d)", r.StartSlot, r.Count)
+}
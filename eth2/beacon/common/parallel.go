@@ -0,0 +1,98 @@
+package common
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// DefaultMinRangeSize is the minimum number of items per worker if Parallelism.MinRangeSize is not set.
+const DefaultMinRangeSize uint64 = 1 << 13
+
+// Parallelism configures how epoch processing splits validator ranges across workers.
+// The zero value uses runtime.GOMAXPROCS workers, with ranges of at least DefaultMinRangeSize items.
+type Parallelism struct {
+	// MaxWorkers limits the number of goroutines that validator ranges are split across.
+	// If zero or negative, runtime.GOMAXPROCS is used. Set to 1 to process everything on the calling goroutine.
+	MaxWorkers int
+	// MinRangeSize is the minimum number of items per worker, smaller ranges are not worth the goroutine overhead.
+	// If zero, DefaultMinRangeSize is used.
+	MinRangeSize uint64
+}
+
+// IndexRange is the half-open range [Start, End) of indices.
+type IndexRange struct {
+	Start uint64
+	End   uint64
+}
+
+// SplitRanges splits [0, count) into contiguous ranges, one per worker.
+// The split only depends on count and the worker settings, and the ranges are ordered:
+// merging per-range results in range order is deterministic.
+func (p Parallelism) SplitRanges(count uint64) []IndexRange {
+	workers := uint64(p.MaxWorkers)
+	if p.MaxWorkers <= 0 {
+		workers = uint64(runtime.GOMAXPROCS(0))
+	}
+	minSize := p.MinRangeSize
+	if minSize == 0 {
+		minSize = DefaultMinRangeSize
+	}
+	if max := (count + minSize - 1) / minSize; workers > max {
+		workers = max
+	}
+	if workers == 0 {
+		workers = 1
+	}
+	out := make([]IndexRange, workers, workers)
+	size := count / workers
+	rem := count % workers
+	start := uint64(0)
+	for i := uint64(0); i < workers; i++ {
+		end := start + size
+		// spread the remainder over the first ranges
+		if i < rem {
+			end += 1
+		}
+		out[i] = IndexRange{Start: start, End: end}
+		start = end
+	}
+	return out
+}
+
+// ForEachRange calls fn for each of the ranges, each in its own goroutine, and waits for all of them to complete.
+// A single range is processed on the calling goroutine.
+// The context passed to fn is canceled as soon as any of the calls fails, or when the parent context is done.
+// The error of the first failing call is returned.
+func ForEachRange(ctx context.Context, ranges []IndexRange, fn func(ctx context.Context, i int, r IndexRange) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(ranges) == 1 {
+		return fn(ctx, 0, ranges[0])
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i := range ranges {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := fn(ctx, i, ranges[i]); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// ParallelRanges splits [0, count) with SplitRanges, and runs fn for each range with ForEachRange.
+func (p Parallelism) ParallelRanges(ctx context.Context, count uint64, fn func(ctx context.Context, i int, r IndexRange) error) error {
+	return ForEachRange(ctx, p.SplitRanges(count), fn)
+}
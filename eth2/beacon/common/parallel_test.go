@@ -0,0 +1,70 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSplitRanges(t *testing.T) {
+	p := Parallelism{MaxWorkers: 4, MinRangeSize: 10}
+	for _, c := range []struct {
+		count  uint64
+		ranges int
+	}{{0, 1}, {1, 1}, {10, 1}, {11, 2}, {39, 4}, {40, 4}, {1000, 4}, {1003, 4}} {
+		ranges := p.SplitRanges(c.count)
+		if len(ranges) != c.ranges {
+			t.Errorf("count %d: expected %d ranges, got %d", c.count, c.ranges, len(ranges))
+			continue
+		}
+		next := uint64(0)
+		for i, r := range ranges {
+			if r.Start != next {
+				t.Errorf("count %d: range %d starts at %d, expected %d", c.count, i, r.Start, next)
+			}
+			if r.End < r.Start {
+				t.Errorf("count %d: range %d is inverted", c.count, i)
+			}
+			next = r.End
+		}
+		if next != c.count {
+			t.Errorf("count %d: ranges end at %d", c.count, next)
+		}
+	}
+}
+
+func TestForEachRangeError(t *testing.T) {
+	ranges := []IndexRange{{0, 10}, {10, 20}, {20, 30}}
+	var sum uint64
+	err := ForEachRange(context.Background(), ranges, func(ctx context.Context, i int, r IndexRange) error {
+		atomic.AddUint64(&sum, r.End-r.Start)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 30 {
+		t.Fatalf("expected all 30 indices to be processed, got %d", sum)
+	}
+	fail := errors.New("fail")
+	err = ForEachRange(context.Background(), ranges, func(ctx context.Context, i int, r IndexRange) error {
+		if i == 1 {
+			return fail
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != fail {
+		t.Fatalf("expected failure of range 1, got: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = ForEachRange(ctx, ranges, func(ctx context.Context, i int, r IndexRange) error {
+		t.Fatal("unexpected call with canceled context")
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got: %v", err)
+	}
+}
@@ -11,6 +11,8 @@ import (
 type ProposersEpoch struct {
 	Spec  *Spec
 	Epoch Epoch
+	// Seed is the proposer seed of the epoch, used to check if the proposers are still consistent with a state.
+	Seed Root
 	// Proposers is a slice of SLOTS_PER_EPOCH proposer indices for the epoch
 	Proposers []ValidatorIndex
 
@@ -43,13 +45,14 @@ func ComputeProposers(spec *Spec, state BeaconState, epoch Epoch, active []Valid
 		return nil, err
 	}
 
+	epochSeed, err := GetSeed(spec, mixes, epoch, DOMAIN_BEACON_PROPOSER)
+	if err != nil {
+		return nil, err
+	}
+
 	hFn := hashing.GetHashFn()
 	// compute beacon proposers
 	{
-		epochSeed, err := GetSeed(spec, mixes, epoch, DOMAIN_BEACON_PROPOSER)
-		if err != nil {
-			return nil, err
-		}
 		var buf [32 + 8]byte
 		copy(buf[0:32], epochSeed[:])
 		for i := Slot(0); i < spec.SLOTS_PER_EPOCH; i++ {
@@ -69,6 +72,7 @@ func ComputeProposers(spec *Spec, state BeaconState, epoch Epoch, active []Valid
 	return &ProposersEpoch{
 		Spec:              spec,
 		Epoch:             epoch,
+		Seed:              epochSeed,
 		Proposers:         proposers,
 		CommitteesPerSlot: committeesPerSlot,
 	}, nil
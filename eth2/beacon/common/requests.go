@@ -1,6 +1,7 @@
 package common
 
 import (
+	"encoding/json"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
 	. "github.com/protolambda/ztyp/view"
@@ -144,6 +145,13 @@ func (li DepositRequests) HashTreeRoot(spec *Spec, hFn tree.HashFn) Root {
 	}, length, uint64(spec.MAX_DEPOSIT_REQUESTS_PER_PAYLOAD))
 }
 
+func (li DepositRequests) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]DepositRequest{}) // encode as empty list, not null
+	}
+	return json.Marshal([]DepositRequest(li))
+}
+
 type WithdrawalRequests []WithdrawalRequest
 
 func WithdrawalRequestsType(spec *Spec) ListTypeDef {
@@ -182,6 +190,13 @@ func (li WithdrawalRequests) HashTreeRoot(spec *Spec, hFn tree.HashFn) Root {
 	}, length, uint64(spec.MAX_WITHDRAWAL_REQUESTS_PER_PAYLOAD))
 }
 
+func (li WithdrawalRequests) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]WithdrawalRequest{}) // encode as empty list, not null
+	}
+	return json.Marshal([]WithdrawalRequest(li))
+}
+
 type ConsolidationRequests []ConsolidationRequest
 
 func ConsolidationRequestsType(spec *Spec) ListTypeDef {
@@ -219,3 +234,10 @@ func (li ConsolidationRequests) HashTreeRoot(spec *Spec, hFn tree.HashFn) Root {
 		return nil
 	}, length, uint64(spec.MAX_CONSOLIDATION_REQUESTS_PER_PAYLOAD))
 }
+
+func (li ConsolidationRequests) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]ConsolidationRequest{}) // encode as empty list, not null
+	}
+	return json.Marshal([]ConsolidationRequest(li))
+}
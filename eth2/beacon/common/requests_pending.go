@@ -1,6 +1,7 @@
 package common
 
 import (
+	"encoding/json"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
 	. "github.com/protolambda/ztyp/view"
@@ -142,6 +143,13 @@ func (li PendingDeposits) HashTreeRoot(spec *Spec, hFn tree.HashFn) Root {
 	}, length, uint64(spec.PENDING_DEPOSITS_LIMIT))
 }
 
+func (li PendingDeposits) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]PendingDeposit{}) // encode as empty list, not null
+	}
+	return json.Marshal([]PendingDeposit(li))
+}
+
 type PendingPartialWithdrawals []PendingPartialWithdrawal
 
 func PendingPartialWithdrawalsType(spec *Spec) ListTypeDef {
@@ -180,6 +188,13 @@ func (li PendingPartialWithdrawals) HashTreeRoot(spec *Spec, hFn tree.HashFn) Ro
 	}, length, uint64(spec.PENDING_PARTIAL_WITHDRAWALS_LIMIT))
 }
 
+func (li PendingPartialWithdrawals) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]PendingPartialWithdrawal{}) // encode as empty list, not null
+	}
+	return json.Marshal([]PendingPartialWithdrawal(li))
+}
+
 type PendingConsolidations []PendingConsolidation
 
 func PendingConsolidationsType(spec *Spec) ListTypeDef {
@@ -217,3 +232,10 @@ func (li PendingConsolidations) HashTreeRoot(spec *Spec, hFn tree.HashFn) Root {
 		return nil
 	}, length, uint64(spec.PENDING_CONSOLIDATIONS_LIMIT))
 }
+
+func (li PendingConsolidations) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]PendingConsolidation{}) // encode as empty list, not null
+	}
+	return json.Marshal([]PendingConsolidation(li))
+}
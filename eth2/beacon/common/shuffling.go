@@ -55,11 +55,16 @@ func CommitteeCount(spec *Spec, activeValidators uint64) uint64 {
 // With a high amount of shards, or low amount of validators,
 // some shards may not have a committee this epoch.
 type ShufflingEpoch struct {
-	Epoch         Epoch
+	Epoch Epoch
+	// Seed is the attester seed of the epoch, used to check if the shuffling is still consistent with a state.
+	Seed          Root
 	ActiveIndices []ValidatorIndex
 	Shuffling     []ValidatorIndex // the active validator indices, shuffled into their committee
 	// slot (vector SLOTS_PER_EPOCH) -> index of committee (< MAX_COMMITTEES_PER_SLOT) -> index of validator within committee -> validator
 	Committees [][][]ValidatorIndex // slices of Shuffling, 1 per slot. Committee can be nil slice.
+	// Aggregate pubkeys of the committees, computed when needed.
+	// The aggregates move along with the shuffling when the epochs are rotated.
+	Aggregates AggregatePubkeyCache
 }
 
 func ComputeShufflingEpoch(spec *Spec, state BeaconState, indicesBounded []BoundedIndex, epoch Epoch) (*ShufflingEpoch, error) {
@@ -87,6 +92,7 @@ func ActiveIndices(indicesBounded []BoundedIndex, epoch Epoch) []ValidatorIndex
 func NewShufflingEpoch(spec *Spec, indicesBounded []BoundedIndex, seed Root, epoch Epoch) *ShufflingEpoch {
 	shep := &ShufflingEpoch{
 		Epoch: epoch,
+		Seed:  seed,
 	}
 
 	shep.ActiveIndices = ActiveIndices(indicesBounded, epoch)
@@ -100,6 +106,12 @@ func NewShufflingEpoch(spec *Spec, indicesBounded []BoundedIndex, seed Root, epo
 	// (name is misleading, unshuffle as a list results in original indices to be traced back to their functional committee position)
 	UnshuffleList(uint8(spec.SHUFFLE_ROUND_COUNT), shep.Shuffling, seed)
 
+	shep.sliceCommittees(spec)
+	return shep
+}
+
+// sliceCommittees splits the shuffling into the committees of each slot.
+func (shep *ShufflingEpoch) sliceCommittees(spec *Spec) {
 	validatorCount := uint64(len(shep.Shuffling))
 	committeesPerSlot := CommitteeCount(spec, validatorCount)
 	committeeCount := committeesPerSlot * uint64(spec.SLOTS_PER_EPOCH)
@@ -114,5 +126,4 @@ func NewShufflingEpoch(spec *Spec, indicesBounded []BoundedIndex, seed Root, epo
 			shep.Committees[slot] = append(shep.Committees[slot], committee)
 		}
 	}
-	return shep
 }
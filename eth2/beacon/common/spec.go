@@ -14,6 +14,7 @@ const RANDOM_SUBNETS_PER_VALIDATOR = 1
 const EPOCHS_PER_RANDOM_SUBNET_SUBSCRIPTION = 256
 const BLS_WITHDRAWAL_PREFIX = 0
 const ETH1_ADDRESS_WITHDRAWAL_PREFIX = 1
+const COMPOUNDING_WITHDRAWAL_PREFIX = 2
 const SYNC_COMMITTEE_SUBNET_COUNT = 4
 const TARGET_AGGREGATORS_PER_SYNC_SUBCOMMITTEE = 16
 
@@ -350,6 +351,9 @@ type Spec struct {
 	Config          `json:",inline" yaml:",inline"`
 
 	ExecutionEngine `json:"-" yaml:"-"`
+
+	// Parallelism of the epoch processing, this is not part of the config.
+	Parallelism Parallelism `json:"-" yaml:"-"`
 }
 
 // Wraps the object to parametrize with given spec. JSON and YAML functionality is proxied to the inner value.
@@ -357,6 +361,36 @@ func (spec *Spec) Wrap(des SpecObj) SSZObj {
 	return &specObj{spec, des}
 }
 
+// Fork names, as used in the beacon-APIs, the configs and the spec tests.
+const (
+	Phase0    = "phase0"
+	Altair    = "altair"
+	Bellatrix = "bellatrix"
+	Capella   = "capella"
+	Deneb     = "deneb"
+	Electra   = "electra"
+	Fulu      = "fulu"
+)
+
+// ForkName returns the name of the fork that is active at the given epoch.
+func (spec *Spec) ForkName(epoch Epoch) string {
+	if epoch < spec.ALTAIR_FORK_EPOCH {
+		return Phase0
+	} else if epoch < spec.BELLATRIX_FORK_EPOCH {
+		return Altair
+	} else if epoch < spec.CAPELLA_FORK_EPOCH {
+		return Bellatrix
+	} else if epoch < spec.DENEB_FORK_EPOCH {
+		return Capella
+	} else if epoch < spec.ELECTRA_FORK_EPOCH {
+		return Deneb
+	} else if epoch < spec.FULU_FORK_EPOCH {
+		return Electra
+	} else {
+		return Fulu
+	}
+}
+
 func (spec *Spec) ForkVersion(slot Slot) Version {
 	epoch := spec.SlotToEpoch(slot)
 	if epoch < spec.ALTAIR_FORK_EPOCH {
@@ -366,8 +400,10 @@ func (spec *Spec) ForkVersion(slot Slot) Version {
 	} else if epoch < spec.CAPELLA_FORK_EPOCH {
 		return spec.BELLATRIX_FORK_VERSION
 	} else if epoch < spec.DENEB_FORK_EPOCH {
-		return spec.DENEB_FORK_VERSION
+		return spec.CAPELLA_FORK_VERSION
 	} else if epoch < spec.ELECTRA_FORK_EPOCH {
+		return spec.DENEB_FORK_VERSION
+	} else if epoch < spec.FULU_FORK_EPOCH {
 		return spec.ELECTRA_FORK_VERSION
 	} else {
 		return spec.FULU_FORK_VERSION
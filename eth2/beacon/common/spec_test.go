@@ -0,0 +1,52 @@
+package common
+
+import "testing"
+
+func TestForkVersion(t *testing.T) {
+	var spec Spec
+	spec.SLOTS_PER_EPOCH = 8
+	spec.GENESIS_FORK_VERSION = Version{0, 0, 0, 1}
+	spec.ALTAIR_FORK_VERSION = Version{1, 0, 0, 1}
+	spec.ALTAIR_FORK_EPOCH = 1
+	spec.BELLATRIX_FORK_VERSION = Version{2, 0, 0, 1}
+	spec.BELLATRIX_FORK_EPOCH = 2
+	spec.CAPELLA_FORK_VERSION = Version{3, 0, 0, 1}
+	spec.CAPELLA_FORK_EPOCH = 3
+	spec.DENEB_FORK_VERSION = Version{4, 0, 0, 1}
+	spec.DENEB_FORK_EPOCH = 4
+	spec.ELECTRA_FORK_VERSION = Version{5, 0, 0, 1}
+	spec.ELECTRA_FORK_EPOCH = 5
+	spec.FULU_FORK_VERSION = Version{6, 0, 0, 1}
+	spec.FULU_FORK_EPOCH = 6
+	cases := []struct {
+		slot     Slot
+		expected Version
+	}{
+		{0, spec.GENESIS_FORK_VERSION},
+		{7, spec.GENESIS_FORK_VERSION},
+		{8, spec.ALTAIR_FORK_VERSION},
+		{15, spec.ALTAIR_FORK_VERSION},
+		{16, spec.BELLATRIX_FORK_VERSION},
+		{23, spec.BELLATRIX_FORK_VERSION},
+		{24, spec.CAPELLA_FORK_VERSION},
+		{31, spec.CAPELLA_FORK_VERSION},
+		{32, spec.DENEB_FORK_VERSION},
+		{39, spec.DENEB_FORK_VERSION},
+		{40, spec.ELECTRA_FORK_VERSION},
+		{47, spec.ELECTRA_FORK_VERSION},
+		{48, spec.FULU_FORK_VERSION},
+		{1000, spec.FULU_FORK_VERSION},
+	}
+	for _, c := range cases {
+		if got := spec.ForkVersion(c.slot); got != c.expected {
+			t.Errorf("slot %d: got fork version %s, expected %s", c.slot, got, c.expected)
+		}
+	}
+
+	// forks that are scheduled at the same epoch are skipped to the last of them
+	spec.CAPELLA_FORK_EPOCH = 2
+	spec.DENEB_FORK_EPOCH = 2
+	if got := spec.ForkVersion(16); got != spec.DENEB_FORK_VERSION {
+		t.Errorf("got fork version %s, expected deneb version", got)
+	}
+}
@@ -15,6 +15,8 @@ type BatchRoots interface {
 
 type HistoricalRoots interface {
 	Append(root Root) error
+	Length() (uint64, error)
+	Root(i uint64) (Root, error)
 }
 
 type Eth1DataVotes interface {
@@ -22,6 +24,7 @@ type Eth1DataVotes interface {
 	Length() (uint64, error)
 	Count(dat Eth1Data) (uint64, error)
 	Append(dat Eth1Data) error
+	Vote(i uint64) (Eth1Data, error)
 }
 
 type Validator interface {
@@ -0,0 +1,146 @@
+package common
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+)
+
+const NODE_ID_BITS = 256
+
+// NodeID is the discv5 node identity, a big-endian uint256.
+type NodeID [NODE_ID_BITS / 8]byte
+
+func (id NodeID) String() string {
+	return "0x" + hex.EncodeToString(id[:])
+}
+
+// mod returns the node ID modulo m.
+func (id NodeID) mod(m uint64) uint64 {
+	var r uint64
+	for _, b := range id {
+		r = (r<<8 | uint64(b)) % m
+	}
+	return r
+}
+
+// prefix returns the top bits of the node ID, up to 64 bits.
+func (id NodeID) prefix(bits uint64) uint64 {
+	if bits == 0 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(id[:8]) >> (64 - bits)
+}
+
+// subscriptionOffset is the node-specific offset of the subnet subscription periods.
+func (id NodeID) subscriptionOffset(spec *Spec) Epoch {
+	return Epoch(id.mod(uint64(spec.EPOCHS_PER_SUBNET_SUBSCRIPTION)))
+}
+
+// ComputeSubscribedSubnet computes the index-th long-lived attestation subnet of a node at the given epoch.
+func ComputeSubscribedSubnet(spec *Spec, nodeID NodeID, epoch Epoch, index uint64) uint64 {
+	prefixBits := uint64(spec.ATTESTATION_SUBNET_PREFIX_BITS)
+	nodeIDPrefix := nodeID.prefix(prefixBits)
+	nodeOffset := nodeID.subscriptionOffset(spec)
+	var period [8]byte
+	binary.LittleEndian.PutUint64(period[:], uint64(epoch+nodeOffset)/uint64(spec.EPOCHS_PER_SUBNET_SUBSCRIPTION))
+	permutationSeed := Root(hashing.Hash(period[:]))
+	permutatedPrefix := PermuteIndex(uint8(spec.SHUFFLE_ROUND_COUNT), ValidatorIndex(nodeIDPrefix), 1<<prefixBits, permutationSeed)
+	return (uint64(permutatedPrefix) + index) % uint64(spec.ATTESTATION_SUBNET_COUNT)
+}
+
+// ComputeSubscribedSubnets computes the SUBNETS_PER_NODE long-lived attestation subnets of a node at the given epoch.
+func ComputeSubscribedSubnets(spec *Spec, nodeID NodeID, epoch Epoch) []uint64 {
+	out := make([]uint64, spec.SUBNETS_PER_NODE)
+	for i := range out {
+		out[i] = ComputeSubscribedSubnet(spec, nodeID, epoch, uint64(i))
+	}
+	return out
+}
+
+// AttnetSubscription is the set of long-lived attestation subnets of a node, stable from Start (inclusive) to End (exclusive).
+type AttnetSubscription struct {
+	Start   Epoch
+	End     Epoch
+	Subnets []uint64
+	Attnets AttnetBits
+}
+
+func (s *AttnetSubscription) String() string {
+	return fmt.Sprintf("AttnetSubscription(epochs: [%d, %d), subnets: %v)", s.Start, s.End, s.Subnets)
+}
+
+// ComputeAttnetSubscription computes the long-lived attestation subnets of a node at the given epoch,
+// with the epoch range in which the subscription does not rotate.
+func ComputeAttnetSubscription(spec *Spec, nodeID NodeID, epoch Epoch) AttnetSubscription {
+	span := Epoch(spec.EPOCHS_PER_SUBNET_SUBSCRIPTION)
+	offset := nodeID.subscriptionOffset(spec)
+	// the shifted epoch is a multiple of span at every rotation
+	shifted := epoch + offset
+	start := shifted - shifted%span
+	out := AttnetSubscription{
+		Start:   0,
+		End:     start + span - offset,
+		Subnets: ComputeSubscribedSubnets(spec, nodeID, epoch),
+	}
+	if start >= offset {
+		out.Start = start - offset
+	}
+	for _, subnet := range out.Subnets {
+		out.Attnets.SetBit(subnet, true)
+	}
+	return out
+}
+
+// AttnetSubscriptionSchedule computes the current and the next count-1 subscriptions of a node,
+// so a node can subscribe to the subnets of the next period ahead of the rotation.
+func AttnetSubscriptionSchedule(spec *Spec, nodeID NodeID, epoch Epoch, count uint64) []AttnetSubscription {
+	out := make([]AttnetSubscription, 0, count)
+	for i := uint64(0); i < count; i++ {
+		sub := ComputeAttnetSubscription(spec, nodeID, epoch)
+		out = append(out, sub)
+		epoch = sub.End
+	}
+	return out
+}
+
+// SyncCommitteeSubnets returns the sync committee subnets of the given validators in the committee.
+func SyncCommitteeSubnets(spec *Spec, isc *IndexedSyncCommittee, validators []ValidatorIndex) (out SyncnetBits) {
+	for _, v := range validators {
+		for _, subnet := range isc.Subnets(spec, v) {
+			out.SetBit(subnet, true)
+		}
+	}
+	return out
+}
+
+// SyncnetSubscription is the set of sync committee subnets of a node, for the sync committee period from Start (inclusive) to End (exclusive).
+type SyncnetSubscription struct {
+	Start    Epoch
+	End      Epoch
+	Syncnets SyncnetBits
+}
+
+func (s *SyncnetSubscription) String() string {
+	return fmt.Sprintf("SyncnetSubscription(epochs: [%d, %d), syncnets: %s)", s.Start, s.End, s.Syncnets)
+}
+
+// SyncnetSubscriptions computes the sync committee subnets of the given validators,
+// for the current sync committee period and the next, to subscribe ahead of the period change.
+func (epc *EpochsContext) SyncnetSubscriptions(validators []ValidatorIndex) (current SyncnetSubscription, next SyncnetSubscription, err error) {
+	if epc.CurrentSyncCommittee == nil || epc.NextSyncCommittee == nil {
+		return current, next, errors.New("no sync committees, pre-altair epochs context")
+	}
+	period := epc.Spec.EPOCHS_PER_SYNC_COMMITTEE_PERIOD
+	epoch := epc.CurrentEpoch.Epoch
+	current.Start = epoch - epoch%period
+	current.End = current.Start + period
+	current.Syncnets = SyncCommitteeSubnets(epc.Spec, epc.CurrentSyncCommittee, validators)
+	next.Start = current.End
+	next.End = next.Start + period
+	next.Syncnets = SyncCommitteeSubnets(epc.Spec, epc.NextSyncCommittee, validators)
+	return current, next, nil
+}
@@ -0,0 +1,179 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"testing"
+)
+
+func testSubnetSpec() *Spec {
+	spec := &Spec{}
+	spec.SHUFFLE_ROUND_COUNT = 90
+	spec.EPOCHS_PER_SUBNET_SUBSCRIPTION = 256
+	spec.SUBNETS_PER_NODE = 2
+	spec.ATTESTATION_SUBNET_COUNT = 64
+	spec.ATTESTATION_SUBNET_PREFIX_BITS = 6
+	spec.EPOCHS_PER_SYNC_COMMITTEE_PERIOD = 256
+	spec.SYNC_COMMITTEE_SIZE = 32
+	return spec
+}
+
+// specShuffledIndex transcribes compute_shuffled_index of the spec, independent of PermuteIndex.
+func specShuffledIndex(index uint64, indexCount uint64, seed Root, rounds uint8) uint64 {
+	for round := uint8(0); round < rounds; round++ {
+		pivotHash := sha256.Sum256(append(seed[:], round))
+		pivot := binary.LittleEndian.Uint64(pivotHash[:8]) % indexCount
+		flip := (pivot + indexCount - index) % indexCount
+		position := index
+		if flip > position {
+			position = flip
+		}
+		var positionDiv [4]byte
+		binary.LittleEndian.PutUint32(positionDiv[:], uint32(position/256))
+		source := sha256.Sum256(append(append(seed[:], round), positionDiv[:]...))
+		if (source[(position%256)/8]>>(position%8))%2 == 1 {
+			index = flip
+		}
+	}
+	return index
+}
+
+// specSubscribedSubnet transcribes compute_subscribed_subnet of the p2p spec, with uint256 node ID arithmetic.
+func specSubscribedSubnet(spec *Spec, nodeID *big.Int, epoch Epoch, index uint64) uint64 {
+	prefixBits := uint(spec.ATTESTATION_SUBNET_PREFIX_BITS)
+	nodeIDPrefix := new(big.Int).Rsh(nodeID, NODE_ID_BITS-prefixBits).Uint64()
+	nodeOffset := new(big.Int).Mod(nodeID, new(big.Int).SetUint64(uint64(spec.EPOCHS_PER_SUBNET_SUBSCRIPTION))).Uint64()
+	var period [8]byte
+	binary.LittleEndian.PutUint64(period[:], (uint64(epoch)+nodeOffset)/uint64(spec.EPOCHS_PER_SUBNET_SUBSCRIPTION))
+	seed := Root(sha256.Sum256(period[:]))
+	permutatedPrefix := specShuffledIndex(nodeIDPrefix, 1<<prefixBits, seed, uint8(spec.SHUFFLE_ROUND_COUNT))
+	return (permutatedPrefix + index) % uint64(spec.ATTESTATION_SUBNET_COUNT)
+}
+
+func TestComputeSubscribedSubnets(t *testing.T) {
+	spec := testSubnetSpec()
+	// mainnet configuration, node IDs as decimal uint256
+	inputs := []struct {
+		nodeID string
+		epoch  Epoch
+	}{
+		{"0", 54321},
+		{"88752428858350697756262172400162263450541348766581994718383409852729519486397", 1017090249},
+		{"18732750322395381632951253735273868184515463718109267674920115648614659369468", 1827566880},
+		{"27726842142488109545414954493849224833670205008410190955613662332153332462900", 846255942},
+		{"39755236029158558527862903296867805548949739810920318269566095185775868999998", 766597383},
+		{"31899136003441886988955119620035330314647133604576220223892254902004850516297", 1204990115},
+		{"58579998103852084482416614330746509727562027284701078483890722833654510444626", 1616209495},
+		{"28248042035542126088870192155378394518950310811868093527036637864276176517397", 1774367616},
+		{"60930578857433095740782970114409273483106482059893286066493409689627770333527", 1484598751},
+		{"103822458477361691467064888613019442068586830412598673713899771287914656699997", 3525502229},
+	}
+	for _, v := range inputs {
+		id, ok := new(big.Int).SetString(v.nodeID, 10)
+		if !ok {
+			t.Fatalf("bad node ID %s", v.nodeID)
+		}
+		var nodeID NodeID
+		id.FillBytes(nodeID[:])
+		subnets := ComputeSubscribedSubnets(spec, nodeID, v.epoch)
+		if uint64(len(subnets)) != uint64(spec.SUBNETS_PER_NODE) {
+			t.Fatalf("expected %d subnets, got %d", spec.SUBNETS_PER_NODE, len(subnets))
+		}
+		for index, subnet := range subnets {
+			if expected := specSubscribedSubnet(spec, id, v.epoch, uint64(index)); subnet != expected {
+				t.Fatalf("node %s epoch %d index %d: got subnet %d, spec computes %d", v.nodeID, v.epoch, index, subnet, expected)
+			}
+		}
+	}
+	// node IDs across the full uint256 range
+	for i := 0; i < 20; i++ {
+		var nodeID NodeID
+		h := sha256.Sum256([]byte{byte(i)})
+		copy(nodeID[:], h[:])
+		id := new(big.Int).SetBytes(nodeID[:])
+		for _, epoch := range []Epoch{0, 1, 255, 256, 1000, 123456} {
+			for index, subnet := range ComputeSubscribedSubnets(spec, nodeID, epoch) {
+				if expected := specSubscribedSubnet(spec, id, epoch, uint64(index)); subnet != expected {
+					t.Fatalf("node %s epoch %d index %d: got subnet %d, spec computes %d", nodeID, epoch, index, subnet, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestAttnetSubscriptionSchedule(t *testing.T) {
+	spec := testSubnetSpec()
+	var nodeID NodeID
+	nodeID[0] = 0xab
+	nodeID[31] = 100 // offset of 100 epochs
+	schedule := AttnetSubscriptionSchedule(spec, nodeID, 1000, 3)
+	if len(schedule) != 3 {
+		t.Fatalf("expected 3 subscriptions, got %d", len(schedule))
+	}
+	// (epoch + 100) rotates at multiples of 256
+	expectedRanges := [][2]Epoch{{924, 1180}, {1180, 1436}, {1436, 1692}}
+	for i, sub := range schedule {
+		if sub.Start != expectedRanges[i][0] || sub.End != expectedRanges[i][1] {
+			t.Fatalf("subscription %d: got %s, expected epochs %v", i, &sub, expectedRanges[i])
+		}
+		for epoch := sub.Start; epoch < sub.End; epoch += 17 {
+			got := ComputeSubscribedSubnets(spec, nodeID, epoch)
+			for j := range got {
+				if got[j] != sub.Subnets[j] {
+					t.Fatalf("subnets change within subscription %s at epoch %d", &sub, epoch)
+				}
+			}
+		}
+		count := 0
+		for subnet := uint64(0); subnet < ATTESTATION_SUBNET_COUNT; subnet++ {
+			if sub.Attnets.GetBit(subnet) {
+				count++
+				if subnet != sub.Subnets[0] && subnet != sub.Subnets[1] {
+					t.Fatalf("unexpected attnet bit %d", subnet)
+				}
+			}
+		}
+		if count != 2 {
+			t.Fatalf("expected 2 attnet bits, got %d", count)
+		}
+	}
+	// the first period of the node starts at genesis
+	if first := ComputeAttnetSubscription(spec, nodeID, 0); first.Start != 0 || first.End != 156 {
+		t.Fatalf("unexpected first subscription: %s", &first)
+	}
+}
+
+func TestSyncnetSubscriptions(t *testing.T) {
+	spec := testSubnetSpec()
+	indices := func(offset ValidatorIndex) []ValidatorIndex {
+		out := make([]ValidatorIndex, 32)
+		for i := range out {
+			out[i] = offset + ValidatorIndex(i)
+		}
+		return out
+	}
+	epc := &EpochsContext{
+		Spec:                 spec,
+		CurrentEpoch:         &ShufflingEpoch{Epoch: 300},
+		CurrentSyncCommittee: &IndexedSyncCommittee{Indices: indices(0)},
+		NextSyncCommittee:    &IndexedSyncCommittee{Indices: indices(16)},
+	}
+	// subcommittees of 8: validator 3 is in subnet 0 now, validators 20 and 30 in subnets 2 and 3 now, and 0 and 1 next
+	current, next, err := epc.SyncnetSubscriptions([]ValidatorIndex{3, 20, 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Start != 256 || current.End != 512 || next.Start != 512 || next.End != 768 {
+		t.Fatalf("unexpected periods: %s, %s", &current, &next)
+	}
+	if current.Syncnets != (SyncnetBits{0b1101}) {
+		t.Fatalf("unexpected current syncnets: %s", current.Syncnets)
+	}
+	if next.Syncnets != (SyncnetBits{0b0011}) {
+		t.Fatalf("unexpected next syncnets: %s", next.Syncnets)
+	}
+	if _, _, err := (&EpochsContext{Spec: spec, CurrentEpoch: &ShufflingEpoch{}}).SyncnetSubscriptions(nil); err == nil {
+		t.Fatal("expected error without sync committees")
+	}
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -45,6 +46,13 @@ func (li SyncCommitteePubkeys) HashTreeRoot(spec *Spec, hFn tree.HashFn) Root {
 	}, uint64(spec.SYNC_COMMITTEE_SIZE))
 }
 
+func (li SyncCommitteePubkeys) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]BLSPubkey{}) // encode as empty list, not null
+	}
+	return json.Marshal([]BLSPubkey(li))
+}
+
 type SyncCommitteePubkeysView struct {
 	*ComplexVectorView
 }
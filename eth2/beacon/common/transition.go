@@ -108,6 +108,24 @@ func StateTransition(ctx context.Context, spec *Spec, epc *EpochsContext, state
 	return PostSlotTransition(ctx, spec, epc, state, benv, validateResult)
 }
 
+// StateTransitionBatchVerified is like StateTransition, but defers the verification of all block signatures:
+// the proposer signature and the signatures of the operations are collected,
+// and checked together with a single randomized batch verification after processing the block.
+// If the batch is invalid, the signatures are checked one by one to report the offending signature.
+// Deposit signatures are not deferred: an invalid deposit signature does not invalidate the block.
+// Returns an error if the slot is older or equal to what the state is already at.
+// Mutates the state, does not copy.
+func StateTransitionBatchVerified(ctx context.Context, spec *Spec, epc *EpochsContext, state UpgradeableBeaconState, benv *BeaconBlockEnvelope, validateResult bool) error {
+	if err := ProcessSlots(ctx, spec, epc, state, benv.Slot); err != nil {
+		return err
+	}
+	batch := NewSignatureBatch()
+	if err := PostSlotTransition(WithSignatureBatch(ctx, batch), spec, epc, state, benv, validateResult); err != nil {
+		return err
+	}
+	return batch.Verify()
+}
+
 // PostSlotTransition finishes a state transition after applying ProcessSlots(..., block.Slot).
 func PostSlotTransition(ctx context.Context, spec *Spec, epc *EpochsContext, state BeaconState, benv *BeaconBlockEnvelope, validateResult bool) error {
 	slot, err := state.Slot()
@@ -140,7 +158,11 @@ func PostSlotTransition(ctx context.Context, spec *Spec, epc *EpochsContext, sta
 		if !ok {
 			return fmt.Errorf("unknown pubkey for proposer %d", proposer)
 		}
-		if !benv.VerifySignatureVersioned(spec, fork.CurrentVersion, genValRoot, proposer, pub) {
+		set, err := benv.SignatureSetVersioned(spec, fork.CurrentVersion, genValRoot, proposer, pub)
+		if err != nil {
+			return fmt.Errorf("block has invalid signature: %v", err)
+		}
+		if !VerifySignature(ctx, set.Description, set.Pubkey, set.SigningRoot, set.Signature) {
 			return errors.New("block has invalid signature")
 		}
 	}
@@ -1,7 +1,9 @@
 package common
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"sync"
 )
 
@@ -155,3 +157,100 @@ func (pc *PubkeyCache) AddValidator(index ValidatorIndex, pub BLSPubkey) (*Pubke
 	pc.pub2idx[pub] = index
 	return pc, nil
 }
+
+// Count returns the number of known validator pubkeys, including those of the parent caches.
+func (pc *PubkeyCache) Count() ValidatorIndex {
+	pc.rwLock.RLock()
+	defer pc.rwLock.RUnlock()
+	return pc.trustedParentCount + ValidatorIndex(len(pc.idx2pub))
+}
+
+// WritePubkeys writes the pubkeys of validator index start and onwards as consecutive 48 byte records,
+// and returns the index to continue from the next time.
+// Appending to the same output every time persists the cache incrementally, to be restored with ReadPubkeys.
+func (pc *PubkeyCache) WritePubkeys(w io.Writer, start ValidatorIndex) (next ValidatorIndex, err error) {
+	end := pc.Count()
+	for i := start; i < end; i++ {
+		pub, ok := pc.Pubkey(i)
+		if !ok {
+			return i, fmt.Errorf("missing pubkey of validator %d", i)
+		}
+		if _, err := w.Write(pub.Compressed[:]); err != nil {
+			return i, err
+		}
+	}
+	return end, nil
+}
+
+// ReadPubkeys adds the pubkeys written with WritePubkeys, starting at validator 0, until EOF.
+// Pubkeys of validators that are already in the cache are checked to match, the remaining pubkeys are added.
+// Like AddValidator, a forked cache is returned if the pubkeys conflict with those already in the cache.
+func (pc *PubkeyCache) ReadPubkeys(r io.Reader) (*PubkeyCache, error) {
+	known := pc.Count()
+	for index := ValidatorIndex(0); ; index++ {
+		var pub BLSPubkey
+		if _, err := io.ReadFull(r, pub[:]); err != nil {
+			if err == io.EOF {
+				return pc, nil
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("incomplete pubkey record of validator %d: %w", index, err)
+			}
+			return nil, err
+		}
+		if index < known {
+			if cached, ok := pc.Pubkey(index); !ok || cached.Compressed != pub {
+				return nil, fmt.Errorf("pubkey record of validator %d does not match the cache", index)
+			}
+			continue
+		}
+		var err error
+		pc, err = pc.AddValidator(index, pub)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// SyncValidators adds the pubkeys of the validators in the registry that are not in the cache yet.
+// The last known validator that is also in the registry is checked, to detect a cache of a different validator set.
+// Like AddValidator, a forked cache is returned if the pubkeys conflict with those already in the cache.
+func (pc *PubkeyCache) SyncValidators(vals ValidatorRegistry) (*PubkeyCache, error) {
+	valCount, err := vals.ValidatorCount()
+	if err != nil {
+		return nil, err
+	}
+	start := pc.Count()
+	if last := ValidatorIndex(valCount); last > 0 && start > 0 {
+		if start < last {
+			last = start
+		}
+		last -= 1
+		v, err := vals.Validator(last)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := v.Pubkey()
+		if err != nil {
+			return nil, err
+		}
+		if cached, ok := pc.Pubkey(last); !ok || cached.Compressed != pub {
+			return nil, fmt.Errorf("pubkey cache does not match validator %d of the registry", last)
+		}
+	}
+	for i := start; i < ValidatorIndex(valCount); i++ {
+		v, err := vals.Validator(i)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := v.Pubkey()
+		if err != nil {
+			return nil, err
+		}
+		pc, err = pc.AddValidator(i, pub)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pc, nil
+}
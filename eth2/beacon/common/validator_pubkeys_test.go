@@ -0,0 +1,125 @@
+package common_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestPubkeyCachePersistence(t *testing.T) {
+	spec := configs.Mainnet
+	state, _ := testEpochStates(t, spec, 100)
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := common.NewPubkeyCache(vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	next, err := pc.WritePubkeys(&buf, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != 100 {
+		t.Fatalf("expected to continue at 100, got %d", next)
+	}
+	// new validators are appended to the same output
+	for i, v := range testValidators(110, spec.MAX_EFFECTIVE_BALANCE)[100:] {
+		if err := state.AddValidator(spec, v.Pubkey, v.WithdrawalCredentials, v.Balance); err != nil {
+			t.Fatal(err)
+		}
+		if pc, err = pc.AddValidator(common.ValidatorIndex(100+i), v.Pubkey); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if next, err = pc.WritePubkeys(&buf, next); err != nil {
+		t.Fatal(err)
+	}
+	if next != 110 || buf.Len() != 110*48 {
+		t.Fatalf("expected 110 pubkeys, continuing at %d, with %d bytes", next, buf.Len())
+	}
+
+	restored, err := common.EmptyPubkeyCache().ReadPubkeys(bytes.NewReader(buf.Bytes()[:100*48]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Count() != 100 {
+		t.Fatalf("expected 100 restored pubkeys, got %d", restored.Count())
+	}
+	vals, err = state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored, err = restored.SyncValidators(vals); err != nil {
+		t.Fatal(err)
+	}
+	for i := common.ValidatorIndex(0); i < 110; i++ {
+		a, _ := pc.Pubkey(i)
+		b, ok := restored.Pubkey(i)
+		if !ok || a.Compressed != b.Compressed {
+			t.Fatalf("pubkey %d differs", i)
+		}
+	}
+
+	if _, err := common.EmptyPubkeyCache().ReadPubkeys(bytes.NewReader(buf.Bytes()[:100*48+10])); err == nil {
+		t.Fatal("expected error for incomplete pubkey record")
+	}
+	// a cache of other validators does not match the registry
+	shifted := common.EmptyPubkeyCache()
+	for i := common.ValidatorIndex(0); i < 10; i++ {
+		pub, _ := pc.Pubkey(i + 1)
+		if shifted, err = shifted.AddValidator(i, pub.Compressed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := shifted.SyncValidators(vals); err == nil {
+		t.Fatal("expected inconsistent pubkey cache error")
+	}
+	// nor does it match the persisted pubkeys
+	if _, err := shifted.ReadPubkeys(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Fatal("expected error for pubkey records that do not match the cache")
+	}
+}
+
+func TestPubkeyCacheReadPubkeysPartial(t *testing.T) {
+	spec := configs.Mainnet
+	state, _ := testEpochStates(t, spec, 64)
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := common.NewPubkeyCache(vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := pc.WritePubkeys(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	// a cache that knows the first validators already, reading the full output
+	partial := common.EmptyPubkeyCache()
+	for i := common.ValidatorIndex(0); i < 5; i++ {
+		pub, _ := pc.Pubkey(i)
+		if partial, err = partial.AddValidator(i, pub.Compressed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	restored, err := partial.ReadPubkeys(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Count() != 64 {
+		t.Fatalf("expected 64 pubkeys, got %d", restored.Count())
+	}
+	for i := common.ValidatorIndex(0); i < 64; i++ {
+		a, _ := pc.Pubkey(i)
+		b, ok := restored.Pubkey(i)
+		if !ok || a.Compressed != b.Compressed {
+			t.Fatalf("pubkey %d misaligned", i)
+		}
+	}
+}
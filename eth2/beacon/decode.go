@@ -0,0 +1,146 @@
+package beacon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/protolambda/ztyp/codec"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// The slot is at a fixed SSZ offset in every fork:
+// a BeaconState starts with genesis_time (8) and genesis_validators_root (32),
+// a SignedBeaconBlock starts with the message offset (4) and the signature (96).
+const (
+	StateSlotOffset = 8 + 32
+	BlockSlotOffset = 4 + 96
+)
+
+// PeekStateSlot reads the slot of an SSZ encoded BeaconState of any fork.
+func PeekStateSlot(data []byte) (common.Slot, error) {
+	if len(data) < StateSlotOffset+8 {
+		return 0, fmt.Errorf("state too short to read slot: %d bytes", len(data))
+	}
+	return common.Slot(binary.LittleEndian.Uint64(data[StateSlotOffset:])), nil
+}
+
+// PeekBlockSlot reads the slot of an SSZ encoded SignedBeaconBlock of any fork.
+func PeekBlockSlot(data []byte) (common.Slot, error) {
+	if len(data) < BlockSlotOffset+8 {
+		return 0, fmt.Errorf("block too short to read slot: %d bytes", len(data))
+	}
+	return common.Slot(binary.LittleEndian.Uint64(data[BlockSlotOffset:])), nil
+}
+
+// BeaconStateViewDecoder returns the function to decode a BeaconState view of the fork at the given slot.
+func BeaconStateViewDecoder(spec *common.Spec, slot common.Slot) (func(dr *codec.DecodingReader) (common.BeaconState, error), error) {
+	switch version := spec.ForkVersion(slot); version {
+	case spec.GENESIS_FORK_VERSION:
+		return func(dr *codec.DecodingReader) (common.BeaconState, error) {
+			return phase0.AsBeaconStateView(phase0.BeaconStateType(spec).Deserialize(dr))
+		}, nil
+	case spec.ALTAIR_FORK_VERSION:
+		return func(dr *codec.DecodingReader) (common.BeaconState, error) {
+			return altair.AsBeaconStateView(altair.BeaconStateType(spec).Deserialize(dr))
+		}, nil
+	case spec.BELLATRIX_FORK_VERSION:
+		return func(dr *codec.DecodingReader) (common.BeaconState, error) {
+			return bellatrix.AsBeaconStateView(bellatrix.BeaconStateType(spec).Deserialize(dr))
+		}, nil
+	case spec.CAPELLA_FORK_VERSION:
+		return func(dr *codec.DecodingReader) (common.BeaconState, error) {
+			return capella.AsBeaconStateView(capella.BeaconStateType(spec).Deserialize(dr))
+		}, nil
+	case spec.DENEB_FORK_VERSION:
+		return func(dr *codec.DecodingReader) (common.BeaconState, error) {
+			return deneb.AsBeaconStateView(deneb.BeaconStateType(spec).Deserialize(dr))
+		}, nil
+	case spec.ELECTRA_FORK_VERSION:
+		return func(dr *codec.DecodingReader) (common.BeaconState, error) {
+			return electra.AsBeaconStateView(electra.BeaconStateType(spec).Deserialize(dr))
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported fork version %s at slot %d", version, slot)
+	}
+}
+
+// SignedBeaconBlockAllocator returns an allocator for the signed block type of the fork at the given slot.
+func SignedBeaconBlockAllocator(spec *common.Spec, slot common.Slot) (func() OpaqueBlock, error) {
+	switch version := spec.ForkVersion(slot); version {
+	case spec.GENESIS_FORK_VERSION:
+		return func() OpaqueBlock { return new(phase0.SignedBeaconBlock) }, nil
+	case spec.ALTAIR_FORK_VERSION:
+		return func() OpaqueBlock { return new(altair.SignedBeaconBlock) }, nil
+	case spec.BELLATRIX_FORK_VERSION:
+		return func() OpaqueBlock { return new(bellatrix.SignedBeaconBlock) }, nil
+	case spec.CAPELLA_FORK_VERSION:
+		return func() OpaqueBlock { return new(capella.SignedBeaconBlock) }, nil
+	case spec.DENEB_FORK_VERSION:
+		return func() OpaqueBlock { return new(deneb.SignedBeaconBlock) }, nil
+	case spec.ELECTRA_FORK_VERSION:
+		return func() OpaqueBlock { return new(electra.SignedBeaconBlock) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported fork version %s at slot %d", version, slot)
+	}
+}
+
+// DecodeBeaconState decodes an SSZ encoded BeaconState, into a view of the fork at the slot of the state.
+func DecodeBeaconState(spec *common.Spec, data []byte) (common.BeaconState, error) {
+	slot, err := PeekStateSlot(data)
+	if err != nil {
+		return nil, err
+	}
+	decode, err := BeaconStateViewDecoder(spec, slot)
+	if err != nil {
+		return nil, err
+	}
+	return decode(codec.NewDecodingReader(bytes.NewReader(data), uint64(len(data))))
+}
+
+// ReadBeaconState decodes an SSZ encoded BeaconState of the given byte size from the reader,
+// into a view of the fork at the slot of the state.
+// Only the start of the state is buffered to read the slot, the remainder is decoded while streaming.
+func ReadBeaconState(spec *common.Spec, r io.Reader, size uint64) (common.BeaconState, error) {
+	var prefix [StateSlotOffset + 8]byte
+	if size < uint64(len(prefix)) {
+		return nil, fmt.Errorf("state too short to read slot: %d bytes", size)
+	}
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, fmt.Errorf("failed to read state slot: %w", err)
+	}
+	slot, err := PeekStateSlot(prefix[:])
+	if err != nil {
+		return nil, err
+	}
+	decode, err := BeaconStateViewDecoder(spec, slot)
+	if err != nil {
+		return nil, err
+	}
+	return decode(codec.NewDecodingReader(io.MultiReader(bytes.NewReader(prefix[:]), r), size))
+}
+
+// DecodeSignedBeaconBlock decodes an SSZ encoded SignedBeaconBlock, into the type of the fork at the slot of the block.
+func DecodeSignedBeaconBlock(spec *common.Spec, data []byte) (OpaqueBlock, error) {
+	slot, err := PeekBlockSlot(data)
+	if err != nil {
+		return nil, err
+	}
+	alloc, err := SignedBeaconBlockAllocator(spec, slot)
+	if err != nil {
+		return nil, err
+	}
+	block := alloc()
+	if err := block.Deserialize(spec, codec.NewDecodingReader(bytes.NewReader(data), uint64(len(data)))); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
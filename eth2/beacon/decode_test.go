@@ -0,0 +1,105 @@
+package beacon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func encodeAtSlot(t *testing.T, v view.View, slotOffset int, slot common.Slot) []byte {
+	var buf bytes.Buffer
+	if err := v.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	binary.LittleEndian.PutUint64(data[slotOffset:], uint64(slot))
+	return data
+}
+
+func TestDecodeByFork(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 1
+	spec.BELLATRIX_FORK_EPOCH = 2
+	spec.CAPELLA_FORK_EPOCH = 3
+	spec.DENEB_FORK_EPOCH = 4
+	spec.ELECTRA_FORK_EPOCH = 5
+	spec.FULU_FORK_EPOCH = 6
+
+	forks := []struct {
+		version    common.Version
+		stateType  view.TypeDef
+		blockType  view.TypeDef
+		stateValue common.BeaconState
+		blockValue OpaqueBlock
+	}{
+		{spec.GENESIS_FORK_VERSION, phase0.BeaconStateType(&spec), phase0.SignedBeaconBlockType(&spec), new(phase0.BeaconStateView), new(phase0.SignedBeaconBlock)},
+		{spec.ALTAIR_FORK_VERSION, altair.BeaconStateType(&spec), altair.SignedBeaconBlockType(&spec), new(altair.BeaconStateView), new(altair.SignedBeaconBlock)},
+		{spec.BELLATRIX_FORK_VERSION, bellatrix.BeaconStateType(&spec), bellatrix.SignedBeaconBlockType(&spec), new(bellatrix.BeaconStateView), new(bellatrix.SignedBeaconBlock)},
+		{spec.CAPELLA_FORK_VERSION, capella.BeaconStateType(&spec), capella.SignedBeaconBlockType(&spec), new(capella.BeaconStateView), new(capella.SignedBeaconBlock)},
+		{spec.DENEB_FORK_VERSION, deneb.BeaconStateType(&spec), deneb.SignedBeaconBlockType(&spec), new(deneb.BeaconStateView), new(deneb.SignedBeaconBlock)},
+		{spec.ELECTRA_FORK_VERSION, electra.BeaconStateType(&spec), electra.SignedBeaconBlockType(&spec), new(electra.BeaconStateView), new(electra.SignedBeaconBlock)},
+	}
+	for i, fork := range forks {
+		// the first and the last slot of the fork
+		for _, slot := range []common.Slot{common.Slot(i) * spec.SLOTS_PER_EPOCH, common.Slot(i+1)*spec.SLOTS_PER_EPOCH - 1} {
+			if v := spec.ForkVersion(slot); v != fork.version {
+				t.Fatalf("slot %d: expected fork version %s, got %s", slot, fork.version, v)
+			}
+			expectedState := fmt.Sprintf("%T", fork.stateValue)
+			stateData := encodeAtSlot(t, fork.stateType.Default(nil), StateSlotOffset, slot)
+			state, err := DecodeBeaconState(&spec, stateData)
+			if err != nil {
+				t.Fatalf("slot %d: %v", slot, err)
+			}
+			if got := fmt.Sprintf("%T", state); got != expectedState {
+				t.Fatalf("slot %d: expected %s, got %s", slot, expectedState, got)
+			}
+			state, err = ReadBeaconState(&spec, bytes.NewReader(stateData), uint64(len(stateData)))
+			if err != nil {
+				t.Fatalf("slot %d: %v", slot, err)
+			}
+			if got := fmt.Sprintf("%T", state); got != expectedState {
+				t.Fatalf("slot %d: expected %s, got %s", slot, expectedState, got)
+			}
+			if s, err := state.Slot(); err != nil || s != slot {
+				t.Fatalf("slot %d: decoded state at slot %d", slot, s)
+			}
+
+			expectedBlock := fmt.Sprintf("%T", fork.blockValue)
+			block, err := DecodeSignedBeaconBlock(&spec, encodeAtSlot(t, fork.blockType.Default(nil), BlockSlotOffset, slot))
+			if err != nil {
+				t.Fatalf("slot %d: %v", slot, err)
+			}
+			if got := fmt.Sprintf("%T", block); got != expectedBlock {
+				t.Fatalf("slot %d: expected %s, got %s", slot, expectedBlock, got)
+			}
+			if env := block.Envelope(&spec, common.ForkDigest{}); env.Slot != slot {
+				t.Fatalf("slot %d: decoded block at slot %d", slot, env.Slot)
+			}
+		}
+	}
+
+	fuluSlot := common.Slot(6) * spec.SLOTS_PER_EPOCH
+	if _, err := DecodeSignedBeaconBlock(&spec, encodeAtSlot(t, forks[5].blockType.Default(nil), BlockSlotOffset, fuluSlot)); err == nil {
+		t.Fatal("expected unsupported fork error")
+	}
+	if _, err := DecodeBeaconState(&spec, make([]byte, StateSlotOffset+4)); err == nil {
+		t.Fatal("expected error on truncated state")
+	}
+	if _, err := ReadBeaconState(&spec, bytes.NewReader(make([]byte, 100)), 1000); err == nil {
+		t.Fatal("expected error on truncated stream")
+	}
+}
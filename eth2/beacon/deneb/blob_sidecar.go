@@ -0,0 +1,158 @@
+package deneb
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/conv"
+	"github.com/protolambda/ztyp/tree"
+	. "github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+const BYTES_PER_FIELD_ELEMENT = 32
+
+func BlobSize(spec *common.Spec) uint64 {
+	return uint64(spec.FIELD_ELEMENTS_PER_BLOB) * BYTES_PER_FIELD_ELEMENT
+}
+
+type Blob []byte
+
+func (b *Blob) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	size := BlobSize(spec)
+	if uint64(cap(*b)) < size {
+		*b = make(Blob, size)
+	} else {
+		*b = (*b)[:size]
+	}
+	_, err := dr.Read(*b)
+	return err
+}
+
+func (b Blob) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	if size := BlobSize(spec); uint64(len(b)) != size {
+		return fmt.Errorf("blob has size %d, expected %d", len(b), size)
+	}
+	return w.Write(b)
+}
+
+func (b Blob) ByteLength(spec *common.Spec) uint64 {
+	return BlobSize(spec)
+}
+
+func (b *Blob) FixedLength(spec *common.Spec) uint64 {
+	return BlobSize(spec)
+}
+
+func (b Blob) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	return hFn.ByteVectorHTR(b)
+}
+
+func (b Blob) MarshalText() ([]byte, error) {
+	return conv.BytesMarshalText(b[:])
+}
+
+func (b *Blob) UnmarshalText(text []byte) error {
+	return conv.DynamicBytesUnmarshalText((*[]byte)(b), text)
+}
+
+func (b Blob) String() string {
+	return conv.BytesString(b[:])
+}
+
+func BlobType(spec *common.Spec) *BasicVectorTypeDef {
+	return BasicVectorType(ByteType, BlobSize(spec))
+}
+
+// KZGCommitmentInclusionProof is the Merkle branch of a KZG commitment in the blob_kzg_commitments of the block body.
+type KZGCommitmentInclusionProof []common.Root
+
+func (p *KZGCommitmentInclusionProof) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	roots := make([]common.Root, spec.KZG_COMMITMENT_INCLUSION_PROOF_DEPTH)
+	if err := tree.ReadRoots(dr, &roots, uint64(spec.KZG_COMMITMENT_INCLUSION_PROOF_DEPTH)); err != nil {
+		return err
+	}
+	*p = roots
+	return nil
+}
+
+func (p KZGCommitmentInclusionProof) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	if depth := uint64(spec.KZG_COMMITMENT_INCLUSION_PROOF_DEPTH); uint64(len(p)) != depth {
+		return fmt.Errorf("inclusion proof has length %d, expected %d", len(p), depth)
+	}
+	return tree.WriteRoots(w, p)
+}
+
+func (p KZGCommitmentInclusionProof) ByteLength(spec *common.Spec) uint64 {
+	return uint64(spec.KZG_COMMITMENT_INCLUSION_PROOF_DEPTH) * 32
+}
+
+func (p *KZGCommitmentInclusionProof) FixedLength(spec *common.Spec) uint64 {
+	return uint64(spec.KZG_COMMITMENT_INCLUSION_PROOF_DEPTH) * 32
+}
+
+func (p KZGCommitmentInclusionProof) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	length := uint64(len(p))
+	return hFn.ComplexVectorHTR(func(i uint64) tree.HTR {
+		if i < length {
+			return &p[i]
+		}
+		return nil
+	}, uint64(spec.KZG_COMMITMENT_INCLUSION_PROOF_DEPTH))
+}
+
+func (p KZGCommitmentInclusionProof) MarshalJSON() ([]byte, error) {
+	if p == nil {
+		return json.Marshal([]common.Root{}) // encode as empty list, not null
+	}
+	return json.Marshal([]common.Root(p))
+}
+
+func KZGCommitmentInclusionProofType(spec *common.Spec) VectorTypeDef {
+	return VectorType(RootType, uint64(spec.KZG_COMMITMENT_INCLUSION_PROOF_DEPTH))
+}
+
+type BlobSidecar struct {
+	Index                       Uint64View                     `json:"index" yaml:"index"`
+	Blob                        Blob                           `json:"blob" yaml:"blob"`
+	KZGCommitment               common.KZGCommitment           `json:"kzg_commitment" yaml:"kzg_commitment"`
+	KZGProof                    common.KZGProof                `json:"kzg_proof" yaml:"kzg_proof"`
+	SignedBlockHeader           common.SignedBeaconBlockHeader `json:"signed_block_header" yaml:"signed_block_header"`
+	KZGCommitmentInclusionProof KZGCommitmentInclusionProof    `json:"kzg_commitment_inclusion_proof" yaml:"kzg_commitment_inclusion_proof"`
+}
+
+func (b *BlobSidecar) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	return dr.FixedLenContainer(&b.Index, spec.Wrap(&b.Blob), &b.KZGCommitment, &b.KZGProof,
+		&b.SignedBlockHeader, spec.Wrap(&b.KZGCommitmentInclusionProof))
+}
+
+func (b *BlobSidecar) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	return w.FixedLenContainer(&b.Index, spec.Wrap(&b.Blob), &b.KZGCommitment, &b.KZGProof,
+		&b.SignedBlockHeader, spec.Wrap(&b.KZGCommitmentInclusionProof))
+}
+
+func (b *BlobSidecar) ByteLength(spec *common.Spec) uint64 {
+	return BlobSidecarType(spec).TypeByteLength()
+}
+
+func (b *BlobSidecar) FixedLength(spec *common.Spec) uint64 {
+	return BlobSidecarType(spec).TypeByteLength()
+}
+
+func (b *BlobSidecar) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	return hFn.HashTreeRoot(&b.Index, spec.Wrap(&b.Blob), &b.KZGCommitment, &b.KZGProof,
+		&b.SignedBlockHeader, spec.Wrap(&b.KZGCommitmentInclusionProof))
+}
+
+func BlobSidecarType(spec *common.Spec) *ContainerTypeDef {
+	return ContainerType("BlobSidecar", []FieldDef{
+		{"index", Uint64Type},
+		{"blob", BlobType(spec)},
+		{"kzg_commitment", common.KZGCommitmentType},
+		{"kzg_proof", common.KZGProofType},
+		{"signed_block_header", common.SignedBeaconBlockHeaderType},
+		{"kzg_commitment_inclusion_proof", KZGCommitmentInclusionProofType(spec)},
+	})
+}
@@ -0,0 +1,43 @@
+package deneb
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// GenesisFromEth1 builds a genesis state that starts at Deneb, like phase0.GenesisFromEth1.
+// The header is the latest execution payload header of the genesis state.
+// If nil, the header only refers to the EL genesis block by eth1BlockHash, with empty transactions and withdrawals.
+func GenesisFromEth1(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, deps []common.Deposit, ignoreSignaturesAndProofs bool, header *ExecutionPayloadHeader) (*BeaconStateView, *common.EpochsContext, error) {
+	state := NewBeaconStateView(spec)
+	hFn := tree.GetHashFn()
+	emptyBodyRoot := BeaconBlockBodyType(spec).New().HashTreeRoot(hFn)
+	epc, err := bellatrix.InitGenesisState(spec, state, spec.DENEB_FORK_VERSION, emptyBodyRoot, eth1BlockHash, time, deps, ignoreSignaturesAndProofs, func() error {
+		if header == nil {
+			header = &ExecutionPayloadHeader{
+				BlockHash:        eth1BlockHash,
+				TransactionsRoot: common.PayloadTransactions{}.HashTreeRoot(spec, hFn),
+				WithdrawalsRoot:  common.Withdrawals{}.HashTreeRoot(spec, hFn),
+			}
+		}
+		return state.SetLatestExecutionPayloadHeader(header)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
+
+// To build a Deneb genesis state without Eth 1.0 deposits, i.e. directly from a sequence of minimal validator data.
+func KickStartState(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, validators []phase0.KickstartValidatorData, header *ExecutionPayloadHeader) (*BeaconStateView, *common.EpochsContext, error) {
+	state, epc, err := GenesisFromEth1(spec, eth1BlockHash, 0, phase0.KickstartDeposits(validators), true, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetGenesisTime(time); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
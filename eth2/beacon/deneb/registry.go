@@ -34,6 +34,7 @@ func ProcessEpochRegistryUpdates(ctx context.Context, spec *common.Spec, epc *co
 			if err := val.SetExitEpoch(exitEnd); err != nil {
 				return err
 			}
+			flats[index].ExitEpoch = exitEnd
 			withdrawEpoch := exitEnd + spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY
 			if withdrawEpoch < exitEnd { // practically impossible, but here for spec test introduced in consensus-specs#2887
 				return fmt.Errorf("exit epoch overflow: %d + %d = %d", exitEnd, spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY, withdrawEpoch)
@@ -41,6 +42,7 @@ func ProcessEpochRegistryUpdates(ctx context.Context, spec *common.Spec, epc *co
 			if err := val.SetWithdrawableEpoch(withdrawEpoch); err != nil {
 				return err
 			}
+			flats[index].WithdrawableEpoch = withdrawEpoch
 			endChurn += 1
 			if endChurn >= registerData.ChurnLimit {
 				endChurn = 0
@@ -60,6 +62,7 @@ func ProcessEpochRegistryUpdates(ctx context.Context, spec *common.Spec, epc *co
 			if err := val.SetActivationEligibilityEpoch(eligibilityEpoch); err != nil {
 				return err
 			}
+			flats[index].ActivationEligibilityEpoch = eligibilityEpoch
 		}
 	}
 
@@ -90,6 +93,7 @@ func ProcessEpochRegistryUpdates(ctx context.Context, spec *common.Spec, epc *co
 			if err := val.SetActivationEpoch(activationEpoch); err != nil {
 				return err
 			}
+			flats[index].ActivationEpoch = activationEpoch
 		}
 	}
 	return nil
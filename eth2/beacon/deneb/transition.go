@@ -15,10 +15,12 @@ func (state *BeaconStateView) ProcessEpoch(ctx context.Context, spec *common.Spe
 	if err != nil {
 		return err
 	}
-	flats, err := common.FlattenValidators(vals)
+	flats, err := common.FlattenValidatorsContext(ctx, spec.Parallelism, vals)
 	if err != nil {
 		return err
 	}
+	// The registry changes of the epoch transition are applied to the flats, to rotate the epochs with
+	epc.TrackTransitionFlats(flats)
 	attesterData, err := altair.ComputeEpochAttesterData(ctx, spec, epc, flats, state)
 	if err != nil {
 		return err
@@ -2,6 +2,8 @@ package electra
 
 import (
 	"encoding/json"
+	"fmt"
+
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 	"github.com/protolambda/ztyp/codec"
@@ -43,6 +45,36 @@ var SingleAttestationType = ContainerType("SingleAttestation", []FieldDef{
 	{"signature", common.BLSSignatureType},
 })
 
+// ToAttestation converts the single attestation into an aggregatable attestation, with the committee bit
+// of the committee index set, and the attester as the only participant of the given beacon committee.
+func (a *SingleAttestation) ToAttestation(spec *common.Spec, committee []common.ValidatorIndex) (*Attestation, error) {
+	if uint64(a.CommitteeIndex) >= uint64(spec.MAX_COMMITTEES_PER_SLOT) {
+		return nil, fmt.Errorf("committee index %d out of range", a.CommitteeIndex)
+	}
+	position := -1
+	for i, vi := range committee {
+		if vi == a.AttesterIndex {
+			position = i
+			break
+		}
+	}
+	if position < 0 {
+		return nil, fmt.Errorf("attester %d is not a member of committee %d", a.AttesterIndex, a.CommitteeIndex)
+	}
+	bitLen := uint64(len(committee))
+	aggBits := make(AttestationBits, bitLen/8+1, bitLen/8+1)
+	aggBits.SetBit(bitLen, true) // bitlist delimiter
+	aggBits.SetBit(uint64(position), true)
+	committeeBits := make(CommitteeBits, (uint64(spec.MAX_COMMITTEES_PER_SLOT)+7)/8)
+	committeeBits.SetBit(uint64(a.CommitteeIndex), true)
+	return &Attestation{
+		AggregationBits: aggBits,
+		Data:            a.Data,
+		Signature:       a.Signature,
+		CommitteeBits:   committeeBits,
+	}, nil
+}
+
 type Attestation struct {
 	// [Modified in Electra:EIP7549]
 	AggregationBits AttestationBits        `json:"aggregation_bits" yaml:"aggregation_bits"`
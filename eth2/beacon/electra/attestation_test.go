@@ -0,0 +1,78 @@
+package electra
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+)
+
+func TestSingleAttestationToAttestation(t *testing.T) {
+	spec := configs.Mainnet
+	committee := []common.ValidatorIndex{40, 12, 7, 33, 90, 2, 18, 61, 5}
+	single := &SingleAttestation{CommitteeIndex: 3, AttesterIndex: 90}
+	single.Data.Slot = 1234
+	single.Signature[0] = 0xc0
+
+	att, err := single.ToAttestation(spec, committee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bl := att.AggregationBits.BitLen(); bl != uint64(len(committee)) {
+		t.Fatalf("expected %d aggregation bits, got %d", len(committee), bl)
+	}
+	voter, err := att.AggregationBits.SingleParticipant(committee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if voter != single.AttesterIndex {
+		t.Fatalf("expected voter %d, got %d", single.AttesterIndex, voter)
+	}
+	for i := uint64(0); i < uint64(spec.MAX_COMMITTEES_PER_SLOT); i++ {
+		if att.CommitteeBits.GetBit(i) != (i == 3) {
+			t.Fatalf("unexpected committee bit %d", i)
+		}
+	}
+	if att.Data != single.Data || att.Signature != single.Signature {
+		t.Fatal("data and signature must be copied")
+	}
+	// the converted attestation must be valid SSZ
+	var buf bytes.Buffer
+	if err := att.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Attestation
+	if err := decoded.Deserialize(spec, codec.NewDecodingReader(bytes.NewReader(buf.Bytes()), uint64(buf.Len()))); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.HashTreeRoot(spec, tree.GetHashFn()) != att.HashTreeRoot(spec, tree.GetHashFn()) {
+		t.Fatal("SSZ roundtrip changed the attestation")
+	}
+
+	single.AttesterIndex = 91
+	if _, err := single.ToAttestation(spec, committee); err == nil {
+		t.Fatal("expected error for attester outside of committee")
+	}
+	single.AttesterIndex = 90
+	single.CommitteeIndex = common.CommitteeIndex(spec.MAX_COMMITTEES_PER_SLOT)
+	if _, err := single.ToAttestation(spec, committee); err == nil {
+		t.Fatal("expected error for out of range committee index")
+	}
+}
+
+func TestCommitteeIndices(t *testing.T) {
+	spec := configs.Mainnet
+	bits := make(CommitteeBits, (uint64(spec.MAX_COMMITTEES_PER_SLOT)+7)/8)
+	if got := bits.CommitteeIndices(); len(got) != 0 {
+		t.Fatalf("expected no committee indices, got %v", got)
+	}
+	bits.SetBit(9, true)
+	bits.SetBit(2, true)
+	got := bits.CommitteeIndices()
+	if len(got) != 2 || got[0] != 2 || got[1] != 9 || bits.OnesCount() != 2 {
+		t.Fatalf("unexpected committee indices: %v", got)
+	}
+}
@@ -0,0 +1,49 @@
+package electra
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// The view types must use the Electra limit of attester slashings in a block,
+// MAX_ATTESTER_SLASHINGS_ELECTRA, like the struct types, or the roots of the two forms differ.
+func TestAttesterSlashingsViewRoot(t *testing.T) {
+	hFn := tree.GetHashFn()
+	for _, spec := range []*common.Spec{configs.Mainnet, configs.Minimal} {
+		slashing := AttesterSlashing{}
+		slashing.Attestation1.AttestingIndices = common.SlotCommitteeIndices{1, 2, 3}
+		slashing.Attestation2.AttestingIndices = common.SlotCommitteeIndices{2, 3}
+		slashing.Attestation2.Data.BeaconBlockRoot = common.Root{0xaa}
+		body := &BeaconBlockBody{AttesterSlashings: AttesterSlashings{slashing}}
+		body.SyncAggregate.SyncCommitteeBits = make([]byte, spec.SYNC_COMMITTEE_SIZE/8)
+
+		var buf bytes.Buffer
+		if err := body.AttesterSlashings.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+			t.Fatal(err)
+		}
+		slashingsView, err := BlockAttesterSlashingsType(spec).Deserialize(codec.NewDecodingReader(bytes.NewReader(buf.Bytes()), uint64(buf.Len())))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slashingsView.HashTreeRoot(hFn) != body.AttesterSlashings.HashTreeRoot(spec, hFn) {
+			t.Fatalf("%s: attester slashings view root does not match struct root", spec.CONFIG_NAME)
+		}
+
+		buf.Reset()
+		if err := body.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+			t.Fatal(err)
+		}
+		bodyView, err := BeaconBlockBodyType(spec).Deserialize(codec.NewDecodingReader(bytes.NewReader(buf.Bytes()), uint64(buf.Len())))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bodyView.HashTreeRoot(hFn) != body.HashTreeRoot(spec, hFn) {
+			t.Fatalf("%s: block body view root does not match struct root", spec.CONFIG_NAME)
+		}
+	}
+}
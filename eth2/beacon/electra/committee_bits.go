@@ -66,6 +66,22 @@ func (li CommitteeBits) SetBit(i uint64, v bool) {
 	bitfields.SetBit(li, i, v)
 }
 
+func (li CommitteeBits) OnesCount() uint64 {
+	return bitfields.BitvectorOnesCount(li)
+}
+
+// CommitteeIndices returns the indices of the committees with a set bit, in ascending order.
+// This is get_committee_indices in the spec.
+func (li CommitteeBits) CommitteeIndices() []common.CommitteeIndex {
+	var out []common.CommitteeIndex
+	for i := uint64(0); i < uint64(len(li))*8; i++ {
+		if li.GetBit(i) {
+			out = append(out, common.CommitteeIndex(i))
+		}
+	}
+	return out
+}
+
 type CommitteeBitsView struct {
 	*BitVectorView
 }
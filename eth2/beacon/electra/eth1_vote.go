@@ -0,0 +1,36 @@
+package electra
+
+import (
+	"context"
+
+	. "github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+type DepositRequestsBeaconState interface {
+	common.BeaconState
+
+	DepositRequestsStartIndex() (Uint64View, error)
+}
+
+// GetEth1Vote computes the eth1 data vote of a proposer, like phase0.GetEth1Vote.
+// Eth1 voting is phased out with EIP-6110: once all deposits up to the start of the deposit requests are processed,
+// the current eth1 data of the state is voted for. States of forks before Electra vote like in phase0.
+func GetEth1Vote(ctx context.Context, spec *common.Spec, state common.BeaconState, eth1Chain phase0.Eth1BlockSource) (common.Eth1Data, error) {
+	if drState, ok := state.(DepositRequestsBeaconState); ok {
+		depIndex, err := state.Eth1DepositIndex()
+		if err != nil {
+			return common.Eth1Data{}, err
+		}
+		startIndex, err := drState.DepositRequestsStartIndex()
+		if err != nil {
+			return common.Eth1Data{}, err
+		}
+		if uint64(depIndex) == uint64(startIndex) {
+			return state.Eth1Data()
+		}
+	}
+	return phase0.GetEth1Vote(ctx, spec, state, eth1Chain)
+}
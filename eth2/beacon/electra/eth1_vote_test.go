@@ -0,0 +1,59 @@
+package electra
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestGetEth1VoteDepositRequests(t *testing.T) {
+	spec := configs.Minimal
+	ctx := context.Background()
+	state := NewBeaconStateView(spec)
+	if err := state.SetGenesisTime(1_000_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.SetSlot(common.Slot(spec.EPOCHS_PER_ETH1_VOTING_PERIOD) * spec.SLOTS_PER_EPOCH * 10); err != nil {
+		t.Fatal(err)
+	}
+	stateEth1Data := common.Eth1Data{DepositCount: 1, BlockHash: common.Root{0xcc}}
+	if err := state.SetEth1Data(stateEth1Data); err != nil {
+		t.Fatal(err)
+	}
+	periodStart, err := phase0.VotingPeriodStartTime(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	followTime := common.Timestamp(spec.SECONDS_PER_ETH1_BLOCK * spec.ETH1_FOLLOW_DISTANCE)
+	candidate := phase0.Eth1Block{Timestamp: periodStart - followTime, BlockHash: common.Root{0xaa}, DepositCount: 2}
+	chain := phase0.MemoryEth1Chain{candidate}
+
+	if err := state.SetDepositRequestsStartIndex(2); err != nil {
+		t.Fatal(err)
+	}
+	// deposits before the deposit requests are not all processed yet, eth1 data is still voted on
+	vote, err := GetEth1Vote(ctx, spec, state, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vote != candidate.Eth1Data() {
+		t.Fatalf("expected vote for candidate block, got %v", vote)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := state.IncrementDepositIndex(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// all eth1 bridge deposits are processed, eth1 voting is phased out
+	vote, err = GetEth1Vote(ctx, spec, state, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vote != stateEth1Data {
+		t.Fatalf("expected vote for state eth1 data, got %v", vote)
+	}
+}
@@ -0,0 +1,81 @@
+package electra
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/tree"
+	. "github.com/protolambda/ztyp/view"
+)
+
+// UNSET_DEPOSIT_REQUESTS_START_INDEX marks the start of the deposit requests as not known yet.
+const UNSET_DEPOSIT_REQUESTS_START_INDEX = ^uint64(0)
+
+// HasCompoundingWithdrawalCredential checks for the 0x02 withdrawal credentials prefix.
+func HasCompoundingWithdrawalCredential(withdrawalCreds common.Root) bool {
+	return withdrawalCreds[0] == common.COMPOUNDING_WITHDRAWAL_PREFIX
+}
+
+// MaxEffectiveBalance returns the max effective balance of a validator with the given withdrawal credentials.
+func MaxEffectiveBalance(spec *common.Spec, withdrawalCreds common.Root) common.Gwei {
+	if HasCompoundingWithdrawalCredential(withdrawalCreds) {
+		return spec.MAX_EFFECTIVE_BALANCE_ELECTRA
+	}
+	return spec.MIN_ACTIVATION_BALANCE
+}
+
+// GenesisFromEth1 builds a genesis state that starts at Electra, like phase0.GenesisFromEth1.
+// The deposits are all applied at genesis, there are no pending deposits left in the genesis state.
+// Validators with compounding withdrawal credentials may have an effective balance up to MAX_EFFECTIVE_BALANCE_ELECTRA.
+// The header is the latest execution payload header of the genesis state.
+// If nil, the header only refers to the EL genesis block by eth1BlockHash, with empty transactions and withdrawals.
+func GenesisFromEth1(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, deps []common.Deposit, ignoreSignaturesAndProofs bool, header *deneb.ExecutionPayloadHeader) (*BeaconStateView, *common.EpochsContext, error) {
+	state := NewBeaconStateView(spec)
+	hFn := tree.GetHashFn()
+	settings := &phase0.GenesisSettings{
+		ForkVersion:   spec.ELECTRA_FORK_VERSION,
+		EmptyBodyRoot: BeaconBlockBodyType(spec).New().HashTreeRoot(hFn),
+		MaxEffectiveBalance: func(withdrawalCreds common.Root) common.Gwei {
+			return MaxEffectiveBalance(spec, withdrawalCreds)
+		},
+		ActivationBalance: spec.MIN_ACTIVATION_BALANCE,
+	}
+	epc, err := phase0.InitGenesisState(spec, state, settings, eth1BlockHash, time, deps, ignoreSignaturesAndProofs)
+	if err != nil {
+		return nil, nil, err
+	}
+	committee, err := ComputeNextSyncCommittee(spec, epc, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := altair.SetGenesisSyncCommittees(spec, epc, state, committee); err != nil {
+		return nil, nil, err
+	}
+	if header == nil {
+		header = &deneb.ExecutionPayloadHeader{
+			BlockHash:        eth1BlockHash,
+			TransactionsRoot: common.PayloadTransactions{}.HashTreeRoot(spec, hFn),
+			WithdrawalsRoot:  common.Withdrawals{}.HashTreeRoot(spec, hFn),
+		}
+	}
+	if err := state.SetLatestExecutionPayloadHeader(header); err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetDepositRequestsStartIndex(Uint64View(UNSET_DEPOSIT_REQUESTS_START_INDEX)); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
+
+// To build an Electra genesis state without Eth 1.0 deposits, i.e. directly from a sequence of minimal validator data.
+func KickStartState(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, validators []phase0.KickstartValidatorData, header *deneb.ExecutionPayloadHeader) (*BeaconStateView, *common.EpochsContext, error) {
+	state, epc, err := GenesisFromEth1(spec, eth1BlockHash, 0, phase0.KickstartDeposits(validators), true, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetGenesisTime(time); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
@@ -0,0 +1,107 @@
+package electra
+
+import (
+	"math/big"
+	"testing"
+
+	kbls "github.com/kilic/bls12-381"
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestKickStartState(t *testing.T) {
+	spec := configs.Minimal
+	g1 := kbls.NewG1()
+	var validators []phase0.KickstartValidatorData
+	for i := int64(1); i <= 64; i++ {
+		var pub kbls.PointG1
+		g1.MulScalarBig(&pub, g1.One(), big.NewInt(i))
+		creds := common.Root{common.ETH1_ADDRESS_WITHDRAWAL_PREFIX}
+		balance := spec.MIN_ACTIVATION_BALANCE
+		switch {
+		case i%4 == 0:
+			// compounding validators may have more effective balance
+			creds[0] = common.COMPOUNDING_WITHDRAWAL_PREFIX
+			balance = spec.MIN_ACTIVATION_BALANCE * 4
+		case i%4 == 1:
+			// not compounding, effective balance is capped
+			balance = spec.MIN_ACTIVATION_BALANCE * 2
+		case i == 7:
+			// not enough balance to be activated at genesis
+			balance = spec.MIN_ACTIVATION_BALANCE - spec.EFFECTIVE_BALANCE_INCREMENT
+		}
+		validators = append(validators, phase0.KickstartValidatorData{
+			Pubkey:                common.BLSPubkey((*blsu.Pubkey)(&pub).Serialize()),
+			WithdrawalCredentials: creds,
+			Balance:               balance,
+		})
+	}
+	header := &deneb.ExecutionPayloadHeader{BlockHash: common.Root{0xee}, BlockNumber: 1}
+	state, epc, err := KickStartState(spec, common.Root{0xaa}, 1_000_000, validators, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fork, err := state.Fork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork.PreviousVersion != spec.ELECTRA_FORK_VERSION || fork.CurrentVersion != spec.ELECTRA_FORK_VERSION {
+		t.Fatalf("unexpected fork: %v", fork)
+	}
+	if genesisTime, err := state.GenesisTime(); err != nil {
+		t.Fatal(err)
+	} else if genesisTime != 1_000_000 {
+		t.Fatalf("unexpected genesis time: %d", genesisTime)
+	}
+	raw, err := state.Raw(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.LatestExecutionPayloadHeader.BlockHash != header.BlockHash || raw.LatestExecutionPayloadHeader.BlockNumber != 1 {
+		t.Fatal("unexpected latest execution payload header")
+	}
+	if len(raw.PendingDeposits) != 0 {
+		t.Fatalf("expected no pending deposits, got %d", len(raw.PendingDeposits))
+	}
+	if uint64(raw.DepositRequestsStartIndex) != UNSET_DEPOSIT_REQUESTS_START_INDEX {
+		t.Fatalf("unexpected deposit requests start index: %d", raw.DepositRequestsStartIndex)
+	}
+	if raw.Eth1DepositIndex != 64 || raw.Eth1Data.DepositCount != 64 {
+		t.Fatal("expected all deposits to be processed")
+	}
+
+	for i, v := range raw.Validators {
+		data := &validators[i]
+		if raw.Balances[i] != data.Balance {
+			t.Fatalf("validator %d: unexpected balance %d", i, raw.Balances[i])
+		}
+		expectedEff := data.Balance
+		if max := MaxEffectiveBalance(spec, data.WithdrawalCredentials); expectedEff > max {
+			expectedEff = max
+		}
+		if v.EffectiveBalance != expectedEff {
+			t.Fatalf("validator %d: unexpected effective balance %d", i, v.EffectiveBalance)
+		}
+		expectActive := expectedEff >= spec.MIN_ACTIVATION_BALANCE
+		if active := v.ActivationEpoch == common.GENESIS_EPOCH; active != expectActive {
+			t.Fatalf("validator %d: expected active %v", i, expectActive)
+		}
+	}
+
+	expectedCommittee, err := ComputeNextSyncCommittee(spec, epc, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.CurrentSyncCommittee.AggregatePubkey != expectedCommittee.AggregatePubkey ||
+		raw.NextSyncCommittee.AggregatePubkey != expectedCommittee.AggregatePubkey {
+		t.Fatal("unexpected sync committees")
+	}
+	if len(raw.CurrentSyncCommittee.Pubkeys) != int(spec.SYNC_COMMITTEE_SIZE) {
+		t.Fatalf("unexpected sync committee size: %d", len(raw.CurrentSyncCommittee.Pubkeys))
+	}
+}
@@ -0,0 +1,80 @@
+package electra
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+)
+
+// MAX_RANDOM_VALUE is the maximum 2-byte random value used in the sync committee selection since Electra.
+const MAX_RANDOM_VALUE = 1<<16 - 1
+
+// ComputeNextSyncCommittee is like common.ComputeNextSyncCommittee, with the Electra sync committee selection.
+func ComputeNextSyncCommittee(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState) (*common.SyncCommittee, error) {
+	indices, err := ComputeSyncCommitteeIndices(spec, state, epc.NextEpoch.Epoch, epc.NextEpoch.ActiveIndices)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute sync committee indices for next epoch %d: %w", epc.NextEpoch.Epoch, err)
+	}
+	return common.IndicesToSyncCommittee(indices, epc.ValidatorPubkeyCache)
+}
+
+// ComputeSyncCommitteeIndices is like common.ComputeSyncCommitteeIndices,
+// but selects validators with a 2-byte random value, against MAX_EFFECTIVE_BALANCE_ELECTRA.
+func ComputeSyncCommitteeIndices(spec *common.Spec, state common.BeaconState, baseEpoch common.Epoch, active []common.ValidatorIndex) ([]common.ValidatorIndex, error) {
+	if len(active) == 0 {
+		return nil, errors.New("no active validators to compute sync committee from")
+	}
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	if epoch := spec.SlotToEpoch(slot); baseEpoch > epoch+1 {
+		return nil, fmt.Errorf("state at slot %d (epoch %d) is not far along enough to compute sync committee data for epoch %d", slot, epoch, baseEpoch)
+	}
+	syncCommitteeIndices := make([]common.ValidatorIndex, 0, spec.SYNC_COMMITTEE_SIZE)
+	mixes, err := state.RandaoMixes()
+	if err != nil {
+		return nil, err
+	}
+	periodSeed, err := common.GetSeed(spec, mixes, baseEpoch, common.DOMAIN_SYNC_COMMITTEE)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	hFn := hashing.GetHashFn()
+	var buf [32 + 8]byte
+	copy(buf[0:32], periodSeed[:])
+	var h [32]byte
+	i := common.ValidatorIndex(0)
+	for uint64(len(syncCommitteeIndices)) < uint64(spec.SYNC_COMMITTEE_SIZE) {
+		shuffledIndex := common.PermuteIndex(uint8(spec.SHUFFLE_ROUND_COUNT), i%common.ValidatorIndex(len(active)),
+			uint64(len(active)), periodSeed)
+		candidateIndex := active[shuffledIndex]
+		validator, err := vals.Validator(candidateIndex)
+		if err != nil {
+			return nil, err
+		}
+		effectiveBalance, err := validator.EffectiveBalance()
+		if err != nil {
+			return nil, err
+		}
+		// every 16 rounds, create a new source for randomValue
+		if i%16 == 0 {
+			binary.LittleEndian.PutUint64(buf[32:32+8], uint64(i/16))
+			h = hFn(buf[:])
+		}
+		offset := (i % 16) * 2
+		randomValue := binary.LittleEndian.Uint16(h[offset : offset+2])
+		if effectiveBalance*MAX_RANDOM_VALUE >= spec.MAX_EFFECTIVE_BALANCE_ELECTRA*common.Gwei(randomValue) {
+			syncCommitteeIndices = append(syncCommitteeIndices, candidateIndex)
+		}
+		i += 1
+	}
+	return syncCommitteeIndices, nil
+}
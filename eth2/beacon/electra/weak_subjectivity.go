@@ -0,0 +1,24 @@
+package electra
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// GetBalanceChurnLimit returns the churn of the given total active stake, in Gwei, per epoch.
+func GetBalanceChurnLimit(spec *common.Spec, totalActiveStake common.Gwei) common.Gwei {
+	churn := totalActiveStake / common.Gwei(spec.CHURN_LIMIT_QUOTIENT)
+	if minChurn := common.Gwei(spec.MIN_PER_EPOCH_CHURN_LIMIT_ELECTRA); churn < minChurn {
+		churn = minChurn
+	}
+	return churn - churn%spec.EFFECTIVE_BALANCE_INCREMENT
+}
+
+// ComputeWeakSubjectivityPeriod computes the number of epochs, after the current epoch of the epochs-context,
+// within which a state of that epoch is safe to sync from.
+// Since Electra the period is derived from the balance churn, instead of the validator count churn.
+func ComputeWeakSubjectivityPeriod(spec *common.Spec, epc *common.EpochsContext) common.Epoch {
+	t := uint64(epc.TotalActiveStake)
+	delta := uint64(GetBalanceChurnLimit(spec, epc.TotalActiveStake))
+	epochsForValidatorSetChurn := common.SAFETY_DECAY * t / (2 * delta * 100)
+	return spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY + common.Epoch(epochsForValidatorSetChurn)
+}
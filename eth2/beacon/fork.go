@@ -62,6 +62,18 @@ func (d *ForkDecoder) BlockAllocator(digest common.ForkDigest) (func() OpaqueBlo
 	}
 }
 
+// BlobSidecarAllocator returns an allocator for the blob sidecar type of the fork with the given digest.
+func (d *ForkDecoder) BlobSidecarAllocator(digest common.ForkDigest) (func() common.SpecObj, error) {
+	switch digest {
+	case d.Deneb, d.Electra:
+		return func() common.SpecObj { return new(deneb.BlobSidecar) }, nil
+	case d.Genesis, d.Altair, d.Bellatrix, d.Capella:
+		return nil, fmt.Errorf("no blob sidecars before deneb, got fork digest: %s", digest)
+	default:
+		return nil, fmt.Errorf("unrecognized fork digest: %s", digest)
+	}
+}
+
 func (d *ForkDecoder) ForkDigest(epoch common.Epoch) common.ForkDigest {
 	if epoch < d.Spec.ALTAIR_FORK_EPOCH {
 		return d.Genesis
@@ -204,3 +216,23 @@ func EnvelopeToSignedBeaconBlock(benv *common.BeaconBlockEnvelope) (common.SpecO
 		return nil, fmt.Errorf("cannot convert beacon block envelope to full signed block, unrecognized body type: %T", x)
 	}
 }
+
+// StateForkName returns the name of the fork of the tree-backed state view.
+func StateForkName(state common.BeaconState) (string, error) {
+	switch state.(type) {
+	case *phase0.BeaconStateView:
+		return common.Phase0, nil
+	case *altair.BeaconStateView:
+		return common.Altair, nil
+	case *bellatrix.BeaconStateView:
+		return common.Bellatrix, nil
+	case *capella.BeaconStateView:
+		return common.Capella, nil
+	case *deneb.BeaconStateView:
+		return common.Deneb, nil
+	case *electra.BeaconStateView:
+		return common.Electra, nil
+	default:
+		return "", fmt.Errorf("unsupported state type: %T", state)
+	}
+}
@@ -101,14 +101,14 @@ func ProcessAttestations(ctx context.Context, spec *common.Spec, epc *common.Epo
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if err := ProcessAttestation(spec, epc, state, &ops[i]); err != nil {
+		if err := ProcessAttestation(ctx, spec, epc, state, &ops[i]); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func ProcessAttestation(spec *common.Spec, epc *common.EpochsContext, state Phase0PendingAttestationsBeaconState, attestation *Attestation) error {
+func ProcessAttestation(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state Phase0PendingAttestationsBeaconState, attestation *Attestation) error {
 	data := &attestation.Data
 
 	// Check slot
@@ -171,7 +171,7 @@ func ProcessAttestation(spec *common.Spec, epc *common.EpochsContext, state Phas
 	}
 	if indexedAtt, err := attestation.ConvertToIndexed(spec, committee); err != nil {
 		return fmt.Errorf("attestation could not be converted to an indexed attestation: %v", err)
-	} else if err := ValidateIndexedAttestation(spec, epc, state, indexedAtt); err != nil {
+	} else if err := ValidateCommitteeAttestation(ctx, spec, epc, state, attestation, indexedAtt); err != nil {
 		return fmt.Errorf("attestation could not be verified in its indexed form: %v", err)
 	}
 
@@ -73,19 +73,25 @@ func ComputeEpochAttesterData(ctx context.Context, spec *common.Spec, epc *commo
 		Flats:     flats,
 	}
 
-	for i := common.ValidatorIndex(0); i < count; i++ {
-		flat := &flats[i]
+	err = spec.Parallelism.ParallelRanges(ctx, uint64(count), func(ctx context.Context, _ int, r common.IndexRange) error {
+		for i := common.ValidatorIndex(r.Start); i < common.ValidatorIndex(r.End); i++ {
+			flat := &flats[i]
 
-		status := &out.Statuses[i]
-		status.AttestedProposer = common.ValidatorIndexMarker
+			status := &out.Statuses[i]
+			status.AttestedProposer = common.ValidatorIndexMarker
 
-		if !flat.Slashed {
-			status.Flags |= UnslashedAttester
-		}
+			if !flat.Slashed {
+				status.Flags |= UnslashedAttester
+			}
 
-		if flat.IsActive(prevEpoch) || (flat.Slashed && (prevEpoch+1 < flat.WithdrawableEpoch)) {
-			status.Flags |= EligibleAttester
+			if flat.IsActive(prevEpoch) || (flat.Slashed && (prevEpoch+1 < flat.WithdrawableEpoch)) {
+				status.Flags |= EligibleAttester
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	processEpoch := func(
@@ -191,23 +197,39 @@ func ComputeEpochAttesterData(ctx context.Context, spec *common.Spec, epc *commo
 		return nil, err
 	}
 
-	for i := 0; i < len(out.Statuses); i++ {
-		status := &out.Statuses[i]
-		flat := &flats[i]
-		// nested, since they are subsets anyway
-		if status.Flags.HasMarkers(PrevSourceAttester | UnslashedAttester) {
-			out.PrevEpochUnslashedStake.SourceStake += flat.EffectiveBalance
-			// already know it's unslashed, just look if attesting target, then head
-			if status.Flags.HasMarkers(PrevTargetAttester) {
-				out.PrevEpochUnslashedStake.TargetStake += flat.EffectiveBalance
-				if status.Flags.HasMarkers(PrevHeadAttester) {
-					out.PrevEpochUnslashedStake.HeadStake += flat.EffectiveBalance
+	stakeRanges := spec.Parallelism.SplitRanges(uint64(count))
+	prevStakes := make([]EpochStakeSummary, len(stakeRanges))
+	currStakes := make([]common.Gwei, len(stakeRanges))
+	err = common.ForEachRange(ctx, stakeRanges, func(ctx context.Context, ri int, r common.IndexRange) error {
+		prevStake := &prevStakes[ri]
+		for i := r.Start; i < r.End; i++ {
+			status := &out.Statuses[i]
+			flat := &flats[i]
+			// nested, since they are subsets anyway
+			if status.Flags.HasMarkers(PrevSourceAttester | UnslashedAttester) {
+				prevStake.SourceStake += flat.EffectiveBalance
+				// already know it's unslashed, just look if attesting target, then head
+				if status.Flags.HasMarkers(PrevTargetAttester) {
+					prevStake.TargetStake += flat.EffectiveBalance
+					if status.Flags.HasMarkers(PrevHeadAttester) {
+						prevStake.HeadStake += flat.EffectiveBalance
+					}
 				}
 			}
+			if status.Flags.HasMarkers(CurrTargetAttester | UnslashedAttester) {
+				currStakes[ri] += flat.EffectiveBalance
+			}
 		}
-		if status.Flags.HasMarkers(CurrTargetAttester | UnslashedAttester) {
-			out.CurrEpochUnslashedTargetStake += flat.EffectiveBalance
-		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for ri := range stakeRanges {
+		out.PrevEpochUnslashedStake.SourceStake += prevStakes[ri].SourceStake
+		out.PrevEpochUnslashedStake.TargetStake += prevStakes[ri].TargetStake
+		out.PrevEpochUnslashedStake.HeadStake += prevStakes[ri].HeadStake
+		out.CurrEpochUnslashedTargetStake += currStakes[ri]
 	}
 	if out.PrevEpochUnslashedStake.SourceStake < spec.EFFECTIVE_BALANCE_INCREMENT {
 		out.PrevEpochUnslashedStake.SourceStake = spec.EFFECTIVE_BALANCE_INCREMENT
@@ -17,7 +17,7 @@ func ProcessAttesterSlashings(ctx context.Context, spec *common.Spec, epc *commo
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if err := ProcessAttesterSlashing(spec, epc, state, &ops[i]); err != nil {
+		if err := ProcessAttesterSlashing(ctx, spec, epc, state, &ops[i]); err != nil {
 			return err
 		}
 	}
@@ -104,7 +104,7 @@ func (li AttesterSlashings) MarshalJSON() ([]byte, error) {
 	return json.Marshal([]AttesterSlashing(li))
 }
 
-func ProcessAttesterSlashing(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, attesterSlashing *AttesterSlashing) error {
+func ProcessAttesterSlashing(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, attesterSlashing *AttesterSlashing) error {
 	sa1 := &attesterSlashing.Attestation1
 	sa2 := &attesterSlashing.Attestation2
 
@@ -112,10 +112,10 @@ func ProcessAttesterSlashing(spec *common.Spec, epc *common.EpochsContext, state
 		return errors.New("attester slashing has no valid reasoning")
 	}
 
-	if err := ValidateIndexedAttestation(spec, epc, state, sa1); err != nil {
+	if err := ValidateIndexedAttestation(ctx, spec, epc, state, sa1); err != nil {
 		return errors.New("attestation 1 of attester slashing cannot be verified")
 	}
-	if err := ValidateIndexedAttestation(spec, epc, state, sa2); err != nil {
+	if err := ValidateIndexedAttestation(ctx, spec, epc, state, sa2); err != nil {
 		return errors.New("attestation 2 of attester slashing cannot be verified")
 	}
 
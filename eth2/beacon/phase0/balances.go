@@ -1,6 +1,7 @@
 package phase0
 
 import (
+	"encoding/json"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
@@ -38,6 +39,13 @@ func (li Balances) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root
 	}, length, uint64(spec.VALIDATOR_REGISTRY_LIMIT))
 }
 
+func (li Balances) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]common.Gwei{}) // encode as empty list, not null
+	}
+	return json.Marshal([]common.Gwei(li))
+}
+
 func (li Balances) View(limit uint64) (*RegistryBalancesView, error) {
 	// TODO: bad copy, converting to a tree more directly somehow would be nice.
 	tmp := make([]BasicView, len(li), len(li))
@@ -59,80 +59,100 @@ func AttestationRewardsAndPenalties(ctx context.Context, spec *common.Spec,
 
 	isInactivityLeak := finalityDelay > spec.MIN_EPOCHS_TO_INACTIVITY_PENALTY
 
-	for i := common.ValidatorIndex(0); i < validatorCount; i++ {
-		// every 1024 validators, check if the context is done.
-		if i&((1<<10)-1) == 0 {
-			if err := ctx.Err(); err != nil {
-				return nil, err
+	// Every worker writes to the deltas of a distinct set of validators.
+	// The proposer rewards are not bound to the validator range, and are added afterwards.
+	err = spec.Parallelism.ParallelRanges(ctx, uint64(validatorCount), func(ctx context.Context, _ int, r common.IndexRange) error {
+		start, end := common.ValidatorIndex(r.Start), common.ValidatorIndex(r.End)
+		for i := start; i < end; i++ {
+			// every 1024 validators, check if the context is done.
+			if (i-start)&((1<<10)-1) == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
 			}
-		}
-		status := &attesterStatuses[i]
-		effBalance := attesterData.Flats[i].EffectiveBalance
-		baseReward := effBalance * common.Gwei(spec.BASE_REWARD_FACTOR) /
-			balanceSqRoot / common.BASE_REWARDS_PER_EPOCH
-
-		// Inclusion delay
-		if status.Flags.HasMarkers(PrevSourceAttester | UnslashedAttester) {
-			// Inclusion speed bonus
-			proposerReward := baseReward / common.Gwei(spec.PROPOSER_REWARD_QUOTIENT)
-			res.InclusionDelay.Rewards[status.AttestedProposer] += proposerReward
-			maxAttesterReward := baseReward - proposerReward
-			res.InclusionDelay.Rewards[i] += maxAttesterReward / common.Gwei(status.InclusionDelay)
-		}
-
-		if status.Flags&EligibleAttester != 0 {
-			// Since full base reward will be canceled out by inactivity penalty deltas,
-			// optimal participation receives full base reward compensation here.
+			status := &attesterStatuses[i]
+			effBalance := attesterData.Flats[i].EffectiveBalance
+			baseReward := effBalance * common.Gwei(spec.BASE_REWARD_FACTOR) /
+				balanceSqRoot / common.BASE_REWARDS_PER_EPOCH
 
-			// Expected FFG source
+			// Inclusion delay
 			if status.Flags.HasMarkers(PrevSourceAttester | UnslashedAttester) {
-				if isInactivityLeak {
-					res.Source.Rewards[i] += baseReward
+				// Inclusion speed bonus
+				proposerReward := baseReward / common.Gwei(spec.PROPOSER_REWARD_QUOTIENT)
+				maxAttesterReward := baseReward - proposerReward
+				res.InclusionDelay.Rewards[i] += maxAttesterReward / common.Gwei(status.InclusionDelay)
+			}
+
+			if status.Flags&EligibleAttester != 0 {
+				// Since full base reward will be canceled out by inactivity penalty deltas,
+				// optimal participation receives full base reward compensation here.
+
+				// Expected FFG source
+				if status.Flags.HasMarkers(PrevSourceAttester | UnslashedAttester) {
+					if isInactivityLeak {
+						res.Source.Rewards[i] += baseReward
+					} else {
+						// Justification-participation reward
+						res.Source.Rewards[i] += baseReward * prevEpochSourceStake / totalBalance
+					}
 				} else {
-					// Justification-participation reward
-					res.Source.Rewards[i] += baseReward * prevEpochSourceStake / totalBalance
+					//Justification-non-participation R-penalty
+					res.Source.Penalties[i] += baseReward
 				}
-			} else {
-				//Justification-non-participation R-penalty
-				res.Source.Penalties[i] += baseReward
-			}
 
-			// Expected FFG target
-			if status.Flags.HasMarkers(PrevTargetAttester | UnslashedAttester) {
-				if isInactivityLeak {
-					res.Target.Rewards[i] += baseReward
+				// Expected FFG target
+				if status.Flags.HasMarkers(PrevTargetAttester | UnslashedAttester) {
+					if isInactivityLeak {
+						res.Target.Rewards[i] += baseReward
+					} else {
+						// Boundary-attestation reward
+						res.Target.Rewards[i] += baseReward * prevEpochTargetStake / totalBalance
+					}
 				} else {
-					// Boundary-attestation reward
-					res.Target.Rewards[i] += baseReward * prevEpochTargetStake / totalBalance
+					//Boundary-attestation-non-participation R-penalty
+					res.Target.Penalties[i] += baseReward
 				}
-			} else {
-				//Boundary-attestation-non-participation R-penalty
-				res.Target.Penalties[i] += baseReward
-			}
 
-			// Expected head
-			if status.Flags.HasMarkers(PrevHeadAttester | UnslashedAttester) {
-				if isInactivityLeak {
-					res.Head.Rewards[i] += baseReward
+				// Expected head
+				if status.Flags.HasMarkers(PrevHeadAttester | UnslashedAttester) {
+					if isInactivityLeak {
+						res.Head.Rewards[i] += baseReward
+					} else {
+						// Canonical-participation reward
+						res.Head.Rewards[i] += baseReward * prevEpochHeadStake / totalBalance
+					}
 				} else {
-					// Canonical-participation reward
-					res.Head.Rewards[i] += baseReward * prevEpochHeadStake / totalBalance
+					// Non-canonical-participation R-penalty
+					res.Head.Penalties[i] += baseReward
 				}
-			} else {
-				// Non-canonical-participation R-penalty
-				res.Head.Penalties[i] += baseReward
-			}
 
-			// Take away max rewards if we're not finalizing
-			if isInactivityLeak {
-				// If validator is performing optimally this cancels all rewards for a neutral balance
-				proposerReward := baseReward / common.Gwei(spec.PROPOSER_REWARD_QUOTIENT)
-				res.Inactivity.Penalties[i] += common.BASE_REWARDS_PER_EPOCH*baseReward - proposerReward
-				if !status.Flags.HasMarkers(PrevTargetAttester | UnslashedAttester) {
-					res.Inactivity.Penalties[i] += effBalance * common.Gwei(finalityDelay) / common.Gwei(settings.InactivityPenaltyQuotient)
+				// Take away max rewards if we're not finalizing
+				if isInactivityLeak {
+					// If validator is performing optimally this cancels all rewards for a neutral balance
+					proposerReward := baseReward / common.Gwei(spec.PROPOSER_REWARD_QUOTIENT)
+					res.Inactivity.Penalties[i] += common.BASE_REWARDS_PER_EPOCH*baseReward - proposerReward
+					if !status.Flags.HasMarkers(PrevTargetAttester | UnslashedAttester) {
+						res.Inactivity.Penalties[i] += effBalance * common.Gwei(finalityDelay) / common.Gwei(settings.InactivityPenaltyQuotient)
+					}
 				}
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Inclusion speed bonus for the proposers
+	for i := common.ValidatorIndex(0); i < validatorCount; i++ {
+		status := &attesterStatuses[i]
+		if status.Flags.HasMarkers(PrevSourceAttester | UnslashedAttester) {
+			effBalance := attesterData.Flats[i].EffectiveBalance
+			baseReward := effBalance * common.Gwei(spec.BASE_REWARD_FACTOR) /
+				balanceSqRoot / common.BASE_REWARDS_PER_EPOCH
+			proposerReward := baseReward / common.Gwei(spec.PROPOSER_REWARD_QUOTIENT)
+			res.InclusionDelay.Rewards[status.AttestedProposer] += proposerReward
+		}
 	}
 
 	return res, nil
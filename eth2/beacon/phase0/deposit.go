@@ -172,6 +172,21 @@ func ProcessDeposit(spec *common.Spec, epc *common.EpochsContext, state common.B
 		} else {
 			epc.ValidatorPubkeyCache = pc
 		}
+		vals, err := state.Validators()
+		if err != nil {
+			return err
+		}
+		val, err := vals.Validator(valIndex)
+		if err != nil {
+			return err
+		}
+		eff, err := val.EffectiveBalance()
+		if err != nil {
+			return err
+		}
+		if err := epc.AppendEffectiveBalance(vals, valIndex, eff); err != nil {
+			return err
+		}
 	} else {
 		// Increase balance by deposit amount
 		bals, err := state.Balances()
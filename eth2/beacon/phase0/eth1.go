@@ -2,6 +2,7 @@ package phase0
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 
 	"github.com/protolambda/zrnt/eth2/beacon/common"
@@ -44,6 +45,13 @@ func (li Eth1DataVotes) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.
 	}, length, uint64(spec.EPOCHS_PER_ETH1_VOTING_PERIOD)*uint64(spec.SLOTS_PER_EPOCH))
 }
 
+func (li Eth1DataVotes) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]common.Eth1Data{}) // encode as empty list, not null
+	}
+	return json.Marshal([]common.Eth1Data(li))
+}
+
 func Eth1DataVotesType(spec *common.Spec) ListTypeDef {
 	return ListType(common.Eth1DataType, uint64(spec.EPOCHS_PER_ETH1_VOTING_PERIOD)*uint64(spec.SLOTS_PER_EPOCH))
 }
@@ -90,6 +98,14 @@ func (v *Eth1DataVotesView) Append(dat common.Eth1Data) error {
 	return v.ComplexListView.Append(dat.View())
 }
 
+func (v *Eth1DataVotesView) Vote(i uint64) (common.Eth1Data, error) {
+	dat, err := common.AsEth1Data(v.Get(i))
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	return dat.Raw()
+}
+
 func ProcessEth1Vote(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, data common.Eth1Data) error {
 	if err := ctx.Err(); err != nil {
 		return err
@@ -0,0 +1,145 @@
+package phase0
+
+import (
+	"context"
+	"sort"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// Eth1Block is the eth1 block data that is voted on, see the honest validator spec.
+type Eth1Block struct {
+	Timestamp    common.Timestamp    `json:"timestamp" yaml:"timestamp"`
+	BlockHash    common.Root         `json:"block_hash" yaml:"block_hash"`
+	DepositRoot  common.Root         `json:"deposit_root" yaml:"deposit_root"`
+	DepositCount common.DepositIndex `json:"deposit_count" yaml:"deposit_count"`
+}
+
+func (b *Eth1Block) Eth1Data() common.Eth1Data {
+	return common.Eth1Data{
+		DepositRoot:  b.DepositRoot,
+		DepositCount: b.DepositCount,
+		BlockHash:    b.BlockHash,
+	}
+}
+
+// Eth1BlockSource provides the eth1 blocks to vote on, e.g. from an eth1 node or a local cache of it.
+type Eth1BlockSource interface {
+	// Eth1BlocksByTimestamp returns the blocks with a timestamp within the inclusive range,
+	// ordered by ascending block height.
+	Eth1BlocksByTimestamp(ctx context.Context, min common.Timestamp, max common.Timestamp) ([]Eth1Block, error)
+}
+
+// MemoryEth1Chain is an in-memory Eth1BlockSource, with blocks ordered by ascending block height.
+type MemoryEth1Chain []Eth1Block
+
+func (c MemoryEth1Chain) Eth1BlocksByTimestamp(ctx context.Context, min common.Timestamp, max common.Timestamp) ([]Eth1Block, error) {
+	start := sort.Search(len(c), func(i int) bool {
+		return c[i].Timestamp >= min
+	})
+	end := sort.Search(len(c), func(i int) bool {
+		return c[i].Timestamp > max
+	})
+	if end < start {
+		end = start
+	}
+	out := make([]Eth1Block, end-start)
+	copy(out, c[start:end])
+	return out, nil
+}
+
+// VotingPeriodStartTime is the time of the first slot of the current eth1 voting period.
+func VotingPeriodStartTime(spec *common.Spec, state common.BeaconState) (common.Timestamp, error) {
+	slot, err := state.Slot()
+	if err != nil {
+		return 0, err
+	}
+	genesisTime, err := state.GenesisTime()
+	if err != nil {
+		return 0, err
+	}
+	periodSlots := common.Slot(spec.EPOCHS_PER_ETH1_VOTING_PERIOD) * spec.SLOTS_PER_EPOCH
+	return spec.TimeAtSlot(slot-slot%periodSlots, genesisTime)
+}
+
+// IsCandidateBlock checks if the eth1 block is within the follow distance range of the voting period.
+func IsCandidateBlock(spec *common.Spec, block *Eth1Block, periodStart common.Timestamp) bool {
+	followTime := common.Timestamp(spec.SECONDS_PER_ETH1_BLOCK * spec.ETH1_FOLLOW_DISTANCE)
+	return block.Timestamp+followTime <= periodStart && block.Timestamp+followTime*2 >= periodStart
+}
+
+// GetEth1Vote computes the eth1 data a proposer votes for: the most popular valid vote of the current voting period,
+// or else the latest candidate block of the eth1 chain, or else the current eth1 data of the state.
+func GetEth1Vote(ctx context.Context, spec *common.Spec, state common.BeaconState, eth1Chain Eth1BlockSource) (common.Eth1Data, error) {
+	stateEth1Data, err := state.Eth1Data()
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	periodStart, err := VotingPeriodStartTime(spec, state)
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	followTime := common.Timestamp(spec.SECONDS_PER_ETH1_BLOCK * spec.ETH1_FOLLOW_DISTANCE)
+	var minTime common.Timestamp
+	if periodStart > followTime*2 {
+		minTime = periodStart - followTime*2
+	}
+	var maxTime common.Timestamp
+	if periodStart > followTime {
+		maxTime = periodStart - followTime
+	}
+	blocks, err := eth1Chain.Eth1BlocksByTimestamp(ctx, minTime, maxTime)
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	var votesToConsider []common.Eth1Data
+	candidates := make(map[common.Eth1Data]struct{})
+	for i := range blocks {
+		block := &blocks[i]
+		// Ensure cannot move back to earlier deposit contract states
+		if IsCandidateBlock(spec, block, periodStart) && block.DepositCount >= stateEth1Data.DepositCount {
+			vote := block.Eth1Data()
+			votesToConsider = append(votesToConsider, vote)
+			candidates[vote] = struct{}{}
+		}
+	}
+
+	// Default vote on latest eth1 block data in the period range unless eth1 chain is not live
+	best := stateEth1Data
+	if len(votesToConsider) > 0 {
+		best = votesToConsider[len(votesToConsider)-1]
+	}
+
+	// Valid votes already cast during this period. Ties are broken by the earliest vote.
+	votes, err := state.Eth1DataVotes()
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	voteCount, err := votes.Length()
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	counts := make(map[common.Eth1Data]uint64)
+	var valid []common.Eth1Data
+	for i := uint64(0); i < voteCount; i++ {
+		vote, err := votes.Vote(i)
+		if err != nil {
+			return common.Eth1Data{}, err
+		}
+		if _, ok := candidates[vote]; !ok {
+			continue
+		}
+		if counts[vote] == 0 {
+			valid = append(valid, vote)
+		}
+		counts[vote] += 1
+	}
+	bestCount := uint64(0)
+	for _, vote := range valid {
+		if c := counts[vote]; c > bestCount {
+			best = vote
+			bestCount = c
+		}
+	}
+	return best, nil
+}
@@ -0,0 +1,115 @@
+package phase0
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+// testEth1Chain creates eth1 blocks every SECONDS_PER_ETH1_BLOCK, before and after the voting period start,
+// with the deposit count of each block equal to its height.
+func testEth1Chain(spec *common.Spec, periodStart common.Timestamp) MemoryEth1Chain {
+	followTime := common.Timestamp(spec.SECONDS_PER_ETH1_BLOCK * spec.ETH1_FOLLOW_DISTANCE)
+	var chain MemoryEth1Chain
+	for i := uint64(0); ; i++ {
+		timestamp := periodStart - 3*followTime + common.Timestamp(i*uint64(spec.SECONDS_PER_ETH1_BLOCK))
+		if timestamp > periodStart {
+			return chain
+		}
+		chain = append(chain, Eth1Block{
+			Timestamp:    timestamp,
+			BlockHash:    common.Root{byte(i), 0xaa},
+			DepositRoot:  common.Root{byte(i), 0xbb},
+			DepositCount: common.DepositIndex(i),
+		})
+	}
+}
+
+func TestGetEth1Vote(t *testing.T) {
+	spec := configs.Minimal
+	ctx := context.Background()
+	state := NewBeaconStateView(spec)
+	genesisTime := common.Timestamp(1_000_000)
+	periodSlots := common.Slot(spec.EPOCHS_PER_ETH1_VOTING_PERIOD) * spec.SLOTS_PER_EPOCH
+	if err := state.SetGenesisTime(genesisTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.SetSlot(periodSlots*2 + 5); err != nil {
+		t.Fatal(err)
+	}
+	periodStart, err := VotingPeriodStartTime(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := genesisTime + common.Timestamp(periodSlots*2)*spec.SECONDS_PER_SLOT; periodStart != expected {
+		t.Fatalf("expected period start %d, got %d", expected, periodStart)
+	}
+
+	chain := testEth1Chain(spec, periodStart)
+	var candidates []Eth1Block
+	for i := range chain {
+		if IsCandidateBlock(spec, &chain[i], periodStart) {
+			candidates = append(candidates, chain[i])
+		}
+	}
+	if len(candidates) != int(spec.ETH1_FOLLOW_DISTANCE)+1 {
+		t.Fatalf("expected %d candidate blocks, got %d", spec.ETH1_FOLLOW_DISTANCE+1, len(candidates))
+	}
+	stateEth1Data := common.Eth1Data{DepositCount: candidates[2].DepositCount, BlockHash: common.Root{0xcc}}
+	if err := state.SetEth1Data(stateEth1Data); err != nil {
+		t.Fatal(err)
+	}
+
+	// without votes, the latest candidate is voted for
+	vote, err := GetEth1Vote(ctx, spec, state, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest := candidates[len(candidates)-1].Eth1Data(); vote != latest {
+		t.Fatalf("expected vote for latest candidate %v, got %v", latest, vote)
+	}
+
+	// without eth1 blocks, the current eth1 data is voted for
+	vote, err = GetEth1Vote(ctx, spec, state, MemoryEth1Chain{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vote != stateEth1Data {
+		t.Fatalf("expected vote for state eth1 data, got %v", vote)
+	}
+
+	// votes for blocks outside of the range, or with fewer deposits, are not counted,
+	// and ties are broken by the earliest vote.
+	votes, err := state.Eth1DataVotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := candidates[5].Eth1Data(), candidates[4].Eth1Data()
+	for _, v := range []common.Eth1Data{
+		chain[0].Eth1Data(), candidates[1].Eth1Data(), a, b, b, chain[0].Eth1Data(),
+		candidates[1].Eth1Data(), a, chain[len(chain)-1].Eth1Data(), chain[0].Eth1Data(),
+	} {
+		if err := votes.Append(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vote, err = GetEth1Vote(ctx, spec, state, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vote != a {
+		t.Fatalf("expected vote %v, got %v", a, vote)
+	}
+	if err := votes.Append(b); err != nil {
+		t.Fatal(err)
+	}
+	vote, err = GetEth1Vote(ctx, spec, state, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vote != b {
+		t.Fatalf("expected vote %v, got %v", b, vote)
+	}
+}
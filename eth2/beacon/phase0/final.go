@@ -44,6 +44,7 @@ func ProcessEffectiveBalanceUpdates(ctx context.Context, spec *common.Spec, epc
 			if err := val.SetEffectiveBalance(effBalance); err != nil {
 				return err
 			}
+			flats[i].EffectiveBalance = effBalance
 		}
 	}
 	return nil
@@ -25,15 +25,48 @@ func NewDepositRootsView() *DepositRootsView {
 
 func GenesisFromEth1(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, deps []common.Deposit, ignoreSignaturesAndProofs bool) (*BeaconStateView, *common.EpochsContext, error) {
 	state := NewBeaconStateView(spec)
-	if err := state.SetGenesisTime(time + spec.GENESIS_DELAY); err != nil {
+	emptyBody := BeaconBlockBody{}
+	settings := &GenesisSettings{
+		ForkVersion:   spec.GENESIS_FORK_VERSION,
+		EmptyBodyRoot: emptyBody.HashTreeRoot(spec, tree.GetHashFn()),
+		MaxEffectiveBalance: func(withdrawalCreds common.Root) common.Gwei {
+			return spec.MAX_EFFECTIVE_BALANCE
+		},
+		ActivationBalance: spec.MAX_EFFECTIVE_BALANCE,
+	}
+	epc, err := InitGenesisState(spec, state, settings, eth1BlockHash, time, deps, ignoreSignaturesAndProofs)
+	if err != nil {
 		return nil, nil, err
 	}
+	return state, epc, nil
+}
+
+// GenesisSettings are the fork-specific parts of the genesis state initialization.
+type GenesisSettings struct {
+	// ForkVersion is used as both the previous and current version of the genesis fork.
+	ForkVersion common.Version
+	// EmptyBodyRoot is the root of the empty block body of the fork, for the latest block header.
+	EmptyBodyRoot common.Root
+	// MaxEffectiveBalance of a validator with the given withdrawal credentials.
+	MaxEffectiveBalance func(withdrawalCreds common.Root) common.Gwei
+	// ActivationBalance is the minimum effective balance of a validator to be activated at genesis.
+	ActivationBalance common.Gwei
+}
+
+// InitGenesisState initializes the empty state of any fork as genesis state from the given deposits,
+// and returns the epochs context of the genesis state.
+// Fork-specific genesis state contents, like sync committees, are left to the caller.
+func InitGenesisState(spec *common.Spec, state common.BeaconState, settings *GenesisSettings,
+	eth1BlockHash common.Root, time common.Timestamp, deps []common.Deposit, ignoreSignaturesAndProofs bool) (*common.EpochsContext, error) {
+	if err := state.SetGenesisTime(time + spec.GENESIS_DELAY); err != nil {
+		return nil, err
+	}
 	if err := state.SetFork(common.Fork{
-		PreviousVersion: spec.GENESIS_FORK_VERSION,
-		CurrentVersion:  spec.GENESIS_FORK_VERSION,
+		PreviousVersion: settings.ForkVersion,
+		CurrentVersion:  settings.ForkVersion,
 		Epoch:           common.GENESIS_EPOCH,
 	}); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	eth1Dat := common.Eth1Data{
 		DepositRoot:  common.Root{}, // incrementally overwritten during deposit processing
@@ -41,28 +74,27 @@ func GenesisFromEth1(spec *common.Spec, eth1BlockHash common.Root, time common.T
 		BlockHash:    eth1BlockHash,
 	}
 	if err := state.SetEth1Data(eth1Dat); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	emptyBody := BeaconBlockBody{}
 	latestHeader := &common.BeaconBlockHeader{
-		BodyRoot: emptyBody.HashTreeRoot(spec, tree.GetHashFn()),
+		BodyRoot: settings.EmptyBodyRoot,
 	}
 	if err := state.SetLatestBlockHeader(latestHeader); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	// Seed RANDAO with Eth1 entropy
 	err := state.SeedRandao(spec, eth1BlockHash)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	vals, err := state.Validators()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	pc, err := common.NewPubkeyCache(vals)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	// Create mostly empty epochs context. Just need the pubkey cache first
 	epc := &common.EpochsContext{
@@ -70,87 +102,91 @@ func GenesisFromEth1(spec *common.Spec, eth1BlockHash common.Root, time common.T
 		ValidatorPubkeyCache: pc,
 	}
 
-	depRootsView := NewDepositRootsView()
+	depTree := common.NewDepositTree()
 
-	hFn := tree.GetHashFn()
 	updateDepTreeRoot := func() error {
 		eth1Dat, err := state.Eth1Data()
 		if err != nil {
 			return err
 		}
-		eth1Dat.DepositRoot = depRootsView.HashTreeRoot(hFn)
+		eth1Dat.DepositRoot = depTree.Root()
 		return state.SetEth1Data(eth1Dat)
 	}
 	// Process deposits
 	for i := range deps {
-		depRoot := RootView(deps[i].Data.HashTreeRoot(tree.GetHashFn()))
-		if err := depRootsView.Append(&depRoot); err != nil {
-			return nil, nil, err
+		if err := depTree.PushDeposit(&deps[i].Data); err != nil {
+			return nil, err
 		}
 		if err := updateDepTreeRoot(); err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		// in the rare case someone tries to create a genesis block using invalid data, error.
+		// Note: since Electra deposits are queued as pending deposits, which are all applied at genesis.
+		// The resulting balances are the same as when processing the deposits directly like in phase0.
 		if err := ProcessDeposit(spec, epc, state, &deps[i], ignoreSignaturesAndProofs); err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 	}
 	if err := updateDepTreeRoot(); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	// fetch validator registry again, the state changed.
 	vals, err = state.Validators()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	valCount, err := vals.ValidatorCount()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	if common.Slot(valCount) < spec.SLOTS_PER_EPOCH {
-		return nil, nil, errors.New("not enough validators to init full featured BeaconState")
+		return nil, errors.New("not enough validators to init full featured BeaconState")
 	}
 	bals, err := state.Balances()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	// Process activations
 	for i := uint64(0); i < valCount; i++ {
 		val, err := vals.Validator(common.ValidatorIndex(i))
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		balance, err := bals.GetBalance(common.ValidatorIndex(i))
 		if err != nil {
-			return nil, nil, err
+			return nil, err
+		}
+		withdrawalCreds, err := val.WithdrawalCredentials()
+		if err != nil {
+			return nil, err
 		}
 		vEff := balance - (balance % spec.EFFECTIVE_BALANCE_INCREMENT)
-		if vEff > spec.MAX_EFFECTIVE_BALANCE {
-			vEff = spec.MAX_EFFECTIVE_BALANCE
+		if maxEff := settings.MaxEffectiveBalance(withdrawalCreds); vEff > maxEff {
+			vEff = maxEff
 		}
 		if err := val.SetEffectiveBalance(vEff); err != nil {
-			return nil, nil, err
+			return nil, err
 		}
-		if vEff == spec.MAX_EFFECTIVE_BALANCE {
+		if vEff >= settings.ActivationBalance {
 			if err := val.SetActivationEligibilityEpoch(common.GENESIS_EPOCH); err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 			if err := val.SetActivationEpoch(common.GENESIS_EPOCH); err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 		}
 	}
-	if err := state.SetGenesisValidatorsRoot(vals.HashTreeRoot(hFn)); err != nil {
-		return nil, nil, err
+	if err := state.SetGenesisValidatorsRoot(vals.HashTreeRoot(tree.GetHashFn())); err != nil {
+		return nil, err
 	}
 	// Complete computation of epc
 	if err := epc.LoadShuffling(state); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	if err := epc.LoadProposers(state); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	return state, epc, nil
+	return epc, nil
 }
 
 func IsValidGenesisState(spec *common.Spec, state common.BeaconState) (bool, error) {
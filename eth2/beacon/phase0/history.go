@@ -1,6 +1,7 @@
 package phase0
 
 import (
+	"encoding/json"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
 	. "github.com/protolambda/ztyp/view"
@@ -38,6 +39,13 @@ func (li HistoricalBatchRoots) HashTreeRoot(spec *common.Spec, hFn tree.HashFn)
 	}, length)
 }
 
+func (li HistoricalBatchRoots) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]common.Root{}) // encode as empty list, not null
+	}
+	return json.Marshal([]common.Root(li))
+}
+
 type HistoricalBatch struct {
 	BlockRoots HistoricalBatchRoots `json:"block_roots" yaml:"block_roots"`
 	StateRoots HistoricalBatchRoots `json:"state_roots" yaml:"state_roots"`
@@ -137,6 +145,13 @@ func (li HistoricalRoots) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) commo
 	}, length, uint64(spec.HISTORICAL_ROOTS_LIMIT))
 }
 
+func (li HistoricalRoots) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]common.Root{}) // encode as empty list, not null
+	}
+	return json.Marshal([]common.Root(li))
+}
+
 func HistoricalRootsType(spec *common.Spec) ListTypeDef {
 	return ListType(RootType, uint64(spec.HISTORICAL_ROOTS_LIMIT))
 }
@@ -153,3 +168,7 @@ func (h *HistoricalRootsView) Append(root common.Root) error {
 	v := RootView(root)
 	return h.ComplexListView.Append(&v)
 }
+
+func (h *HistoricalRootsView) Root(i uint64) (common.Root, error) {
+	return AsRoot(h.Get(i))
+}
@@ -1,6 +1,7 @@
 package phase0
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
@@ -96,24 +97,31 @@ func ValidateIndexedAttestationNoSignature(spec *common.Spec, state common.Beaco
 	return nil
 }
 
-func ValidateIndexedAttestationSignature(spec *common.Spec, dom common.BLSDomain, pubCache *common.PubkeyCache, indexedAttestation *IndexedAttestation) error {
+func indexedAttestationPubkeys(pubCache *common.PubkeyCache, indexedAttestation *IndexedAttestation) ([]*blsu.Pubkey, error) {
 	pubkeys := make([]*blsu.Pubkey, 0, len(indexedAttestation.AttestingIndices))
 	for _, i := range indexedAttestation.AttestingIndices {
 		pub, ok := pubCache.Pubkey(i)
 		if !ok {
-			return fmt.Errorf("could not find pubkey for index %d", i)
+			return nil, fmt.Errorf("could not find pubkey for index %d", i)
 		}
 		blsPub, err := pub.Pubkey()
 		if err != nil {
-			return fmt.Errorf("failed to deserialize pubkey in cache: %v", err)
+			return nil, fmt.Errorf("failed to deserialize pubkey in cache: %v", err)
 		}
 		pubkeys = append(pubkeys, blsPub)
 	}
 	// empty attestation. (Double check, since this function is public, the user might not have validated if it's empty or not)
 	if len(pubkeys) <= 0 {
-		return errors.New("in phase 0 no empty attestation signatures are allowed")
+		return nil, errors.New("in phase 0 no empty attestation signatures are allowed")
 	}
+	return pubkeys, nil
+}
 
+func ValidateIndexedAttestationSignature(spec *common.Spec, dom common.BLSDomain, pubCache *common.PubkeyCache, indexedAttestation *IndexedAttestation) error {
+	pubkeys, err := indexedAttestationPubkeys(pubCache, indexedAttestation)
+	if err != nil {
+		return err
+	}
 	signingRoot := common.ComputeSigningRoot(indexedAttestation.Data.HashTreeRoot(tree.GetHashFn()), dom)
 	sig, err := indexedAttestation.Signature.Signature()
 	if err != nil {
@@ -126,7 +134,8 @@ func ValidateIndexedAttestationSignature(spec *common.Spec, dom common.BLSDomain
 }
 
 // Verify validity of slashable_attestation fields.
-func ValidateIndexedAttestation(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, indexedAttestation *IndexedAttestation) error {
+// The signature check is deferred if the context has a signature batch, see common.WithSignatureBatch.
+func ValidateIndexedAttestation(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, indexedAttestation *IndexedAttestation) error {
 	if err := ValidateIndexedAttestationNoSignature(spec, state, indexedAttestation); err != nil {
 		return err
 	}
@@ -134,5 +143,48 @@ func ValidateIndexedAttestation(spec *common.Spec, epc *common.EpochsContext, st
 	if err != nil {
 		return err
 	}
-	return ValidateIndexedAttestationSignature(spec, dom, epc.ValidatorPubkeyCache, indexedAttestation)
+	pubkeys, err := indexedAttestationPubkeys(epc.ValidatorPubkeyCache, indexedAttestation)
+	if err != nil {
+		return err
+	}
+	dataRoot := indexedAttestation.Data.HashTreeRoot(tree.GetHashFn())
+	signingRoot := common.ComputeSigningRoot(dataRoot, dom)
+	sig, err := indexedAttestation.Signature.Signature()
+	if err != nil {
+		return fmt.Errorf("failed to deserialize and sub-group check indexed attestation signature: %v", err)
+	}
+	desc := fmt.Sprintf("indexed attestation with data %s", dataRoot)
+	if !common.VerifyAggregateSignature(ctx, desc, pubkeys, signingRoot, sig) {
+		return errors.New("could not verify BLS signature for indexed attestation")
+	}
+	return nil
+}
+
+// ValidateCommitteeAttestation is ValidateIndexedAttestation for the indexed form of an attestation of a single committee:
+// instead of aggregating all attesting pubkeys, the pre-aggregated committee pubkey in the epc is used.
+func ValidateCommitteeAttestation(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state common.BeaconState,
+	attestation *Attestation, indexedAttestation *IndexedAttestation) error {
+	if err := ValidateIndexedAttestationNoSignature(spec, state, indexedAttestation); err != nil {
+		return err
+	}
+	data := &attestation.Data
+	dom, err := common.GetDomain(state, common.DOMAIN_BEACON_ATTESTER, data.Target.Epoch)
+	if err != nil {
+		return err
+	}
+	aggPub, err := epc.AggregateCommitteeParticipants(data.Slot, data.Index, attestation.AggregationBits.GetBit)
+	if err != nil {
+		return err
+	}
+	dataRoot := data.HashTreeRoot(tree.GetHashFn())
+	signingRoot := common.ComputeSigningRoot(dataRoot, dom)
+	sig, err := attestation.Signature.Signature()
+	if err != nil {
+		return fmt.Errorf("failed to deserialize and sub-group check indexed attestation signature: %v", err)
+	}
+	desc := fmt.Sprintf("attestation of committee %d at slot %d with data %s", data.Index, data.Slot, dataRoot)
+	if !common.VerifySignature(ctx, desc, aggPub, signingRoot, sig) {
+		return errors.New("could not verify BLS signature for indexed attestation")
+	}
+	return nil
 }
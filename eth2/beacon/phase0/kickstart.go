@@ -14,8 +14,9 @@ type KickstartValidatorData struct {
 	Balance               common.Gwei
 }
 
-// To build a genesis state without Eth 1.0 deposits, i.e. directly from a sequence of minimal validator data.
-func KickStartState(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, validators []KickstartValidatorData) (*BeaconStateView, *common.EpochsContext, error) {
+// KickstartDeposits creates the deposits of the validators, with a placeholder signature.
+// Signatures and proofs have to be ignored when processing these deposits.
+func KickstartDeposits(validators []KickstartValidatorData) []common.Deposit {
 	deps := make([]common.Deposit, len(validators), len(validators))
 
 	placeholderSig := common.BLSSignature((*blsu.Signature)(kbls.NewG2().One()).Serialize())
@@ -29,8 +30,12 @@ func KickStartState(spec *common.Spec, eth1BlockHash common.Root, time common.Ti
 			Signature:             placeholderSig,
 		}
 	}
+	return deps
+}
 
-	state, epc, err := GenesisFromEth1(spec, eth1BlockHash, 0, deps, true)
+// To build a genesis state without Eth 1.0 deposits, i.e. directly from a sequence of minimal validator data.
+func KickStartState(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, validators []KickstartValidatorData) (*BeaconStateView, *common.EpochsContext, error) {
+	state, epc, err := GenesisFromEth1(spec, eth1BlockHash, 0, KickstartDeposits(validators), true)
 	if err != nil {
 		return nil, nil, err
 	}
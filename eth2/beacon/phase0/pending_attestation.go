@@ -1,6 +1,7 @@
 package phase0
 
 import (
+	"encoding/json"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
@@ -218,6 +219,13 @@ func (li PendingAttestations) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) c
 	}, length, uint64(spec.MAX_ATTESTATIONS)*uint64(spec.SLOTS_PER_EPOCH))
 }
 
+func (li PendingAttestations) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]*PendingAttestation{}) // encode as empty list, not null
+	}
+	return json.Marshal([]*PendingAttestation(li))
+}
+
 func PendingAttestationsType(spec *common.Spec) ListTypeDef {
 	return ComplexListType(PendingAttestationType(spec), uint64(spec.MAX_ATTESTATIONS)*uint64(spec.SLOTS_PER_EPOCH))
 }
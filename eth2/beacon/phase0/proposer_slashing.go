@@ -6,8 +6,6 @@ import (
 	"errors"
 	"fmt"
 
-	blsu "github.com/protolambda/bls12-381-util"
-
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
@@ -95,7 +93,7 @@ func ProcessProposerSlashings(ctx context.Context, spec *common.Spec, epc *commo
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if err := ProcessProposerSlashing(spec, epc, state, &ops[i]); err != nil {
+		if err := ProcessProposerSlashing(ctx, spec, epc, state, &ops[i]); err != nil {
 			return err
 		}
 	}
@@ -118,7 +116,7 @@ func ValidateProposerSlashingNoSignature(spec *common.Spec, ps *ProposerSlashing
 	return nil
 }
 
-func ValidateProposerSlashing(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, ps *ProposerSlashing) error {
+func ValidateProposerSlashing(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, ps *ProposerSlashing) error {
 	if err := ValidateProposerSlashingNoSignature(spec, ps); err != nil {
 		return err
 	}
@@ -171,17 +169,17 @@ func ValidateProposerSlashing(spec *common.Spec, epc *common.EpochsContext, stat
 		return err
 	}
 	// Verify signatures
-	if !blsu.Verify(blsPub, sigRoot1[:], sig1) {
+	if !common.VerifySignature(ctx, fmt.Sprintf("proposer slashing of %d, header 1", proposerIndex), blsPub, sigRoot1, sig1) {
 		return errors.New("proposer slashing header 1 has invalid BLS signature")
 	}
-	if !blsu.Verify(blsPub, sigRoot2[:], sig2) {
+	if !common.VerifySignature(ctx, fmt.Sprintf("proposer slashing of %d, header 2", proposerIndex), blsPub, sigRoot2, sig2) {
 		return errors.New("proposer slashing header 2 has invalid BLS signature")
 	}
 	return nil
 }
 
-func ProcessProposerSlashing(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, ps *ProposerSlashing) error {
-	if err := ValidateProposerSlashing(spec, epc, state, ps); err != nil {
+func ProcessProposerSlashing(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, ps *ProposerSlashing) error {
+	if err := ValidateProposerSlashing(ctx, spec, epc, state, ps); err != nil {
 		return err
 	}
 	return SlashValidator(spec, epc, state, ps.SignedHeader1.Message.ProposerIndex, nil)
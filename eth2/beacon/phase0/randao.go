@@ -2,11 +2,10 @@ package phase0
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 
-	blsu "github.com/protolambda/bls12-381-util"
-
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	. "github.com/protolambda/zrnt/eth2/util/hashing"
 	"github.com/protolambda/ztyp/codec"
@@ -43,6 +42,13 @@ func (li RandaoMixes) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Ro
 	}, length)
 }
 
+func (li RandaoMixes) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]common.Root{}) // encode as empty list, not null
+	}
+	return json.Marshal([]common.Root(li))
+}
+
 func RandaoMixesType(spec *common.Spec) VectorTypeDef {
 	return VectorType(common.Bytes32Type, uint64(spec.EPOCHS_PER_HISTORICAL_VECTOR))
 }
@@ -118,7 +124,7 @@ func ProcessRandaoReveal(ctx context.Context, spec *common.Spec, epc *common.Epo
 		return fmt.Errorf("failed to deserialize and sub-group check randao reveal: %v", err)
 	}
 	// Verify RANDAO reveal
-	if !blsu.Verify(blsPub, sigRoot[:], revealSig) {
+	if !common.VerifySignature(ctx, "randao reveal", blsPub, sigRoot, revealSig) {
 		return errors.New("randao invalid")
 	}
 	mixes, err := state.RandaoMixes()
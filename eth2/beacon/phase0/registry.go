@@ -2,6 +2,7 @@ package phase0
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sort"
 
@@ -75,6 +76,13 @@ func (li ValidatorRegistry) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) com
 	}, length, uint64(spec.VALIDATOR_REGISTRY_LIMIT))
 }
 
+func (li ValidatorRegistry) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]*Validator{}) // encode as empty list, not null
+	}
+	return json.Marshal([]*Validator(li))
+}
+
 func ValidatorsRegistryType(spec *common.Spec) ListTypeDef {
 	return ComplexListType(ValidatorType, uint64(spec.VALIDATOR_REGISTRY_LIMIT))
 }
@@ -221,6 +229,7 @@ func ProcessEpochRegistryUpdates(ctx context.Context, spec *common.Spec, epc *co
 			if err := val.SetExitEpoch(exitEnd); err != nil {
 				return err
 			}
+			flats[index].ExitEpoch = exitEnd
 			withdrawEpoch := exitEnd + spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY
 			if withdrawEpoch < exitEnd { // practically impossible, but here for spec test introduced in consensus-specs#2887
 				return fmt.Errorf("exit epoch overflow: %d + %d = %d", exitEnd, spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY, withdrawEpoch)
@@ -228,6 +237,7 @@ func ProcessEpochRegistryUpdates(ctx context.Context, spec *common.Spec, epc *co
 			if err := val.SetWithdrawableEpoch(withdrawEpoch); err != nil {
 				return err
 			}
+			flats[index].WithdrawableEpoch = withdrawEpoch
 			endChurn += 1
 			if endChurn >= registerData.ChurnLimit {
 				endChurn = 0
@@ -247,6 +257,7 @@ func ProcessEpochRegistryUpdates(ctx context.Context, spec *common.Spec, epc *co
 			if err := val.SetActivationEligibilityEpoch(eligibilityEpoch); err != nil {
 				return err
 			}
+			flats[index].ActivationEligibilityEpoch = eligibilityEpoch
 		}
 	}
 
@@ -274,6 +285,7 @@ func ProcessEpochRegistryUpdates(ctx context.Context, spec *common.Spec, epc *co
 			if err := val.SetActivationEpoch(activationEpoch); err != nil {
 				return err
 			}
+			flats[index].ActivationEpoch = activationEpoch
 		}
 	}
 	return nil
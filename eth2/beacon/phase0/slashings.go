@@ -2,6 +2,7 @@ package phase0
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/ztyp/codec"
@@ -46,6 +47,13 @@ func (li SlashingsHistory) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) comm
 	}, uint64(len(li)))
 }
 
+func (li SlashingsHistory) MarshalJSON() ([]byte, error) {
+	if li == nil {
+		return json.Marshal([]common.Gwei{}) // encode as empty list, not null
+	}
+	return json.Marshal([]common.Gwei(li))
+}
+
 // Balances slashed at every withdrawal period
 func SlashingsType(spec *common.Spec) VectorTypeDef {
 	return VectorType(common.GweiType, uint64(spec.EPOCHS_PER_SLASHINGS_VECTOR))
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 
-	blsu "github.com/protolambda/bls12-381-util"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
@@ -63,7 +62,7 @@ func ProcessVoluntaryExits(ctx context.Context, spec *common.Spec, epc *common.E
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if err := ProcessVoluntaryExit(spec, epc, state, &ops[i]); err != nil {
+		if err := ProcessVoluntaryExit(ctx, spec, epc, state, &ops[i]); err != nil {
 			return err
 		}
 	}
@@ -131,7 +130,7 @@ var SignedVoluntaryExitType = ContainerType("SignedVoluntaryExit", []FieldDef{
 	{"signature", common.BLSSignatureType},
 })
 
-func ValidateVoluntaryExit(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, signedExit *SignedVoluntaryExit) error {
+func ValidateVoluntaryExit(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, signedExit *SignedVoluntaryExit) error {
 	exit := &signedExit.Message
 	currentEpoch := epc.CurrentEpoch.Epoch
 	vals, err := state.Validators()
@@ -191,14 +190,14 @@ func ValidateVoluntaryExit(spec *common.Spec, epc *common.EpochsContext, state c
 		return fmt.Errorf("failed to deserialize and sub-group check exit signature: %v", err)
 	}
 	// Verify signature
-	if !blsu.Verify(blsPub, sigRoot[:], sig) {
+	if !common.VerifySignature(ctx, fmt.Sprintf("voluntary exit of %d", exit.ValidatorIndex), blsPub, sigRoot, sig) {
 		return errors.New("voluntary exit signature could not be verified")
 	}
 	return nil
 }
 
-func ProcessVoluntaryExit(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, signedExit *SignedVoluntaryExit) error {
-	if err := ValidateVoluntaryExit(spec, epc, state, signedExit); err != nil {
+func ProcessVoluntaryExit(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, signedExit *SignedVoluntaryExit) error {
+	if err := ValidateVoluntaryExit(ctx, spec, epc, state, signedExit); err != nil {
 		return err
 	}
 	return InitiateValidatorExit(spec, epc, state, signedExit.Message.ValidatorIndex)
@@ -0,0 +1,30 @@
+package phase0
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/util/math"
+)
+
+// ComputeWeakSubjectivityPeriod computes the number of epochs, after the current epoch of the epochs-context,
+// within which a state of that epoch is safe to sync from.
+func ComputeWeakSubjectivityPeriod(spec *common.Spec, epc *common.EpochsContext) common.Epoch {
+	wsPeriod := spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY
+	N := uint64(len(epc.CurrentEpoch.ActiveIndices))
+	if N == 0 {
+		return wsPeriod
+	}
+	t := uint64(epc.TotalActiveStake/common.ETH_TO_GWEI) / N
+	T := uint64(spec.MAX_EFFECTIVE_BALANCE / common.ETH_TO_GWEI)
+	delta := spec.GetChurnLimit(N)
+	Delta := uint64(spec.MAX_DEPOSITS) * uint64(spec.SLOTS_PER_EPOCH)
+	const D = common.SAFETY_DECAY
+
+	if T*(200+3*D) < t*(200+12*D) {
+		epochsForValidatorSetChurn := N * (t*(200+12*D) - T*(200+3*D)) / (600 * delta * (2*t + T))
+		epochsForBalanceTopUps := N * (200 + 3*D) / (600 * Delta)
+		wsPeriod += common.Epoch(math.MaxU64(epochsForValidatorSetChurn, epochsForBalanceTopUps))
+	} else {
+		wsPeriod += common.Epoch(3 * N * D * t / (200 * Delta * (T - t)))
+	}
+	return wsPeriod
+}
@@ -0,0 +1,353 @@
+package beaconapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/protolambda/ztyp/codec"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// acceptSSZ prefers SSZ, but also accepts JSON from servers without SSZ support.
+const acceptSSZ = ContentTypeSSZ + ";q=1.0," + ContentTypeJSON + ";q=0.9"
+
+// Client is a beacon API client. Fork-specific responses are decoded into the types of the fork,
+// as named by the Eth-Consensus-Version header or the version of the response.
+type Client struct {
+	Spec *common.Spec
+	// Addr is the base URL of the beacon node, e.g. "http://localhost:5052"
+	Addr string
+	HTTP *http.Client
+}
+
+func NewClient(spec *common.Spec, addr string) *Client {
+	return &Client{Spec: spec, Addr: strings.TrimSuffix(addr, "/"), HTTP: http.DefaultClient}
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, accept string, body interface{}) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		bodyReader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.Addr+path, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if body != nil {
+		req.Header.Set("Content-Type", ContentTypeJSON)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var apiErr APIError
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if err := json.Unmarshal(data, &apiErr); err != nil || apiErr.Code == 0 {
+			return nil, &APIError{Code: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+		}
+		return nil, &apiErr
+	}
+	return resp, nil
+}
+
+// getJSON gets the JSON response of the path, and decodes it into dst.
+func (c *Client) getJSON(ctx context.Context, path string, dst interface{}) error {
+	resp, err := c.do(ctx, http.MethodGet, path, ContentTypeJSON, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", path, err)
+	}
+	return nil
+}
+
+// postJSON posts the JSON body to the path, and decodes the JSON response into dst.
+func (c *Client) postJSON(ctx context.Context, path string, body interface{}, dst interface{}) error {
+	resp, err := c.do(ctx, http.MethodPost, path, ContentTypeJSON, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) Genesis(ctx context.Context) (*GenesisData, error) {
+	var out GenesisData
+	if err := c.getJSON(ctx, "/eth/v1/beacon/genesis", &DataResponse{Data: &out}); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) BlockHeader(ctx context.Context, blockID string) (*BlockHeaderInfo, error) {
+	var out BlockHeaderInfo
+	if err := c.getJSON(ctx, "/eth/v1/beacon/headers/"+url.PathEscape(blockID), &Response{Data: &out}); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) BlockRoot(ctx context.Context, blockID string) (common.Root, error) {
+	var out RootData
+	if err := c.getJSON(ctx, "/eth/v1/beacon/blocks/"+url.PathEscape(blockID)+"/root", &Response{Data: &out}); err != nil {
+		return common.Root{}, err
+	}
+	return out.Root, nil
+}
+
+func (c *Client) StateRoot(ctx context.Context, stateID string) (common.Root, error) {
+	var out RootData
+	if err := c.getJSON(ctx, "/eth/v1/beacon/states/"+url.PathEscape(stateID)+"/root", &Response{Data: &out}); err != nil {
+		return common.Root{}, err
+	}
+	return out.Root, nil
+}
+
+func (c *Client) FinalityCheckpoints(ctx context.Context, stateID string) (*FinalityCheckpoints, error) {
+	var out FinalityCheckpoints
+	if err := c.getJSON(ctx, "/eth/v1/beacon/states/"+url.PathEscape(stateID)+"/finality_checkpoints", &Response{Data: &out}); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) Syncing(ctx context.Context) (*SyncingStatus, error) {
+	var out SyncingStatus
+	if err := c.getJSON(ctx, "/eth/v1/node/syncing", &DataResponse{Data: &out}); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) AttesterDuties(ctx context.Context, epoch common.Epoch, indices []common.ValidatorIndex) (dependentRoot common.Root, duties []AttesterDutyData, err error) {
+	resp := DependentResponse{Data: &duties}
+	if err := c.postJSON(ctx, fmt.Sprintf("/eth/v1/validator/duties/attester/%d", epoch), indices, &resp); err != nil {
+		return common.Root{}, nil, err
+	}
+	return resp.DependentRoot, duties, nil
+}
+
+func (c *Client) ProposerDuties(ctx context.Context, epoch common.Epoch) (dependentRoot common.Root, duties []ProposerDutyData, err error) {
+	resp := DependentResponse{Data: &duties}
+	if err := c.getJSON(ctx, fmt.Sprintf("/eth/v1/validator/duties/proposer/%d", epoch), &resp); err != nil {
+		return common.Root{}, nil, err
+	}
+	return resp.DependentRoot, duties, nil
+}
+
+// getVersioned gets a fork-specific object, preferring SSZ. The SSZ response is decoded with decodeSSZ,
+// for the version in the Eth-Consensus-Version header, and a JSON response is decoded into dst.
+func (c *Client) getVersioned(ctx context.Context, path string, dst json.Unmarshaler,
+	decodeSSZ func(version string, dr *codec.DecodingReader) error) error {
+	resp, err := c.do(ctx, http.MethodGet, path, acceptSSZ, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == ContentTypeSSZ {
+		version := resp.Header.Get(ConsensusVersionHeader)
+		if version == "" {
+			return fmt.Errorf("missing %s header in SSZ response of %s", ConsensusVersionHeader, path)
+		}
+		dr, err := sszBody(resp)
+		if err != nil {
+			return err
+		}
+		if err := decodeSSZ(version, dr); err != nil {
+			return fmt.Errorf("failed to decode %s SSZ response of %s: %w", version, path, err)
+		}
+		return nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := dst.UnmarshalJSON(data); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", path, err)
+	}
+	return nil
+}
+
+// sszBody returns a decoding reader of the SSZ response body.
+// If the server sent the content length, the body is decoded while streaming, like beacon.ReadBeaconState does,
+// instead of reading a full state into memory first. Otherwise the body is read fully.
+func sszBody(resp *http.Response) (*codec.DecodingReader, error) {
+	if resp.ContentLength >= 0 {
+		// buffered: the decoder fails on an io.EOF that is returned together with the last bytes
+		return codec.NewDecodingReader(bufio.NewReader(resp.Body), uint64(resp.ContentLength)), nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return codec.NewDecodingReader(bytes.NewReader(data), uint64(len(data))), nil
+}
+
+// SignedBeaconBlock gets the signed block of the given block id, as signed block type of the fork of the block.
+func (c *Client) SignedBeaconBlock(ctx context.Context, blockID string) (block beacon.OpaqueBlock, version string, err error) {
+	var resp VersionedSignedBeaconBlock
+	err = c.getVersioned(ctx, "/eth/v2/beacon/blocks/"+url.PathEscape(blockID), &resp,
+		func(v string, dr *codec.DecodingReader) error {
+			alloc, err := SignedBeaconBlockAllocator(v)
+			if err != nil {
+				return err
+			}
+			resp.Version = v
+			resp.Data = alloc()
+			return resp.Data.Deserialize(c.Spec, dr)
+		})
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.Data, resp.Version, nil
+}
+
+// BeaconState gets the state of the given state id, as tree-backed state view of the fork of the state.
+func (c *Client) BeaconState(ctx context.Context, stateID string) (state common.BeaconState, version string, err error) {
+	var resp VersionedBeaconState
+	err = c.getVersioned(ctx, "/eth/v2/debug/beacon/states/"+url.PathEscape(stateID), &resp,
+		func(v string, dr *codec.DecodingReader) error {
+			state, err = DecodeBeaconStateView(c.Spec, v, dr)
+			version = v
+			return err
+		})
+	if err != nil {
+		return nil, "", err
+	}
+	if state != nil {
+		return state, version, nil
+	}
+	// JSON response, convert the plain state into a view
+	var buf bytes.Buffer
+	if err := c.Spec.Wrap(resp.Data).Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		return nil, "", err
+	}
+	state, err = DecodeBeaconStateView(c.Spec, resp.Version, codec.NewDecodingReader(&buf, uint64(buf.Len())))
+	if err != nil {
+		return nil, "", err
+	}
+	return state, resp.Version, nil
+}
+
+// Event topics of the event stream.
+const (
+	HeadTopic                = "head"
+	BlockTopic               = "block"
+	FinalizedCheckpointTopic = "finalized_checkpoint"
+)
+
+type HeadEvent struct {
+	Slot                      common.Slot `json:"slot"`
+	Block                     common.Root `json:"block"`
+	State                     common.Root `json:"state"`
+	EpochTransition           bool        `json:"epoch_transition"`
+	PreviousDutyDependentRoot common.Root `json:"previous_duty_dependent_root"`
+	CurrentDutyDependentRoot  common.Root `json:"current_duty_dependent_root"`
+	ExecutionOptimistic       bool        `json:"execution_optimistic"`
+}
+
+type BlockEvent struct {
+	Slot                common.Slot `json:"slot"`
+	Block               common.Root `json:"block"`
+	ExecutionOptimistic bool        `json:"execution_optimistic"`
+}
+
+type FinalizedCheckpointEvent struct {
+	Block               common.Root  `json:"block"`
+	State               common.Root  `json:"state"`
+	Epoch               common.Epoch `json:"epoch"`
+	ExecutionOptimistic bool         `json:"execution_optimistic"`
+}
+
+// Event is an event of the event stream. The data is a *HeadEvent, *BlockEvent or *FinalizedCheckpointEvent
+// for the topics of these types, and json.RawMessage for any other topic.
+type Event struct {
+	Topic string
+	Data  interface{}
+}
+
+func decodeEvent(topic string, data []byte) (*Event, error) {
+	var dst interface{}
+	switch topic {
+	case HeadTopic:
+		dst = new(HeadEvent)
+	case BlockTopic:
+		dst = new(BlockEvent)
+	case FinalizedCheckpointTopic:
+		dst = new(FinalizedCheckpointEvent)
+	default:
+		return &Event{Topic: topic, Data: json.RawMessage(data)}, nil
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event: %w", topic, err)
+	}
+	return &Event{Topic: topic, Data: dst}, nil
+}
+
+// SubscribeEvents subscribes to the event stream of the given topics, and calls fn for every event.
+// It blocks until the context is canceled, the stream ends, or fn returns an error.
+func (c *Client) SubscribeEvents(ctx context.Context, topics []string, fn func(ev *Event) error) error {
+	path := "/eth/v1/events?topics=" + url.QueryEscape(strings.Join(topics, ","))
+	resp, err := c.do(ctx, http.MethodGet, path, "text/event-stream", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	var topic string
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// end of the event
+			if topic != "" || len(data) > 0 {
+				ev, err := decodeEvent(topic, data)
+				if err != nil {
+					return err
+				}
+				if err := fn(ev); err != nil {
+					return err
+				}
+			}
+			topic, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// comment, used as keep-alive
+		case strings.HasPrefix(line, "event:"):
+			topic = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,221 @@
+package beaconapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+// standInServer serves a golden deneb block and a default electra state,
+// in SSZ if the client accepts it and sszEnabled is set.
+// The SSZ state is sent with its content length, unless chunked is set.
+type standInServer struct {
+	t          *testing.T
+	sszEnabled bool
+	chunked    bool
+	block      []byte
+	state      []byte
+}
+
+func (s *standInServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/eth/v1/beacon/genesis":
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		_, _ = w.Write([]byte(`{"data":{"genesis_time":"1606824023","genesis_validators_root":"0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95","genesis_fork_version":"0x00000000"}}`))
+	case "/eth/v2/beacon/blocks/head":
+		if s.sszEnabled && acceptsSSZ(r) {
+			var resp VersionedSignedBeaconBlock
+			if err := json.Unmarshal(s.block, &resp); err != nil {
+				s.t.Error(err)
+			}
+			w.Header().Set("Content-Type", ContentTypeSSZ)
+			w.Header().Set(ConsensusVersionHeader, resp.Version)
+			if err := configs.Mainnet.Wrap(resp.Data).Serialize(codec.NewEncodingWriter(w)); err != nil {
+				s.t.Error(err)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		_, _ = w.Write(s.block)
+	case "/eth/v2/debug/beacon/states/finalized":
+		w.Header().Set(ConsensusVersionHeader, Electra)
+		if s.sszEnabled && acceptsSSZ(r) {
+			w.Header().Set("Content-Type", ContentTypeSSZ)
+			if !s.chunked {
+				w.Header().Set("Content-Length", fmt.Sprint(len(s.state)))
+			}
+			_, _ = w.Write(s.state)
+			return
+		}
+		var state electra.BeaconState
+		if err := state.Deserialize(configs.Mainnet, codec.NewDecodingReader(bytes.NewReader(s.state), uint64(len(s.state)))); err != nil {
+			s.t.Error(err)
+		}
+		writeJSON(w, http.StatusOK, &VersionedBeaconState{Version: Electra, Finalized: true, Data: &state})
+	case "/eth/v1/events":
+		if got := r.URL.Query().Get("topics"); got != "head,block,finalized_checkpoint,chain_reorg" {
+			s.t.Errorf("unexpected topics: %q", got)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, ": keep-alive\n\n")
+		_, _ = fmt.Fprint(w, "event: head\ndata: {\"slot\":\"10\",\"block\":\"0x9a2fefd2fdb57f74993c7780ea5b9030d2897b615b89f808011ca5aebed54eaf\",\"state\":\"0x600e852a08c1200654ddf11025f1ceacb3c2e74bdd5c630cde0838b2591b69f9\",\"epoch_transition\":false,\"previous_duty_dependent_root\":\"0x5e0043f107cb57913498fbf2f99ff55e730bf1e151f02f221e977c91a90a0e91\",\"current_duty_dependent_root\":\"0x5e0043f107cb57913498fbf2f99ff55e730bf1e151f02f221e977c91a90a0e91\",\"execution_optimistic\":false}\n\n")
+		_, _ = fmt.Fprint(w, "event: block\ndata: {\"slot\":\"10\",\"block\":\"0x9a2fefd2fdb57f74993c7780ea5b9030d2897b615b89f808011ca5aebed54eaf\",\"execution_optimistic\":false}\n\n")
+		_, _ = fmt.Fprint(w, "event: finalized_checkpoint\ndata: {\"block\":\"0x9a2fefd2fdb57f74993c7780ea5b9030d2897b615b89f808011ca5aebed54eaf\",\n")
+		_, _ = fmt.Fprint(w, "data: \"state\":\"0x600e852a08c1200654ddf11025f1ceacb3c2e74bdd5c630cde0838b2591b69f9\",\"epoch\":\"2\",\"execution_optimistic\":false}\n\n")
+		_, _ = fmt.Fprint(w, "event: chain_reorg\ndata: {\"slot\":\"200\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	case "/eth/v1/node/syncing":
+		// never responds, until the request is canceled
+		<-r.Context().Done()
+	default:
+		writeError(w, notFound("unknown route: %s", r.URL.Path))
+	}
+}
+
+func newStandIn(t *testing.T) *standInServer {
+	var state bytes.Buffer
+	if err := electra.BeaconStateType(configs.Mainnet).New().Serialize(codec.NewEncodingWriter(&state)); err != nil {
+		t.Fatal(err)
+	}
+	return &standInServer{t: t, block: readGolden(t, "block_deneb.json"), state: state.Bytes()}
+}
+
+func TestClientBlock(t *testing.T) {
+	standIn := newStandIn(t)
+	srv := httptest.NewServer(standIn)
+	defer srv.Close()
+	client := NewClient(configs.Mainnet, srv.URL+"/")
+
+	genesis, err := client.Genesis(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if genesis.GenesisTime != 1606824023 {
+		t.Fatalf("unexpected genesis: %+v", genesis)
+	}
+
+	var roots []common.Root
+	for _, ssz := range []bool{false, true} {
+		standIn.sszEnabled = ssz
+		block, version, err := client.SignedBeaconBlock(context.Background(), "head")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, ok := block.(*deneb.SignedBeaconBlock)
+		if !ok || version != Deneb {
+			t.Fatalf("ssz %v: got %s block %T", ssz, version, block)
+		}
+		roots = append(roots, b.Message.HashTreeRoot(configs.Mainnet, tree.GetHashFn()))
+	}
+	if roots[0] != roots[1] {
+		t.Fatal("SSZ and JSON blocks differ")
+	}
+
+	_, _, err = client.SignedBeaconBlock(context.Background(), "finalized")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		t.Fatalf("expected not-found API error, got %v", err)
+	}
+}
+
+func TestClientState(t *testing.T) {
+	standIn := newStandIn(t)
+	srv := httptest.NewServer(standIn)
+	defer srv.Close()
+	client := NewClient(configs.Mainnet, srv.URL)
+
+	var roots []common.Root
+	for _, c := range []struct{ ssz, chunked bool }{{false, false}, {true, false}, {true, true}} {
+		standIn.sszEnabled, standIn.chunked = c.ssz, c.chunked
+		state, version, err := client.BeaconState(context.Background(), "finalized")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := state.(*electra.BeaconStateView); !ok || version != Electra {
+			t.Fatalf("ssz %v, chunked %v: got %s state %T", c.ssz, c.chunked, version, state)
+		}
+		roots = append(roots, state.HashTreeRoot(tree.GetHashFn()))
+	}
+	if roots[0] != roots[1] || roots[0] != roots[2] {
+		t.Fatal("SSZ and JSON states differ")
+	}
+
+	// a state that is cut off while streaming
+	standIn.sszEnabled, standIn.chunked = true, false
+	full := standIn.state
+	standIn.state = full[:len(full)/2]
+	if _, _, err := client.BeaconState(context.Background(), "finalized"); err == nil {
+		t.Fatal("expected error for truncated SSZ state")
+	}
+	standIn.state = full
+}
+
+func TestClientEvents(t *testing.T) {
+	srv := httptest.NewServer(newStandIn(t))
+	defer srv.Close()
+	client := NewClient(configs.Mainnet, srv.URL)
+
+	var events []*Event
+	errStop := errors.New("stop")
+	err := client.SubscribeEvents(context.Background(),
+		[]string{HeadTopic, BlockTopic, FinalizedCheckpointTopic, "chain_reorg"},
+		func(ev *Event) error {
+			events = append(events, ev)
+			if len(events) == 4 {
+				return errStop
+			}
+			return nil
+		})
+	if err != errStop {
+		t.Fatalf("expected stop, got %v", err)
+	}
+	if head, ok := events[0].Data.(*HeadEvent); !ok || head.Slot != 10 {
+		t.Fatalf("unexpected head event: %+v", events[0])
+	}
+	if block, ok := events[1].Data.(*BlockEvent); !ok || block.Block != events[0].Data.(*HeadEvent).Block {
+		t.Fatalf("unexpected block event: %+v", events[1])
+	}
+	if fin, ok := events[2].Data.(*FinalizedCheckpointEvent); !ok || fin.Epoch != 2 || fin.State == (common.Root{}) {
+		t.Fatalf("unexpected finalized checkpoint event: %+v", events[2])
+	}
+	if raw, ok := events[3].Data.(json.RawMessage); !ok || events[3].Topic != "chain_reorg" || string(raw) != `{"slot":"200"}` {
+		t.Fatalf("unexpected other event: %+v", events[3])
+	}
+
+	// the subscription ends when the context is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	err = client.SubscribeEvents(ctx, []string{HeadTopic, BlockTopic, FinalizedCheckpointTopic, "chain_reorg"},
+		func(ev *Event) error {
+			cancel()
+			return nil
+		})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled subscription, got %v", err)
+	}
+}
+
+func TestClientCancel(t *testing.T) {
+	srv := httptest.NewServer(newStandIn(t))
+	defer srv.Close()
+	client := NewClient(configs.Mainnet, srv.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Syncing(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
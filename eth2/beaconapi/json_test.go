@@ -0,0 +1,299 @@
+package beaconapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/interop"
+)
+
+// The testdata has the response layout of the beacon-APIs, filled with the example values of the
+// beacon-APIs primitive types (root, signature, pubkey, address and transaction examples).
+// Decoded values are checked against those examples, not only against the re-encoded output.
+// The state fixture is an interop genesis state instead, checked against the same state built in the test.
+var (
+	exampleRoot      = common.Root{0xcf, 0x8e, 0x0d, 0x4e, 0x95, 0x87, 0x36, 0x9b, 0x23, 0x01, 0xd0, 0x79, 0x03, 0x47, 0x32, 0x03, 0x02, 0xcc, 0x09, 0x43, 0xd5, 0xa1, 0x88, 0x45, 0x60, 0x36, 0x7e, 0x82, 0x08, 0xd9, 0x20, 0xf2}
+	exampleSignature = "0x1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505cc411d61252fb6cb3fa0017b679f8bb2305b26a285fa2737f175668d0dff91cc1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505"
+	examplePubkey    = "0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a"
+)
+
+func readGolden(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// assertSameJSON checks that the re-encoded object matches the golden JSON, ignoring formatting.
+func assertSameJSON(t *testing.T, golden []byte, obj interface{}) {
+	t.Helper()
+	encoded, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected, got interface{}
+	if err := json.Unmarshal(golden, &expected); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(encoded, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Fatalf("JSON mismatch:\nexpected: %s\ngot:      %s", bytes.TrimSpace(golden), encoded)
+	}
+}
+
+// sszRoundtrip checks that the decoded object is also valid SSZ.
+func sszRoundtrip(t *testing.T, spec *common.Spec, obj common.SpecObj, dst common.SpecObj) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := spec.Wrap(obj).Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Deserialize(spec, codec.NewDecodingReader(&buf, uint64(buf.Len()))); err != nil {
+		t.Fatal(err)
+	}
+	hFn := tree.GetHashFn()
+	if obj.HashTreeRoot(spec, hFn) != dst.HashTreeRoot(spec, hFn) {
+		t.Fatal("SSZ roundtrip changed the object")
+	}
+}
+
+func TestGoldenBlocks(t *testing.T) {
+	spec := configs.Mainnet
+	for version, expected := range map[string]interface{}{
+		Phase0:    new(phase0.SignedBeaconBlock),
+		Altair:    new(altair.SignedBeaconBlock),
+		Bellatrix: new(bellatrix.SignedBeaconBlock),
+		Capella:   new(capella.SignedBeaconBlock),
+		Deneb:     new(deneb.SignedBeaconBlock),
+		Electra:   new(electra.SignedBeaconBlock),
+	} {
+		t.Run(version, func(t *testing.T) {
+			golden := readGolden(t, "block_"+version+".json")
+			var resp VersionedSignedBeaconBlock
+			if err := json.Unmarshal(golden, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Version != version {
+				t.Fatalf("got version %q", resp.Version)
+			}
+			if reflect.TypeOf(resp.Data) != reflect.TypeOf(expected) {
+				t.Fatalf("got %T, expected %T", resp.Data, expected)
+			}
+			assertSameJSON(t, golden, &resp)
+			alloc, err := SignedBeaconBlockAllocator(version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sszRoundtrip(t, spec, resp.Data, alloc())
+		})
+	}
+}
+
+func TestGoldenPhase0BlockFields(t *testing.T) {
+	var resp VersionedSignedBeaconBlock
+	if err := json.Unmarshal(readGolden(t, "block_phase0.json"), &resp); err != nil {
+		t.Fatal(err)
+	}
+	block := resp.Data.(*phase0.SignedBeaconBlock)
+	if block.Message.Slot != 1 || block.Message.ProposerIndex != 1 {
+		t.Fatalf("unexpected slot %d and proposer %d", block.Message.Slot, block.Message.ProposerIndex)
+	}
+	if block.Message.ParentRoot != exampleRoot || block.Message.StateRoot != exampleRoot {
+		t.Fatal("unexpected block roots")
+	}
+	if got := block.Signature.String(); got != exampleSignature {
+		t.Fatalf("unexpected signature: %s", got)
+	}
+	body := &block.Message.Body
+	if common.Root(body.Graffiti) != exampleRoot || body.Eth1Data.DepositCount != 1 {
+		t.Fatal("unexpected graffiti or eth1 data")
+	}
+	if got := body.Deposits[0].Data.Pubkey.String(); got != examplePubkey {
+		t.Fatalf("unexpected deposit pubkey: %s", got)
+	}
+}
+
+func TestGoldenElectraBlockFields(t *testing.T) {
+	var resp VersionedSignedBeaconBlock
+	if err := json.Unmarshal(readGolden(t, "block_electra.json"), &resp); err != nil {
+		t.Fatal(err)
+	}
+	body := &resp.Data.(*electra.SignedBeaconBlock).Message.Body
+	if got := body.ExecutionPayload.BaseFeePerGas; got.String() != "1000000000" {
+		t.Fatalf("unexpected base fee: %s", got.String())
+	}
+	if got := body.ExecutionRequests.Deposits[0].Amount; got != 32_000_000_000 {
+		t.Fatalf("unexpected deposit request amount: %d", got)
+	}
+	if got := body.Attestations[0].CommitteeBits; !got.GetBit(0) {
+		t.Fatalf("expected committee bit 0 to be set: %s", got)
+	}
+}
+
+func TestGoldenHeader(t *testing.T) {
+	golden := readGolden(t, "header.json")
+	var info BlockHeaderInfo
+	resp := Response{Data: &info}
+	if err := json.Unmarshal(golden, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Finalized || !info.Canonical || info.Header.Message.Slot != 1 {
+		t.Fatalf("unexpected header response: %+v", info)
+	}
+	assertSameJSON(t, golden, &resp)
+}
+
+func TestGoldenBlobSidecars(t *testing.T) {
+	spec := configs.Mainnet
+	golden := readGolden(t, "blob_sidecars.json")
+	var sidecars []*deneb.BlobSidecar
+	if err := json.Unmarshal(golden, &struct {
+		Data *[]*deneb.BlobSidecar `json:"data"`
+	}{Data: &sidecars}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sidecars) != 1 || len(sidecars[0].Blob) != int(deneb.BlobSize(spec)) {
+		t.Fatal("unexpected blob sidecars")
+	}
+	assertSameJSON(t, golden, map[string]interface{}{"data": sidecars})
+	sszRoundtrip(t, spec, sidecars[0], new(deneb.BlobSidecar))
+}
+
+func TestStateRoundtrip(t *testing.T) {
+	spec := configs.Minimal
+	for version, typ := range map[string]*view.ContainerTypeDef{
+		Phase0:    phase0.BeaconStateType(spec),
+		Altair:    altair.BeaconStateType(spec),
+		Bellatrix: bellatrix.BeaconStateType(spec),
+		Capella:   capella.BeaconStateType(spec),
+		Deneb:     deneb.BeaconStateType(spec),
+		Electra:   electra.BeaconStateType(spec),
+	} {
+		t.Run(version, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := typ.New().Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+				t.Fatal(err)
+			}
+			alloc, err := BeaconStateAllocator(version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := alloc()
+			if err := state.Deserialize(spec, codec.NewDecodingReader(&buf, uint64(buf.Len()))); err != nil {
+				t.Fatal(err)
+			}
+			encoded, err := json.Marshal(&VersionedResponse{Version: version, Data: state})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(encoded), "null") {
+				t.Fatal("state JSON contains null values")
+			}
+			var resp VersionedBeaconState
+			if err := json.Unmarshal(encoded, &resp); err != nil {
+				t.Fatal(err)
+			}
+			hFn := tree.GetHashFn()
+			if resp.Data.HashTreeRoot(spec, hFn) != state.HashTreeRoot(spec, hFn) {
+				t.Fatal("state changed in JSON roundtrip")
+			}
+		})
+	}
+}
+
+// TestGoldenState decodes a getStateV2 response of a minimal-config Deneb genesis state of 64 interop validators,
+// and checks it against the same state built with deneb.KickStartState.
+func TestGoldenState(t *testing.T) {
+	spec := configs.Minimal
+	keys, err := interop.NewKeys(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &deneb.ExecutionPayloadHeader{BlockHash: common.Root{0xee}, BlockNumber: 1}
+	expected, _, err := deneb.KickStartState(spec, common.Root{0xaa}, 1_000_000, keys.KickstartValidators(spec.MAX_EFFECTIVE_BALANCE), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := readGolden(t, "state_deneb.json")
+	var resp VersionedBeaconState
+	if err := json.Unmarshal(golden, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Version != Deneb {
+		t.Fatalf("unexpected version %q", resp.Version)
+	}
+	state, ok := resp.Data.(*deneb.BeaconState)
+	if !ok {
+		t.Fatalf("unexpected state type %T", resp.Data)
+	}
+	if len(state.Validators) != len(keys) || len(state.Balances) != len(keys) {
+		t.Fatalf("expected %d validators and balances, got %d and %d", len(keys), len(state.Validators), len(state.Balances))
+	}
+	for i, v := range state.Validators {
+		if v.Pubkey != keys[i].Pubkey {
+			t.Fatalf("validator %d: unexpected pubkey %s", i, v.Pubkey)
+		}
+	}
+	if state.LatestExecutionPayloadHeader.BlockHash != header.BlockHash {
+		t.Fatalf("unexpected execution block hash %s", state.LatestExecutionPayloadHeader.BlockHash)
+	}
+	hFn := tree.GetHashFn()
+	if got, exp := state.HashTreeRoot(spec, hFn), expected.HashTreeRoot(hFn); got != exp {
+		t.Fatalf("decoded state root %s does not match the built state root %s", got, exp)
+	}
+	assertSameJSON(t, golden, &resp)
+	sszRoundtrip(t, spec, state, new(deneb.BeaconState))
+}
+
+func TestDecodeVersionedErrors(t *testing.T) {
+	var resp VersionedSignedBeaconBlock
+	for _, input := range []string{
+		`{"data":{}}`,
+		`{"version":"phase0"}`,
+		`{"version":"unknown","data":{}}`,
+		`{"version":"phase0","data":{"message":{"slot":"one"}}}`,
+	} {
+		if err := json.Unmarshal([]byte(input), &resp); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+}
+
+func TestEpochVersion(t *testing.T) {
+	spec := *configs.Mainnet
+	spec.ALTAIR_FORK_EPOCH = 1
+	spec.BELLATRIX_FORK_EPOCH = 2
+	spec.CAPELLA_FORK_EPOCH = 3
+	spec.DENEB_FORK_EPOCH = 4
+	spec.ELECTRA_FORK_EPOCH = 5
+	spec.FULU_FORK_EPOCH = 6
+	for epoch, expected := range []string{Phase0, Altair, Bellatrix, Capella, Deneb, Electra, Fulu} {
+		if got := EpochVersion(&spec, common.Epoch(epoch)); got != expected {
+			t.Errorf("epoch %d: got %q, expected %q", epoch, got, expected)
+		}
+	}
+	if got := SlotVersion(&spec, spec.SLOTS_PER_EPOCH*3-1); got != Bellatrix {
+		t.Errorf("got %q for last slot of epoch 2", got)
+	}
+}
@@ -0,0 +1,123 @@
+package beaconapi
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// Response is the envelope of a beacon-API response with data that is the same in every fork.
+type Response struct {
+	ExecutionOptimistic bool        `json:"execution_optimistic"`
+	Finalized           bool        `json:"finalized"`
+	Data                interface{} `json:"data"`
+}
+
+// VersionedResponse is the envelope of a beacon-API response with fork-specific data.
+// The version names the fork of the data.
+type VersionedResponse struct {
+	Version             string      `json:"version"`
+	ExecutionOptimistic bool        `json:"execution_optimistic"`
+	Finalized           bool        `json:"finalized"`
+	Data                interface{} `json:"data"`
+}
+
+// DataAllocator allocates the data of a versioned response, for the fork with the given name.
+type DataAllocator func(version string) (interface{}, error)
+
+// DecodeVersioned decodes a versioned response, with data allocated by alloc for the version of the response.
+func DecodeVersioned(b []byte, alloc DataAllocator) (*VersionedResponse, error) {
+	var env struct {
+		Version             string          `json:"version"`
+		ExecutionOptimistic bool            `json:"execution_optimistic"`
+		Finalized           bool            `json:"finalized"`
+		Data                json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b, &env); err != nil {
+		return nil, err
+	}
+	if env.Version == "" {
+		return nil, fmt.Errorf("missing version in versioned response")
+	}
+	if len(env.Data) == 0 {
+		return nil, fmt.Errorf("missing data in %s response", env.Version)
+	}
+	data, err := alloc(env.Version)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(env.Data, data); err != nil {
+		return nil, fmt.Errorf("failed to decode %s data: %w", env.Version, err)
+	}
+	return &VersionedResponse{
+		Version:             env.Version,
+		ExecutionOptimistic: env.ExecutionOptimistic,
+		Finalized:           env.Finalized,
+		Data:                data,
+	}, nil
+}
+
+// VersionedSignedBeaconBlock is a versioned response of a signed block, as served by /eth/v2/beacon/blocks/{block_id}.
+type VersionedSignedBeaconBlock struct {
+	Version             string             `json:"version"`
+	ExecutionOptimistic bool               `json:"execution_optimistic"`
+	Finalized           bool               `json:"finalized"`
+	Data                beacon.OpaqueBlock `json:"data"`
+}
+
+func (v *VersionedSignedBeaconBlock) UnmarshalJSON(b []byte) error {
+	resp, err := DecodeVersioned(b, func(version string) (interface{}, error) {
+		alloc, err := SignedBeaconBlockAllocator(version)
+		if err != nil {
+			return nil, err
+		}
+		return alloc(), nil
+	})
+	if err != nil {
+		return err
+	}
+	*v = VersionedSignedBeaconBlock{
+		Version:             resp.Version,
+		ExecutionOptimistic: resp.ExecutionOptimistic,
+		Finalized:           resp.Finalized,
+		Data:                resp.Data.(beacon.OpaqueBlock),
+	}
+	return nil
+}
+
+// VersionedBeaconState is a versioned response of a state, as served by /eth/v2/debug/beacon/states/{state_id}.
+type VersionedBeaconState struct {
+	Version             string         `json:"version"`
+	ExecutionOptimistic bool           `json:"execution_optimistic"`
+	Finalized           bool           `json:"finalized"`
+	Data                common.SpecObj `json:"data"`
+}
+
+func (v *VersionedBeaconState) UnmarshalJSON(b []byte) error {
+	resp, err := DecodeVersioned(b, func(version string) (interface{}, error) {
+		alloc, err := BeaconStateAllocator(version)
+		if err != nil {
+			return nil, err
+		}
+		return alloc(), nil
+	})
+	if err != nil {
+		return err
+	}
+	*v = VersionedBeaconState{
+		Version:             resp.Version,
+		ExecutionOptimistic: resp.ExecutionOptimistic,
+		Finalized:           resp.Finalized,
+		Data:                resp.Data.(common.SpecObj),
+	}
+	return nil
+}
+
+// BlockHeaderInfo is the data of a block header response, as served by /eth/v1/beacon/headers/{block_id}.
+type BlockHeaderInfo struct {
+	Root      common.Root                    `json:"root"`
+	Canonical bool                           `json:"canonical"`
+	Header    common.SignedBeaconBlockHeader `json:"header"`
+}
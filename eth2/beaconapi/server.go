@@ -0,0 +1,305 @@
+package beaconapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/pool"
+)
+
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeSSZ  = "application/octet-stream"
+
+	// ConsensusVersionHeader names the fork of a versioned response, see the version constants.
+	ConsensusVersionHeader = "Eth-Consensus-Version"
+)
+
+// APIError is the error response of the beacon API.
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("beacon API error %d: %s", e.Code, e.Message)
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &APIError{Code: http.StatusBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &APIError{Code: http.StatusNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// BlockStore provides blocks by block root. The chain itself only tracks block roots and states.
+type BlockStore interface {
+	Block(root common.Root) (block beacon.OpaqueBlock, ok bool)
+}
+
+// Pools are the operation pools served by the pool endpoints. Nil pools are served as empty.
+type Pools struct {
+	Attestations      *pool.AttestationPool
+	AttesterSlashings *pool.AttesterSlashingPool
+	ProposerSlashings *pool.ProposerSlashingPool
+	VoluntaryExits    *pool.VoluntaryExitPool
+}
+
+type handlerFn func(s *Server, w http.ResponseWriter, r *http.Request, params []string) error
+
+type route struct {
+	method string
+	// path segments, "{}" segments are parameters
+	path    []string
+	handler handlerFn
+}
+
+var routes = []route{
+	{http.MethodGet, []string{"eth", "v1", "beacon", "genesis"}, (*Server).getGenesis},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "headers"}, (*Server).getHeaders},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "headers", "{}"}, (*Server).getHeader},
+	{http.MethodGet, []string{"eth", "v2", "beacon", "blocks", "{}"}, (*Server).getBlock},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "blocks", "{}", "root"}, (*Server).getBlockRoot},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "states", "{}", "root"}, (*Server).getStateRoot},
+	{http.MethodGet, []string{"eth", "v2", "debug", "beacon", "states", "{}"}, (*Server).getState},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "states", "{}", "finality_checkpoints"}, (*Server).getFinalityCheckpoints},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "states", "{}", "validators"}, (*Server).getValidators},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "states", "{}", "validators", "{}"}, (*Server).getValidator},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "states", "{}", "committees"}, (*Server).getCommittees},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "pool", "attestations"}, (*Server).getPoolAttestations},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "pool", "attester_slashings"}, (*Server).getPoolAttesterSlashings},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "pool", "proposer_slashings"}, (*Server).getPoolProposerSlashings},
+	{http.MethodGet, []string{"eth", "v1", "beacon", "pool", "voluntary_exits"}, (*Server).getPoolVoluntaryExits},
+	{http.MethodPost, []string{"eth", "v1", "validator", "duties", "attester", "{}"}, (*Server).postAttesterDuties},
+	{http.MethodGet, []string{"eth", "v1", "validator", "duties", "proposer", "{}"}, (*Server).getProposerDuties},
+	{http.MethodPost, []string{"eth", "v1", "validator", "duties", "sync", "{}"}, (*Server).postSyncCommitteeDuties},
+	{http.MethodGet, []string{"eth", "v1", "node", "syncing"}, (*Server).getSyncing},
+}
+
+// Server serves a core subset of the beacon API, backed by a chain, a block store and operation pools.
+type Server struct {
+	Spec  *common.Spec
+	Chain beacon.Chain
+	// Blocks is optional, block endpoints are not found without it,
+	// and headers are served with a zero signature.
+	Blocks BlockStore
+	Pools  Pools
+	// Now is the wall clock, used for the sync distance. Defaults to time.Now.
+	Now func() time.Time
+}
+
+func NewServer(spec *common.Spec, chain beacon.Chain, blocks BlockStore, pools Pools) *Server {
+	return &Server{Spec: spec, Chain: chain, Blocks: blocks, Pools: pools, Now: time.Now}
+}
+
+func matchRoute(rt *route, segments []string) (params []string, ok bool) {
+	if len(rt.path) != len(segments) {
+		return nil, false
+	}
+	for i, seg := range rt.path {
+		if seg == "{}" {
+			params = append(params, segments[i])
+		} else if seg != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	pathFound := false
+	for i := range routes {
+		rt := &routes[i]
+		params, ok := matchRoute(rt, segments)
+		if !ok {
+			continue
+		}
+		pathFound = true
+		if rt.method != r.Method {
+			continue
+		}
+		if err := rt.handler(s, w, r, params); err != nil {
+			writeError(w, err)
+		}
+		return
+	}
+	if pathFound {
+		writeError(w, &APIError{Code: http.StatusMethodNotAllowed, Message: "method not allowed"})
+	} else {
+		writeError(w, notFound("unknown route: %s", r.URL.Path))
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = &APIError{Code: http.StatusInternalServerError, Message: err.Error()}
+	}
+	writeJSON(w, apiErr.Code, apiErr)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		code = http.StatusInternalServerError
+		data, _ = json.Marshal(&APIError{Code: code, Message: err.Error()})
+	}
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
+
+// acceptsSSZ checks if the client prefers SSZ over JSON.
+func acceptsSSZ(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		switch mediaType {
+		case ContentTypeSSZ:
+			return true
+		case ContentTypeJSON, "*/*":
+			return false
+		}
+	}
+	return false
+}
+
+func parseRoot(v string) (common.Root, error) {
+	var root common.Root
+	if err := root.UnmarshalText([]byte(v)); err != nil {
+		return common.Root{}, badRequest("invalid root %q: %v", v, err)
+	}
+	return root, nil
+}
+
+func parseUint(name string, v string) (uint64, error) {
+	x, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, badRequest("invalid %s %q", name, v)
+	}
+	return x, nil
+}
+
+// canonEntry gets the canonical entry at the given slot, including the block if there is any.
+func (s *Server) canonEntry(slot common.Slot, block bool) (beacon.ChainEntry, bool) {
+	entry, ok := s.Chain.ByCanonStep(common.AsStep(slot, true))
+	if ok && entry != nil {
+		return entry, true
+	}
+	if block {
+		return nil, false
+	}
+	entry, ok = s.Chain.ByCanonStep(common.AsStep(slot, false))
+	return entry, ok && entry != nil
+}
+
+// namedEntry resolves the ids that are shared by state ids and block ids.
+func (s *Server) namedEntry(id string) (entry beacon.ChainEntry, ok bool, err error) {
+	switch id {
+	case "head":
+		entry, err = s.Chain.Head()
+	case "finalized":
+		entry, err = s.Chain.Finalized()
+	case "justified":
+		entry, err = s.Chain.Justified()
+	case "genesis":
+		entry, ok = s.canonEntry(0, true)
+		if !ok {
+			return nil, true, notFound("genesis not available")
+		}
+	default:
+		return nil, false, nil
+	}
+	return entry, true, err
+}
+
+// StateEntry resolves a state id: "head", "genesis", "finalized", "justified", a slot, or a 0x-prefixed state root.
+func (s *Server) StateEntry(id string) (beacon.ChainEntry, error) {
+	if entry, ok, err := s.namedEntry(id); ok {
+		return entry, err
+	}
+	if strings.HasPrefix(id, "0x") {
+		root, err := parseRoot(id)
+		if err != nil {
+			return nil, err
+		}
+		entry, ok := s.Chain.ByStateRoot(root)
+		if !ok {
+			return nil, notFound("unknown state root %s", root)
+		}
+		return entry, nil
+	}
+	slot, err := parseUint("state id", id)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := s.canonEntry(common.Slot(slot), false)
+	if !ok {
+		return nil, notFound("no canonical state at slot %d", slot)
+	}
+	return entry, nil
+}
+
+// BlockEntry resolves a block id: "head", "genesis", "finalized", "justified", a slot, or a 0x-prefixed block root.
+// The entry of a named id may be an empty slot, its block root is the root of the latest block.
+func (s *Server) BlockEntry(id string) (beacon.ChainEntry, error) {
+	if entry, ok, err := s.namedEntry(id); ok {
+		return entry, err
+	}
+	if strings.HasPrefix(id, "0x") {
+		root, err := parseRoot(id)
+		if err != nil {
+			return nil, err
+		}
+		entry, ok := s.Chain.ByBlock(root)
+		if !ok {
+			return nil, notFound("unknown block root %s", root)
+		}
+		return entry, nil
+	}
+	slot, err := parseUint("block id", id)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := s.canonEntry(common.Slot(slot), true)
+	if !ok {
+		return nil, notFound("no canonical block at slot %d", slot)
+	}
+	return entry, nil
+}
+
+// isFinalized checks if the entry is canonical and at or before the finalized checkpoint.
+func (s *Server) isFinalized(entry beacon.ChainEntry) bool {
+	fin := s.Chain.FinalizedCheckpoint()
+	finSlot, err := s.Spec.EpochStartSlot(fin.Epoch)
+	if err != nil {
+		return false
+	}
+	slot := entry.Step().Slot()
+	if slot > finSlot {
+		return false
+	}
+	root, err := entry.BlockRoot()
+	if err != nil {
+		return false
+	}
+	canon, ok := s.canonEntry(slot, false)
+	if !ok {
+		return false
+	}
+	canonRoot, err := canon.BlockRoot()
+	return err == nil && canonRoot == root
+}
+
+func (s *Server) response(entry beacon.ChainEntry, data interface{}) *Response {
+	// the chain does not track payload validity, all served data is considered to be validated
+	return &Response{ExecutionOptimistic: false, Finalized: s.isFinalized(entry), Data: data}
+}
@@ -0,0 +1,539 @@
+package beaconapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/pool"
+)
+
+func (s *Server) getGenesis(w http.ResponseWriter, r *http.Request, _ []string) error {
+	genesis := s.Chain.Genesis()
+	writeJSON(w, http.StatusOK, &DataResponse{Data: &GenesisData{
+		GenesisTime:           genesis.Time,
+		GenesisValidatorsRoot: genesis.ValidatorsRoot,
+		GenesisForkVersion:    s.Spec.GENESIS_FORK_VERSION,
+	}})
+	return nil
+}
+
+func (s *Server) block(root common.Root) (beacon.OpaqueBlock, bool) {
+	if s.Blocks == nil {
+		return nil, false
+	}
+	return s.Blocks.Block(root)
+}
+
+// headerInfo gets the header of the latest block of the entry.
+// The header is taken from the state if the block itself is not available, with a zero signature.
+func (s *Server) headerInfo(ctx context.Context, entry beacon.ChainEntry) (*BlockHeaderInfo, error) {
+	root, err := entry.BlockRoot()
+	if err != nil {
+		return nil, err
+	}
+	out := &BlockHeaderInfo{Root: root}
+	if block, ok := s.block(root); ok {
+		env := block.Envelope(s.Spec, common.ForkDigest{})
+		out.Header.Message = env.BeaconBlockHeader
+		out.Header.Signature = env.Signature
+	} else {
+		state, err := entry.State(ctx)
+		if err != nil {
+			return nil, err
+		}
+		header, err := state.LatestBlockHeader()
+		if err != nil {
+			return nil, err
+		}
+		// the state root is only filled in by the slot processing after the block
+		if header.StateRoot == (common.Root{}) {
+			header.StateRoot = state.HashTreeRoot(tree.GetHashFn())
+		}
+		out.Header.Message = *header
+	}
+	if got := out.Header.Message.HashTreeRoot(tree.GetHashFn()); got != root {
+		return nil, fmt.Errorf("header root %s does not match block root %s", got, root)
+	}
+	if canon, ok := s.canonEntry(out.Header.Message.Slot, true); ok {
+		canonRoot, err := canon.BlockRoot()
+		if err != nil {
+			return nil, err
+		}
+		out.Canonical = canonRoot == root
+	}
+	return out, nil
+}
+
+func (s *Server) getHeader(w http.ResponseWriter, r *http.Request, params []string) error {
+	entry, err := s.BlockEntry(params[0])
+	if err != nil {
+		return err
+	}
+	info, err := s.headerInfo(r.Context(), entry)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, s.response(entry, info))
+	return nil
+}
+
+func (s *Server) getHeaders(w http.ResponseWriter, r *http.Request, _ []string) error {
+	q := r.URL.Query()
+	var entries []beacon.ChainEntry
+	if q.Get("slot") == "" && q.Get("parent_root") == "" {
+		head, err := s.Chain.Head()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, head)
+	} else {
+		var slot *common.Slot
+		var parentRoot *common.Root
+		if v := q.Get("slot"); v != "" {
+			x, err := parseUint("slot", v)
+			if err != nil {
+				return err
+			}
+			slot = (*common.Slot)(&x)
+		}
+		if v := q.Get("parent_root"); v != "" {
+			root, err := parseRoot(v)
+			if err != nil {
+				return err
+			}
+			parentRoot = &root
+		}
+		results, err := s.Chain.Search(parentRoot, slot)
+		if err != nil {
+			return err
+		}
+		for _, res := range results {
+			if res.Step().Block() {
+				entries = append(entries, res.ChainEntry)
+			}
+		}
+	}
+	out := make([]*BlockHeaderInfo, 0, len(entries))
+	finalized := len(entries) > 0
+	for _, entry := range entries {
+		info, err := s.headerInfo(r.Context(), entry)
+		if err != nil {
+			return err
+		}
+		out = append(out, info)
+		finalized = finalized && s.isFinalized(entry)
+	}
+	writeJSON(w, http.StatusOK, &Response{Finalized: finalized, Data: out})
+	return nil
+}
+
+func (s *Server) getBlock(w http.ResponseWriter, r *http.Request, params []string) error {
+	entry, err := s.BlockEntry(params[0])
+	if err != nil {
+		return err
+	}
+	root, err := entry.BlockRoot()
+	if err != nil {
+		return err
+	}
+	block, ok := s.block(root)
+	if !ok {
+		return notFound("block %s not available", root)
+	}
+	version, err := SignedBeaconBlockVersion(block)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(ConsensusVersionHeader, version)
+	if acceptsSSZ(r) {
+		var buf bytes.Buffer
+		if err := s.Spec.Wrap(block).Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", ContentTypeSSZ)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(buf.Bytes())
+		return nil
+	}
+	writeJSON(w, http.StatusOK, &VersionedSignedBeaconBlock{
+		Version:   version,
+		Finalized: s.isFinalized(entry),
+		Data:      block,
+	})
+	return nil
+}
+
+func (s *Server) getBlockRoot(w http.ResponseWriter, r *http.Request, params []string) error {
+	entry, err := s.BlockEntry(params[0])
+	if err != nil {
+		return err
+	}
+	root, err := entry.BlockRoot()
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, s.response(entry, &RootData{Root: root}))
+	return nil
+}
+
+func (s *Server) getStateRoot(w http.ResponseWriter, r *http.Request, params []string) error {
+	entry, err := s.StateEntry(params[0])
+	if err != nil {
+		return err
+	}
+	root, err := entry.StateRoot()
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, s.response(entry, &RootData{Root: root}))
+	return nil
+}
+
+func (s *Server) getState(w http.ResponseWriter, r *http.Request, params []string) error {
+	entry, err := s.StateEntry(params[0])
+	if err != nil {
+		return err
+	}
+	state, err := entry.State(r.Context())
+	if err != nil {
+		return err
+	}
+	version, err := BeaconStateVersion(state)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := state.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		return err
+	}
+	w.Header().Set(ConsensusVersionHeader, version)
+	if acceptsSSZ(r) {
+		w.Header().Set("Content-Type", ContentTypeSSZ)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(buf.Bytes())
+		return nil
+	}
+	// the JSON encoding is only defined for the plain state structs, not for the tree-backed views
+	alloc, err := BeaconStateAllocator(version)
+	if err != nil {
+		return err
+	}
+	data := alloc()
+	if err := data.Deserialize(s.Spec, codec.NewDecodingReader(&buf, uint64(buf.Len()))); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, &VersionedBeaconState{
+		Version:   version,
+		Finalized: s.isFinalized(entry),
+		Data:      data,
+	})
+	return nil
+}
+
+func (s *Server) getFinalityCheckpoints(w http.ResponseWriter, r *http.Request, params []string) error {
+	entry, err := s.StateEntry(params[0])
+	if err != nil {
+		return err
+	}
+	state, err := entry.State(r.Context())
+	if err != nil {
+		return err
+	}
+	var out FinalityCheckpoints
+	if out.PreviousJustified, err = state.PreviousJustifiedCheckpoint(); err != nil {
+		return err
+	}
+	if out.CurrentJustified, err = state.CurrentJustifiedCheckpoint(); err != nil {
+		return err
+	}
+	if out.Finalized, err = state.FinalizedCheckpoint(); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, s.response(entry, &out))
+	return nil
+}
+
+// splitQuery gets all values of a query parameter, which may be repeated or comma-separated.
+func splitQuery(r *http.Request, name string) (out []string) {
+	for _, v := range r.URL.Query()[name] {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// validatorIndex resolves a validator id, either an index or a 0x-prefixed pubkey.
+func validatorIndex(epc *common.EpochsContext, count uint64, id string) (common.ValidatorIndex, bool, error) {
+	if strings.HasPrefix(id, "0x") {
+		var pub common.BLSPubkey
+		if err := pub.UnmarshalText([]byte(id)); err != nil {
+			return 0, false, badRequest("invalid validator pubkey %q: %v", id, err)
+		}
+		index, ok := epc.ValidatorPubkeyCache.ValidatorIndex(pub)
+		return index, ok && uint64(index) < count, nil
+	}
+	index, err := parseUint("validator index", id)
+	if err != nil {
+		return 0, false, err
+	}
+	return common.ValidatorIndex(index), index < count, nil
+}
+
+// validators gets the validators with the given ids, or all validators if there are none,
+// filtered by status if there are any statuses. Unknown validators are skipped.
+func (s *Server) validators(ctx context.Context, entry beacon.ChainEntry, ids []string, statuses []string) ([]ValidatorData, error) {
+	state, err := entry.State(ctx)
+	if err != nil {
+		return nil, err
+	}
+	epc, err := entry.EpochsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	epoch := s.Spec.SlotToEpoch(slot)
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	balances, err := state.Balances()
+	if err != nil {
+		return nil, err
+	}
+	count, err := vals.ValidatorCount()
+	if err != nil {
+		return nil, err
+	}
+	var indices []common.ValidatorIndex
+	if len(ids) == 0 {
+		indices = make([]common.ValidatorIndex, count)
+		for i := range indices {
+			indices[i] = common.ValidatorIndex(i)
+		}
+	} else {
+		for _, id := range ids {
+			index, ok, err := validatorIndex(epc, count, id)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				indices = append(indices, index)
+			}
+		}
+	}
+	out := make([]ValidatorData, 0, len(indices))
+	for _, index := range indices {
+		val, err := vals.Validator(index)
+		if err != nil {
+			return nil, err
+		}
+		var flat common.FlatValidator
+		if err := val.Flatten(&flat); err != nil {
+			return nil, err
+		}
+		balance, err := balances.GetBalance(index)
+		if err != nil {
+			return nil, err
+		}
+		status := ValidatorStatus(&flat, balance, epoch)
+		if len(statuses) > 0 {
+			match := false
+			for _, filter := range statuses {
+				if matchStatus(status, filter) {
+					match = true
+					break
+				}
+			}
+			if !match {
+				continue
+			}
+		}
+		pub, err := val.Pubkey()
+		if err != nil {
+			return nil, err
+		}
+		creds, err := val.WithdrawalCredentials()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ValidatorData{
+			Index:   index,
+			Balance: balance,
+			Status:  status,
+			Validator: phase0.Validator{
+				Pubkey:                     pub,
+				WithdrawalCredentials:      creds,
+				EffectiveBalance:           flat.EffectiveBalance,
+				Slashed:                    flat.Slashed,
+				ActivationEligibilityEpoch: flat.ActivationEligibilityEpoch,
+				ActivationEpoch:            flat.ActivationEpoch,
+				ExitEpoch:                  flat.ExitEpoch,
+				WithdrawableEpoch:          flat.WithdrawableEpoch,
+			},
+		})
+	}
+	return out, nil
+}
+
+func (s *Server) getValidators(w http.ResponseWriter, r *http.Request, params []string) error {
+	entry, err := s.StateEntry(params[0])
+	if err != nil {
+		return err
+	}
+	out, err := s.validators(r.Context(), entry, splitQuery(r, "id"), splitQuery(r, "status"))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, s.response(entry, out))
+	return nil
+}
+
+func (s *Server) getValidator(w http.ResponseWriter, r *http.Request, params []string) error {
+	entry, err := s.StateEntry(params[0])
+	if err != nil {
+		return err
+	}
+	out, err := s.validators(r.Context(), entry, params[1:2], nil)
+	if err != nil {
+		return err
+	}
+	if len(out) == 0 {
+		return notFound("unknown validator %q", params[1])
+	}
+	writeJSON(w, http.StatusOK, s.response(entry, &out[0]))
+	return nil
+}
+
+func (s *Server) getCommittees(w http.ResponseWriter, r *http.Request, params []string) error {
+	entry, err := s.StateEntry(params[0])
+	if err != nil {
+		return err
+	}
+	epc, err := entry.EpochsContext(r.Context())
+	if err != nil {
+		return err
+	}
+	q := r.URL.Query()
+	epoch := epc.CurrentEpoch.Epoch
+	if v := q.Get("epoch"); v != "" {
+		x, err := parseUint("epoch", v)
+		if err != nil {
+			return err
+		}
+		epoch = common.Epoch(x)
+	}
+	count, err := epc.GetCommitteeCountPerSlot(epoch)
+	if err != nil {
+		return badRequest("committees of epoch %d not available: %v", epoch, err)
+	}
+	startSlot, err := s.Spec.EpochStartSlot(epoch)
+	if err != nil {
+		return badRequest("invalid epoch %d: %v", epoch, err)
+	}
+	slots := [2]common.Slot{startSlot, startSlot + s.Spec.SLOTS_PER_EPOCH}
+	if v := q.Get("slot"); v != "" {
+		x, err := parseUint("slot", v)
+		if err != nil {
+			return err
+		}
+		if slot := common.Slot(x); slot < slots[0] || slot >= slots[1] {
+			return badRequest("slot %d is not in epoch %d", slot, epoch)
+		} else {
+			slots = [2]common.Slot{slot, slot + 1}
+		}
+	}
+	indices := [2]uint64{0, count}
+	if v := q.Get("index"); v != "" {
+		x, err := parseUint("committee index", v)
+		if err != nil {
+			return err
+		}
+		if x >= count {
+			return badRequest("committee index %d out of range, epoch %d has %d committees per slot", x, epoch, count)
+		}
+		indices = [2]uint64{x, x + 1}
+	}
+	out := make([]CommitteeData, 0, uint64(slots[1]-slots[0])*(indices[1]-indices[0]))
+	for slot := slots[0]; slot < slots[1]; slot++ {
+		for i := indices[0]; i < indices[1]; i++ {
+			committee, err := epc.GetBeaconCommittee(slot, common.CommitteeIndex(i))
+			if err != nil {
+				return err
+			}
+			out = append(out, CommitteeData{
+				Index:      common.CommitteeIndex(i),
+				Slot:       slot,
+				Validators: append(make([]common.ValidatorIndex, 0, len(committee)), committee...),
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, s.response(entry, out))
+	return nil
+}
+
+func (s *Server) getPoolAttestations(w http.ResponseWriter, r *http.Request, _ []string) error {
+	out := make([]*phase0.Attestation, 0)
+	if s.Pools.Attestations != nil {
+		q := r.URL.Query()
+		var opts []pool.AttSearchOption
+		if v := q.Get("slot"); v != "" {
+			x, err := parseUint("slot", v)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, pool.WithSlot(common.Slot(x)))
+		}
+		if v := q.Get("committee_index"); v != "" {
+			x, err := parseUint("committee index", v)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, pool.WithCommittee(common.CommitteeIndex(x)))
+		}
+		out = append(out, s.Pools.Attestations.Search(opts...)...)
+	}
+	writeJSON(w, http.StatusOK, &DataResponse{Data: out})
+	return nil
+}
+
+func (s *Server) getPoolAttesterSlashings(w http.ResponseWriter, r *http.Request, _ []string) error {
+	out := make([]*phase0.AttesterSlashing, 0)
+	if s.Pools.AttesterSlashings != nil {
+		out = append(out, s.Pools.AttesterSlashings.All()...)
+	}
+	writeJSON(w, http.StatusOK, &DataResponse{Data: out})
+	return nil
+}
+
+func (s *Server) getPoolProposerSlashings(w http.ResponseWriter, r *http.Request, _ []string) error {
+	out := make([]*phase0.ProposerSlashing, 0)
+	if s.Pools.ProposerSlashings != nil {
+		out = append(out, s.Pools.ProposerSlashings.All()...)
+	}
+	writeJSON(w, http.StatusOK, &DataResponse{Data: out})
+	return nil
+}
+
+func (s *Server) getPoolVoluntaryExits(w http.ResponseWriter, r *http.Request, _ []string) error {
+	out := make([]*phase0.SignedVoluntaryExit, 0)
+	if s.Pools.VoluntaryExits != nil {
+		out = append(out, s.Pools.VoluntaryExits.All()...)
+	}
+	writeJSON(w, http.StatusOK, &DataResponse{Data: out})
+	return nil
+}
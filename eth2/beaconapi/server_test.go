@@ -0,0 +1,396 @@
+package beaconapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/interop"
+	"github.com/protolambda/zrnt/eth2/pool"
+)
+
+// stubChain is a chain of a single block entry, at the head.
+type stubChain struct {
+	entry   *stubEntry
+	genesis beacon.GenesisInfo
+}
+
+type stubEntry struct {
+	step  common.Step
+	state common.BeaconState
+	epc   *common.EpochsContext
+}
+
+func (e *stubEntry) Step() common.Step { return e.step }
+
+func (e *stubEntry) header() (*common.BeaconBlockHeader, error) {
+	header, err := e.state.LatestBlockHeader()
+	if err != nil {
+		return nil, err
+	}
+	header.StateRoot = e.state.HashTreeRoot(tree.GetHashFn())
+	return header, nil
+}
+
+func (e *stubEntry) BlockRoot() (common.Root, error) {
+	header, err := e.header()
+	if err != nil {
+		return common.Root{}, err
+	}
+	return header.HashTreeRoot(tree.GetHashFn()), nil
+}
+
+func (e *stubEntry) ParentRoot() (common.Root, error) {
+	header, err := e.header()
+	if err != nil {
+		return common.Root{}, err
+	}
+	return header.ParentRoot, nil
+}
+
+func (e *stubEntry) StateRoot() (common.Root, error) {
+	return e.state.HashTreeRoot(tree.GetHashFn()), nil
+}
+
+func (e *stubEntry) EpochsContext(ctx context.Context) (*common.EpochsContext, error) {
+	return e.epc, nil
+}
+
+func (e *stubEntry) State(ctx context.Context) (common.BeaconState, error) {
+	return e.state, nil
+}
+
+func (c *stubChain) ByStateRoot(root common.Root) (beacon.ChainEntry, bool) {
+	stateRoot, _ := c.entry.StateRoot()
+	return c.entry, root == stateRoot
+}
+
+func (c *stubChain) ByBlock(root common.Root) (beacon.ChainEntry, bool) {
+	blockRoot, _ := c.entry.BlockRoot()
+	return c.entry, root == blockRoot
+}
+
+func (c *stubChain) ByBlockSlot(root common.Root, slot common.Slot) (beacon.ChainEntry, bool) {
+	entry, ok := c.ByBlock(root)
+	return entry, ok && slot == c.entry.step.Slot()
+}
+
+func (c *stubChain) Search(parentRoot *common.Root, slot *common.Slot) ([]beacon.SearchEntry, error) {
+	parent, _ := c.entry.ParentRoot()
+	if (parentRoot != nil && *parentRoot != parent) || (slot != nil && *slot != c.entry.step.Slot()) {
+		return nil, nil
+	}
+	return []beacon.SearchEntry{{ChainEntry: c.entry, Canonical: true}}, nil
+}
+
+func (c *stubChain) Closest(fromBlockRoot common.Root, toSlot common.Slot) (beacon.ChainEntry, bool) {
+	return c.ByBlock(fromBlockRoot)
+}
+
+func (c *stubChain) InSubtree(anchor common.Root, root common.Root) (unknown bool, inSubtree bool) {
+	return true, false
+}
+
+func (c *stubChain) ByCanonStep(step common.Step) (beacon.ChainEntry, bool) {
+	return c.entry, step == c.entry.step
+}
+
+func (c *stubChain) Iter() (beacon.ChainIter, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *stubChain) JustifiedCheckpoint() common.Checkpoint {
+	cp, _ := c.entry.state.CurrentJustifiedCheckpoint()
+	return cp
+}
+
+func (c *stubChain) FinalizedCheckpoint() common.Checkpoint {
+	cp, _ := c.entry.state.FinalizedCheckpoint()
+	return cp
+}
+
+func (c *stubChain) Justified() (beacon.ChainEntry, error) { return c.entry, nil }
+func (c *stubChain) Finalized() (beacon.ChainEntry, error) { return c.entry, nil }
+func (c *stubChain) Head() (beacon.ChainEntry, error)      { return c.entry, nil }
+
+func (c *stubChain) Towards(ctx context.Context, fromBlockRoot common.Root, toSlot common.Slot) (beacon.ChainEntry, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *stubChain) Genesis() beacon.GenesisInfo { return c.genesis }
+
+type stubBlocks map[common.Root]beacon.OpaqueBlock
+
+func (b stubBlocks) Block(root common.Root) (beacon.OpaqueBlock, bool) {
+	block, ok := b[root]
+	return block, ok
+}
+
+func apiGet(t *testing.T, url string, accept string, expectedCode int, dst interface{}) http.Header {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	return apiDo(t, req, expectedCode, dst)
+}
+
+func apiPost(t *testing.T, url string, body string, expectedCode int, dst interface{}) http.Header {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", ContentTypeJSON)
+	return apiDo(t, req, expectedCode, dst)
+}
+
+func apiDo(t *testing.T, req *http.Request, expectedCode int, dst interface{}) http.Header {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != expectedCode {
+		t.Fatalf("%s %s: got status %d, expected %d: %s", req.Method, req.URL.Path, resp.StatusCode, expectedCode, data)
+	}
+	switch x := dst.(type) {
+	case nil:
+	case *[]byte:
+		*x = data
+	default:
+		if err := json.Unmarshal(data, dst); err != nil {
+			t.Fatalf("failed to decode %s: %v", data, err)
+		}
+	}
+	return resp.Header
+}
+
+func TestBeaconAPIServer(t *testing.T) {
+	spec := configs.Mainnet
+	keys, err := interop.NewKeys(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _, err := altair.KickStartState(spec, common.Root{123}, 1564000000, keys.KickstartValidators(spec.MAX_EFFECTIVE_BALANCE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot := spec.SLOTS_PER_EPOCH*3 - 1
+	if err := state.SetSlot(slot); err != nil {
+		t.Fatal(err)
+	}
+	epc, err := common.NewEpochsContext(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the latest block of the state is an (empty) block at the state slot
+	var block altair.SignedBeaconBlock
+	{
+		var buf bytes.Buffer
+		if err := altair.SignedBeaconBlockType(spec).New().Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+			t.Fatal(err)
+		}
+		if err := block.Deserialize(spec, codec.NewDecodingReader(&buf, uint64(buf.Len()))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	block.Message.Slot = slot
+	block.Message.ProposerIndex = 42
+	block.Message.ParentRoot = common.Root{0x01}
+	block.Signature = common.BLSSignature{0x02}
+	header := block.Message.Header(spec)
+	if err := state.SetLatestBlockHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	stateRoot := state.HashTreeRoot(tree.GetHashFn())
+	block.Message.StateRoot = stateRoot
+	blockRoot := block.Message.HashTreeRoot(spec, tree.GetHashFn())
+
+	chain := &stubChain{
+		entry:   &stubEntry{step: common.AsStep(slot, true), state: state, epc: epc},
+		genesis: beacon.GenesisInfo{Time: 1_600_000_000, ValidatorsRoot: common.Root{0x03}},
+	}
+	exits := pool.NewVoluntaryExitPool(spec)
+	exit := &phase0.SignedVoluntaryExit{Message: phase0.VoluntaryExit{Epoch: 2, ValidatorIndex: 7}}
+	if err := exits.AddVoluntaryExit(context.Background(), exit); err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(spec, chain, stubBlocks{blockRoot: &block}, Pools{VoluntaryExits: exits})
+	// 5 slots after the head
+	server.Now = func() time.Time {
+		return time.Unix(int64(chain.genesis.Time)+int64(spec.SECONDS_PER_SLOT)*int64(slot+5), 0)
+	}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+	url := srv.URL
+
+	var genesis struct{ Data GenesisData }
+	apiGet(t, url+"/eth/v1/beacon/genesis", "", 200, &genesis)
+	if genesis.Data.GenesisTime != chain.genesis.Time || genesis.Data.GenesisValidatorsRoot != chain.genesis.ValidatorsRoot {
+		t.Fatalf("unexpected genesis: %+v", genesis.Data)
+	}
+
+	for _, id := range []string{"head", "finalized", "justified", fmt.Sprint(slot), stateRoot.String()} {
+		var root struct{ Data RootData }
+		apiGet(t, url+"/eth/v1/beacon/states/"+id+"/root", "", 200, &root)
+		if root.Data.Root != stateRoot {
+			t.Fatalf("state %s: got root %s, expected %s", id, root.Data.Root, stateRoot)
+		}
+	}
+	for _, id := range []string{"head", fmt.Sprint(slot), blockRoot.String()} {
+		var root struct{ Data RootData }
+		apiGet(t, url+"/eth/v1/beacon/blocks/"+id+"/root", "", 200, &root)
+		if root.Data.Root != blockRoot {
+			t.Fatalf("block %s: got root %s, expected %s", id, root.Data.Root, blockRoot)
+		}
+	}
+	apiGet(t, url+"/eth/v1/beacon/states/123/root", "", 404, nil)
+	apiGet(t, url+"/eth/v1/beacon/states/0x1234/root", "", 400, nil)
+	apiGet(t, url+"/eth/v1/beacon/states/abc/root", "", 400, nil)
+	apiGet(t, url+"/eth/v1/beacon/states/head/unknown", "", 404, nil)
+	apiPost(t, url+"/eth/v1/beacon/genesis", "", 405, nil)
+
+	var checkpoints struct{ Data FinalityCheckpoints }
+	apiGet(t, url+"/eth/v1/beacon/states/head/finality_checkpoints", "", 200, &checkpoints)
+	if expected, _ := state.CurrentJustifiedCheckpoint(); checkpoints.Data.CurrentJustified != expected {
+		t.Fatalf("unexpected finality checkpoints: %+v", checkpoints.Data)
+	}
+
+	// header and block
+	var headerResp struct {
+		Finalized bool
+		Data      BlockHeaderInfo
+	}
+	apiGet(t, url+"/eth/v1/beacon/headers/head", "", 200, &headerResp)
+	if headerResp.Data.Root != blockRoot || !headerResp.Data.Canonical || headerResp.Data.Header.Signature != block.Signature {
+		t.Fatalf("unexpected header: %+v", headerResp.Data)
+	}
+	var headers struct{ Data []BlockHeaderInfo }
+	apiGet(t, url+"/eth/v1/beacon/headers?parent_root="+block.Message.ParentRoot.String(), "", 200, &headers)
+	if len(headers.Data) != 1 || headers.Data[0].Root != blockRoot {
+		t.Fatalf("unexpected headers: %+v", headers.Data)
+	}
+	var blockResp VersionedSignedBeaconBlock
+	h := apiGet(t, url+"/eth/v2/beacon/blocks/head", "", 200, &blockResp)
+	if blockResp.Version != Altair || h.Get(ConsensusVersionHeader) != Altair {
+		t.Fatalf("unexpected block version %q", blockResp.Version)
+	}
+	if got := blockResp.Data.(*altair.SignedBeaconBlock).Message.HashTreeRoot(spec, tree.GetHashFn()); got != blockRoot {
+		t.Fatalf("unexpected JSON block root %s", got)
+	}
+	var raw []byte
+	h = apiGet(t, url+"/eth/v2/beacon/blocks/"+blockRoot.String(), "application/octet-stream;q=1,application/json;q=0.9", 200, &raw)
+	if h.Get("Content-Type") != ContentTypeSSZ {
+		t.Fatalf("unexpected content type %q", h.Get("Content-Type"))
+	}
+	var sszBlock altair.SignedBeaconBlock
+	if err := sszBlock.Deserialize(spec, codec.NewDecodingReader(bytes.NewReader(raw), uint64(len(raw)))); err != nil {
+		t.Fatal(err)
+	}
+	if sszBlock.Message.HashTreeRoot(spec, tree.GetHashFn()) != blockRoot {
+		t.Fatal("unexpected SSZ block")
+	}
+
+	// validators
+	pub5, _ := epc.ValidatorPubkeyCache.Pubkey(5)
+	var validators struct{ Data []ValidatorData }
+	apiGet(t, url+"/eth/v1/beacon/states/head/validators?id=3,"+pub5.Compressed.String()+"&id=5000&status=active", "", 200, &validators)
+	if len(validators.Data) != 2 || validators.Data[0].Index != 3 || validators.Data[1].Index != 5 {
+		t.Fatalf("unexpected validators: %+v", validators.Data)
+	}
+	if v := validators.Data[1]; v.Status != StatusActiveOngoing || v.Validator.Pubkey != pub5.Compressed || v.Balance != spec.MAX_EFFECTIVE_BALANCE {
+		t.Fatalf("unexpected validator: %+v", v)
+	}
+	apiGet(t, url+"/eth/v1/beacon/states/head/validators?status=pending", "", 200, &validators)
+	if len(validators.Data) != 0 {
+		t.Fatalf("expected no pending validators, got %d", len(validators.Data))
+	}
+	var validator struct{ Data ValidatorData }
+	apiGet(t, url+"/eth/v1/beacon/states/head/validators/"+pub5.Compressed.String(), "", 200, &validator)
+	if validator.Data.Index != 5 {
+		t.Fatalf("unexpected validator: %+v", validator.Data)
+	}
+	apiGet(t, url+"/eth/v1/beacon/states/head/validators/1000", "", 404, nil)
+
+	// committees
+	var committees struct{ Data []CommitteeData }
+	apiGet(t, url+"/eth/v1/beacon/states/head/committees?slot="+fmt.Sprint(slot), "", 200, &committees)
+	count, _ := epc.GetCommitteeCountPerSlot(2)
+	if uint64(len(committees.Data)) != count {
+		t.Fatalf("expected %d committees, got %d", count, len(committees.Data))
+	}
+	for _, c := range committees.Data {
+		expected, _ := epc.GetBeaconCommittee(slot, c.Index)
+		if c.Slot != slot || len(c.Validators) != len(expected) || c.Validators[0] != expected[0] {
+			t.Fatalf("unexpected committee: %+v", c)
+		}
+	}
+	apiGet(t, url+"/eth/v1/beacon/states/head/committees?epoch=10", "", 400, nil)
+
+	// duties
+	var attDuties struct {
+		DependentRoot common.Root `json:"dependent_root"`
+		Data          []AttesterDutyData
+	}
+	apiPost(t, url+"/eth/v1/validator/duties/attester/3", `["1","2","3"]`, 200, &attDuties)
+	if len(attDuties.Data) != 3 {
+		t.Fatalf("expected 3 attester duties, got %d", len(attDuties.Data))
+	}
+	apiPost(t, url+"/eth/v1/validator/duties/attester/5", `["1"]`, 400, nil)
+	apiPost(t, url+"/eth/v1/validator/duties/attester/3", `[1`, 400, nil)
+	var propDuties struct{ Data []ProposerDutyData }
+	apiGet(t, url+"/eth/v1/validator/duties/proposer/2", "", 200, &propDuties)
+	if uint64(len(propDuties.Data)) != uint64(spec.SLOTS_PER_EPOCH) {
+		t.Fatalf("expected a proposer duty per slot, got %d", len(propDuties.Data))
+	}
+	var syncDuties struct {
+		Data []SyncCommitteeDutyData
+	}
+	members := epc.CurrentSyncCommittee.Indices
+	apiPost(t, url+"/eth/v1/validator/duties/sync/2", fmt.Sprintf(`["%d"]`, members[0]), 200, &syncDuties)
+	if len(syncDuties.Data) != 1 || syncDuties.Data[0].ValidatorIndex != members[0] {
+		t.Fatalf("unexpected sync committee duties: %+v", syncDuties.Data)
+	}
+
+	// node and pools
+	var syncing struct{ Data SyncingStatus }
+	apiGet(t, url+"/eth/v1/node/syncing", "", 200, &syncing)
+	if syncing.Data.HeadSlot != slot || syncing.Data.SyncDistance != 5 || syncing.Data.IsSyncing {
+		t.Fatalf("unexpected sync status: %+v", syncing.Data)
+	}
+	var poolExits struct{ Data []phase0.SignedVoluntaryExit }
+	apiGet(t, url+"/eth/v1/beacon/pool/voluntary_exits", "", 200, &poolExits)
+	if len(poolExits.Data) != 1 || poolExits.Data[0].Message != exit.Message {
+		t.Fatalf("unexpected pool exits: %+v", poolExits.Data)
+	}
+	var poolAtts []byte
+	apiGet(t, url+"/eth/v1/beacon/pool/attestations", "", 200, &poolAtts)
+	if string(poolAtts) != `{"data":[]}` {
+		t.Fatalf("unexpected pool attestations: %s", poolAtts)
+	}
+}
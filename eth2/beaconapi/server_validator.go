@@ -0,0 +1,157 @@
+package beaconapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/duties"
+)
+
+// headInfo gets the epochs context and state of the head, which duties are computed from.
+func (s *Server) headInfo(ctx context.Context) (*common.EpochsContext, common.BeaconState, error) {
+	head, err := s.Chain.Head()
+	if err != nil {
+		return nil, nil, err
+	}
+	epc, err := head.EpochsContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	state, err := head.State(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return epc, state, nil
+}
+
+func parseEpoch(v string) (common.Epoch, error) {
+	x, err := parseUint("epoch", v)
+	return common.Epoch(x), err
+}
+
+func decodeIndices(r *http.Request) ([]common.ValidatorIndex, error) {
+	var indices []common.ValidatorIndex
+	if err := json.NewDecoder(r.Body).Decode(&indices); err != nil {
+		return nil, badRequest("invalid validator indices: %v", err)
+	}
+	return indices, nil
+}
+
+func (s *Server) postAttesterDuties(w http.ResponseWriter, r *http.Request, params []string) error {
+	epoch, err := parseEpoch(params[0])
+	if err != nil {
+		return err
+	}
+	indices, err := decodeIndices(r)
+	if err != nil {
+		return err
+	}
+	epc, state, err := s.headInfo(r.Context())
+	if err != nil {
+		return err
+	}
+	if epoch != epc.CurrentEpoch.Epoch && epoch != epc.NextEpoch.Epoch {
+		return badRequest("attester duties are only available for epochs %d and %d", epc.CurrentEpoch.Epoch, epc.NextEpoch.Epoch)
+	}
+	res, err := duties.GetAttesterDuties(s.Spec, epc, state, epoch, indices)
+	if err != nil {
+		return err
+	}
+	out := make([]AttesterDutyData, 0, len(res.Duties))
+	for _, d := range res.Duties {
+		out = append(out, AttesterDutyData{
+			Pubkey:                  d.Pubkey,
+			ValidatorIndex:          d.ValidatorIndex,
+			CommitteeIndex:          d.CommitteeIndex,
+			CommitteeLength:         view.Uint64View(d.CommitteeLength),
+			CommitteesAtSlot:        view.Uint64View(d.CommitteesAtSlot),
+			ValidatorCommitteeIndex: view.Uint64View(d.ValidatorCommitteeIndex),
+			Slot:                    d.Slot,
+		})
+	}
+	writeJSON(w, http.StatusOK, &DependentResponse{DependentRoot: res.DependentRoot, Data: out})
+	return nil
+}
+
+func (s *Server) getProposerDuties(w http.ResponseWriter, r *http.Request, params []string) error {
+	epoch, err := parseEpoch(params[0])
+	if err != nil {
+		return err
+	}
+	epc, state, err := s.headInfo(r.Context())
+	if err != nil {
+		return err
+	}
+	if epoch != epc.Proposers.Epoch {
+		return badRequest("proposer duties are only available for epoch %d", epc.Proposers.Epoch)
+	}
+	res, err := duties.GetProposerDuties(s.Spec, epc, state)
+	if err != nil {
+		return err
+	}
+	out := make([]ProposerDutyData, 0, len(res.Duties))
+	for _, d := range res.Duties {
+		out = append(out, ProposerDutyData{Pubkey: d.Pubkey, ValidatorIndex: d.ValidatorIndex, Slot: d.Slot})
+	}
+	writeJSON(w, http.StatusOK, &DependentResponse{DependentRoot: res.DependentRoot, Data: out})
+	return nil
+}
+
+func (s *Server) postSyncCommitteeDuties(w http.ResponseWriter, r *http.Request, params []string) error {
+	epoch, err := parseEpoch(params[0])
+	if err != nil {
+		return err
+	}
+	indices, err := decodeIndices(r)
+	if err != nil {
+		return err
+	}
+	epc, _, err := s.headInfo(r.Context())
+	if err != nil {
+		return err
+	}
+	res, err := duties.GetSyncCommitteeDuties(s.Spec, epc, epoch, indices)
+	if err != nil {
+		return badRequest("sync committee duties not available: %v", err)
+	}
+	out := make([]SyncCommitteeDutyData, 0, len(res.Duties))
+	for _, d := range res.Duties {
+		positions := make([]view.Uint64View, 0, len(d.ValidatorSyncCommitteeIndices))
+		for _, p := range d.ValidatorSyncCommitteeIndices {
+			positions = append(positions, view.Uint64View(p))
+		}
+		out = append(out, SyncCommitteeDutyData{
+			Pubkey:                        d.Pubkey,
+			ValidatorIndex:                d.ValidatorIndex,
+			ValidatorSyncCommitteeIndices: positions,
+		})
+	}
+	writeJSON(w, http.StatusOK, &Response{Data: out})
+	return nil
+}
+
+func (s *Server) getSyncing(w http.ResponseWriter, r *http.Request, _ []string) error {
+	head, err := s.Chain.Head()
+	if err != nil {
+		return err
+	}
+	headSlot := head.Step().Slot()
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	currentSlot := s.Spec.TimeToSlot(common.Timestamp(now.Unix()), s.Chain.Genesis().Time)
+	out := &SyncingStatus{HeadSlot: headSlot}
+	if currentSlot > headSlot {
+		out.SyncDistance = view.Uint64View(currentSlot - headSlot)
+	}
+	// a head that is more than an epoch behind is not explained by missed proposals
+	out.IsSyncing = uint64(out.SyncDistance) > uint64(s.Spec.SLOTS_PER_EPOCH)
+	writeJSON(w, http.StatusOK, &DataResponse{Data: out})
+	return nil
+}
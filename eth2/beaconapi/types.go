@@ -0,0 +1,130 @@
+package beaconapi
+
+import (
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// DataResponse is the envelope of a beacon-API response without finality information.
+type DataResponse struct {
+	Data interface{} `json:"data"`
+}
+
+// DependentResponse is the envelope of a duties response, which changes if and only if the dependent root changes.
+type DependentResponse struct {
+	DependentRoot       common.Root `json:"dependent_root"`
+	ExecutionOptimistic bool        `json:"execution_optimistic"`
+	Data                interface{} `json:"data"`
+}
+
+type GenesisData struct {
+	GenesisTime           common.Timestamp `json:"genesis_time"`
+	GenesisValidatorsRoot common.Root      `json:"genesis_validators_root"`
+	GenesisForkVersion    common.Version   `json:"genesis_fork_version"`
+}
+
+type RootData struct {
+	Root common.Root `json:"root"`
+}
+
+type FinalityCheckpoints struct {
+	PreviousJustified common.Checkpoint `json:"previous_justified"`
+	CurrentJustified  common.Checkpoint `json:"current_justified"`
+	Finalized         common.Checkpoint `json:"finalized"`
+}
+
+// Validator statuses, see ValidatorStatus.
+const (
+	StatusPendingInitialized = "pending_initialized"
+	StatusPendingQueued      = "pending_queued"
+	StatusActiveOngoing      = "active_ongoing"
+	StatusActiveExiting      = "active_exiting"
+	StatusActiveSlashed      = "active_slashed"
+	StatusExitedUnslashed    = "exited_unslashed"
+	StatusExitedSlashed      = "exited_slashed"
+	StatusWithdrawalPossible = "withdrawal_possible"
+	StatusWithdrawalDone     = "withdrawal_done"
+)
+
+// ValidatorStatus computes the status of a validator at the given epoch.
+func ValidatorStatus(v *common.FlatValidator, balance common.Gwei, epoch common.Epoch) string {
+	switch {
+	case epoch < v.ActivationEpoch:
+		if v.ActivationEligibilityEpoch == common.FAR_FUTURE_EPOCH {
+			return StatusPendingInitialized
+		}
+		return StatusPendingQueued
+	case epoch < v.ExitEpoch:
+		if v.Slashed {
+			return StatusActiveSlashed
+		}
+		if v.ExitEpoch == common.FAR_FUTURE_EPOCH {
+			return StatusActiveOngoing
+		}
+		return StatusActiveExiting
+	case epoch < v.WithdrawableEpoch:
+		if v.Slashed {
+			return StatusExitedSlashed
+		}
+		return StatusExitedUnslashed
+	default:
+		if balance == 0 {
+			return StatusWithdrawalDone
+		}
+		return StatusWithdrawalPossible
+	}
+}
+
+// matchStatus checks if the status matches the filter, which is either a status or a status prefix:
+// "pending", "active", "exited" or "withdrawal".
+func matchStatus(status string, filter string) bool {
+	if status == filter {
+		return true
+	}
+	return len(status) > len(filter) && status[:len(filter)] == filter && status[len(filter)] == '_'
+}
+
+type ValidatorData struct {
+	Index     common.ValidatorIndex `json:"index"`
+	Balance   common.Gwei           `json:"balance"`
+	Status    string                `json:"status"`
+	Validator phase0.Validator      `json:"validator"`
+}
+
+type CommitteeData struct {
+	Index      common.CommitteeIndex   `json:"index"`
+	Slot       common.Slot             `json:"slot"`
+	Validators []common.ValidatorIndex `json:"validators"`
+}
+
+type AttesterDutyData struct {
+	Pubkey                  common.BLSPubkey      `json:"pubkey"`
+	ValidatorIndex          common.ValidatorIndex `json:"validator_index"`
+	CommitteeIndex          common.CommitteeIndex `json:"committee_index"`
+	CommitteeLength         view.Uint64View       `json:"committee_length"`
+	CommitteesAtSlot        view.Uint64View       `json:"committees_at_slot"`
+	ValidatorCommitteeIndex view.Uint64View       `json:"validator_committee_index"`
+	Slot                    common.Slot           `json:"slot"`
+}
+
+type ProposerDutyData struct {
+	Pubkey         common.BLSPubkey      `json:"pubkey"`
+	ValidatorIndex common.ValidatorIndex `json:"validator_index"`
+	Slot           common.Slot           `json:"slot"`
+}
+
+type SyncCommitteeDutyData struct {
+	Pubkey                        common.BLSPubkey      `json:"pubkey"`
+	ValidatorIndex                common.ValidatorIndex `json:"validator_index"`
+	ValidatorSyncCommitteeIndices []view.Uint64View     `json:"validator_sync_committee_indices"`
+}
+
+type SyncingStatus struct {
+	HeadSlot     common.Slot     `json:"head_slot"`
+	SyncDistance view.Uint64View `json:"sync_distance"`
+	IsSyncing    bool            `json:"is_syncing"`
+	IsOptimistic bool            `json:"is_optimistic"`
+	ELOffline    bool            `json:"el_offline"`
+}
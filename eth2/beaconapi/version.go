@@ -0,0 +1,122 @@
+package beaconapi
+
+import (
+	"fmt"
+
+	"github.com/protolambda/ztyp/codec"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// Fork names, as used in the "version" field of versioned responses and the Eth-Consensus-Version header.
+const (
+	Phase0    = common.Phase0
+	Altair    = common.Altair
+	Bellatrix = common.Bellatrix
+	Capella   = common.Capella
+	Deneb     = common.Deneb
+	Electra   = common.Electra
+	Fulu      = common.Fulu
+)
+
+// EpochVersion returns the name of the fork that is active at the given epoch.
+func EpochVersion(spec *common.Spec, epoch common.Epoch) string {
+	return spec.ForkName(epoch)
+}
+
+// SlotVersion returns the name of the fork that is active at the given slot.
+func SlotVersion(spec *common.Spec, slot common.Slot) string {
+	return EpochVersion(spec, spec.SlotToEpoch(slot))
+}
+
+// SignedBeaconBlockAllocator returns an allocator for the signed block type of the named fork.
+func SignedBeaconBlockAllocator(version string) (func() beacon.OpaqueBlock, error) {
+	switch version {
+	case Phase0:
+		return func() beacon.OpaqueBlock { return new(phase0.SignedBeaconBlock) }, nil
+	case Altair:
+		return func() beacon.OpaqueBlock { return new(altair.SignedBeaconBlock) }, nil
+	case Bellatrix:
+		return func() beacon.OpaqueBlock { return new(bellatrix.SignedBeaconBlock) }, nil
+	case Capella:
+		return func() beacon.OpaqueBlock { return new(capella.SignedBeaconBlock) }, nil
+	case Deneb:
+		return func() beacon.OpaqueBlock { return new(deneb.SignedBeaconBlock) }, nil
+	case Electra:
+		return func() beacon.OpaqueBlock { return new(electra.SignedBeaconBlock) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported block version: %q", version)
+	}
+}
+
+// SignedBeaconBlockVersion returns the name of the fork of the signed block type.
+func SignedBeaconBlockVersion(block beacon.OpaqueBlock) (string, error) {
+	switch block.(type) {
+	case *phase0.SignedBeaconBlock:
+		return Phase0, nil
+	case *altair.SignedBeaconBlock:
+		return Altair, nil
+	case *bellatrix.SignedBeaconBlock:
+		return Bellatrix, nil
+	case *capella.SignedBeaconBlock:
+		return Capella, nil
+	case *deneb.SignedBeaconBlock:
+		return Deneb, nil
+	case *electra.SignedBeaconBlock:
+		return Electra, nil
+	default:
+		return "", fmt.Errorf("unsupported block type: %T", block)
+	}
+}
+
+// BeaconStateAllocator returns an allocator for the state type of the named fork.
+func BeaconStateAllocator(version string) (func() common.SpecObj, error) {
+	switch version {
+	case Phase0:
+		return func() common.SpecObj { return new(phase0.BeaconState) }, nil
+	case Altair:
+		return func() common.SpecObj { return new(altair.BeaconState) }, nil
+	case Bellatrix:
+		return func() common.SpecObj { return new(bellatrix.BeaconState) }, nil
+	case Capella:
+		return func() common.SpecObj { return new(capella.BeaconState) }, nil
+	case Deneb:
+		return func() common.SpecObj { return new(deneb.BeaconState) }, nil
+	case Electra:
+		return func() common.SpecObj { return new(electra.BeaconState) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported state version: %q", version)
+	}
+}
+
+// BeaconStateVersion returns the name of the fork of the tree-backed state view.
+func BeaconStateVersion(state common.BeaconState) (string, error) {
+	return beacon.StateForkName(state)
+}
+
+// DecodeBeaconStateView decodes a state of the named fork from SSZ, into a tree-backed state view.
+func DecodeBeaconStateView(spec *common.Spec, version string, dr *codec.DecodingReader) (common.BeaconState, error) {
+	switch version {
+	case Phase0:
+		return phase0.AsBeaconStateView(phase0.BeaconStateType(spec).Deserialize(dr))
+	case Altair:
+		return altair.AsBeaconStateView(altair.BeaconStateType(spec).Deserialize(dr))
+	case Bellatrix:
+		return bellatrix.AsBeaconStateView(bellatrix.BeaconStateType(spec).Deserialize(dr))
+	case Capella:
+		return capella.AsBeaconStateView(capella.BeaconStateType(spec).Deserialize(dr))
+	case Deneb:
+		return deneb.AsBeaconStateView(deneb.BeaconStateType(spec).Deserialize(dr))
+	case Electra:
+		return electra.AsBeaconStateView(electra.BeaconStateType(spec).Deserialize(dr))
+	default:
+		return nil, fmt.Errorf("unsupported state version: %q", version)
+	}
+}
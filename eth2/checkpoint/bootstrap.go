@@ -0,0 +1,137 @@
+package checkpoint
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/forkchoice"
+	"github.com/protolambda/zrnt/eth2/forkchoice/proto"
+)
+
+// Anchor is a trusted finalized state and block to sync from, instead of genesis.
+type Anchor struct {
+	// Name of the fork of the state
+	Version string
+	State   common.BeaconState
+	Block   beacon.OpaqueBlock
+
+	BlockRoot common.Root
+	StateRoot common.Root
+	// Checkpoint of the anchor: the epoch of the state, and the root of the block
+	Checkpoint common.Checkpoint
+	// Number of epochs, after the checkpoint epoch, that the anchor is safe to sync from.
+	WeakSubjectivityPeriod common.Epoch
+
+	EpochsContext *common.EpochsContext
+	ForkChoice    forkchoice.Forkchoice
+	Chain         *AnchorChain
+}
+
+// Bootstrap decodes the finalized state and block, of any fork, and checks them against
+// the trusted weak-subjectivity checkpoint, and the current time.
+// The epochs-context, forkchoice and chain are all initialized to start from the anchor.
+// The sink, if not nil, receives the forkchoice nodes that get pruned.
+func Bootstrap(ctx context.Context, spec *common.Spec, stateData []byte, blockData []byte,
+	ws common.Checkpoint, now common.Timestamp, sink proto.NodeSink) (*Anchor, error) {
+	state, err := beacon.DecodeBeaconState(spec, stateData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode state: %w", err)
+	}
+	version, err := beacon.StateForkName(state)
+	if err != nil {
+		return nil, err
+	}
+	block, err := beacon.DecodeSignedBeaconBlock(spec, blockData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode block: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	a := &Anchor{Version: version, State: state, Block: block}
+	if err := a.verify(spec, ws); err != nil {
+		return nil, err
+	}
+
+	a.EpochsContext, err = common.NewEpochsContext(spec, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize epochs context: %w", err)
+	}
+	switch version {
+	case common.Electra:
+		a.WeakSubjectivityPeriod = electra.ComputeWeakSubjectivityPeriod(spec, a.EpochsContext)
+	default:
+		a.WeakSubjectivityPeriod = phase0.ComputeWeakSubjectivityPeriod(spec, a.EpochsContext)
+	}
+	genesisTime, err := state.GenesisTime()
+	if err != nil {
+		return nil, err
+	}
+	currentEpoch := spec.SlotToEpoch(spec.TimeToSlot(now, genesisTime))
+	if currentEpoch > a.Checkpoint.Epoch+a.WeakSubjectivityPeriod {
+		return nil, fmt.Errorf("checkpoint %s is outside of the weak subjectivity period of %d epochs, current epoch is %d",
+			&a.Checkpoint, a.WeakSubjectivityPeriod, currentEpoch)
+	}
+
+	a.Chain, err = NewAnchorChain(spec, a.Checkpoint, a.Block, a.State, a.EpochsContext)
+	if err != nil {
+		return nil, err
+	}
+	entry := a.Chain.anchor
+	parentRoot, _ := entry.ParentRoot()
+	a.ForkChoice, err = proto.NewProtoForkChoice(spec, a.Checkpoint, a.Checkpoint,
+		a.BlockRoot, entry.Step().Slot(), parentRoot, justifiedBalances(a.EpochsContext), sink)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize forkchoice: %w", err)
+	}
+	return a, nil
+}
+
+// verify checks that the state is the post-state of the block, and that the block and state match the checkpoint.
+func (a *Anchor) verify(spec *common.Spec, ws common.Checkpoint) error {
+	a.StateRoot = a.State.HashTreeRoot(tree.GetHashFn())
+	env := a.Block.Envelope(spec, common.ForkDigest{})
+	a.BlockRoot = env.BlockRoot
+	slot, err := a.State.Slot()
+	if err != nil {
+		return err
+	}
+	if env.Slot > slot {
+		return fmt.Errorf("block slot %d is after state slot %d", env.Slot, slot)
+	}
+	if env.Slot == slot && env.StateRoot != a.StateRoot {
+		return fmt.Errorf("block state root %s does not match state root %s", env.StateRoot, a.StateRoot)
+	}
+	// The state root in the latest header is only filled in by the next slot processing.
+	header, err := a.State.LatestBlockHeader()
+	if err != nil {
+		return err
+	}
+	if header.StateRoot == (common.Root{}) {
+		header.StateRoot = a.StateRoot
+	}
+	if headerRoot := header.HashTreeRoot(tree.GetHashFn()); headerRoot != a.BlockRoot {
+		return fmt.Errorf("latest block header %s of state does not match block %s", headerRoot, a.BlockRoot)
+	}
+	a.Checkpoint = common.Checkpoint{Epoch: spec.SlotToEpoch(slot), Root: a.BlockRoot}
+	if a.Checkpoint != ws {
+		return fmt.Errorf("state and block checkpoint %s does not match weak subjectivity checkpoint %s", &a.Checkpoint, &ws)
+	}
+	return nil
+}
+
+// justifiedBalances returns the effective balances of the active validators, and zero for all others.
+func justifiedBalances(epc *common.EpochsContext) []common.Gwei {
+	balances := make([]common.Gwei, len(epc.EffectiveBalances))
+	for _, i := range epc.CurrentEpoch.ActiveIndices {
+		balances[i] = epc.EffectiveBalances[i]
+	}
+	return balances
+}
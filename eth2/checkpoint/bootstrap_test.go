@@ -0,0 +1,164 @@
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/interop"
+)
+
+var spec = configs.Mainnet
+
+func encodeSSZ(t *testing.T, obj interface {
+	Serialize(w *codec.EncodingWriter) error
+}) []byte {
+	var buf bytes.Buffer
+	if err := obj.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// checkpointAnchor creates a finalized state at the last slot of epoch 2, and the latest block of it, at blockSlot.
+func checkpointAnchor(t *testing.T, blockSlot common.Slot) (stateData []byte, blockData []byte, ws common.Checkpoint, genesisTime common.Timestamp) {
+	keys, err := interop.NewKeys(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _, err := phase0.KickStartState(spec, common.Root{123}, 1564000000, keys.KickstartValidators(spec.MAX_EFFECTIVE_BALANCE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot := spec.SLOTS_PER_EPOCH*3 - 1
+	if err := state.SetSlot(slot); err != nil {
+		t.Fatal(err)
+	}
+	block := phase0.SignedBeaconBlock{
+		Message: phase0.BeaconBlock{Slot: blockSlot, ProposerIndex: 42, ParentRoot: common.Root{0x01}},
+	}
+	header := block.Message.Header(spec)
+	if blockSlot < slot {
+		// the slot processing after the block already filled in the state root of the block
+		header.StateRoot = common.Root{0x02}
+	}
+	if err := state.SetLatestBlockHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	if blockSlot < slot {
+		block.Message.StateRoot = header.StateRoot
+	} else {
+		block.Message.StateRoot = state.HashTreeRoot(tree.GetHashFn())
+	}
+	genesisTime, err = state.GenesisTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws = common.Checkpoint{Epoch: spec.SlotToEpoch(slot), Root: block.Message.HashTreeRoot(spec, tree.GetHashFn())}
+	return encodeSSZ(t, state), encodeSSZ(t, spec.Wrap(&block)), ws, genesisTime
+}
+
+func TestCheckpointBootstrap(t *testing.T) {
+	slot := spec.SLOTS_PER_EPOCH*3 - 1
+	stateData, blockData, ws, genesisTime := checkpointAnchor(t, slot)
+	now, err := spec.TimeAtSlot(slot+5, genesisTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchor, err := Bootstrap(context.Background(), spec, stateData, blockData, ws, now, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anchor.Version != common.Phase0 || anchor.BlockRoot != ws.Root || anchor.Checkpoint != ws {
+		t.Fatalf("unexpected anchor: %s %s %s", anchor.Version, anchor.BlockRoot, &anchor.Checkpoint)
+	}
+	// 1000 validators of 32 ETH: 256 epochs of withdrawability delay, and 12 epochs of validator set churn
+	if anchor.WeakSubjectivityPeriod != 268 {
+		t.Fatalf("unexpected weak subjectivity period: %d", anchor.WeakSubjectivityPeriod)
+	}
+	head, err := anchor.ForkChoice.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Root != ws.Root || head.Slot != slot {
+		t.Fatalf("unexpected forkchoice head: %s", head)
+	}
+	entry, ok := anchor.Chain.ByBlock(ws.Root)
+	if !ok || entry.Step() != common.AsStep(slot, true) {
+		t.Fatal("expected anchor block in chain")
+	}
+	if root, _ := entry.StateRoot(); root != anchor.StateRoot {
+		t.Fatalf("unexpected anchor state root: %s", root)
+	}
+	if fin := anchor.Chain.FinalizedCheckpoint(); fin != ws {
+		t.Fatalf("unexpected finalized checkpoint: %s", &fin)
+	}
+
+	// empty slots on top of the anchor, across the epoch boundary
+	next, err := anchor.Chain.Towards(context.Background(), ws.Root, slot+2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextState, err := next.State(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nextSlot, _ := nextState.Slot(); nextSlot != slot+2 || next.Step().Block() {
+		t.Fatalf("unexpected entry after anchor: %s", next.Step())
+	}
+	if anchorState, _ := entry.State(context.Background()); anchorState.HashTreeRoot(tree.GetHashFn()) != anchor.StateRoot {
+		t.Fatal("anchor state was modified")
+	}
+}
+
+func TestCheckpointBootstrapEmptySlot(t *testing.T) {
+	slot := spec.SLOTS_PER_EPOCH*3 - 1
+	stateData, blockData, ws, genesisTime := checkpointAnchor(t, slot-1)
+	anchor, err := Bootstrap(context.Background(), spec, stateData, blockData, ws, genesisTime, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := anchor.Chain.ByBlock(ws.Root); ok {
+		t.Fatal("post-block state of the anchor block is not known")
+	}
+	head, err := anchor.Chain.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent, _ := head.ParentRoot(); head.Step() != common.AsStep(slot, false) || parent != ws.Root {
+		t.Fatalf("unexpected anchor entry: %s", head.Step())
+	}
+}
+
+func TestCheckpointBootstrapInvalid(t *testing.T) {
+	slot := spec.SLOTS_PER_EPOCH*3 - 1
+	stateData, blockData, ws, genesisTime := checkpointAnchor(t, slot)
+	ctx := context.Background()
+
+	if _, err := Bootstrap(ctx, spec, stateData, blockData, common.Checkpoint{Epoch: ws.Epoch, Root: common.Root{0x03}}, genesisTime, nil); err == nil {
+		t.Fatal("expected error on checkpoint root mismatch")
+	}
+	if _, err := Bootstrap(ctx, spec, stateData, blockData, common.Checkpoint{Epoch: ws.Epoch + 1, Root: ws.Root}, genesisTime, nil); err == nil {
+		t.Fatal("expected error on checkpoint epoch mismatch")
+	}
+	otherStateData, _, _, _ := checkpointAnchor(t, slot-1)
+	if _, err := Bootstrap(ctx, spec, otherStateData, blockData, ws, genesisTime, nil); err == nil {
+		t.Fatal("expected error on state of other block")
+	}
+	late, err := spec.TimeAtSlot(spec.SLOTS_PER_EPOCH*(3+268), genesisTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Bootstrap(ctx, spec, stateData, blockData, ws, late, nil); err == nil {
+		t.Fatal("expected error outside of weak subjectivity period")
+	}
+	if _, err := Bootstrap(ctx, spec, stateData[:40], blockData, ws, genesisTime, nil); err == nil {
+		t.Fatal("expected error on truncated state")
+	}
+}
@@ -0,0 +1,227 @@
+package checkpoint
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+type anchorEntry struct {
+	step       common.Step
+	blockRoot  common.Root
+	parentRoot common.Root
+	stateRoot  common.Root
+	epc        *common.EpochsContext
+	state      common.BeaconState
+}
+
+var _ beacon.ChainEntry = (*anchorEntry)(nil)
+
+func (e *anchorEntry) Step() common.Step {
+	return e.step
+}
+
+func (e *anchorEntry) BlockRoot() (common.Root, error) {
+	return e.blockRoot, nil
+}
+
+func (e *anchorEntry) ParentRoot() (common.Root, error) {
+	return e.parentRoot, nil
+}
+
+func (e *anchorEntry) StateRoot() (common.Root, error) {
+	return e.stateRoot, nil
+}
+
+func (e *anchorEntry) EpochsContext(ctx context.Context) (*common.EpochsContext, error) {
+	return e.epc.Clone(), nil
+}
+
+func (e *anchorEntry) State(ctx context.Context) (common.BeaconState, error) {
+	return e.state.CopyState()
+}
+
+// AnchorChain is a chain of just the anchor, the finalized starting point of checkpoint sync.
+// Empty slots on top of the anchor can be computed with Towards.
+type AnchorChain struct {
+	spec       *common.Spec
+	checkpoint common.Checkpoint
+	genesis    beacon.GenesisInfo
+	anchor     *anchorEntry
+}
+
+var _ beacon.Chain = (*AnchorChain)(nil)
+
+// NewAnchorChain creates a chain that starts at the given state, the post-state of the given block,
+// optionally with empty slots after the block. The chain is justified and finalized at the given checkpoint.
+func NewAnchorChain(spec *common.Spec, checkpoint common.Checkpoint, block beacon.OpaqueBlock,
+	state common.BeaconState, epc *common.EpochsContext) (*AnchorChain, error) {
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	genesisTime, err := state.GenesisTime()
+	if err != nil {
+		return nil, err
+	}
+	genesisValRoot, err := state.GenesisValidatorsRoot()
+	if err != nil {
+		return nil, err
+	}
+	env := block.Envelope(spec, common.ForkDigest{})
+	if env.Slot > slot {
+		return nil, fmt.Errorf("block slot %d is after state slot %d", env.Slot, slot)
+	}
+	entry := &anchorEntry{
+		step:      common.AsStep(slot, env.Slot == slot),
+		blockRoot: env.BlockRoot,
+		stateRoot: state.HashTreeRoot(tree.GetHashFn()),
+		epc:       epc,
+		state:     state,
+	}
+	if entry.step.Block() {
+		entry.parentRoot = env.ParentRoot
+	} else {
+		entry.parentRoot = env.BlockRoot
+	}
+	return &AnchorChain{
+		spec:       spec,
+		checkpoint: checkpoint,
+		genesis:    beacon.GenesisInfo{Time: genesisTime, ValidatorsRoot: genesisValRoot},
+		anchor:     entry,
+	}, nil
+}
+
+func (c *AnchorChain) ByStateRoot(root common.Root) (entry beacon.ChainEntry, ok bool) {
+	if root != c.anchor.stateRoot {
+		return nil, false
+	}
+	return c.anchor, true
+}
+
+func (c *AnchorChain) ByBlock(root common.Root) (entry beacon.ChainEntry, ok bool) {
+	// the post-block state is only known if the anchor is not an empty slot
+	if root != c.anchor.blockRoot || !c.anchor.step.Block() {
+		return nil, false
+	}
+	return c.anchor, true
+}
+
+func (c *AnchorChain) ByBlockSlot(root common.Root, slot common.Slot) (entry beacon.ChainEntry, ok bool) {
+	if root != c.anchor.blockRoot || slot != c.anchor.step.Slot() {
+		return nil, false
+	}
+	return c.anchor, true
+}
+
+func (c *AnchorChain) Search(parentRoot *common.Root, slot *common.Slot) ([]beacon.SearchEntry, error) {
+	if parentRoot != nil && *parentRoot != c.anchor.parentRoot {
+		return nil, nil
+	}
+	if slot != nil && *slot != c.anchor.step.Slot() {
+		return nil, nil
+	}
+	return []beacon.SearchEntry{{ChainEntry: c.anchor, Canonical: true}}, nil
+}
+
+func (c *AnchorChain) Closest(fromBlockRoot common.Root, toSlot common.Slot) (entry beacon.ChainEntry, ok bool) {
+	if fromBlockRoot != c.anchor.blockRoot || toSlot < c.anchor.step.Slot() {
+		return nil, false
+	}
+	return c.anchor, true
+}
+
+func (c *AnchorChain) InSubtree(anchor common.Root, root common.Root) (unknown bool, inSubtree bool) {
+	if anchor != c.anchor.blockRoot || root != c.anchor.blockRoot {
+		return true, false
+	}
+	return false, true
+}
+
+func (c *AnchorChain) ByCanonStep(step common.Step) (entry beacon.ChainEntry, ok bool) {
+	if step != c.anchor.step {
+		return nil, false
+	}
+	return c.anchor, true
+}
+
+func (c *AnchorChain) Iter() (beacon.ChainIter, error) {
+	return anchorIter{c.anchor}, nil
+}
+
+func (c *AnchorChain) JustifiedCheckpoint() common.Checkpoint {
+	return c.checkpoint
+}
+
+func (c *AnchorChain) FinalizedCheckpoint() common.Checkpoint {
+	return c.checkpoint
+}
+
+func (c *AnchorChain) Justified() (beacon.ChainEntry, error) {
+	return c.anchor, nil
+}
+
+func (c *AnchorChain) Finalized() (beacon.ChainEntry, error) {
+	return c.anchor, nil
+}
+
+func (c *AnchorChain) Head() (beacon.ChainEntry, error) {
+	return c.anchor, nil
+}
+
+func (c *AnchorChain) Towards(ctx context.Context, fromBlockRoot common.Root, toSlot common.Slot) (beacon.ChainEntry, error) {
+	if fromBlockRoot != c.anchor.blockRoot {
+		return nil, fmt.Errorf("unknown block root: %s", fromBlockRoot)
+	}
+	slot := c.anchor.step.Slot()
+	if toSlot < slot {
+		return nil, fmt.Errorf("cannot transition anchor at slot %d back to slot %d", slot, toSlot)
+	}
+	if toSlot == slot {
+		return c.anchor, nil
+	}
+	state, err := c.anchor.state.CopyState()
+	if err != nil {
+		return nil, err
+	}
+	epc := c.anchor.epc.Clone()
+	upgradeable := &beacon.StandardUpgradeableBeaconState{BeaconState: state}
+	if err := common.ProcessSlots(ctx, c.spec, epc, upgradeable, toSlot); err != nil {
+		return nil, err
+	}
+	return &anchorEntry{
+		step:       common.AsStep(toSlot, false),
+		blockRoot:  c.anchor.blockRoot,
+		parentRoot: c.anchor.blockRoot,
+		stateRoot:  upgradeable.HashTreeRoot(tree.GetHashFn()),
+		epc:        epc,
+		state:      upgradeable.BeaconState,
+	}, nil
+}
+
+func (c *AnchorChain) Genesis() beacon.GenesisInfo {
+	return c.genesis
+}
+
+type anchorIter struct {
+	anchor *anchorEntry
+}
+
+func (it anchorIter) Start() common.Step {
+	return it.anchor.step
+}
+
+func (it anchorIter) End() common.Step {
+	return it.anchor.step + 1
+}
+
+func (it anchorIter) Entry(step common.Step) (entry beacon.ChainEntry, err error) {
+	if step != it.anchor.step {
+		return nil, fmt.Errorf("step %s is out of bounds, chain only has anchor at %s", step, it.anchor.step)
+	}
+	return it.anchor, nil
+}
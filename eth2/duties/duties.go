@@ -0,0 +1,283 @@
+package duties
+
+import (
+	"fmt"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+type AttesterDuty struct {
+	Pubkey         common.BLSPubkey      `json:"pubkey" yaml:"pubkey"`
+	ValidatorIndex common.ValidatorIndex `json:"validator_index" yaml:"validator_index"`
+	Slot           common.Slot           `json:"slot" yaml:"slot"`
+	CommitteeIndex common.CommitteeIndex `json:"committee_index" yaml:"committee_index"`
+	// CommitteeLength is the number of validators in the committee
+	CommitteeLength uint64 `json:"committee_length" yaml:"committee_length"`
+	// CommitteesAtSlot is the number of committees in the slot
+	CommitteesAtSlot uint64 `json:"committees_at_slot" yaml:"committees_at_slot"`
+	// ValidatorCommitteeIndex is the position of the validator in the committee
+	ValidatorCommitteeIndex uint64 `json:"validator_committee_index" yaml:"validator_committee_index"`
+}
+
+type AttesterDuties struct {
+	Epoch common.Epoch `json:"epoch" yaml:"epoch"`
+	// DependentRoot is the block root at the end of the epoch before the previous epoch.
+	// The duties change if and only if the dependent root changes.
+	DependentRoot common.Root    `json:"dependent_root" yaml:"dependent_root"`
+	Duties        []AttesterDuty `json:"duties" yaml:"duties"`
+}
+
+type ProposerDuty struct {
+	Pubkey         common.BLSPubkey      `json:"pubkey" yaml:"pubkey"`
+	ValidatorIndex common.ValidatorIndex `json:"validator_index" yaml:"validator_index"`
+	Slot           common.Slot           `json:"slot" yaml:"slot"`
+}
+
+type ProposerDuties struct {
+	Epoch common.Epoch `json:"epoch" yaml:"epoch"`
+	// DependentRoot is the block root at the end of the previous epoch.
+	// The duties change if and only if the dependent root changes.
+	DependentRoot common.Root    `json:"dependent_root" yaml:"dependent_root"`
+	Duties        []ProposerDuty `json:"duties" yaml:"duties"`
+}
+
+type SyncCommitteeDuty struct {
+	Pubkey         common.BLSPubkey      `json:"pubkey" yaml:"pubkey"`
+	ValidatorIndex common.ValidatorIndex `json:"validator_index" yaml:"validator_index"`
+	// ValidatorSyncCommitteeIndices are the positions of the validator in the sync committee,
+	// a validator may be in the committee multiple times.
+	ValidatorSyncCommitteeIndices []uint64 `json:"validator_sync_committee_indices" yaml:"validator_sync_committee_indices"`
+}
+
+type SyncCommitteeDuties struct {
+	Period uint64 `json:"period" yaml:"period"`
+	// StartEpoch is the first epoch of the sync committee period, EndEpoch is the first epoch after it.
+	StartEpoch common.Epoch        `json:"start_epoch" yaml:"start_epoch"`
+	EndEpoch   common.Epoch        `json:"end_epoch" yaml:"end_epoch"`
+	Duties     []SyncCommitteeDuty `json:"duties" yaml:"duties"`
+}
+
+// Duties is the full set of duties of a group of validators, with lookahead.
+type Duties struct {
+	CurrentAttester *AttesterDuties
+	NextAttester    *AttesterDuties
+	Proposer        *ProposerDuties
+	// nil before altair
+	CurrentSyncCommittee *SyncCommitteeDuties
+	NextSyncCommittee    *SyncCommitteeDuties
+}
+
+// ValidatorIndices resolves the validator indices of the given pubkeys.
+func ValidatorIndices(pc *common.PubkeyCache, pubkeys []common.BLSPubkey) ([]common.ValidatorIndex, error) {
+	out := make([]common.ValidatorIndex, 0, len(pubkeys))
+	for _, pub := range pubkeys {
+		index, ok := pc.ValidatorIndex(pub)
+		if !ok {
+			return nil, fmt.Errorf("unknown validator pubkey: %s", pub)
+		}
+		out = append(out, index)
+	}
+	return out, nil
+}
+
+func pubkey(epc *common.EpochsContext, index common.ValidatorIndex) (common.BLSPubkey, error) {
+	pub, ok := epc.ValidatorPubkeyCache.Pubkey(index)
+	if !ok {
+		return common.BLSPubkey{}, fmt.Errorf("unknown validator index: %d", index)
+	}
+	return pub.Compressed, nil
+}
+
+// blockRootBefore returns the root of the latest block before the given slot,
+// or the genesis block root if the slot is 0.
+func blockRootBefore(spec *common.Spec, state common.BeaconState, slot common.Slot) (common.Root, error) {
+	stateSlot, err := state.Slot()
+	if err != nil {
+		return common.Root{}, err
+	}
+	if slot > stateSlot {
+		return common.Root{}, fmt.Errorf("slot %d is after state slot %d, block root is not known yet", slot, stateSlot)
+	}
+	if slot == 0 {
+		if stateSlot > 0 {
+			return common.GetBlockRootAtSlot(spec, state, 0)
+		}
+		// genesis state, the header does not have the state root yet
+		header, err := state.LatestBlockHeader()
+		if err != nil {
+			return common.Root{}, err
+		}
+		if header.StateRoot == (common.Root{}) {
+			header.StateRoot = state.HashTreeRoot(tree.GetHashFn())
+		}
+		return header.HashTreeRoot(tree.GetHashFn()), nil
+	}
+	if stateSlot-(slot-1) > spec.SLOTS_PER_HISTORICAL_ROOT {
+		return common.Root{}, fmt.Errorf("slot %d is too old for state at slot %d", slot, stateSlot)
+	}
+	return common.GetBlockRootAtSlot(spec, state, slot-1)
+}
+
+// GetAttesterDuties computes the attester duties of the given validators for the current or next epoch of the context.
+// The state must be at the current epoch of the context, and is used for the dependent root.
+func GetAttesterDuties(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState,
+	epoch common.Epoch, indices []common.ValidatorIndex) (*AttesterDuties, error) {
+	if epoch != epc.CurrentEpoch.Epoch && epoch != epc.NextEpoch.Epoch {
+		return nil, fmt.Errorf("attester duties are only available for epochs %d and %d, not %d",
+			epc.CurrentEpoch.Epoch, epc.NextEpoch.Epoch, epoch)
+	}
+	var dependentSlot common.Slot
+	if epoch > 0 {
+		var err error
+		if dependentSlot, err = spec.EpochStartSlot(epoch - 1); err != nil {
+			return nil, err
+		}
+	}
+	dependentRoot, err := blockRootBefore(spec, state, dependentSlot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependent root: %w", err)
+	}
+	wanted := make(map[common.ValidatorIndex]struct{}, len(indices))
+	for _, index := range indices {
+		wanted[index] = struct{}{}
+	}
+	committeesPerSlot, err := epc.GetCommitteeCountPerSlot(epoch)
+	if err != nil {
+		return nil, err
+	}
+	startSlot, err := spec.EpochStartSlot(epoch)
+	if err != nil {
+		return nil, err
+	}
+	out := &AttesterDuties{Epoch: epoch, DependentRoot: dependentRoot, Duties: make([]AttesterDuty, 0, len(indices))}
+	for slot := startSlot; slot < startSlot+spec.SLOTS_PER_EPOCH; slot++ {
+		for i := uint64(0); i < committeesPerSlot; i++ {
+			committee, err := epc.GetBeaconCommittee(slot, common.CommitteeIndex(i))
+			if err != nil {
+				return nil, err
+			}
+			for position, index := range committee {
+				if _, ok := wanted[index]; !ok {
+					continue
+				}
+				pub, err := pubkey(epc, index)
+				if err != nil {
+					return nil, err
+				}
+				out.Duties = append(out.Duties, AttesterDuty{
+					Pubkey:                  pub,
+					ValidatorIndex:          index,
+					Slot:                    slot,
+					CommitteeIndex:          common.CommitteeIndex(i),
+					CommitteeLength:         uint64(len(committee)),
+					CommitteesAtSlot:        committeesPerSlot,
+					ValidatorCommitteeIndex: uint64(position),
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+// GetProposerDuties computes the proposers of all slots in the current epoch of the context.
+// The state must be at the current epoch of the context, and is used for the dependent root.
+func GetProposerDuties(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState) (*ProposerDuties, error) {
+	epoch := epc.Proposers.Epoch
+	startSlot, err := spec.EpochStartSlot(epoch)
+	if err != nil {
+		return nil, err
+	}
+	dependentRoot, err := blockRootBefore(spec, state, startSlot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependent root: %w", err)
+	}
+	out := &ProposerDuties{Epoch: epoch, DependentRoot: dependentRoot, Duties: make([]ProposerDuty, 0, spec.SLOTS_PER_EPOCH)}
+	for slot := startSlot; slot < startSlot+spec.SLOTS_PER_EPOCH; slot++ {
+		index, err := epc.GetBeaconProposer(slot)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := pubkey(epc, index)
+		if err != nil {
+			return nil, err
+		}
+		out.Duties = append(out.Duties, ProposerDuty{Pubkey: pub, ValidatorIndex: index, Slot: slot})
+	}
+	return out, nil
+}
+
+// GetSyncCommitteeDuties computes the sync committee duties of the given validators,
+// for the sync committee period of the given epoch, which must be the current or next period of the context.
+func GetSyncCommitteeDuties(spec *common.Spec, epc *common.EpochsContext,
+	epoch common.Epoch, indices []common.ValidatorIndex) (*SyncCommitteeDuties, error) {
+	if epc.CurrentSyncCommittee == nil || epc.NextSyncCommittee == nil {
+		return nil, fmt.Errorf("no sync committees, pre-altair epochs context")
+	}
+	periodLen := spec.EPOCHS_PER_SYNC_COMMITTEE_PERIOD
+	currentPeriod := uint64(epc.CurrentEpoch.Epoch / periodLen)
+	period := uint64(epoch / periodLen)
+	var committee *common.IndexedSyncCommittee
+	switch period {
+	case currentPeriod:
+		committee = epc.CurrentSyncCommittee
+	case currentPeriod + 1:
+		committee = epc.NextSyncCommittee
+	default:
+		return nil, fmt.Errorf("sync committee duties are only available for periods %d and %d, not %d",
+			currentPeriod, currentPeriod+1, period)
+	}
+	start := common.Epoch(period) * periodLen
+	out := &SyncCommitteeDuties{Period: period, StartEpoch: start, EndEpoch: start + periodLen}
+	positions := make(map[common.ValidatorIndex][]uint64, len(indices))
+	for _, index := range indices {
+		positions[index] = nil
+	}
+	for i, index := range committee.Indices {
+		if p, ok := positions[index]; ok {
+			positions[index] = append(p, uint64(i))
+		}
+	}
+	for _, index := range indices {
+		p := positions[index]
+		if len(p) == 0 {
+			continue
+		}
+		// only once per validator, even if requested multiple times
+		positions[index] = nil
+		pub, err := pubkey(epc, index)
+		if err != nil {
+			return nil, err
+		}
+		out.Duties = append(out.Duties, SyncCommitteeDuty{Pubkey: pub, ValidatorIndex: index, ValidatorSyncCommitteeIndices: p})
+	}
+	return out, nil
+}
+
+// GetDuties computes the attester duties for the current and next epoch, the proposer duties for the current epoch,
+// and the sync committee duties for the current and next period (after altair).
+func GetDuties(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, indices []common.ValidatorIndex) (*Duties, error) {
+	var out Duties
+	var err error
+	if out.CurrentAttester, err = GetAttesterDuties(spec, epc, state, epc.CurrentEpoch.Epoch, indices); err != nil {
+		return nil, fmt.Errorf("failed to compute current attester duties: %w", err)
+	}
+	if out.NextAttester, err = GetAttesterDuties(spec, epc, state, epc.NextEpoch.Epoch, indices); err != nil {
+		return nil, fmt.Errorf("failed to compute next attester duties: %w", err)
+	}
+	if out.Proposer, err = GetProposerDuties(spec, epc, state); err != nil {
+		return nil, fmt.Errorf("failed to compute proposer duties: %w", err)
+	}
+	if epc.CurrentSyncCommittee != nil && epc.NextSyncCommittee != nil {
+		current := epc.CurrentEpoch.Epoch
+		if out.CurrentSyncCommittee, err = GetSyncCommitteeDuties(spec, epc, current, indices); err != nil {
+			return nil, fmt.Errorf("failed to compute current sync committee duties: %w", err)
+		}
+		next := out.CurrentSyncCommittee.EndEpoch
+		if out.NextSyncCommittee, err = GetSyncCommitteeDuties(spec, epc, next, indices); err != nil {
+			return nil, fmt.Errorf("failed to compute next sync committee duties: %w", err)
+		}
+	}
+	return &out, nil
+}
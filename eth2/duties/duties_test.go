@@ -0,0 +1,137 @@
+package duties
+
+import (
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/interop"
+)
+
+func TestDuties(t *testing.T) {
+	spec := configs.Mainnet
+	keys, err := interop.NewKeys(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _, err := altair.KickStartState(spec, common.Root{123}, 1564000000, keys.KickstartValidators(spec.MAX_EFFECTIVE_BALANCE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.SetSlot(spec.SLOTS_PER_EPOCH*3 - 1); err != nil {
+		t.Fatal(err)
+	}
+	epc, err := common.NewEpochsContext(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for slot := common.Slot(0); slot < spec.SLOTS_PER_EPOCH*3-1; slot++ {
+		if err := common.SetRecentRoots(spec, state, slot, common.Root{byte(slot), 0xb1}, common.Root{byte(slot), 0x51}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	indices := make([]common.ValidatorIndex, 0, 1000)
+	for i := common.ValidatorIndex(0); i < 1000; i++ {
+		indices = append(indices, i)
+	}
+	d, err := GetDuties(spec, epc, state, indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// current epoch 2 depends on the last block of epoch 0, next epoch 3 on the last block of epoch 1
+	lastSlot := func(epoch common.Epoch) common.Root {
+		return common.Root{byte(spec.SLOTS_PER_EPOCH*common.Slot(epoch+1) - 1), 0xb1}
+	}
+	if d.CurrentAttester.Epoch != 2 || d.CurrentAttester.DependentRoot != lastSlot(0) {
+		t.Fatalf("unexpected current attester duties epoch %d, dependent root %s", d.CurrentAttester.Epoch, d.CurrentAttester.DependentRoot)
+	}
+	if d.NextAttester.Epoch != 3 || d.NextAttester.DependentRoot != lastSlot(1) {
+		t.Fatalf("unexpected next attester duties epoch %d, dependent root %s", d.NextAttester.Epoch, d.NextAttester.DependentRoot)
+	}
+	if d.Proposer.Epoch != 2 || d.Proposer.DependentRoot != lastSlot(1) {
+		t.Fatalf("unexpected proposer duties epoch %d, dependent root %s", d.Proposer.Epoch, d.Proposer.DependentRoot)
+	}
+
+	for _, attDuties := range []*AttesterDuties{d.CurrentAttester, d.NextAttester} {
+		// all validators are active, and attest once per epoch
+		if len(attDuties.Duties) != 1000 {
+			t.Fatalf("expected 1000 attester duties, got %d", len(attDuties.Duties))
+		}
+		seen := make(map[common.ValidatorIndex]bool)
+		for _, duty := range attDuties.Duties {
+			if seen[duty.ValidatorIndex] {
+				t.Fatalf("duplicate duty for validator %d", duty.ValidatorIndex)
+			}
+			seen[duty.ValidatorIndex] = true
+			if spec.SlotToEpoch(duty.Slot) != attDuties.Epoch {
+				t.Fatalf("duty slot %d outside of epoch %d", duty.Slot, attDuties.Epoch)
+			}
+			committee, err := epc.GetBeaconCommittee(duty.Slot, duty.CommitteeIndex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if uint64(len(committee)) != duty.CommitteeLength || committee[duty.ValidatorCommitteeIndex] != duty.ValidatorIndex {
+				t.Fatalf("duty does not match committee: %+v", duty)
+			}
+			if pub, _ := epc.ValidatorPubkeyCache.Pubkey(duty.ValidatorIndex); pub.Compressed != duty.Pubkey {
+				t.Fatalf("wrong pubkey for validator %d", duty.ValidatorIndex)
+			}
+		}
+	}
+
+	if len(d.Proposer.Duties) != int(spec.SLOTS_PER_EPOCH) {
+		t.Fatalf("expected a proposer per slot, got %d", len(d.Proposer.Duties))
+	}
+	for _, duty := range d.Proposer.Duties {
+		if proposer, _ := epc.GetBeaconProposer(duty.Slot); proposer != duty.ValidatorIndex {
+			t.Fatalf("slot %d: got proposer %d, expected %d", duty.Slot, duty.ValidatorIndex, proposer)
+		}
+	}
+
+	for _, c := range []struct {
+		syncDuties *SyncCommitteeDuties
+		committee  *common.IndexedSyncCommittee
+		start      common.Epoch
+	}{
+		{d.CurrentSyncCommittee, epc.CurrentSyncCommittee, 0},
+		{d.NextSyncCommittee, epc.NextSyncCommittee, spec.EPOCHS_PER_SYNC_COMMITTEE_PERIOD},
+	} {
+		if c.syncDuties.StartEpoch != c.start {
+			t.Fatalf("unexpected sync committee period start: %d", c.syncDuties.StartEpoch)
+		}
+		positions := 0
+		for _, duty := range c.syncDuties.Duties {
+			for _, i := range duty.ValidatorSyncCommitteeIndices {
+				if c.committee.Indices[i] != duty.ValidatorIndex {
+					t.Fatalf("validator %d is not at sync committee position %d", duty.ValidatorIndex, i)
+				}
+				positions++
+			}
+		}
+		if positions != len(c.committee.Indices) {
+			t.Fatalf("expected %d sync committee positions, got %d", len(c.committee.Indices), positions)
+		}
+	}
+
+	// a subset of validators, by pubkey
+	pub, _ := epc.ValidatorPubkeyCache.Pubkey(42)
+	subset, err := ValidatorIndices(epc.ValidatorPubkeyCache, []common.BLSPubkey{pub.Compressed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attDuties, err := GetAttesterDuties(spec, epc, state, 3, subset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attDuties.Duties) != 1 || attDuties.Duties[0].ValidatorIndex != 42 {
+		t.Fatalf("unexpected duties for validator 42: %+v", attDuties.Duties)
+	}
+	if _, err := GetAttesterDuties(spec, epc, state, 4, subset); err == nil {
+		t.Fatal("expected error for epoch beyond lookahead")
+	}
+	if _, err := ValidatorIndices(epc.ValidatorPubkeyCache, []common.BLSPubkey{{0xff}}); err == nil {
+		t.Fatal("expected error for unknown pubkey")
+	}
+}
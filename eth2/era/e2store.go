@@ -0,0 +1,143 @@
+package era
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EntryType is the 2-byte type of an e2store entry.
+type EntryType [2]byte
+
+var (
+	TypeEmpty                       = EntryType{0x00, 0x00}
+	TypeCompressedSignedBeaconBlock = EntryType{0x01, 0x00}
+	TypeCompressedBeaconState       = EntryType{0x02, 0x00}
+	TypeVersion                     = EntryType{0x65, 0x32}
+	TypeSlotIndex                   = EntryType{0x69, 0x32}
+)
+
+func (t EntryType) String() string {
+	return fmt.Sprintf("0x%02x%02x", t[0], t[1])
+}
+
+// HeaderSize is the size of the header of every e2store entry:
+// the type (2 bytes), the little-endian data length (4 bytes) and reserved zero bytes (2 bytes).
+const HeaderSize = 8
+
+// MaxEntrySize limits the data of an entry that is read, to not allocate arbitrary amounts of memory.
+const MaxEntrySize = 1 << 31
+
+// WriteEntry writes an e2store entry, and returns the number of bytes written.
+func WriteEntry(w io.Writer, typ EntryType, data []byte) (int64, error) {
+	if uint64(len(data)) > MaxEntrySize {
+		return 0, fmt.Errorf("entry data too large: %d bytes", len(data))
+	}
+	var header [HeaderSize]byte
+	copy(header[:2], typ[:])
+	binary.LittleEndian.PutUint32(header[2:6], uint32(len(data)))
+	n, err := w.Write(header[:])
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := w.Write(data)
+	return int64(n + m), err
+}
+
+// ReadEntryHeader reads the type and data length of an e2store entry.
+func ReadEntryHeader(r io.Reader) (typ EntryType, length uint32, err error) {
+	var header [HeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return EntryType{}, 0, err
+	}
+	copy(typ[:], header[:2])
+	if header[6] != 0 || header[7] != 0 {
+		return typ, 0, fmt.Errorf("entry %s has non-zero reserved header bytes", typ)
+	}
+	return typ, binary.LittleEndian.Uint32(header[2:6]), nil
+}
+
+// ReadEntry reads a complete e2store entry. io.EOF is returned if there are no more entries.
+func ReadEntry(r io.Reader) (typ EntryType, data []byte, err error) {
+	typ, length, err := ReadEntryHeader(r)
+	if err != nil {
+		return typ, nil, err
+	}
+	if length > MaxEntrySize {
+		return typ, nil, fmt.Errorf("entry %s too large: %d bytes", typ, length)
+	}
+	data = make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return typ, nil, fmt.Errorf("failed to read entry %s data: %w", typ, err)
+	}
+	return typ, data, nil
+}
+
+// SlotIndex maps slots to the offsets of their entries in an e2store file.
+// Offsets are relative to the start of the slot index entry itself, zero if there is no entry for the slot.
+type SlotIndex struct {
+	StartSlot uint64
+	Offsets   []int64
+}
+
+// MarshalEntry encodes the slot index as data of a TypeSlotIndex entry:
+// the start slot, the offsets, and the number of offsets, all 8 bytes little-endian.
+func (x *SlotIndex) MarshalEntry() []byte {
+	out := make([]byte, 8+len(x.Offsets)*8+8)
+	binary.LittleEndian.PutUint64(out[:8], x.StartSlot)
+	for i, offset := range x.Offsets {
+		binary.LittleEndian.PutUint64(out[8+i*8:], uint64(offset))
+	}
+	binary.LittleEndian.PutUint64(out[len(out)-8:], uint64(len(x.Offsets)))
+	return out
+}
+
+// UnmarshalEntry decodes the data of a TypeSlotIndex entry.
+func (x *SlotIndex) UnmarshalEntry(data []byte) error {
+	if len(data) < 16 || len(data)%8 != 0 {
+		return fmt.Errorf("invalid slot index length: %d", len(data))
+	}
+	count := binary.LittleEndian.Uint64(data[len(data)-8:])
+	if count != uint64(len(data)-16)/8 {
+		return fmt.Errorf("slot index count %d does not match length %d", count, len(data))
+	}
+	x.StartSlot = binary.LittleEndian.Uint64(data[:8])
+	x.Offsets = make([]int64, count)
+	for i := range x.Offsets {
+		x.Offsets[i] = int64(binary.LittleEndian.Uint64(data[8+i*8:]))
+	}
+	return nil
+}
+
+// readSlotIndexBefore reads the slot index entry that ends at the given position,
+// and returns it together with the position of the start of the entry.
+func readSlotIndexBefore(r io.ReaderAt, end int64) (*SlotIndex, int64, error) {
+	if end < HeaderSize+16 {
+		return nil, 0, fmt.Errorf("no room for slot index before position %d", end)
+	}
+	var countData [8]byte
+	if _, err := r.ReadAt(countData[:], end-8); err != nil {
+		return nil, 0, fmt.Errorf("failed to read slot index count: %w", err)
+	}
+	count := binary.LittleEndian.Uint64(countData[:])
+	if count > uint64(end-HeaderSize-16)/8 {
+		return nil, 0, fmt.Errorf("slot index count %d does not fit before position %d", count, end)
+	}
+	start := end - HeaderSize - 16 - int64(count)*8
+	typ, data, err := ReadEntry(io.NewSectionReader(r, start, end-start))
+	if err != nil {
+		return nil, 0, err
+	}
+	if typ != TypeSlotIndex {
+		return nil, 0, fmt.Errorf("expected slot index entry at %d, got %s", start, typ)
+	}
+	var x SlotIndex
+	if err := x.UnmarshalEntry(data); err != nil {
+		return nil, 0, err
+	}
+	return &x, start, nil
+}
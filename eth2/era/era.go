@@ -0,0 +1,417 @@
+package era
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// Filename returns the standard name of an era file: the config name, the era number,
+// and the first 4 bytes of the historical root of the era (the genesis validators root for era 0).
+func Filename(configName string, era uint64, historicalRoot common.Root) string {
+	return fmt.Sprintf("%s-%05d-%x.era", configName, era, historicalRoot[:4])
+}
+
+// StartSlot returns the slot of the state of the era.
+// The blocks of the era are those of the SLOTS_PER_HISTORICAL_ROOT slots before it.
+func StartSlot(spec *common.Spec, era uint64) common.Slot {
+	return common.Slot(era) * spec.SLOTS_PER_HISTORICAL_ROOT
+}
+
+// Reader reads the blocks and the state of an era file.
+type Reader struct {
+	spec *common.Spec
+	r    io.ReaderAt
+	era  uint64
+	// positions of the entries of the blocks, zero if the slot has no block. Nil for era 0.
+	blockPositions []int64
+	statePosition  int64
+}
+
+// NewReader opens an era file of the given size, using the slot indices at the end of the file.
+func NewReader(spec *common.Spec, r io.ReaderAt, size int64) (*Reader, error) {
+	typ, length, err := ReadEntryHeader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version entry: %w", err)
+	}
+	if typ != TypeVersion || length != 0 {
+		return nil, fmt.Errorf("expected empty version entry, got %s with %d bytes", typ, length)
+	}
+	stateIndex, stateIndexPos, err := readSlotIndexBefore(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state index: %w", err)
+	}
+	if len(stateIndex.Offsets) != 1 {
+		return nil, fmt.Errorf("expected a single state in state index, got %d", len(stateIndex.Offsets))
+	}
+	sphr := uint64(spec.SLOTS_PER_HISTORICAL_ROOT)
+	if stateIndex.StartSlot%sphr != 0 {
+		return nil, fmt.Errorf("state index slot %d is not at an era boundary", stateIndex.StartSlot)
+	}
+	er := &Reader{
+		spec:          spec,
+		r:             r,
+		era:           stateIndex.StartSlot / sphr,
+		statePosition: stateIndexPos + stateIndex.Offsets[0],
+	}
+	if er.era == 0 {
+		return er, nil
+	}
+	blockIndex, blockIndexPos, err := readSlotIndexBefore(r, stateIndexPos)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block index: %w", err)
+	}
+	if blockIndex.StartSlot != stateIndex.StartSlot-sphr || uint64(len(blockIndex.Offsets)) != sphr {
+		return nil, fmt.Errorf("block index with %d slots from slot %d does not match era %d",
+			len(blockIndex.Offsets), blockIndex.StartSlot, er.era)
+	}
+	er.blockPositions = make([]int64, sphr)
+	for i, offset := range blockIndex.Offsets {
+		if offset != 0 {
+			er.blockPositions[i] = blockIndexPos + offset
+		}
+	}
+	return er, nil
+}
+
+// Era returns the number of the era.
+func (er *Reader) Era() uint64 {
+	return er.era
+}
+
+func (er *Reader) readCompressed(pos int64, expected EntryType) ([]byte, error) {
+	r := io.NewSectionReader(er.r, pos, 1<<62)
+	typ, length, err := ReadEntryHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if typ != expected {
+		return nil, fmt.Errorf("expected entry %s at %d, got %s", expected, pos, typ)
+	}
+	return io.ReadAll(snappy.NewReader(io.LimitReader(r, int64(length))))
+}
+
+// State reads the state at the start slot of the era.
+func (er *Reader) State() (common.BeaconState, error) {
+	data, err := er.readCompressed(er.statePosition, TypeCompressedBeaconState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	return beacon.DecodeBeaconState(er.spec, data)
+}
+
+// Block reads the block at the given slot, or returns nil if the slot is empty.
+// The slot must be within the SLOTS_PER_HISTORICAL_ROOT slots before the start slot of the era.
+func (er *Reader) Block(slot common.Slot) (beacon.OpaqueBlock, error) {
+	start := StartSlot(er.spec, er.era)
+	if er.era == 0 || slot >= start || slot < start-er.spec.SLOTS_PER_HISTORICAL_ROOT {
+		return nil, fmt.Errorf("slot %d is not within era %d", slot, er.era)
+	}
+	pos := er.blockPositions[slot%er.spec.SLOTS_PER_HISTORICAL_ROOT]
+	if pos == 0 {
+		return nil, nil
+	}
+	data, err := er.readCompressed(pos, TypeCompressedSignedBeaconBlock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block at slot %d: %w", slot, err)
+	}
+	if blockSlot, err := beacon.PeekBlockSlot(data); err != nil {
+		return nil, err
+	} else if blockSlot != slot {
+		return nil, fmt.Errorf("block index slot %d does not match block slot %d", slot, blockSlot)
+	}
+	return beacon.DecodeSignedBeaconBlock(er.spec, data)
+}
+
+// Blocks calls fn with every block of the era, in slot order.
+func (er *Reader) Blocks(fn func(slot common.Slot, block beacon.OpaqueBlock) error) error {
+	if er.era == 0 {
+		return nil
+	}
+	start := StartSlot(er.spec, er.era) - er.spec.SLOTS_PER_HISTORICAL_ROOT
+	for i, pos := range er.blockPositions {
+		if pos == 0 {
+			continue
+		}
+		slot := start + common.Slot(i)
+		block, err := er.Block(slot)
+		if err != nil {
+			return err
+		}
+		if err := fn(slot, block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify checks the blocks against the block roots of the state,
+// and the block roots against the historical root, or historical summary, of the era in the state.
+func (er *Reader) Verify() error {
+	state, err := er.State()
+	if err != nil {
+		return err
+	}
+	if slot, err := state.Slot(); err != nil {
+		return err
+	} else if slot != StartSlot(er.spec, er.era) {
+		return fmt.Errorf("state slot %d does not match era %d", slot, er.era)
+	}
+	if er.era == 0 {
+		return nil
+	}
+	blockRoots, err := state.BlockRoots()
+	if err != nil {
+		return err
+	}
+	if err := verifyHistoricalRoot(state, er.era, blockRoots); err != nil {
+		return err
+	}
+
+	start := StartSlot(er.spec, er.era) - er.spec.SLOTS_PER_HISTORICAL_ROOT
+	var prevRoot common.Root
+	for i, pos := range er.blockPositions {
+		slot := start + common.Slot(i)
+		expected, err := blockRoots.GetRoot(slot)
+		if err != nil {
+			return err
+		}
+		if pos == 0 {
+			// empty slots repeat the root of the previous block,
+			// which for the first slot is the last block of the previous era.
+			if i == 0 {
+				prevRoot = expected
+			} else if expected != prevRoot {
+				return fmt.Errorf("missing block %s at slot %d", expected, slot)
+			}
+			continue
+		}
+		block, err := er.Block(slot)
+		if err != nil {
+			return err
+		}
+		if root := block.Envelope(er.spec, common.ForkDigest{}).BlockRoot; root != expected {
+			return fmt.Errorf("block at slot %d has root %s, expected %s", slot, root, expected)
+		}
+		prevRoot = expected
+	}
+	return nil
+}
+
+func verifyHistoricalRoot(state common.BeaconState, era uint64, blockRoots common.BatchRoots) error {
+	hFn := tree.GetHashFn()
+	blockRootsRoot := blockRoots.HashTreeRoot(hFn)
+	histRoots, err := state.HistoricalRoots()
+	if err != nil {
+		return err
+	}
+	count, err := histRoots.Length()
+	if err != nil {
+		return err
+	}
+	// historical roots are frozen since Capella, later eras are summarized in historical summaries
+	if era <= count {
+		stateRoots, err := state.StateRoots()
+		if err != nil {
+			return err
+		}
+		expected, err := histRoots.Root(era - 1)
+		if err != nil {
+			return err
+		}
+		if root := tree.Hash(blockRootsRoot, stateRoots.HashTreeRoot(hFn)); root != expected {
+			return fmt.Errorf("historical batch root %s does not match historical root %s of era %d", root, expected, era)
+		}
+		return nil
+	}
+	summariesState, ok := state.(capella.HistoricalSummariesBeaconState)
+	if !ok {
+		return fmt.Errorf("state has %d historical roots, missing era %d", count, era)
+	}
+	summaries, err := summariesState.HistoricalSummaries()
+	if err != nil {
+		return err
+	}
+	summary, err := summaries.Summary(era - 1 - count)
+	if err != nil {
+		return fmt.Errorf("missing historical summary of era %d: %w", era, err)
+	}
+	if summary.BlockSummaryRoot != blockRootsRoot {
+		return fmt.Errorf("block roots %s do not match historical summary %s of era %d",
+			blockRootsRoot, summary.BlockSummaryRoot, era)
+	}
+	return nil
+}
+
+// Writer writes an era file: the version, the blocks in slot order, the state, and then the slot indices.
+type Writer struct {
+	spec *common.Spec
+	w    io.Writer
+	pos  int64
+	era  uint64
+	// positions of the entries of the blocks, zero if the slot has no block
+	blockPositions []int64
+	lastBlockSlot  common.Slot
+	hasBlock       bool
+	statePosition  int64
+}
+
+// NewWriter starts an era file by writing the version entry.
+func NewWriter(spec *common.Spec, w io.Writer, era uint64) (*Writer, error) {
+	ew := &Writer{spec: spec, w: w, era: era}
+	if era > 0 {
+		ew.blockPositions = make([]int64, spec.SLOTS_PER_HISTORICAL_ROOT)
+	}
+	if err := ew.writeEntry(TypeVersion, nil); err != nil {
+		return nil, err
+	}
+	return ew, nil
+}
+
+func (ew *Writer) writeEntry(typ EntryType, data []byte) error {
+	n, err := WriteEntry(ew.w, typ, data)
+	ew.pos += n
+	return err
+}
+
+func (ew *Writer) writeCompressed(typ EntryType, obj interface {
+	Serialize(w *codec.EncodingWriter) error
+}) error {
+	// the entry header needs the compressed length, the entry data is buffered first
+	var compressed bytes.Buffer
+	sw := snappy.NewBufferedWriter(&compressed)
+	if err := obj.Serialize(codec.NewEncodingWriter(sw)); err != nil {
+		return err
+	}
+	if err := sw.Close(); err != nil {
+		return err
+	}
+	return ew.writeEntry(typ, compressed.Bytes())
+}
+
+// WriteBlock appends a block. Blocks must be written in slot order, before the state.
+func (ew *Writer) WriteBlock(block beacon.OpaqueBlock) error {
+	if ew.statePosition != 0 {
+		return fmt.Errorf("cannot write block after state")
+	}
+	start := StartSlot(ew.spec, ew.era)
+	slot := block.Envelope(ew.spec, common.ForkDigest{}).Slot
+	if ew.era == 0 || slot >= start || slot < start-ew.spec.SLOTS_PER_HISTORICAL_ROOT {
+		return fmt.Errorf("block slot %d is not within era %d", slot, ew.era)
+	}
+	if ew.hasBlock && slot <= ew.lastBlockSlot {
+		return fmt.Errorf("block slot %d is not after previous block slot %d", slot, ew.lastBlockSlot)
+	}
+	pos := ew.pos
+	if err := ew.writeCompressed(TypeCompressedSignedBeaconBlock, ew.spec.Wrap(block)); err != nil {
+		return fmt.Errorf("failed to write block at slot %d: %w", slot, err)
+	}
+	ew.blockPositions[slot%ew.spec.SLOTS_PER_HISTORICAL_ROOT] = pos
+	ew.lastBlockSlot = slot
+	ew.hasBlock = true
+	return nil
+}
+
+// WriteState appends the state at the start slot of the era, after all blocks.
+func (ew *Writer) WriteState(state common.BeaconState) error {
+	if ew.statePosition != 0 {
+		return fmt.Errorf("state was already written")
+	}
+	if slot, err := state.Slot(); err != nil {
+		return err
+	} else if slot != StartSlot(ew.spec, ew.era) {
+		return fmt.Errorf("state slot %d does not match era %d", slot, ew.era)
+	}
+	pos := ew.pos
+	if err := ew.writeCompressed(TypeCompressedBeaconState, state); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	ew.statePosition = pos
+	return nil
+}
+
+// Finish writes the slot indices of the blocks (except for era 0) and the state, completing the era file.
+func (ew *Writer) Finish() error {
+	if ew.statePosition == 0 {
+		return fmt.Errorf("era %d has no state", ew.era)
+	}
+	start := uint64(StartSlot(ew.spec, ew.era))
+	if ew.era > 0 {
+		index := SlotIndex{
+			StartSlot: start - uint64(ew.spec.SLOTS_PER_HISTORICAL_ROOT),
+			Offsets:   make([]int64, len(ew.blockPositions)),
+		}
+		for i, pos := range ew.blockPositions {
+			if pos != 0 {
+				index.Offsets[i] = pos - ew.pos
+			}
+		}
+		if err := ew.writeEntry(TypeSlotIndex, index.MarshalEntry()); err != nil {
+			return err
+		}
+	}
+	index := SlotIndex{StartSlot: start, Offsets: []int64{ew.statePosition - ew.pos}}
+	return ew.writeEntry(TypeSlotIndex, index.MarshalEntry())
+}
+
+// BlockSource provides the full blocks of a chain, which only tracks block roots.
+type BlockSource interface {
+	Block(root common.Root) (beacon.OpaqueBlock, bool)
+}
+
+// WriteChainEra writes an era file of the canonical blocks and state of a chain,
+// which must have been finalized past the start slot of the era.
+func WriteChainEra(ctx context.Context, spec *common.Spec, chain beacon.Chain, blocks BlockSource, era uint64, w io.Writer) error {
+	start := StartSlot(spec, era)
+	ew, err := NewWriter(spec, w, era)
+	if err != nil {
+		return err
+	}
+	if era > 0 {
+		for slot := start - spec.SLOTS_PER_HISTORICAL_ROOT; slot < start; slot++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			entry, ok := chain.ByCanonStep(common.AsStep(slot, true))
+			if !ok || entry == nil {
+				continue
+			}
+			root, err := entry.BlockRoot()
+			if err != nil {
+				return err
+			}
+			block, ok := blocks.Block(root)
+			if !ok {
+				return fmt.Errorf("missing block %s at slot %d", root, slot)
+			}
+			if err := ew.WriteBlock(block); err != nil {
+				return err
+			}
+		}
+	}
+	// the state of the era includes the block at the start slot, if any
+	entry, ok := chain.ByCanonStep(common.AsStep(start, true))
+	if !ok || entry == nil {
+		entry, ok = chain.ByCanonStep(common.AsStep(start, false))
+		if !ok || entry == nil {
+			return fmt.Errorf("missing state at slot %d", start)
+		}
+	}
+	state, err := entry.State(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ew.WriteState(state); err != nil {
+		return err
+	}
+	return ew.Finish()
+}
@@ -0,0 +1,281 @@
+package era
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+type testEntry struct {
+	beacon.ChainEntry
+	blockRoot common.Root
+	state     common.BeaconState
+}
+
+func (e *testEntry) BlockRoot() (common.Root, error) {
+	return e.blockRoot, nil
+}
+
+func (e *testEntry) State(ctx context.Context) (common.BeaconState, error) {
+	return e.state, nil
+}
+
+// testChain is the canonical chain of a generated era, only implementing what is needed to export it.
+type testChain struct {
+	beacon.Chain
+	entries map[common.Step]*testEntry
+	blocks  map[common.Root]beacon.OpaqueBlock
+}
+
+func (c *testChain) ByCanonStep(step common.Step) (beacon.ChainEntry, bool) {
+	entry, ok := c.entries[step]
+	return entry, ok
+}
+
+func (c *testChain) Block(root common.Root) (beacon.OpaqueBlock, bool) {
+	block, ok := c.blocks[root]
+	return block, ok
+}
+
+// generateEra generates the blocks of the given era, with every 5th slot empty,
+// and the state at the start of the next era, with matching block and state roots and historical accumulators.
+// The state is of the given type, and has placeholder historical roots for the previous eras.
+func generateEra(t *testing.T, spec *common.Spec, stateType func(spec *common.Spec) common.BeaconState,
+	newBlock func(slot common.Slot, parent common.Root) beacon.OpaqueBlock, era uint64) *testChain {
+	return generateEraWithEmptySlots(t, spec, stateType, newBlock, era, func(slot common.Slot) bool {
+		return slot%5 == 0
+	})
+}
+
+// generateEraWithEmptySlots is like generateEra, but leaves the slots empty that are selected by the given function.
+func generateEraWithEmptySlots(t *testing.T, spec *common.Spec, stateType func(spec *common.Spec) common.BeaconState,
+	newBlock func(slot common.Slot, parent common.Root) beacon.OpaqueBlock, era uint64, empty func(slot common.Slot) bool) *testChain {
+	start := StartSlot(spec, era)
+	state := stateType(spec)
+	if err := state.SetSlot(start); err != nil {
+		t.Fatal(err)
+	}
+	chain := &testChain{entries: make(map[common.Step]*testEntry), blocks: make(map[common.Root]beacon.OpaqueBlock)}
+	histRoots, err := state.HistoricalRoots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockRoot := common.Root{0xaa}
+	if era > 0 {
+		for slot := start - spec.SLOTS_PER_HISTORICAL_ROOT; slot < start; slot++ {
+			if !empty(slot) {
+				block := newBlock(slot, blockRoot)
+				blockRoot = block.Envelope(spec, common.ForkDigest{}).BlockRoot
+				chain.blocks[blockRoot] = block
+				chain.entries[common.AsStep(slot, true)] = &testEntry{blockRoot: blockRoot}
+			}
+			if err := common.SetRecentRoots(spec, state, slot, blockRoot, common.Root{byte(slot), 0xbb}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// older eras are not verified, just the accumulator of this era
+		for i := uint64(1); i < era; i++ {
+			if err := histRoots.Append(common.Root{byte(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if summariesState, ok := state.(capella.HistoricalSummariesBeaconState); ok {
+			if err := capella.UpdateHistoricalSummaries(summariesState); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := common.UpdateHistoricalRoots(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chain.entries[common.AsStep(start, false)] = &testEntry{blockRoot: blockRoot, state: state}
+	return chain
+}
+
+func phase0State(spec *common.Spec) common.BeaconState {
+	return phase0.NewBeaconStateView(spec)
+}
+
+func phase0Block(slot common.Slot, parent common.Root) beacon.OpaqueBlock {
+	return &phase0.SignedBeaconBlock{
+		Message: phase0.BeaconBlock{Slot: slot, ProposerIndex: common.ValidatorIndex(slot), ParentRoot: parent},
+	}
+}
+
+func writeEra(t *testing.T, spec *common.Spec, chain *testChain, era uint64) []byte {
+	var buf bytes.Buffer
+	if err := WriteChainEra(context.Background(), spec, chain, chain, era, &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestEraRoundTrip(t *testing.T) {
+	spec := configs.Minimal
+	for _, era := range []uint64{0, 1, 3} {
+		chain := generateEra(t, spec, phase0State, phase0Block, era)
+		data := writeEra(t, spec, chain, era)
+		r, err := NewReader(spec, bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("era %d: %v", era, err)
+		}
+		if r.Era() != era {
+			t.Fatalf("expected era %d, got %d", era, r.Era())
+		}
+		if err := r.Verify(); err != nil {
+			t.Fatalf("era %d: %v", era, err)
+		}
+		count := 0
+		if err := r.Blocks(func(slot common.Slot, block beacon.OpaqueBlock) error {
+			count++
+			root := block.Envelope(spec, common.ForkDigest{}).BlockRoot
+			if entry, ok := chain.entries[common.AsStep(slot, true)]; !ok || entry.blockRoot != root {
+				t.Fatalf("era %d: unexpected block %s at slot %d", era, root, slot)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if count != len(chain.blocks) {
+			t.Fatalf("era %d: expected %d blocks, got %d", era, len(chain.blocks), count)
+		}
+		state, err := r.State()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := chain.entries[common.AsStep(StartSlot(spec, era), false)].state.HashTreeRoot(tree.GetHashFn())
+		if state.HashTreeRoot(tree.GetHashFn()) != expected {
+			t.Fatalf("era %d: state does not match", era)
+		}
+	}
+}
+
+func TestEraCapella(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	chain := generateEra(t, &spec, func(spec *common.Spec) common.BeaconState {
+		return capella.NewBeaconStateView(spec)
+	}, func(slot common.Slot, parent common.Root) beacon.OpaqueBlock {
+		// start from the default block, for the non-empty sync committee bits
+		var buf bytes.Buffer
+		if err := capella.SignedBeaconBlockType(&spec).New().Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+			t.Fatal(err)
+		}
+		var block capella.SignedBeaconBlock
+		if err := block.Deserialize(&spec, codec.NewDecodingReader(&buf, uint64(buf.Len()))); err != nil {
+			t.Fatal(err)
+		}
+		block.Message.Slot = slot
+		block.Message.ProposerIndex = common.ValidatorIndex(slot)
+		block.Message.ParentRoot = parent
+		return &block
+	}, 2)
+	data := writeEra(t, &spec, chain, 2)
+	r, err := NewReader(&spec, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	block, err := r.Block(StartSlot(&spec, 2) - 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := block.(*capella.SignedBeaconBlock); !ok {
+		t.Fatalf("expected capella block, got %T", block)
+	}
+	if block, err := r.Block(StartSlot(&spec, 2) - 3); err != nil || block != nil {
+		t.Fatalf("expected empty slot, got %v %v", block, err)
+	}
+}
+
+func TestEraVerifyEmptyStart(t *testing.T) {
+	spec := configs.Minimal
+	era := uint64(2)
+	first := StartSlot(spec, era) - spec.SLOTS_PER_HISTORICAL_ROOT
+	// the era starts with 2 empty slots, repeating the root of the last block of the previous era
+	chain := generateEraWithEmptySlots(t, spec, phase0State, phase0Block, era, func(slot common.Slot) bool {
+		return slot < first+2 || slot%5 == 0
+	})
+	data := writeEra(t, spec, chain, era)
+	r, err := NewReader(spec, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if block, err := r.Block(first + 1); block != nil || err != nil {
+		t.Fatalf("expected empty slot, got %v %v", block, err)
+	}
+}
+
+func TestEraVerifyInvalid(t *testing.T) {
+	spec := configs.Minimal
+	start := StartSlot(spec, 1)
+
+	// a missing block
+	chain := generateEra(t, spec, phase0State, phase0Block, 1)
+	delete(chain.entries, common.AsStep(start-2, true))
+	data := writeEra(t, spec, chain, 1)
+	r, err := NewReader(spec, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Verify(); err == nil || !strings.Contains(err.Error(), "missing block") {
+		t.Fatalf("expected missing block error, got %v", err)
+	}
+
+	// a different block
+	chain = generateEra(t, spec, phase0State, phase0Block, 1)
+	other := phase0Block(start-2, common.Root{0xcc})
+	otherRoot := other.Envelope(spec, common.ForkDigest{}).BlockRoot
+	chain.blocks[otherRoot] = other
+	chain.entries[common.AsStep(start-2, true)].blockRoot = otherRoot
+	data = writeEra(t, spec, chain, 1)
+	r, err = NewReader(spec, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Verify(); err == nil {
+		t.Fatal("expected block root mismatch")
+	}
+
+	// block roots that do not match the historical root
+	chain = generateEra(t, spec, phase0State, phase0Block, 1)
+	state := chain.entries[common.AsStep(start, false)].state
+	if err := common.SetRecentRoots(spec, state, start-1, common.Root{0xdd}, common.Root{0xdd}); err != nil {
+		t.Fatal(err)
+	}
+	data = writeEra(t, spec, chain, 1)
+	r, err = NewReader(spec, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Verify(); err == nil || !strings.Contains(err.Error(), "historical root") {
+		t.Fatalf("expected historical root error, got %v", err)
+	}
+
+	if _, err := NewReader(spec, bytes.NewReader(data[:len(data)-1]), int64(len(data)-1)); err == nil {
+		t.Fatal("expected error on truncated era file")
+	}
+}
+
+func TestEraFilename(t *testing.T) {
+	root := common.Root{0x4b, 0x36, 0x3d, 0xb9, 0x4e}
+	if name := Filename("mainnet", 1, root); name != "mainnet-00001-4b363db9.era" {
+		t.Fatalf("unexpected filename: %s", name)
+	}
+}
@@ -0,0 +1,182 @@
+package gossip
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/protolambda/ztyp/codec"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+type fork uint8
+
+const (
+	phase0Fork fork = iota
+	altairFork
+	bellatrixFork
+	capellaFork
+	denebFork
+	electraFork
+	fuluFork
+)
+
+func forkOf(d *beacon.ForkDecoder, digest common.ForkDigest) (fork, error) {
+	switch digest {
+	case d.Genesis:
+		return phase0Fork, nil
+	case d.Altair:
+		return altairFork, nil
+	case d.Bellatrix:
+		return bellatrixFork, nil
+	case d.Capella:
+		return capellaFork, nil
+	case d.Deneb:
+		return denebFork, nil
+	case d.Electra:
+		return electraFork, nil
+	case d.Fulu:
+		return fuluFork, nil
+	default:
+		return 0, fmt.Errorf("unrecognized fork digest: %s", digest)
+	}
+}
+
+// Alloc returns a new object of the message type of the topic, in the fork of the topic.
+// Subnet IDs are checked against the subnet count.
+func Alloc(d *beacon.ForkDecoder, topic Topic) (interface{}, error) {
+	f, err := forkOf(d, topic.ForkDigest)
+	if err != nil {
+		return nil, err
+	}
+	spec := d.Spec
+	switch topic.Kind {
+	case BeaconBlock:
+		alloc, err := d.BlockAllocator(topic.ForkDigest)
+		if err != nil {
+			return nil, err
+		}
+		return alloc(), nil
+	case BeaconAggregateAndProof:
+		if f >= electraFork {
+			return new(electra.SignedAggregateAndProof), nil
+		}
+		return new(phase0.SignedAggregateAndProof), nil
+	case VoluntaryExit:
+		return new(phase0.SignedVoluntaryExit), nil
+	case ProposerSlashing:
+		return new(phase0.ProposerSlashing), nil
+	case AttesterSlashing:
+		if f >= electraFork {
+			return new(electra.AttesterSlashing), nil
+		}
+		return new(phase0.AttesterSlashing), nil
+	case BeaconAttestation:
+		if topic.Subnet >= common.ATTESTATION_SUBNET_COUNT {
+			return nil, fmt.Errorf("attestation subnet %d out of range", topic.Subnet)
+		}
+		if f >= electraFork {
+			return new(electra.SingleAttestation), nil
+		}
+		return new(phase0.Attestation), nil
+	case SyncCommitteeContributionAndProof:
+		if f < altairFork {
+			return nil, fmt.Errorf("no sync committee contributions before altair")
+		}
+		return new(altair.SignedContributionAndProof), nil
+	case SyncCommittee:
+		if f < altairFork {
+			return nil, fmt.Errorf("no sync committee messages before altair")
+		}
+		if topic.Subnet >= common.SYNC_COMMITTEE_SUBNET_COUNT {
+			return nil, fmt.Errorf("sync committee subnet %d out of range", topic.Subnet)
+		}
+		return new(altair.SyncCommitteeMessage), nil
+	case BLSToExecutionChange:
+		if f < capellaFork {
+			return nil, fmt.Errorf("no BLS to execution changes before capella")
+		}
+		return new(common.SignedBLSToExecutionChange), nil
+	case BlobSidecar:
+		var subnets uint64
+		switch f {
+		case denebFork:
+			subnets = uint64(spec.BLOB_SIDECAR_SUBNET_COUNT)
+		case electraFork:
+			subnets = uint64(spec.BLOB_SIDECAR_SUBNET_COUNT_ELECTRA)
+		default:
+			return nil, fmt.Errorf("no blob sidecar topics in fork of digest %s", topic.ForkDigest)
+		}
+		if topic.Subnet >= subnets {
+			return nil, fmt.Errorf("blob sidecar subnet %d out of range", topic.Subnet)
+		}
+		return new(deneb.BlobSidecar), nil
+	default:
+		return nil, fmt.Errorf("unknown topic kind %q", topic.Kind)
+	}
+}
+
+// Decode decompresses and decodes a gossip message into the message type of the topic, in the fork of the topic.
+// The result is one of the pointer types returned by Alloc.
+func Decode(d *beacon.ForkDecoder, topic Topic, data []byte) (interface{}, error) {
+	obj, err := Alloc(d, topic)
+	if err != nil {
+		return nil, err
+	}
+	if err := DecodeInto(d.Spec, data, obj); err != nil {
+		return nil, fmt.Errorf("failed to decode %s message: %w", topic.Kind, err)
+	}
+	return obj, nil
+}
+
+// DecodeInto decompresses and decodes a gossip message into dst,
+// which is either a common.SpecObj or a codec.Deserializable.
+func DecodeInto(spec *common.Spec, data []byte, dst interface{}) error {
+	var des codec.Deserializable
+	switch x := dst.(type) {
+	case common.SpecObj:
+		des = spec.Wrap(x)
+	case codec.Deserializable:
+		des = x
+	default:
+		return fmt.Errorf("cannot decode into %T", dst)
+	}
+	payload, err := DecompressPayload(spec, data)
+	if err != nil {
+		return err
+	}
+	n := uint64(len(payload))
+	if fixed := des.FixedLength(); fixed != 0 && fixed != n {
+		return fmt.Errorf("payload has %d bytes, expected %d", n, fixed)
+	}
+	return des.Deserialize(codec.NewDecodingReader(bytes.NewReader(payload), n))
+}
+
+// DecodeBeaconBlock decodes a message of a beacon_block topic.
+func DecodeBeaconBlock(d *beacon.ForkDecoder, topic Topic, data []byte) (beacon.OpaqueBlock, error) {
+	if topic.Kind != BeaconBlock {
+		return nil, fmt.Errorf("not a block topic: %s", topic)
+	}
+	obj, err := Decode(d, topic, data)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(beacon.OpaqueBlock), nil
+}
+
+// DecodeBlobSidecar decodes a message of a blob_sidecar_{subnet_id} topic.
+func DecodeBlobSidecar(d *beacon.ForkDecoder, topic Topic, data []byte) (*deneb.BlobSidecar, error) {
+	if topic.Kind != BlobSidecar {
+		return nil, fmt.Errorf("not a blob sidecar topic: %s", topic)
+	}
+	obj, err := Decode(d, topic, data)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*deneb.BlobSidecar), nil
+}
@@ -0,0 +1,183 @@
+package gossip
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+var spec = configs.Mainnet
+
+func TestTopics(t *testing.T) {
+	digest := common.ForkDigest{0xb5, 0x30, 0x3f, 0x2a}
+	for expected, topic := range map[string]Topic{
+		"/eth2/b5303f2a/beacon_block/ssz_snappy":                          BeaconBlockTopic(digest),
+		"/eth2/b5303f2a/beacon_aggregate_and_proof/ssz_snappy":            BeaconAggregateAndProofTopic(digest),
+		"/eth2/b5303f2a/voluntary_exit/ssz_snappy":                        VoluntaryExitTopic(digest),
+		"/eth2/b5303f2a/proposer_slashing/ssz_snappy":                     ProposerSlashingTopic(digest),
+		"/eth2/b5303f2a/attester_slashing/ssz_snappy":                     AttesterSlashingTopic(digest),
+		"/eth2/b5303f2a/sync_committee_contribution_and_proof/ssz_snappy": SyncCommitteeContributionAndProofTopic(digest),
+		"/eth2/b5303f2a/bls_to_execution_change/ssz_snappy":               BLSToExecutionChangeTopic(digest),
+		"/eth2/b5303f2a/beacon_attestation_0/ssz_snappy":                  BeaconAttestationTopic(digest, 0),
+		"/eth2/b5303f2a/beacon_attestation_63/ssz_snappy":                 BeaconAttestationTopic(digest, 63),
+		"/eth2/b5303f2a/sync_committee_3/ssz_snappy":                      SyncCommitteeTopic(digest, 3),
+		"/eth2/b5303f2a/blob_sidecar_5/ssz_snappy":                        BlobSidecarTopic(digest, 5),
+	} {
+		if got := topic.String(); got != expected {
+			t.Errorf("got topic %q, expected %q", got, expected)
+		}
+		parsed, err := ParseTopic(expected)
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", expected, err)
+		} else if parsed != topic {
+			t.Errorf("parsed %q as %+v, expected %+v", expected, parsed, topic)
+		}
+	}
+	for _, invalid := range []string{
+		"/eth2/b5303f2a/beacon_block/ssz",
+		"/eth2/b5303f2a/beacon_block",
+		"/eth1/b5303f2a/beacon_block/ssz_snappy",
+		"/eth2/b5303f/beacon_block/ssz_snappy",
+		"/eth2/b5303fzz/beacon_block/ssz_snappy",
+		"/eth2/b5303f2a/unknown/ssz_snappy",
+		"/eth2/b5303f2a/beacon_attestation_/ssz_snappy",
+		"/eth2/b5303f2a/beacon_attestation_01/ssz_snappy",
+		"/eth2/b5303f2a/beacon_attestation_-1/ssz_snappy",
+		"/eth2/b5303f2a/blob_sidecar/ssz_snappy",
+	} {
+		if _, err := ParseTopic(invalid); err == nil {
+			t.Errorf("expected error for topic %q", invalid)
+		}
+	}
+}
+
+func TestMsgID(t *testing.T) {
+	topic := "/eth2/b5303f2a/beacon_block/ssz_snappy"
+	payload := []byte("hello world, hello world, hello world")
+	compressed := snappy.Encode(nil, payload)
+
+	var topicLen [8]byte
+	binary.LittleEndian.PutUint64(topicLen[:], uint64(len(topic)))
+	expectedValid := sha256.Sum256(bytes.Join([][]byte{
+		{0x01, 0x00, 0x00, 0x00}, topicLen[:], []byte(topic), payload}, nil))
+	if got := MsgID(spec, topic, compressed); !bytes.Equal(got[:], expectedValid[:20]) {
+		t.Fatalf("valid snappy: got %x, expected %x", got, expectedValid[:20])
+	}
+
+	invalid := []byte{0xff, 0xff, 0xff, 0xff, 0x01}
+	expectedInvalid := sha256.Sum256(bytes.Join([][]byte{
+		{0x00, 0x00, 0x00, 0x00}, topicLen[:], []byte(topic), invalid}, nil))
+	if got := MsgID(spec, topic, invalid); !bytes.Equal(got[:], expectedInvalid[:20]) {
+		t.Fatalf("invalid snappy: got %x, expected %x", got, expectedInvalid[:20])
+	}
+
+	d := beacon.NewForkDecoder(spec, common.Root{0x42})
+	msgID := MsgIDFn(d)
+	expectedPhase0 := sha256.Sum256(append([]byte{0x01, 0x00, 0x00, 0x00}, payload...))
+	if got := msgID(BeaconBlockTopic(d.Genesis).String(), compressed); !bytes.Equal(got[:], expectedPhase0[:20]) {
+		t.Fatalf("phase0: got %x, expected %x", got, expectedPhase0[:20])
+	}
+	altairTopic := BeaconBlockTopic(d.Altair).String()
+	if got := msgID(altairTopic, compressed); got != MsgID(spec, altairTopic, compressed) {
+		t.Fatal("expected altair message-id for altair topic")
+	}
+}
+
+func TestDecompressPayloadLimits(t *testing.T) {
+	// snappy block header declaring a decoded length above MAX_PAYLOAD_SIZE
+	var header [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(header[:], uint64(spec.MAX_PAYLOAD_SIZE)+1)
+	if _, err := DecompressPayload(spec, header[:n]); err == nil {
+		t.Fatal("expected error for oversized decoded length")
+	}
+	if _, err := DecompressPayload(spec, make([]byte, MaxCompressedLen(uint64(spec.MAX_PAYLOAD_SIZE))+1)); err == nil {
+		t.Fatal("expected error for oversized compressed payload")
+	}
+}
+
+func TestDecodeAttestationByFork(t *testing.T) {
+	d := beacon.NewForkDecoder(spec, common.Root{0x42})
+
+	phase0Att := phase0.Attestation{AggregationBits: []byte{0b101}}
+	phase0Att.Data.Slot = 123
+	data, err := CompressPayload(spec, spec.Wrap(&phase0Att))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := Decode(d, BeaconAttestationTopic(d.Deneb, 3), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	att, ok := obj.(*phase0.Attestation)
+	if !ok {
+		t.Fatalf("expected phase0 attestation, got %T", obj)
+	}
+	if att.HashTreeRoot(spec, tree.GetHashFn()) != phase0Att.HashTreeRoot(spec, tree.GetHashFn()) {
+		t.Fatal("attestation changed in roundtrip")
+	}
+
+	single := electra.SingleAttestation{CommitteeIndex: 2, AttesterIndex: 1000}
+	single.Data.Slot = 456
+	data, err = CompressPayload(spec, &single)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err = Decode(d, BeaconAttestationTopic(d.Electra, 3), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := obj.(*electra.SingleAttestation); !ok || *got != single {
+		t.Fatalf("expected single attestation %v, got %v", single, obj)
+	}
+	// wrong type for the fork
+	if _, err := Decode(d, BeaconAttestationTopic(d.Deneb, 3), data); err == nil {
+		t.Fatal("expected error for electra attestation on deneb topic")
+	}
+	if _, err := Decode(d, BeaconAttestationTopic(d.Electra, common.ATTESTATION_SUBNET_COUNT), data); err == nil {
+		t.Fatal("expected error for subnet out of range")
+	}
+	if _, err := Decode(d, BeaconAttestationTopic(common.ForkDigest{1, 2, 3, 4}, 3), data); err == nil {
+		t.Fatal("expected error for unknown fork digest")
+	}
+	if _, err := Decode(d, BlobSidecarTopic(d.Capella, 0), data); err == nil {
+		t.Fatal("expected error for pre-deneb blob sidecar topic")
+	}
+}
+
+func TestDecodeBeaconBlock(t *testing.T) {
+	d := beacon.NewForkDecoder(spec, common.Root{0x42})
+	var block phase0.SignedBeaconBlock
+	var buf bytes.Buffer
+	if err := phase0.SignedBeaconBlockType(spec).New().Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if err := block.Deserialize(spec, codec.NewDecodingReader(&buf, uint64(buf.Len()))); err != nil {
+		t.Fatal(err)
+	}
+	block.Message.Slot = 42
+	data, err := CompressPayload(spec, spec.Wrap(&block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeBeaconBlock(d, BeaconBlockTopic(d.Genesis), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.HashTreeRoot(spec, tree.GetHashFn()) != block.HashTreeRoot(spec, tree.GetHashFn()) {
+		t.Fatal("block changed in roundtrip")
+	}
+	if _, err := DecodeBeaconBlock(d, VoluntaryExitTopic(d.Genesis), data); err == nil {
+		t.Fatal("expected error for non-block topic")
+	}
+}
@@ -0,0 +1,104 @@
+package gossip
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/protolambda/ztyp/codec"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// MaxCompressedLen is the maximum size of the snappy block encoding of n bytes.
+func MaxCompressedLen(n uint64) uint64 {
+	return 32 + n + n/6
+}
+
+// DecompressPayload decompresses a gossip message with snappy block compression.
+// Both the compressed and decompressed size are checked against MAX_PAYLOAD_SIZE before decompression.
+func DecompressPayload(spec *common.Spec, data []byte) ([]byte, error) {
+	maxSize := uint64(spec.MAX_PAYLOAD_SIZE)
+	if uint64(len(data)) > MaxCompressedLen(maxSize) {
+		return nil, fmt.Errorf("compressed payload of %d bytes is too large", len(data))
+	}
+	n, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy payload: %w", err)
+	}
+	if uint64(n) > maxSize {
+		return nil, fmt.Errorf("payload of %d bytes exceeds MAX_PAYLOAD_SIZE %d", n, maxSize)
+	}
+	out, err := snappy.Decode(nil, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy payload: %w", err)
+	}
+	return out, nil
+}
+
+// CompressPayload encodes and compresses obj, to publish as gossip message.
+func CompressPayload(spec *common.Spec, obj codec.Serializable) ([]byte, error) {
+	if n := obj.ByteLength(); n > uint64(spec.MAX_PAYLOAD_SIZE) {
+		return nil, fmt.Errorf("payload of %d bytes exceeds MAX_PAYLOAD_SIZE %d", n, spec.MAX_PAYLOAD_SIZE)
+	}
+	var buf bytes.Buffer
+	if err := obj.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, buf.Bytes()), nil
+}
+
+const MessageIDLength = 20
+
+type MessageID [MessageIDLength]byte
+
+// MsgID computes the altair+ message-id: the topic is included in the hash.
+// Messages that fail snappy decompression are hashed with the MESSAGE_DOMAIN_INVALID_SNAPPY domain.
+func MsgID(spec *common.Spec, topic string, data []byte) (out MessageID) {
+	h := sha256.New()
+	var topicLen [8]byte
+	binary.LittleEndian.PutUint64(topicLen[:], uint64(len(topic)))
+	if decompressed, err := DecompressPayload(spec, data); err == nil {
+		h.Write(spec.MESSAGE_DOMAIN_VALID_SNAPPY[:])
+		h.Write(topicLen[:])
+		h.Write([]byte(topic))
+		h.Write(decompressed)
+	} else {
+		h.Write(spec.MESSAGE_DOMAIN_INVALID_SNAPPY[:])
+		h.Write(topicLen[:])
+		h.Write([]byte(topic))
+		h.Write(data)
+	}
+	copy(out[:], h.Sum(nil))
+	return
+}
+
+// Phase0MsgID computes the phase0 message-id, without the topic.
+func Phase0MsgID(spec *common.Spec, data []byte) (out MessageID) {
+	h := sha256.New()
+	if decompressed, err := DecompressPayload(spec, data); err == nil {
+		h.Write(spec.MESSAGE_DOMAIN_VALID_SNAPPY[:])
+		h.Write(decompressed)
+	} else {
+		h.Write(spec.MESSAGE_DOMAIN_INVALID_SNAPPY[:])
+		h.Write(data)
+	}
+	copy(out[:], h.Sum(nil))
+	return
+}
+
+// MsgIDFn returns a message-id function for all topics, using the phase0 message-id for genesis-fork topics.
+func MsgIDFn(d *beacon.ForkDecoder) func(topic string, data []byte) MessageID {
+	genesisPrefix := topicPrefix + hex.EncodeToString(d.Genesis[:]) + "/"
+	return func(topic string, data []byte) MessageID {
+		if strings.HasPrefix(topic, genesisPrefix) {
+			return Phase0MsgID(d.Spec, data)
+		}
+		return MsgID(d.Spec, topic, data)
+	}
+}
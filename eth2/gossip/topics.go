@@ -0,0 +1,151 @@
+package gossip
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// TopicKind is the name of a gossip topic, without the subnet suffix of subnet topics.
+type TopicKind string
+
+const (
+	BeaconBlock                       TopicKind = "beacon_block"
+	BeaconAggregateAndProof           TopicKind = "beacon_aggregate_and_proof"
+	VoluntaryExit                     TopicKind = "voluntary_exit"
+	ProposerSlashing                  TopicKind = "proposer_slashing"
+	AttesterSlashing                  TopicKind = "attester_slashing"
+	SyncCommitteeContributionAndProof TopicKind = "sync_committee_contribution_and_proof"
+	BLSToExecutionChange              TopicKind = "bls_to_execution_change"
+
+	// subnet topics, the topic name is suffixed with "_{subnet_id}"
+	BeaconAttestation TopicKind = "beacon_attestation"
+	SyncCommittee     TopicKind = "sync_committee"
+	BlobSidecar       TopicKind = "blob_sidecar"
+)
+
+var globalTopicKinds = []TopicKind{
+	BeaconBlock, BeaconAggregateAndProof, VoluntaryExit, ProposerSlashing,
+	AttesterSlashing, SyncCommitteeContributionAndProof, BLSToExecutionChange,
+}
+
+var subnetTopicKinds = []TopicKind{BeaconAttestation, SyncCommittee, BlobSidecar}
+
+// HasSubnet is true if topics of this kind are split per subnet.
+func (k TopicKind) HasSubnet() bool {
+	switch k {
+	case BeaconAttestation, SyncCommittee, BlobSidecar:
+		return true
+	default:
+		return false
+	}
+}
+
+const topicPrefix = "/eth2/"
+const topicEncoding = "ssz_snappy"
+
+// Topic identifies a gossip topic: "/eth2/{fork_digest}/{name}/ssz_snappy"
+type Topic struct {
+	ForkDigest common.ForkDigest
+	Kind       TopicKind
+	// Subnet is only used if the Kind has subnets.
+	Subnet uint64
+}
+
+// Name returns the topic name, including the subnet suffix for subnet topics.
+func (t Topic) Name() string {
+	if t.Kind.HasSubnet() {
+		return fmt.Sprintf("%s_%d", t.Kind, t.Subnet)
+	}
+	return string(t.Kind)
+}
+
+func (t Topic) String() string {
+	return topicPrefix + hex.EncodeToString(t.ForkDigest[:]) + "/" + t.Name() + "/" + topicEncoding
+}
+
+// ParseTopic parses a full topic string. Subnet IDs are not checked against the subnet count.
+func ParseTopic(topic string) (Topic, error) {
+	var out Topic
+	if !strings.HasPrefix(topic, topicPrefix) {
+		return out, fmt.Errorf("topic %q does not start with %q", topic, topicPrefix)
+	}
+	parts := strings.Split(topic[len(topicPrefix):], "/")
+	if len(parts) != 3 {
+		return out, fmt.Errorf("topic %q is not formatted as /eth2/{fork_digest}/{name}/{encoding}", topic)
+	}
+	if parts[2] != topicEncoding {
+		return out, fmt.Errorf("topic %q has unsupported encoding %q", topic, parts[2])
+	}
+	if len(parts[0]) != 8 {
+		return out, fmt.Errorf("topic %q has invalid fork digest", topic)
+	}
+	if _, err := hex.Decode(out.ForkDigest[:], []byte(parts[0])); err != nil {
+		return out, fmt.Errorf("topic %q has invalid fork digest: %w", topic, err)
+	}
+	name := parts[1]
+	for _, k := range globalTopicKinds {
+		if name == string(k) {
+			out.Kind = k
+			return out, nil
+		}
+	}
+	for _, k := range subnetTopicKinds {
+		if !strings.HasPrefix(name, string(k)+"_") {
+			continue
+		}
+		suffix := name[len(k)+1:]
+		// no leading zeroes or signs, the name must be canonical
+		subnet, err := strconv.ParseUint(suffix, 10, 64)
+		if err != nil || strconv.FormatUint(subnet, 10) != suffix {
+			return out, fmt.Errorf("topic %q has invalid subnet %q", topic, suffix)
+		}
+		out.Kind = k
+		out.Subnet = subnet
+		return out, nil
+	}
+	return out, fmt.Errorf("topic %q has unknown name %q", topic, name)
+}
+
+func BeaconBlockTopic(digest common.ForkDigest) Topic {
+	return Topic{ForkDigest: digest, Kind: BeaconBlock}
+}
+
+func BeaconAggregateAndProofTopic(digest common.ForkDigest) Topic {
+	return Topic{ForkDigest: digest, Kind: BeaconAggregateAndProof}
+}
+
+func VoluntaryExitTopic(digest common.ForkDigest) Topic {
+	return Topic{ForkDigest: digest, Kind: VoluntaryExit}
+}
+
+func ProposerSlashingTopic(digest common.ForkDigest) Topic {
+	return Topic{ForkDigest: digest, Kind: ProposerSlashing}
+}
+
+func AttesterSlashingTopic(digest common.ForkDigest) Topic {
+	return Topic{ForkDigest: digest, Kind: AttesterSlashing}
+}
+
+func SyncCommitteeContributionAndProofTopic(digest common.ForkDigest) Topic {
+	return Topic{ForkDigest: digest, Kind: SyncCommitteeContributionAndProof}
+}
+
+func BLSToExecutionChangeTopic(digest common.ForkDigest) Topic {
+	return Topic{ForkDigest: digest, Kind: BLSToExecutionChange}
+}
+
+func BeaconAttestationTopic(digest common.ForkDigest, subnet uint64) Topic {
+	return Topic{ForkDigest: digest, Kind: BeaconAttestation, Subnet: subnet}
+}
+
+func SyncCommitteeTopic(digest common.ForkDigest, subnet uint64) Topic {
+	return Topic{ForkDigest: digest, Kind: SyncCommittee, Subnet: subnet}
+}
+
+func BlobSidecarTopic(digest common.ForkDigest, subnet uint64) Topic {
+	return Topic{ForkDigest: digest, Kind: BlobSidecar, Subnet: subnet}
+}
@@ -7,11 +7,14 @@ import (
 
 	blsu "github.com/protolambda/bls12-381-util"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 
 	"github.com/protolambda/ztyp/tree"
 )
 
+// AggregatesValBackend provides the data to validate aggregates with.
+// Aggregates for unknown blocks are queued if the backend also implements UnknownBlockQueue.
 type AggregatesValBackend interface {
 	Spec
 	Chain
@@ -28,29 +31,83 @@ type AggregatesValBackend interface {
 	MarkAggregator(targetEpoch common.Epoch, aggregator common.ValidatorIndex)
 }
 
+type UnknownBlockQueue interface {
+	// QueueUnknownBlock is called with a gossip message that is ignored because it votes for a block
+	// that has not been seen yet. The message may be queued, to validate it again once the block is retrieved.
+	QueueUnknownBlock(blockRoot common.Root, msg interface{})
+}
+
 func ValidateAggregateAndProof(ctx context.Context, signedAgg *phase0.SignedAggregateAndProof,
 	aggVal AggregatesValBackend) ([]common.ValidatorIndex, GossipValidatorResult) {
 	spec := aggVal.Spec()
-	// [IGNORE] aggregate.data.slot is within the last ATTESTATION_PROPAGATION_SLOT_RANGE
-	// slots (with a MAXIMUM_GOSSIP_CLOCK_DISPARITY allowance) --
-	// i.e. aggregate.data.slot + ATTESTATION_PROPAGATION_SLOT_RANGE >= current_slot >= aggregate.data.slot
-	// overflow check
 	att := &signedAgg.Message.Aggregate
-	if err := CheckSlotSpan(aggVal.SlotAfter, att.Data.Slot, ATTESTATION_PROPAGATION_SLOT_RANGE); err != nil {
-		return nil, GossipValidatorResult{IGNORE, fmt.Errorf("aggregate attestation not within slot range: %v", err)}
+	if res := validateAggregateData(&att.Data, signedAgg.Message.AggregatorIndex, aggVal); res.Result != ACCEPT {
+		return nil, res
 	}
 
-	// [REJECT] The aggregate attestation's epoch matches its target --
-	// i.e. aggregate.data.target.epoch == compute_epoch_at_slot(aggregate.data.slot)
-	attEpoch := spec.SlotToEpoch(att.Data.Slot)
-	if att.Data.Target.Epoch != attEpoch {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation slot %d is epoch %d and does not match target %d", att.Data.Slot, attEpoch, att.Data.Target.Epoch)}
+	// [IGNORE] The valid aggregate attestation defined by hash_tree_root(aggregate) has not already been seen
+	// (via aggregate gossip, within a verified block, or through the creation of an equivalent aggregate locally).
+	aggRoot := att.HashTreeRoot(spec, tree.GetHashFn())
+	if aggVal.SeenAggregate(aggRoot) {
+		return nil, GossipValidatorResult{IGNORE, fmt.Errorf("attestation aggregate %s has already been seen", aggRoot)}
 	}
 
-	// [IGNORE] The aggregate is the first valid aggregate received for the aggregator with index
-	// aggregate_and_proof.aggregator_index for the epoch aggregate.data.target.epoch.
-	if epoch, index := att.Data.Target.Epoch, signedAgg.Message.AggregatorIndex; aggVal.SeenAggregator(epoch, index) {
-		return nil, GossipValidatorResult{IGNORE, fmt.Errorf("already seen aggregate by %d for epoch %d", index, epoch)}
+	// [REJECT] The attestation has participants --
+	// i.e., len(get_attesting_indices(state, aggregate.data, aggregate.aggregation_bits)) >= 1.
+	if att.AggregationBits.OnesCount() < 1 {
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation has no participants")}
+	}
+
+	if res := validateAggregateVote(&att.Data, signedAgg, aggVal); res.Result != ACCEPT {
+		return nil, res
+	}
+
+	epc, state, res := validateAggregator(ctx, &att.Data, att.Data.Index, &signedAgg.Message.SelectionProof,
+		signedAgg.Message.AggregatorIndex, signedAgg.Message.HashTreeRoot(spec, tree.GetHashFn()), &signedAgg.Signature, aggVal)
+	if res.Result != ACCEPT {
+		return nil, res
+	}
+
+	// [REJECT] The signature of aggregate is valid.
+	// Check signature and bitfields
+	committee, err := epc.GetBeaconCommittee(att.Data.Slot, att.Data.Index)
+	if err != nil {
+		return nil, GossipValidatorResult{IGNORE, err}
+	}
+	if indexedAtt, err := att.ConvertToIndexed(spec, committee); err != nil {
+		// it should always convert.
+		// Something is very wrong if not, e.g. bad bitfield length.
+		return nil, GossipValidatorResult{REJECT, err}
+	} else if err := phase0.ValidateIndexedAttestation(ctx, spec, epc, state, indexedAtt); err != nil {
+		return nil, GossipValidatorResult{REJECT, err}
+	}
+
+	aggVal.MarkAggregate(aggRoot)
+	aggVal.MarkAggregator(att.Data.Target.Epoch, signedAgg.Message.AggregatorIndex)
+
+	return committee, GossipValidatorResult{ACCEPT, nil}
+}
+
+// ValidateElectraAggregateAndProof is ValidateAggregateAndProof for the aggregates after the Electra fork:
+// the committee index is the single set bit of the committee bits, and data.index must be zero.
+func ValidateElectraAggregateAndProof(ctx context.Context, signedAgg *electra.SignedAggregateAndProof,
+	aggVal AggregatesValBackend) ([]common.ValidatorIndex, GossipValidatorResult) {
+	spec := aggVal.Spec()
+	att := &signedAgg.Message.Aggregate
+
+	// [REJECT] aggregate.data.index == 0
+	if att.Data.Index != 0 {
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("aggregate data index must be 0, got %d", att.Data.Index)}
+	}
+	// [REJECT] len(committee_indices) == 1, where committee_indices = get_committee_indices(aggregate).
+	committeeIndices := att.CommitteeBits.CommitteeIndices()
+	if len(committeeIndices) != 1 {
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("aggregate must have exactly 1 committee bit set, got %d", len(committeeIndices))}
+	}
+	index := committeeIndices[0]
+
+	if res := validateAggregateData(&att.Data, signedAgg.Message.AggregatorIndex, aggVal); res.Result != ACCEPT {
+		return nil, res
 	}
 
 	// [IGNORE] The valid aggregate attestation defined by hash_tree_root(aggregate) has not already been seen
@@ -61,107 +118,188 @@ func ValidateAggregateAndProof(ctx context.Context, signedAgg *phase0.SignedAggr
 	}
 
 	// [REJECT] The attestation has participants --
-	// i.e., len(get_attesting_indices(state, aggregate.data, aggregate.aggregation_bits)) >= 1.
+	// i.e., len(get_attesting_indices(state, aggregate)) >= 1.
 	if att.AggregationBits.OnesCount() < 1 {
 		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation has no participants")}
 	}
 
-	// [IGNORE] The block being voted for (aggregate.data.beacon_block_root) has been seen (via both gossip and non-gossip sources)
-	// (a client MAY queue aggregates for processing once block is retrieved).
-	// TODO
+	if res := validateAggregateVote(&att.Data, signedAgg, aggVal); res.Result != ACCEPT {
+		return nil, res
+	}
+
+	epc, state, res := validateAggregator(ctx, &att.Data, index, &signedAgg.Message.SelectionProof,
+		signedAgg.Message.AggregatorIndex, signedAgg.Message.HashTreeRoot(spec, tree.GetHashFn()), &signedAgg.Signature, aggVal)
+	if res.Result != ACCEPT {
+		return nil, res
+	}
+
+	committee, err := epc.GetBeaconCommittee(att.Data.Slot, index)
+	if err != nil {
+		return nil, GossipValidatorResult{IGNORE, err}
+	}
+	// [REJECT] The number of aggregation bits matches the committee size --
+	// i.e. len(aggregate.aggregation_bits) == len(get_beacon_committee(state, aggregate.data.slot, index)).
+	if bl := att.AggregationBits.BitLen(); bl != uint64(len(committee)) {
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("aggregate has bitlength %d, but expected %d bits", bl, len(committee))}
+	}
+
+	// [REJECT] The signature of aggregate is valid.
+	dom, err := common.GetDomain(state, common.DOMAIN_BEACON_ATTESTER, att.Data.Target.Epoch)
+	if err != nil {
+		return nil, GossipValidatorResult{IGNORE, err}
+	}
+	aggPub, err := epc.AggregateCommitteeParticipants(att.Data.Slot, index, att.AggregationBits.GetBit)
+	if err != nil {
+		return nil, GossipValidatorResult{IGNORE, err}
+	}
+	sig, err := att.Signature.Signature()
+	if err != nil {
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("failed to deserialize aggregate attestation signature: %v", err)}
+	}
+	sigRoot := common.ComputeSigningRoot(att.Data.HashTreeRoot(tree.GetHashFn()), dom)
+	if !blsu.Verify(aggPub, sigRoot[:], sig) {
+		return nil, GossipValidatorResult{REJECT, errors.New("invalid aggregate attestation signature")}
+	}
 
+	aggVal.MarkAggregate(aggRoot)
+	aggVal.MarkAggregator(att.Data.Target.Epoch, signedAgg.Message.AggregatorIndex)
+
+	return committee, GossipValidatorResult{ACCEPT, nil}
+}
+
+// validateAggregateData checks the slot and target of the aggregate, and if the aggregator was seen already.
+func validateAggregateData(data *phase0.AttestationData, aggregator common.ValidatorIndex, aggVal AggregatesValBackend) GossipValidatorResult {
+	spec := aggVal.Spec()
+	// [IGNORE] aggregate.data.slot is within the last ATTESTATION_PROPAGATION_SLOT_RANGE
+	// slots (with a MAXIMUM_GOSSIP_CLOCK_DISPARITY allowance) --
+	// i.e. aggregate.data.slot + ATTESTATION_PROPAGATION_SLOT_RANGE >= current_slot >= aggregate.data.slot
+	// overflow check
+	if err := CheckSlotSpan(aggVal.SlotAfter, data.Slot, ATTESTATION_PROPAGATION_SLOT_RANGE); err != nil {
+		return GossipValidatorResult{IGNORE, fmt.Errorf("aggregate attestation not within slot range: %v", err)}
+	}
+
+	// [REJECT] The aggregate attestation's epoch matches its target --
+	// i.e. aggregate.data.target.epoch == compute_epoch_at_slot(aggregate.data.slot)
+	attEpoch := spec.SlotToEpoch(data.Slot)
+	if data.Target.Epoch != attEpoch {
+		return GossipValidatorResult{REJECT, fmt.Errorf("attestation slot %d is epoch %d and does not match target %d", data.Slot, attEpoch, data.Target.Epoch)}
+	}
+
+	// [IGNORE] The aggregate is the first valid aggregate received for the aggregator with index
+	// aggregate_and_proof.aggregator_index for the epoch aggregate.data.target.epoch.
+	if epoch := data.Target.Epoch; aggVal.SeenAggregator(epoch, aggregator) {
+		return GossipValidatorResult{IGNORE, fmt.Errorf("already seen aggregate by %d for epoch %d", aggregator, epoch)}
+	}
+	return GossipValidatorResult{ACCEPT, nil}
+}
+
+// validateAggregateVote checks the block that the aggregate votes for.
+// The message is queued if the block has not been seen yet, and the backend is an UnknownBlockQueue.
+func validateAggregateVote(data *phase0.AttestationData, msg interface{}, aggVal AggregatesValBackend) GossipValidatorResult {
 	// [REJECT] The block being voted for (aggregate.data.beacon_block_root) passes validation.
-	if aggVal.IsBadBlock(att.Data.BeaconBlockRoot) {
-		return nil, GossipValidatorResult{REJECT, errors.New("aggregate voted for invalid block")}
+	if aggVal.IsBadBlock(data.BeaconBlockRoot) {
+		return GossipValidatorResult{REJECT, errors.New("aggregate voted for invalid block")}
 	}
 
 	ch := aggVal.Chain()
 
+	// [IGNORE] The block being voted for (aggregate.data.beacon_block_root) has been seen (via both gossip and non-gossip sources)
+	// (a client MAY queue aggregates for processing once block is retrieved).
+	if _, ok := ch.ByBlock(data.BeaconBlockRoot); !ok {
+		if q, ok := aggVal.(UnknownBlockQueue); ok {
+			q.QueueUnknownBlock(data.BeaconBlockRoot, msg)
+		}
+		return GossipValidatorResult{IGNORE, fmt.Errorf("aggregate voted for unknown block %s", data.BeaconBlockRoot)}
+	}
+
 	// [REJECT] The current finalized_checkpoint is an ancestor of the block defined
 	// by aggregate.data.beacon_block_root --
 	// i.e. get_ancestor(store, attestation.data.beacon_block_root, compute_start_slot_at_epoch(store.finalized_checkpoint.epoch))
 	//        == store.finalized_checkpoint.root
 	fin := ch.FinalizedCheckpoint()
-	if att.Data.BeaconBlockRoot != fin.Root {
-		if unknown, inSubtree := ch.InSubtree(fin.Root, att.Data.BeaconBlockRoot); unknown {
-			return nil, GossipValidatorResult{IGNORE, errors.New("unknown block, cannot check if in subtree")}
+	if data.BeaconBlockRoot != fin.Root {
+		if unknown, inSubtree := ch.InSubtree(fin.Root, data.BeaconBlockRoot); unknown {
+			return GossipValidatorResult{IGNORE, errors.New("unknown block, cannot check if in subtree")}
 		} else if !inSubtree {
-			return nil, GossipValidatorResult{IGNORE, errors.New("block not in subtree of finalized root")}
+			return GossipValidatorResult{IGNORE, errors.New("block not in subtree of finalized root")}
 		}
-	} else if fin.Epoch > att.Data.Target.Epoch {
-		return nil, GossipValidatorResult{REJECT, errors.New("cannot vote for finalized root as target")}
+	} else if fin.Epoch > data.Target.Epoch {
+		return GossipValidatorResult{REJECT, errors.New("cannot vote for finalized root as target")}
 	}
+	return GossipValidatorResult{ACCEPT, nil}
+}
+
+// validateAggregator checks the selection proof and signature of the aggregator of the given committee,
+// and returns the EpochsContext and state of the target, to validate the aggregate itself with.
+func validateAggregator(ctx context.Context, data *phase0.AttestationData, index common.CommitteeIndex,
+	selectionProof *common.BLSSignature, aggregator common.ValidatorIndex, msgRoot common.Root, signature *common.BLSSignature,
+	aggVal AggregatesValBackend) (*common.EpochsContext, common.BeaconState, GossipValidatorResult) {
+	spec := aggVal.Spec()
+	ch := aggVal.Chain()
 
 	// 3 combined steps:
 	// [REJECT] aggregate_and_proof.selection_proof selects the validator as an aggregator for the slot --
-	// i.e. is_aggregator(state, aggregate.data.slot, aggregate.data.index, aggregate_and_proof.selection_proof) returns True.
+	// i.e. is_aggregator(state, aggregate.data.slot, index, aggregate_and_proof.selection_proof) returns True.
 	// [REJECT] The aggregator's validator index is within the committee --
-	// i.e. aggregate_and_proof.aggregator_index in get_beacon_committee(state, aggregate.data.slot, aggregate.data.index).
+	// i.e. aggregate_and_proof.aggregator_index in get_beacon_committee(state, aggregate.data.slot, index).
 	// [REJECT] The aggregate_and_proof.selection_proof is a valid signature of the aggregate.data.slot
 	// by the validator with index aggregate_and_proof.aggregator_index.
 
 	// target epoch was already validated to match the slot, which was validated to be within normal range. No overflows.
-	startSlot, _ := spec.EpochStartSlot(att.Data.Target.Epoch)
+	startSlot, _ := spec.EpochStartSlot(data.Target.Epoch)
 
 	towardsCtx, cancel := context.WithTimeout(ctx, catchupTimeout)
 	defer cancel()
 
-	entry, err := ch.Towards(towardsCtx, att.Data.Target.Root, startSlot)
+	entry, err := ch.Towards(towardsCtx, data.Target.Root, startSlot)
 	if err != nil {
-		return nil, GossipValidatorResult{IGNORE, err}
+		return nil, nil, GossipValidatorResult{IGNORE, err}
 	}
 	epc, err := entry.EpochsContext(ctx)
 	if err != nil {
-		return nil, GossipValidatorResult{IGNORE, err}
+		return nil, nil, GossipValidatorResult{IGNORE, err}
 	}
 	state, err := entry.State(ctx)
 	if err != nil {
-		return nil, GossipValidatorResult{IGNORE, err}
+		return nil, nil, GossipValidatorResult{IGNORE, err}
 	}
-	if valid, err := phase0.ValidateAggregateSelectionProof(spec, epc, state, att.Data.Slot, att.Data.Index, signedAgg.Message.AggregatorIndex, signedAgg.Message.SelectionProof); err != nil {
-		return nil, GossipValidatorResult{IGNORE, err}
+
+	// [REJECT] The committee index is within the expected range --
+	// i.e. index < get_committee_count_per_slot(state, aggregate.data.target.epoch).
+	committeeCountPerSlot, err := epc.GetCommitteeCountPerSlot(data.Target.Epoch)
+	if err != nil {
+		return nil, nil, GossipValidatorResult{IGNORE, err}
+	}
+	if uint64(index) >= committeeCountPerSlot {
+		return nil, nil, GossipValidatorResult{REJECT, fmt.Errorf("committee index %d out of range %d", index, committeeCountPerSlot)}
+	}
+
+	if valid, err := phase0.ValidateAggregateSelectionProof(spec, epc, state, data.Slot, index, aggregator, *selectionProof); err != nil {
+		return nil, nil, GossipValidatorResult{IGNORE, err}
 	} else if !valid {
-		return nil, GossipValidatorResult{REJECT, errors.New("invalid aggregate")}
+		return nil, nil, GossipValidatorResult{REJECT, errors.New("invalid aggregate")}
 	}
 
 	// [REJECT] The aggregator signature, signed_aggregate_and_proof.signature, is valid.
-	dom, err := common.GetDomain(state, common.DOMAIN_AGGREGATE_AND_PROOF, att.Data.Target.Epoch)
+	dom, err := common.GetDomain(state, common.DOMAIN_AGGREGATE_AND_PROOF, data.Target.Epoch)
 	if err != nil {
-		return nil, GossipValidatorResult{IGNORE, err}
+		return nil, nil, GossipValidatorResult{IGNORE, err}
 	}
-	sigRoot := common.ComputeSigningRoot(signedAgg.Message.HashTreeRoot(spec, tree.GetHashFn()), dom)
-	pub, ok := epc.ValidatorPubkeyCache.Pubkey(signedAgg.Message.AggregatorIndex)
+	sigRoot := common.ComputeSigningRoot(msgRoot, dom)
+	pub, ok := epc.ValidatorPubkeyCache.Pubkey(aggregator)
 	if !ok {
-		return nil, GossipValidatorResult{IGNORE, fmt.Errorf("missing pubkey: %d", signedAgg.Message.AggregatorIndex)}
+		return nil, nil, GossipValidatorResult{IGNORE, fmt.Errorf("missing pubkey: %d", aggregator)}
 	}
 	blsPub, err := pub.Pubkey()
 	if err != nil {
-		return nil, GossipValidatorResult{IGNORE, fmt.Errorf("failed to deserialize cached pubkey: %v", err)}
+		return nil, nil, GossipValidatorResult{IGNORE, fmt.Errorf("failed to deserialize cached pubkey: %v", err)}
 	}
-	sig, err := signedAgg.Signature.Signature()
+	sig, err := signature.Signature()
 	if err != nil {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("failed to deserialize aggregate signature: %v", err)}
+		return nil, nil, GossipValidatorResult{REJECT, fmt.Errorf("failed to deserialize aggregate signature: %v", err)}
 	}
-	if !blsu.Verify(blsPub, sigRoot[:2], sig) {
-		return nil, GossipValidatorResult{REJECT, errors.New("invalid aggregate signature")}
+	if !blsu.Verify(blsPub, sigRoot[:], sig) {
+		return nil, nil, GossipValidatorResult{REJECT, errors.New("invalid aggregate signature")}
 	}
-
-	// [REJECT] The signature of aggregate is valid.
-	// Check signature and bitfields
-	committee, err := epc.GetBeaconCommittee(att.Data.Slot, att.Data.Index)
-	if err != nil {
-		return nil, GossipValidatorResult{IGNORE, err}
-	}
-	if indexedAtt, err := att.ConvertToIndexed(spec, committee); err != nil {
-		// it should always convert.
-		// Something is very wrong if not, e.g. bad bitfield length.
-		return nil, GossipValidatorResult{REJECT, err}
-	} else if err := phase0.ValidateIndexedAttestation(spec, epc, state, indexedAtt); err != nil {
-		return nil, GossipValidatorResult{REJECT, err}
-	}
-
-	aggVal.MarkAggregate(aggRoot)
-	aggVal.MarkAggregator(att.Data.Target.Epoch, signedAgg.Message.AggregatorIndex)
-
-	return committee, GossipValidatorResult{ACCEPT, nil}
+	return epc, state, GossipValidatorResult{ACCEPT, nil}
 }
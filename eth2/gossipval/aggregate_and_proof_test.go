@@ -0,0 +1,288 @@
+package gossipval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/interop"
+)
+
+var testBlockRoot = common.Root{0xbb}
+
+// testEntry is the chain entry of the genesis state, all other methods are left unimplemented.
+type testEntry struct {
+	beacon.ChainEntry
+	epc   *common.EpochsContext
+	state common.BeaconState
+}
+
+func (e *testEntry) Step() common.Step {
+	return common.AsStep(0, true)
+}
+
+func (e *testEntry) EpochsContext(ctx context.Context) (*common.EpochsContext, error) {
+	return e.epc, nil
+}
+
+func (e *testEntry) State(ctx context.Context) (common.BeaconState, error) {
+	return e.state, nil
+}
+
+// testChain knows only testBlockRoot, which is also finalized, all other methods are left unimplemented.
+type testChain struct {
+	beacon.Chain
+	entry *testEntry
+}
+
+func (c *testChain) ByBlock(root common.Root) (beacon.ChainEntry, bool) {
+	return c.entry, root == testBlockRoot
+}
+
+func (c *testChain) FinalizedCheckpoint() common.Checkpoint {
+	return common.Checkpoint{Epoch: 0, Root: testBlockRoot}
+}
+
+func (c *testChain) InSubtree(anchor common.Root, root common.Root) (unknown bool, inSubtree bool) {
+	return false, root == anchor
+}
+
+func (c *testChain) Towards(ctx context.Context, fromBlockRoot common.Root, toSlot common.Slot) (beacon.ChainEntry, error) {
+	return c.entry, nil
+}
+
+type testAggregatesBackend struct {
+	spec        *common.Spec
+	chain       *testChain
+	aggregates  map[common.Root]bool
+	aggregators map[common.ValidatorIndex]bool
+}
+
+func (b *testAggregatesBackend) Spec() *common.Spec {
+	return b.spec
+}
+
+func (b *testAggregatesBackend) Chain() beacon.Chain {
+	return b.chain
+}
+
+func (b *testAggregatesBackend) SlotAfter(delta time.Duration) common.Slot {
+	return 0
+}
+
+func (b *testAggregatesBackend) IsBadBlock(root common.Root) bool {
+	return false
+}
+
+func (b *testAggregatesBackend) SeenAggregate(aggRoot common.Root) bool {
+	return b.aggregates[aggRoot]
+}
+
+func (b *testAggregatesBackend) MarkAggregate(aggRoot common.Root) {
+	b.aggregates[aggRoot] = true
+}
+
+func (b *testAggregatesBackend) SeenAggregator(targetEpoch common.Epoch, aggregator common.ValidatorIndex) bool {
+	return b.aggregators[aggregator]
+}
+
+func (b *testAggregatesBackend) MarkAggregator(targetEpoch common.Epoch, aggregator common.ValidatorIndex) {
+	b.aggregators[aggregator] = true
+}
+
+type testQueueingBackend struct {
+	*testAggregatesBackend
+	queued []common.Root
+}
+
+func (b *testQueueingBackend) QueueUnknownBlock(blockRoot common.Root, msg interface{}) {
+	b.queued = append(b.queued, blockRoot)
+}
+
+type electraAggregateTest struct {
+	spec  *common.Spec
+	keys  interop.Keys
+	epc   *common.EpochsContext
+	state common.BeaconState
+}
+
+func newElectraAggregateTest(t *testing.T) *electraAggregateTest {
+	spec := configs.Minimal
+	keys, err := interop.NewKeys(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &deneb.ExecutionPayloadHeader{BlockHash: common.Root{0xee}, BlockNumber: 1}
+	state, epc, err := electra.KickStartState(spec, common.Root{0xaa}, 1_000_000, keys.KickstartValidators(spec.MAX_EFFECTIVE_BALANCE), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &electraAggregateTest{spec: spec, keys: keys, epc: epc, state: state}
+}
+
+func (at *electraAggregateTest) backend() *testAggregatesBackend {
+	return &testAggregatesBackend{
+		spec:        at.spec,
+		chain:       &testChain{entry: &testEntry{epc: at.epc, state: at.state}},
+		aggregates:  make(map[common.Root]bool),
+		aggregators: make(map[common.ValidatorIndex]bool),
+	}
+}
+
+func (at *electraAggregateTest) sign(t *testing.T, typ common.BLSDomainType, msgRoot common.Root, sk *blsu.SecretKey) common.BLSSignature {
+	dom, err := common.GetDomain(at.state, typ, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return interop.SignRoot(sk, common.ComputeSigningRoot(msgRoot, dom))
+}
+
+// aggregate creates an aggregate of the full committee 0 of slot 0, signed by the first committee member.
+// The modify function may change the aggregate before it is signed.
+func (at *electraAggregateTest) aggregate(t *testing.T, modify func(agg *electra.AggregateAndProof)) *electra.SignedAggregateAndProof {
+	committee, err := at.epc.GetBeaconCommittee(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := phase0.AttestationData{
+		Slot:            0,
+		Index:           0,
+		BeaconBlockRoot: testBlockRoot,
+		Source:          common.Checkpoint{Epoch: 0, Root: testBlockRoot},
+		Target:          common.Checkpoint{Epoch: 0, Root: testBlockRoot},
+	}
+	bitLen := uint64(len(committee))
+	aggBits := make(electra.AttestationBits, bitLen/8+1)
+	aggBits.SetBit(bitLen, true) // bitlist delimiter
+	sigs := make([]*blsu.Signature, 0, len(committee))
+	for i, vi := range committee {
+		aggBits.SetBit(uint64(i), true)
+		sigBytes := at.sign(t, common.DOMAIN_BEACON_ATTESTER, data.HashTreeRoot(tree.GetHashFn()), at.keys[vi].Secret)
+		sig, err := sigBytes.Signature()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs = append(sigs, sig)
+	}
+	aggSig, err := blsu.Aggregate(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	committeeBits := make(electra.CommitteeBits, (uint64(at.spec.MAX_COMMITTEES_PER_SLOT)+7)/8)
+	committeeBits.SetBit(0, true)
+	aggregator := committee[0]
+	agg := &electra.AggregateAndProof{
+		AggregatorIndex: aggregator,
+		Aggregate: electra.Attestation{
+			AggregationBits: aggBits,
+			Data:            data,
+			Signature:       aggSig.Serialize(),
+			CommitteeBits:   committeeBits,
+		},
+		SelectionProof: at.sign(t, common.DOMAIN_SELECTION_PROOF, data.Slot.HashTreeRoot(tree.GetHashFn()), at.keys[aggregator].Secret),
+	}
+	if modify != nil {
+		modify(agg)
+	}
+	return &electra.SignedAggregateAndProof{
+		Message:   *agg,
+		Signature: at.sign(t, common.DOMAIN_AGGREGATE_AND_PROOF, agg.HashTreeRoot(at.spec, tree.GetHashFn()), at.keys[agg.AggregatorIndex].Secret),
+	}
+}
+
+func TestValidateElectraAggregateAndProof(t *testing.T) {
+	at := newElectraAggregateTest(t)
+	aggVal := at.backend()
+	signedAgg := at.aggregate(t, nil)
+	committee, res := ValidateElectraAggregateAndProof(context.Background(), signedAgg, aggVal)
+	if res.Result != ACCEPT {
+		t.Fatalf("expected aggregate to be accepted, got: %v", res)
+	}
+	if bl := signedAgg.Message.Aggregate.AggregationBits.BitLen(); uint64(len(committee)) != bl {
+		t.Fatalf("expected committee of %d validators, got %d", bl, len(committee))
+	}
+	// the aggregator was seen now
+	if _, res := ValidateElectraAggregateAndProof(context.Background(), signedAgg, aggVal); res.Result != IGNORE {
+		t.Fatalf("expected repeated aggregate to be ignored, got: %v", res)
+	}
+}
+
+func TestValidateElectraAggregateAndProofReject(t *testing.T) {
+	at := newElectraAggregateTest(t)
+	for name, modify := range map[string]func(agg *electra.AggregateAndProof){
+		"data index": func(agg *electra.AggregateAndProof) {
+			agg.Aggregate.Data.Index = 1
+		},
+		"no committee bits": func(agg *electra.AggregateAndProof) {
+			agg.Aggregate.CommitteeBits.SetBit(0, false)
+		},
+		"multiple committee bits": func(agg *electra.AggregateAndProof) {
+			agg.Aggregate.CommitteeBits.SetBit(1, true)
+		},
+		"committee index out of range": func(agg *electra.AggregateAndProof) {
+			agg.Aggregate.CommitteeBits.SetBit(0, false)
+			agg.Aggregate.CommitteeBits.SetBit(uint64(at.spec.MAX_COMMITTEES_PER_SLOT)-1, true)
+		},
+		"no participants": func(agg *electra.AggregateAndProof) {
+			bits := agg.Aggregate.AggregationBits
+			for i := uint64(0); i < bits.BitLen(); i++ {
+				bits.SetBit(i, false)
+			}
+		},
+		"aggregation bits length": func(agg *electra.AggregateAndProof) {
+			bitLen := agg.Aggregate.AggregationBits.BitLen() + 1
+			bits := make(electra.AttestationBits, bitLen/8+1)
+			bits.SetBit(bitLen, true)
+			bits.SetBit(0, true)
+			agg.Aggregate.AggregationBits = bits
+		},
+		"aggregate signature": func(agg *electra.AggregateAndProof) {
+			agg.Aggregate.Data.Source.Root = common.Root{0xcc}
+		},
+		"selection proof": func(agg *electra.AggregateAndProof) {
+			agg.SelectionProof = agg.Aggregate.Signature
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			signedAgg := at.aggregate(t, modify)
+			if _, res := ValidateElectraAggregateAndProof(context.Background(), signedAgg, at.backend()); res.Result != REJECT {
+				t.Fatalf("expected aggregate to be rejected, got: %v", res)
+			}
+		})
+	}
+	t.Run("aggregator signature", func(t *testing.T) {
+		signedAgg := at.aggregate(t, nil)
+		signedAgg.Signature = signedAgg.Message.SelectionProof
+		if _, res := ValidateElectraAggregateAndProof(context.Background(), signedAgg, at.backend()); res.Result != REJECT {
+			t.Fatalf("expected aggregate to be rejected, got: %v", res)
+		}
+	})
+}
+
+func TestValidateElectraAggregateAndProofUnknownBlock(t *testing.T) {
+	at := newElectraAggregateTest(t)
+	unknown := common.Root{0xdd}
+	signedAgg := at.aggregate(t, func(agg *electra.AggregateAndProof) {
+		agg.Aggregate.Data.BeaconBlockRoot = unknown
+	})
+	// without a queue the aggregate is only ignored
+	if _, res := ValidateElectraAggregateAndProof(context.Background(), signedAgg, at.backend()); res.Result != IGNORE {
+		t.Fatalf("expected aggregate for unknown block to be ignored, got: %v", res)
+	}
+	aggVal := &testQueueingBackend{testAggregatesBackend: at.backend()}
+	if _, res := ValidateElectraAggregateAndProof(context.Background(), signedAgg, aggVal); res.Result != IGNORE {
+		t.Fatalf("expected aggregate for unknown block to be ignored, got: %v", res)
+	}
+	if len(aggVal.queued) != 1 || aggVal.queued[0] != unknown {
+		t.Fatalf("expected aggregate to be queued for the unknown block, got %v", aggVal.queued)
+	}
+}
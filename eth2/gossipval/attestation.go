@@ -7,6 +7,7 @@ import (
 
 	blsu "github.com/protolambda/bls12-381-util"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 
 	"time"
@@ -35,52 +36,162 @@ func ValidateAttestation(ctx context.Context, subnet uint64, att *phase0.Attesta
 	attVal AttestationValBackend) (comm []common.ValidatorIndex, res GossipValidatorResult) {
 	spec := attVal.Spec()
 
-	targetSlot, err := spec.EpochStartSlot(att.Data.Target.Epoch)
+	targetEpc, res := validateAttestationData(ctx, &att.Data, attVal)
+	if res.Result != ACCEPT {
+		return nil, res
+	}
+
+	// [REJECT] The attestation is unaggregated -- that is, it has exactly one participating validator
+	if participants := att.AggregationBits.OnesCount(); participants != 1 {
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation has too many participants set, expected 1, got %d", participants)}
+	}
+
+	committee, res := validateAttestationCommittee(spec, targetEpc, subnet, &att.Data, att.Data.Index)
+	if res.Result != ACCEPT {
+		return nil, res
+	}
+
+	// [REJECT] The number of aggregation bits matches the committee size -- i.e. len(attestation.aggregation_bits) == len(get_beacon_committee(state, data.slot, data.index))
+	if bl := att.AggregationBits.BitLen(); bl != uint64(len(committee)) {
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation has bitlength %d, but expected %d bits", bl, len(committee))}
+	}
+
+	// [IGNORE] There has been no other valid attestation seen on an attestation subnet that has an identical attestation.data.target.epoch and participating validator index.
+	voter, err := att.AggregationBits.SingleParticipant(committee)
+	if err != nil {
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation was expected to have a single voter, but failed: %w", err)}
+	}
+	if attVal.SeenAttestation(att.Data.Target.Epoch, voter) {
+		return nil, GossipValidatorResult{IGNORE, errors.New("attestation vote was already seen (this attestation may be slashable if signature is valid!)")}
+	}
+
+	// [REJECT] The signature of attestation is valid.
+	if res := verifyAttestationSignature(targetEpc, voter, &att.Data, att.Signature, attVal); res.Result != ACCEPT {
+		return nil, res
+	}
+	attVal.MarkAttestation(att.Data.Target.Epoch, voter)
+	return committee, GossipValidatorResult{ACCEPT, nil}
+}
+
+// ValidateSingleAttestation validates an attestation of a beacon_attestation subnet after the Electra fork.
+// The committee of the attester, and the attestation converted to an aggregatable electra.Attestation, are returned.
+func ValidateSingleAttestation(ctx context.Context, subnet uint64, att *electra.SingleAttestation,
+	attVal AttestationValBackend) (comm []common.ValidatorIndex, out *electra.Attestation, res GossipValidatorResult) {
+	spec := attVal.Spec()
+
+	// [REJECT] attestation.data.index == 0
+	if att.Data.Index != 0 {
+		return nil, nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation data index must be 0, got %d", att.Data.Index)}
+	}
+
+	targetEpc, res := validateAttestationData(ctx, &att.Data, attVal)
+	if res.Result != ACCEPT {
+		return nil, nil, res
+	}
+
+	committee, res := validateAttestationCommittee(spec, targetEpc, subnet, &att.Data, att.CommitteeIndex)
+	if res.Result != ACCEPT {
+		return nil, nil, res
+	}
+
+	// [REJECT] The attester is a member of the committee -- i.e.
+	// attestation.attester_index in get_beacon_committee(state, attestation.data.slot, attestation.committee_index).
+	out, err := att.ToAttestation(spec, committee)
+	if err != nil {
+		return nil, nil, GossipValidatorResult{REJECT, err}
+	}
+
+	// [IGNORE] There has been no other valid attestation seen on an attestation subnet that has an identical
+	// attestation.data.target.epoch and participating validator index.
+	if attVal.SeenAttestation(att.Data.Target.Epoch, att.AttesterIndex) {
+		return nil, nil, GossipValidatorResult{IGNORE, errors.New("attestation vote was already seen (this attestation may be slashable if signature is valid!)")}
+	}
+
+	// [REJECT] The signature of attestation is valid.
+	if res := verifyAttestationSignature(targetEpc, att.AttesterIndex, &att.Data, att.Signature, attVal); res.Result != ACCEPT {
+		return nil, nil, res
+	}
+	attVal.MarkAttestation(att.Data.Target.Epoch, att.AttesterIndex)
+	return committee, out, GossipValidatorResult{ACCEPT, nil}
+}
+
+// ValidateSubnetAttestation validates a message of a beacon_attestation subnet, with the rules of the fork of the attestation:
+// a *phase0.Attestation before the Electra fork, and a *electra.SingleAttestation after.
+// The committee of the attester, and the attestation in the aggregatable form of the fork, are returned.
+func ValidateSubnetAttestation(ctx context.Context, subnet uint64, msg interface{},
+	attVal AttestationValBackend) (comm []common.ValidatorIndex, out interface{}, res GossipValidatorResult) {
+	spec := attVal.Spec()
+	switch att := msg.(type) {
+	case *phase0.Attestation:
+		if epoch := spec.SlotToEpoch(att.Data.Slot); epoch >= spec.ELECTRA_FORK_EPOCH {
+			return nil, nil, GossipValidatorResult{REJECT, fmt.Errorf("phase0 attestation not valid in post-electra epoch %d", epoch)}
+		}
+		comm, res = ValidateAttestation(ctx, subnet, att, attVal)
+		if res.Result != ACCEPT {
+			return nil, nil, res
+		}
+		return comm, att, res
+	case *electra.SingleAttestation:
+		if epoch := spec.SlotToEpoch(att.Data.Slot); epoch < spec.ELECTRA_FORK_EPOCH {
+			return nil, nil, GossipValidatorResult{REJECT, fmt.Errorf("single attestation not valid in pre-electra epoch %d", epoch)}
+		}
+		var converted *electra.Attestation
+		comm, converted, res = ValidateSingleAttestation(ctx, subnet, att, attVal)
+		if res.Result != ACCEPT {
+			return nil, nil, res
+		}
+		return comm, converted, res
+	default:
+		return nil, nil, GossipValidatorResult{REJECT, fmt.Errorf("unrecognized attestation type: %T", msg)}
+	}
+}
+
+// validateAttestationData checks the slot, target and voted-for block of an unaggregated attestation,
+// and returns the EpochsContext of the target, to look up the committees with.
+func validateAttestationData(ctx context.Context, data *phase0.AttestationData, attVal AttestationValBackend) (*common.EpochsContext, GossipValidatorResult) {
+	spec := attVal.Spec()
+
+	targetSlot, err := spec.EpochStartSlot(data.Target.Epoch)
 	if err != nil {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("cannot get start slot of attestation target epoch %d: %w", att.Data.Target.Epoch, err)}
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("cannot get start slot of attestation target epoch %d: %w", data.Target.Epoch, err)}
 	}
 
 	// [IGNORE] attestation.data.slot is within the last ATTESTATION_PROPAGATION_SLOT_RANGE slots
 	// (within a MAXIMUM_GOSSIP_CLOCK_DISPARITY allowance) --
 	// i.e. attestation.data.slot + ATTESTATION_PROPAGATION_SLOT_RANGE >= current_slot >= attestation.data.slot
 
-	if err := CheckSlotSpan(attVal.SlotAfter, att.Data.Slot, ATTESTATION_PROPAGATION_SLOT_RANGE); err != nil {
+	if err := CheckSlotSpan(attVal.SlotAfter, data.Slot, ATTESTATION_PROPAGATION_SLOT_RANGE); err != nil {
 		return nil, GossipValidatorResult{IGNORE, fmt.Errorf("individual attestation not within slot range: %v", err)}
 	}
 
 	// [REJECT] The attestation's epoch matches its target --
 	// i.e. attestation.data.target.epoch == compute_epoch_at_slot(attestation.data.slot)
-	attEpoch := spec.SlotToEpoch(att.Data.Slot)
-	if att.Data.Target.Epoch != attEpoch {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation slot %d is epoch %d and does not match target %d", att.Data.Slot, attEpoch, att.Data.Target.Epoch)}
-	}
-
-	// [REJECT] The attestation is unaggregated -- that is, it has exactly one participating validator
-	if participants := att.AggregationBits.OnesCount(); participants != 1 {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation has too many participants set, expected 1, got %d", participants)}
+	attEpoch := spec.SlotToEpoch(data.Slot)
+	if data.Target.Epoch != attEpoch {
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation slot %d is epoch %d and does not match target %d", data.Slot, attEpoch, data.Target.Epoch)}
 	}
 
 	// [REJECT] The block being voted for (attestation.data.beacon_block_root) passes validation.
-	if attVal.IsBadBlock(att.Data.BeaconBlockRoot) {
+	if attVal.IsBadBlock(data.BeaconBlockRoot) {
 		return nil, GossipValidatorResult{REJECT, errors.New("attestation voted for invalid block")}
 	}
 
 	ch := attVal.Chain()
 	// [IGNORE] The block being voted for (attestation.data.beacon_block_root) has been seen
 	// (via both gossip and non-gossip sources) (a client MAY queue aggregates for processing once block is retrieved).
-	blockRef, ok := ch.ByBlock(att.Data.BeaconBlockRoot)
+	blockRef, ok := ch.ByBlock(data.BeaconBlockRoot)
 	if !ok {
 		return nil, GossipValidatorResult{IGNORE, errors.New("attestation voted for unknown block")}
 	}
 	// TODO: this is a nice sanity check, but not strictly necessary if forkchoice handles it anyway.
-	if refSlot := blockRef.Step().Slot(); refSlot > att.Data.Slot {
+	if refSlot := blockRef.Step().Slot(); refSlot > data.Slot {
 		return nil, GossipValidatorResult{REJECT, errors.New("attestation voted for block in the future")}
 	}
 
 	// [REJECT] The attestation's target block is an ancestor of the block named in the LMD vote --
 	// i.e. get_ancestor(store, attestation.data.beacon_block_root, compute_start_slot_at_epoch(attestation.data.target.epoch))
 	//        == attestation.data.target.root
-	if unknown, inSubtree := ch.InSubtree(att.Data.Target.Root, att.Data.BeaconBlockRoot); unknown {
+	if unknown, inSubtree := ch.InSubtree(data.Target.Root, data.BeaconBlockRoot); unknown {
 		return nil, GossipValidatorResult{IGNORE, errors.New("unknown block and/or target, cannot check if in subtree")}
 	} else if !inSubtree {
 		return nil, GossipValidatorResult{REJECT, errors.New("block not in subtree of target")}
@@ -91,13 +202,13 @@ func ValidateAttestation(ctx context.Context, subnet uint64, att *phase0.Attesta
 	// i.e. get_ancestor(store, attestation.data.beacon_block_root, compute_start_slot_at_epoch(store.finalized_checkpoint.epoch))
 	//        == store.finalized_checkpoint.root
 	fin := ch.FinalizedCheckpoint()
-	if att.Data.BeaconBlockRoot != fin.Root {
-		if unknown, inSubtree := ch.InSubtree(fin.Root, att.Data.BeaconBlockRoot); unknown {
+	if data.BeaconBlockRoot != fin.Root {
+		if unknown, inSubtree := ch.InSubtree(fin.Root, data.BeaconBlockRoot); unknown {
 			return nil, GossipValidatorResult{IGNORE, errors.New("unknown block, cannot check if in subtree")}
 		} else if !inSubtree {
 			return nil, GossipValidatorResult{IGNORE, errors.New("block not in subtree of finalized root")}
 		}
-	} else if fin.Epoch > att.Data.Target.Epoch {
+	} else if fin.Epoch > data.Target.Epoch {
 		return nil, GossipValidatorResult{REJECT, errors.New("cannot vote for finalized root as target")}
 	}
 
@@ -105,80 +216,74 @@ func ValidateAttestation(ctx context.Context, subnet uint64, att *phase0.Attesta
 
 	towardsCtx, cancel := context.WithTimeout(ctx, catchupTimeout)
 	defer cancel()
-	targetRef, err := ch.Towards(towardsCtx, att.Data.Target.Root, targetSlot)
+	targetRef, err := ch.Towards(towardsCtx, data.Target.Root, targetSlot)
 	if err != nil {
-		return nil, GossipValidatorResult{IGNORE, fmt.Errorf("unknown target root %s: %w", att.Data.Target.Root, err)}
+		return nil, GossipValidatorResult{IGNORE, fmt.Errorf("unknown target root %s: %w", data.Target.Root, err)}
 	}
 
 	targetEpc, err := targetRef.EpochsContext(ctx)
 	if err != nil {
-		return nil, GossipValidatorResult{IGNORE, fmt.Errorf("unavailable target epc %s: %w", att.Data.Target.Root, err)}
+		return nil, GossipValidatorResult{IGNORE, fmt.Errorf("unavailable target epc %s: %w", data.Target.Root, err)}
 	}
+	return targetEpc, GossipValidatorResult{ACCEPT, nil}
+}
 
+// validateAttestationCommittee checks the committee index and subnet of an unaggregated attestation,
+// and returns the committee. The committee index is data.index before Electra, and the committee_index field after.
+func validateAttestationCommittee(spec *common.Spec, targetEpc *common.EpochsContext, subnet uint64,
+	data *phase0.AttestationData, index common.CommitteeIndex) ([]common.ValidatorIndex, GossipValidatorResult) {
 	// [REJECT] The committee index is within the expected range --
-	// i.e. data.index < get_committee_count_per_slot(state, data.target.epoch).
-	committeeCountPerSlot, err := targetEpc.GetCommitteeCountPerSlot(att.Data.Target.Epoch)
+	// i.e. index < get_committee_count_per_slot(state, data.target.epoch).
+	committeeCountPerSlot, err := targetEpc.GetCommitteeCountPerSlot(data.Target.Epoch)
 	if err != nil {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("cannot get committee count for slot %d: %w", att.Data.Slot, err)}
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("cannot get committee count for slot %d: %w", data.Slot, err)}
 	}
-	if uint64(att.Data.Index) >= committeeCountPerSlot {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("committee index %d out of range %d", att.Data.Index, committeeCountPerSlot)}
+	if uint64(index) >= committeeCountPerSlot {
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("committee index %d out of range %d", index, committeeCountPerSlot)}
 	}
 
 	// [REJECT] The attestation is for the correct subnet --
-	// i.e. compute_subnet_for_attestation(committees_per_slot, attestation.data.slot, attestation.data.index)
+	// i.e. compute_subnet_for_attestation(committees_per_slot, attestation.data.slot, index)
 	//   == subnet_id, where committees_per_slot = get_committee_count_per_slot(state, attestation.data.target.epoch)
-	assignedSubnet, err := phase0.ComputeSubnetForAttestation(spec, committeeCountPerSlot, att.Data.Slot, att.Data.Index)
+	assignedSubnet, err := phase0.ComputeSubnetForAttestation(spec, committeeCountPerSlot, data.Slot, index)
 	if err != nil {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("cannot get subnet for attestation (slot %d, committee index %d): %w", att.Data.Slot, att.Data.Index, err)}
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("cannot get subnet for attestation (slot %d, committee index %d): %w", data.Slot, index, err)}
 	}
 	if subnet != assignedSubnet {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation (slot %d, committee index %d) received on subnet %d, but should be on subnet %d", att.Data.Slot, att.Data.Index, subnet, assignedSubnet)}
+		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation (slot %d, committee index %d) received on subnet %d, but should be on subnet %d", data.Slot, index, subnet, assignedSubnet)}
 	}
 
-	// [REJECT] The number of aggregation bits matches the committee size -- i.e. len(attestation.aggregation_bits) == len(get_beacon_committee(state, data.slot, data.index))
-	committee, err := targetEpc.GetBeaconCommittee(att.Data.Slot, att.Data.Index)
+	committee, err := targetEpc.GetBeaconCommittee(data.Slot, index)
 	if err != nil {
 		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation was validated, but committee is not available: %w", err)}
 	}
+	return committee, GossipValidatorResult{ACCEPT, nil}
+}
 
-	if bl := att.AggregationBits.BitLen(); bl != uint64(len(committee)) {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation has bitlength %d, but expected %d bits", bl, len(committee))}
-	}
-
-	// [IGNORE] There has been no other valid attestation seen on an attestation subnet that has an identical attestation.data.target.epoch and participating validator index.
-	voter, err := att.AggregationBits.SingleParticipant(committee)
-	if err != nil {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("attestation was expected to have a single voter, but failed: %w", err)}
-	}
-	if attVal.SeenAttestation(att.Data.Target.Epoch, voter) {
-		return nil, GossipValidatorResult{IGNORE, errors.New("attestation vote was already seen (this attestation may be slashable if signature is valid!)")}
-	}
-
-	// [REJECT] The signature of attestation is valid.
-
+// verifyAttestationSignature verifies the signature of an unaggregated attestation by a member of the committee.
+func verifyAttestationSignature(targetEpc *common.EpochsContext, voter common.ValidatorIndex,
+	data *phase0.AttestationData, signature common.BLSSignature, attVal AttestationValBackend) GossipValidatorResult {
 	// We already know that the voter is part of the committee in the target epoch,
 	// we can just hit the cache without further checking the validator index.
 	pubkey, ok := targetEpc.ValidatorPubkeyCache.Pubkey(voter)
 	if !ok {
-		return nil, GossipValidatorResult{IGNORE, errors.New("failed to find pubkey for voter, cache is wrong")}
+		return GossipValidatorResult{IGNORE, errors.New("failed to find pubkey for voter, cache is wrong")}
 	}
-	dom, err := attVal.GetDomain(common.DOMAIN_BEACON_ATTESTER, att.Data.Target.Epoch)
+	dom, err := attVal.GetDomain(common.DOMAIN_BEACON_ATTESTER, data.Target.Epoch)
 	if err != nil {
-		return nil, GossipValidatorResult{IGNORE, errors.New("failed to get domain info for signature check")}
+		return GossipValidatorResult{IGNORE, errors.New("failed to get domain info for signature check")}
 	}
-	sigRoot := common.ComputeSigningRoot(att.Data.HashTreeRoot(tree.GetHashFn()), dom)
-	sig, err := att.Signature.Signature()
+	sigRoot := common.ComputeSigningRoot(data.HashTreeRoot(tree.GetHashFn()), dom)
+	sig, err := signature.Signature()
 	if err != nil {
-		return nil, GossipValidatorResult{REJECT, fmt.Errorf("failed to deserialize attestation signature: %v", err)}
+		return GossipValidatorResult{REJECT, fmt.Errorf("failed to deserialize attestation signature: %v", err)}
 	}
 	blsPub, err := pubkey.Pubkey()
 	if err != nil {
-		return nil, GossipValidatorResult{IGNORE, fmt.Errorf("failed to deserialize cached pubkey: %v", err)}
+		return GossipValidatorResult{IGNORE, fmt.Errorf("failed to deserialize cached pubkey: %v", err)}
 	}
 	if !blsu.Verify(blsPub, sigRoot[:], sig) {
-		return nil, GossipValidatorResult{REJECT, errors.New("invalid attestation signature")}
+		return GossipValidatorResult{REJECT, errors.New("invalid attestation signature")}
 	}
-	attVal.MarkAttestation(att.Data.Target.Epoch, voter)
-	return committee, GossipValidatorResult{ACCEPT, nil}
+	return GossipValidatorResult{ACCEPT, nil}
 }
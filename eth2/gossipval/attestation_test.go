@@ -0,0 +1,197 @@
+package gossipval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+type testAttestationsBackend struct {
+	*testAggregatesBackend
+	attestations map[common.ValidatorIndex]bool
+}
+
+func (b *testAttestationsBackend) GetDomain(typ common.BLSDomainType, epoch common.Epoch) (common.BLSDomain, error) {
+	return common.GetDomain(b.chain.entry.state, typ, epoch)
+}
+
+func (b *testAttestationsBackend) SeenAttestation(targetEpoch common.Epoch, voter common.ValidatorIndex) bool {
+	return b.attestations[voter]
+}
+
+func (b *testAttestationsBackend) MarkAttestation(targetEpoch common.Epoch, voter common.ValidatorIndex) {
+	b.attestations[voter] = true
+}
+
+// attestationBackend creates a backend with the given Electra fork epoch, on top of the genesis state of the test.
+func (at *electraAggregateTest) attestationBackend(electraForkEpoch common.Epoch) *testAttestationsBackend {
+	b := at.backend()
+	spec := *at.spec
+	spec.ELECTRA_FORK_EPOCH = electraForkEpoch
+	b.spec = &spec
+	return &testAttestationsBackend{
+		testAggregatesBackend: b,
+		attestations:          make(map[common.ValidatorIndex]bool),
+	}
+}
+
+func (at *electraAggregateTest) attestationData() phase0.AttestationData {
+	return phase0.AttestationData{
+		Slot:            0,
+		Index:           0,
+		BeaconBlockRoot: testBlockRoot,
+		Source:          common.Checkpoint{Epoch: 0, Root: testBlockRoot},
+		Target:          common.Checkpoint{Epoch: 0, Root: testBlockRoot},
+	}
+}
+
+// singleAttestation creates an attestation of the first member of committee 0 of slot 0, signed by the attester.
+// The modify function may change the attestation after it is signed.
+func (at *electraAggregateTest) singleAttestation(t *testing.T, modify func(att *electra.SingleAttestation)) *electra.SingleAttestation {
+	committee, err := at.epc.GetBeaconCommittee(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := at.attestationData()
+	att := &electra.SingleAttestation{
+		CommitteeIndex: 0,
+		AttesterIndex:  committee[0],
+		Data:           data,
+		Signature:      at.sign(t, common.DOMAIN_BEACON_ATTESTER, data.HashTreeRoot(tree.GetHashFn()), at.keys[committee[0]].Secret),
+	}
+	if modify != nil {
+		modify(att)
+	}
+	return att
+}
+
+// phase0Attestation creates an attestation of the first member of committee 0 of slot 0, signed by the attester.
+func (at *electraAggregateTest) phase0Attestation(t *testing.T) *phase0.Attestation {
+	committee, err := at.epc.GetBeaconCommittee(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := at.attestationData()
+	bitLen := uint64(len(committee))
+	aggBits := make(phase0.AttestationBits, bitLen/8+1)
+	aggBits.SetBit(bitLen, true) // bitlist delimiter
+	aggBits.SetBit(0, true)
+	return &phase0.Attestation{
+		AggregationBits: aggBits,
+		Data:            data,
+		Signature:       at.sign(t, common.DOMAIN_BEACON_ATTESTER, data.HashTreeRoot(tree.GetHashFn()), at.keys[committee[0]].Secret),
+	}
+}
+
+func TestValidateSingleAttestation(t *testing.T) {
+	at := newElectraAggregateTest(t)
+	attVal := at.attestationBackend(0)
+	att := at.singleAttestation(t, nil)
+	committee, out, res := ValidateSingleAttestation(context.Background(), 0, att, attVal)
+	if res.Result != ACCEPT {
+		t.Fatalf("expected attestation to be accepted, got: %v", res)
+	}
+	if voter, err := out.AggregationBits.SingleParticipant(committee); err != nil {
+		t.Fatal(err)
+	} else if voter != att.AttesterIndex {
+		t.Fatalf("expected attester %d as participant, got %d", att.AttesterIndex, voter)
+	}
+	if indices := out.CommitteeBits.CommitteeIndices(); len(indices) != 1 || indices[0] != 0 {
+		t.Fatalf("expected only committee 0 to be set, got %v", indices)
+	}
+	if out.Data != att.Data || out.Signature != att.Signature {
+		t.Fatal("expected converted attestation to keep the data and signature")
+	}
+	// the attester was seen now
+	if _, _, res := ValidateSingleAttestation(context.Background(), 0, att, attVal); res.Result != IGNORE {
+		t.Fatalf("expected repeated attestation to be ignored, got: %v", res)
+	}
+}
+
+func TestValidateSingleAttestationReject(t *testing.T) {
+	at := newElectraAggregateTest(t)
+	committeeCount, err := at.epc.GetCommitteeCountPerSlot(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	committee, err := at.epc.GetBeaconCommittee(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, modify := range map[string]func(att *electra.SingleAttestation){
+		"data index": func(att *electra.SingleAttestation) {
+			att.Data.Index = 1
+		},
+		"committee index out of range": func(att *electra.SingleAttestation) {
+			att.CommitteeIndex = common.CommitteeIndex(committeeCount)
+		},
+		"attester not in committee": func(att *electra.SingleAttestation) {
+			for vi := common.ValidatorIndex(0); ; vi++ {
+				member := false
+				for _, m := range committee {
+					member = member || m == vi
+				}
+				if !member {
+					att.AttesterIndex = vi
+					return
+				}
+			}
+		},
+		"signature": func(att *electra.SingleAttestation) {
+			att.Signature = at.sign(t, common.DOMAIN_BEACON_ATTESTER, att.Data.HashTreeRoot(tree.GetHashFn()), at.keys[committee[1]].Secret)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			att := at.singleAttestation(t, modify)
+			if _, _, res := ValidateSingleAttestation(context.Background(), 0, att, at.attestationBackend(0)); res.Result != REJECT {
+				t.Fatalf("expected attestation to be rejected, got: %v", res)
+			}
+		})
+	}
+	t.Run("subnet", func(t *testing.T) {
+		att := at.singleAttestation(t, nil)
+		if _, _, res := ValidateSingleAttestation(context.Background(), 1, att, at.attestationBackend(0)); res.Result != REJECT {
+			t.Fatalf("expected attestation to be rejected, got: %v", res)
+		}
+	})
+}
+
+func TestValidateSubnetAttestation(t *testing.T) {
+	at := newElectraAggregateTest(t)
+	t.Run("single attestation after electra", func(t *testing.T) {
+		_, out, res := ValidateSubnetAttestation(context.Background(), 0, at.singleAttestation(t, nil), at.attestationBackend(0))
+		if res.Result != ACCEPT {
+			t.Fatalf("expected attestation to be accepted, got: %v", res)
+		}
+		if _, ok := out.(*electra.Attestation); !ok {
+			t.Fatalf("expected electra attestation, got %T", out)
+		}
+	})
+	t.Run("single attestation before electra", func(t *testing.T) {
+		_, _, res := ValidateSubnetAttestation(context.Background(), 0, at.singleAttestation(t, nil), at.attestationBackend(1))
+		if res.Result != REJECT {
+			t.Fatalf("expected attestation to be rejected, got: %v", res)
+		}
+	})
+	t.Run("phase0 attestation before electra", func(t *testing.T) {
+		att := at.phase0Attestation(t)
+		_, out, res := ValidateSubnetAttestation(context.Background(), 0, att, at.attestationBackend(1))
+		if res.Result != ACCEPT {
+			t.Fatalf("expected attestation to be accepted, got: %v", res)
+		}
+		if out != att {
+			t.Fatalf("expected the phase0 attestation to be returned, got %v", out)
+		}
+	})
+	t.Run("phase0 attestation after electra", func(t *testing.T) {
+		_, _, res := ValidateSubnetAttestation(context.Background(), 0, at.phase0Attestation(t), at.attestationBackend(0))
+		if res.Result != REJECT {
+			t.Fatalf("expected attestation to be rejected, got: %v", res)
+		}
+	})
+}
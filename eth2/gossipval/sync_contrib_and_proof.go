@@ -58,7 +58,7 @@ func ValidateSyncContribAndProof(ctx context.Context, signedContribAndProof *alt
 
 	// [REJECT] The aggregator's validator index is in the declared subcommittee of the current sync committee --
 	// i.e. state.validators[contribution_and_proof.aggregator_index].pubkey in get_sync_subcommittee_pubkeys(state, contribution.subcommittee_index).
-	pubs, indices, err := epc.CurrentSyncCommittee.Subcommittee(spec, uint64(contrib.SubcommitteeIndex))
+	_, indices, err := epc.CurrentSyncCommittee.Subcommittee(spec, uint64(contrib.SubcommitteeIndex))
 	if err != nil {
 		return nil, GossipValidatorResult{REJECT, err}
 	} else {
@@ -98,7 +98,7 @@ func ValidateSyncContribAndProof(ctx context.Context, signedContribAndProof *alt
 
 	// [REJECT] The aggregate signature is valid for the message beacon_block_root and aggregate pubkey
 	// derived from the participation info in aggregation_bits for the subcommittee specified by the contribution.subcommittee_index.
-	if err := contribAndProof.Contribution.VerifySignature(spec, pubs, scpVal.GetDomain); err != nil {
+	if err := contribAndProof.Contribution.VerifySignatureAggregated(spec, epc.CurrentSyncCommittee, scpVal.GetDomain); err != nil {
 		return nil, GossipValidatorResult{REJECT, fmt.Errorf("invalid sync contribution signature: %v", err)}
 	}
 
@@ -0,0 +1,93 @@
+package interop
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// curveOrder is the order r of the BLS12-381 curve subgroup.
+var curveOrder, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+// SecretKey derives the deterministic interop secret key of the validator with the given index:
+// the little-endian sha256 of the 32-byte little-endian index, modulo the curve order.
+func SecretKey(index uint64) (*blsu.SecretKey, error) {
+	var in [32]byte
+	binary.LittleEndian.PutUint64(in[:8], index)
+	h := sha256.Sum256(in[:])
+	// reverse to big-endian
+	for i := 0; i < 16; i++ {
+		h[i], h[31-i] = h[31-i], h[i]
+	}
+	x := new(big.Int).SetBytes(h[:])
+	x.Mod(x, curveOrder)
+	var out [32]byte
+	x.FillBytes(out[:])
+	var sk blsu.SecretKey
+	if err := sk.Deserialize(&out); err != nil {
+		return nil, fmt.Errorf("invalid interop secret key %d: %v", index, err)
+	}
+	return &sk, nil
+}
+
+// Key is a deterministic interop validator key.
+type Key struct {
+	Secret *blsu.SecretKey
+	Pubkey common.BLSPubkey
+}
+
+// Keys are the interop keys of the first validators, in validator index order.
+type Keys []Key
+
+// NewKeys derives the interop keys of the first count validators.
+func NewKeys(count uint64) (Keys, error) {
+	out := make(Keys, 0, count)
+	for i := uint64(0); i < count; i++ {
+		sk, err := SecretKey(i)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := blsu.SkToPk(sk)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Key{Secret: sk, Pubkey: pub.Serialize()})
+	}
+	return out, nil
+}
+
+// BLSWithdrawalCredentials computes the 0x00 withdrawal credentials of the pubkey.
+func BLSWithdrawalCredentials(pub common.BLSPubkey) (out common.Root) {
+	out = sha256.Sum256(pub[:])
+	out[0] = common.BLS_WITHDRAWAL_PREFIX
+	return out
+}
+
+// KickstartValidators creates the genesis validator data of the keys, with BLS withdrawal credentials,
+// to build a genesis state with phase0.KickStartState or the equivalent of a later fork.
+func (keys Keys) KickstartValidators(balance common.Gwei) []phase0.KickstartValidatorData {
+	out := make([]phase0.KickstartValidatorData, 0, len(keys))
+	for i := range keys {
+		out = append(out, phase0.KickstartValidatorData{
+			Pubkey:                keys[i].Pubkey,
+			WithdrawalCredentials: BLSWithdrawalCredentials(keys[i].Pubkey),
+			Balance:               balance,
+		})
+	}
+	return out
+}
+
+// SecretKeys returns the serialized secret keys, e.g. for phase0.KickStartStateWithSignatures.
+func (keys Keys) SecretKeys() [][32]byte {
+	out := make([][32]byte, 0, len(keys))
+	for i := range keys {
+		out = append(out, keys[i].Secret.Serialize())
+	}
+	return out
+}
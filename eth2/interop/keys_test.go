@@ -0,0 +1,139 @@
+package interop
+
+import (
+	"context"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestInteropKeys(t *testing.T) {
+	keys, err := NewKeys(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		secret string
+		pubkey string
+	}{
+		{
+			"0x25295f0d1d592a90b333e26e85149708208e9f8e8bc18f6c77bd62f8ad7a6866",
+			"0xa99a76ed7796f7be22d5b7e85deeb7c5677e88e511e0b337618f8c4eb61349b4bf2d153f649f7b53359fe8b94a38e44c",
+		},
+		{
+			"0x51d0b65185db6989ab0b560d6deed19c7ead0e24b9b6372cbecb1f26bdfad000",
+			"0xb89bebc699769726a318c8e9971bd3171297c61aea4a6578a7a4f94b547dcba5bac16a89108b6b6a1fe3695d1a874a0b",
+		},
+	}
+	for i, exp := range expected {
+		secret := keys[i].Secret.Serialize()
+		if s := common.Root(secret).String(); s != exp.secret {
+			t.Errorf("key %d: unexpected secret key %s", i, s)
+		}
+		if s := keys[i].Pubkey.String(); s != exp.pubkey {
+			t.Errorf("key %d: unexpected pubkey %s", i, s)
+		}
+	}
+}
+
+func TestSigner(t *testing.T) {
+	// start the fork schedule at Altair, like the genesis state
+	specCopy := *configs.Minimal
+	specCopy.ALTAIR_FORK_EPOCH = 0
+	spec := &specCopy
+	keys, err := NewKeys(uint64(spec.SLOTS_PER_EPOCH) * 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, epc, err := altair.KickStartState(spec, common.Root{0xaa}, 1_000_000, keys.KickstartValidators(spec.MAX_EFFECTIVE_BALANCE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gvr, err := state.GenesisValidatorsRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := &Signer{Spec: spec, GenesisValidatorsRoot: gvr}
+
+	// the fork schedule domain matches the domain of the state
+	for _, typ := range []common.BLSDomainType{common.DOMAIN_BEACON_PROPOSER, common.DOMAIN_RANDAO, common.DOMAIN_SYNC_COMMITTEE} {
+		expected, err := common.GetDomain(state, typ, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dom, err := signer.Domain(typ, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dom != expected {
+			t.Fatalf("domain %s does not match state domain %s", dom, expected)
+		}
+	}
+
+	// a RANDAO reveal is accepted by the state transition
+	proposer, err := epc.GetBeaconProposer(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reveal, err := signer.SignRandaoReveal(keys[proposer].Secret, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := phase0.ProcessRandaoReveal(context.Background(), spec, epc, state, reveal); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := altair.SyncCommitteeMessage{Slot: 3, BeaconBlockRoot: common.Root{0xbb}, ValidatorIndex: 5}
+	msg.Signature, err = signer.SignSyncCommitteeMessage(keys[5].Secret, msg.Slot, msg.BeaconBlockRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := msg.VerifySignature(spec, epc, signer.Domain); err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := signer.SignSyncCommitteeSelectionProof(keys[2].Secret, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := altair.ValidateSyncAggregatorSelectionProof(spec, epc, signer.Domain, 2, proof, 3, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	data := phase0.AttestationData{Slot: 1, Target: common.Checkpoint{Epoch: 0, Root: common.Root{0xcc}}}
+	sig, err := signer.SignAttestationData(keys[3].Secret, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dom, err := common.GetDomain(state, common.DOMAIN_BEACON_ATTESTER, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSignature(t, keys[3].Pubkey, common.ComputeSigningRoot(data.HashTreeRoot(tree.GetHashFn()), dom), sig)
+
+	dep := common.DepositData{Pubkey: keys[1].Pubkey, WithdrawalCredentials: BLSWithdrawalCredentials(keys[1].Pubkey), Amount: 1}
+	sig = signer.SignDepositData(keys[1].Secret, &dep)
+	depDom := common.ComputeDomain(common.DOMAIN_DEPOSIT, spec.GENESIS_FORK_VERSION, common.Root{})
+	checkSignature(t, keys[1].Pubkey, common.ComputeSigningRoot(dep.MessageRoot(), depDom), sig)
+}
+
+func checkSignature(t *testing.T, pub common.BLSPubkey, signingRoot common.Root, sig common.BLSSignature) {
+	t.Helper()
+	blsPub, err := pub.Pubkey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blsSig, err := sig.Signature()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !blsu.Verify(blsPub, signingRoot[:], blsSig) {
+		t.Fatal("invalid signature")
+	}
+}
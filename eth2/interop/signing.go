@@ -0,0 +1,115 @@
+package interop
+
+import (
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// Signer signs validator messages, with the domains of the fork schedule of the spec.
+type Signer struct {
+	Spec                  *common.Spec
+	GenesisValidatorsRoot common.Root
+}
+
+// Domain computes the domain of a message with the fork version of the message epoch,
+// following the fork schedule of the spec. It can be used as common.BLSDomainFn.
+func (s *Signer) Domain(typ common.BLSDomainType, epoch common.Epoch) (common.BLSDomain, error) {
+	slot, err := s.Spec.EpochStartSlot(epoch)
+	if err != nil {
+		return common.BLSDomain{}, err
+	}
+	return common.ComputeDomain(typ, s.Spec.ForkVersion(slot), s.GenesisValidatorsRoot), nil
+}
+
+// SignRoot signs the signing root, i.e. the message root already combined with its domain.
+func SignRoot(sk *blsu.SecretKey, signingRoot common.Root) common.BLSSignature {
+	return blsu.Sign(sk, signingRoot[:]).Serialize()
+}
+
+// Sign signs the message root with the domain of the given type and message epoch.
+func (s *Signer) Sign(sk *blsu.SecretKey, typ common.BLSDomainType, epoch common.Epoch, msgRoot common.Root) (common.BLSSignature, error) {
+	dom, err := s.Domain(typ, epoch)
+	if err != nil {
+		return common.BLSSignature{}, err
+	}
+	return SignRoot(sk, common.ComputeSigningRoot(msgRoot, dom)), nil
+}
+
+// SignBlock signs a beacon block of any fork, by its root, as proposer.
+func (s *Signer) SignBlock(sk *blsu.SecretKey, slot common.Slot, blockRoot common.Root) (common.BLSSignature, error) {
+	return s.Sign(sk, common.DOMAIN_BEACON_PROPOSER, s.Spec.SlotToEpoch(slot), blockRoot)
+}
+
+// SignRandaoReveal signs the epoch for the RANDAO reveal of a block.
+func (s *Signer) SignRandaoReveal(sk *blsu.SecretKey, epoch common.Epoch) (common.BLSSignature, error) {
+	return s.Sign(sk, common.DOMAIN_RANDAO, epoch, epoch.HashTreeRoot(tree.GetHashFn()))
+}
+
+// SignAttestationData signs the attestation data, for an attestation of any fork.
+func (s *Signer) SignAttestationData(sk *blsu.SecretKey, data *phase0.AttestationData) (common.BLSSignature, error) {
+	return s.Sign(sk, common.DOMAIN_BEACON_ATTESTER, data.Target.Epoch, data.HashTreeRoot(tree.GetHashFn()))
+}
+
+// SignSelectionProof signs the slot, to select attestation aggregators.
+func (s *Signer) SignSelectionProof(sk *blsu.SecretKey, slot common.Slot) (common.BLSSignature, error) {
+	sigRoot, err := phase0.AggregateSelectionProofSigningRoot(s.Spec, s.Domain, slot)
+	if err != nil {
+		return common.BLSSignature{}, err
+	}
+	return SignRoot(sk, sigRoot), nil
+}
+
+// SignAggregateAndProof signs an aggregate-and-proof of any fork, by its root,
+// with the target epoch of the aggregate attestation.
+func (s *Signer) SignAggregateAndProof(sk *blsu.SecretKey, targetEpoch common.Epoch, aggregateAndProofRoot common.Root) (common.BLSSignature, error) {
+	return s.Sign(sk, common.DOMAIN_AGGREGATE_AND_PROOF, targetEpoch, aggregateAndProofRoot)
+}
+
+// SignSyncCommitteeMessage signs the block root of the slot, as sync committee member.
+func (s *Signer) SignSyncCommitteeMessage(sk *blsu.SecretKey, slot common.Slot, blockRoot common.Root) (common.BLSSignature, error) {
+	return s.Sign(sk, common.DOMAIN_SYNC_COMMITTEE, s.Spec.SlotToEpoch(slot), blockRoot)
+}
+
+// SignSyncCommitteeSelectionProof signs the slot and subcommittee, to select sync committee aggregators.
+func (s *Signer) SignSyncCommitteeSelectionProof(sk *blsu.SecretKey, slot common.Slot, subcommitteeIndex uint64) (common.BLSSignature, error) {
+	sigRoot, err := altair.SyncAggregatorSelectionSigningRoot(s.Spec, s.Domain, slot, subcommitteeIndex)
+	if err != nil {
+		return common.BLSSignature{}, err
+	}
+	return SignRoot(sk, sigRoot), nil
+}
+
+// SignContributionAndProof signs the sync committee contribution-and-proof, as aggregator.
+func (s *Signer) SignContributionAndProof(sk *blsu.SecretKey, msg *altair.ContributionAndProof) (common.BLSSignature, error) {
+	return s.Sign(sk, common.DOMAIN_CONTRIBUTION_AND_PROOF, s.Spec.SlotToEpoch(msg.Contribution.Slot),
+		msg.HashTreeRoot(s.Spec, tree.GetHashFn()))
+}
+
+// SignVoluntaryExit signs the voluntary exit.
+// Exits that can only be included since Deneb are signed with the Capella fork version (EIP-7044).
+// Exits of an earlier epoch are signed with the fork version of the exit epoch,
+// which is only valid for inclusion before Deneb.
+func (s *Signer) SignVoluntaryExit(sk *blsu.SecretKey, exit *phase0.VoluntaryExit) (common.BLSSignature, error) {
+	msgRoot := exit.HashTreeRoot(tree.GetHashFn())
+	if exit.Epoch >= s.Spec.DENEB_FORK_EPOCH {
+		dom := common.ComputeDomain(common.DOMAIN_VOLUNTARY_EXIT, s.Spec.CAPELLA_FORK_VERSION, s.GenesisValidatorsRoot)
+		return SignRoot(sk, common.ComputeSigningRoot(msgRoot, dom)), nil
+	}
+	return s.Sign(sk, common.DOMAIN_VOLUNTARY_EXIT, exit.Epoch, msgRoot)
+}
+
+// SignBLSToExecutionChange signs the withdrawal credentials change, with the genesis fork version.
+func (s *Signer) SignBLSToExecutionChange(sk *blsu.SecretKey, change *common.BLSToExecutionChange) common.BLSSignature {
+	dom := common.ComputeDomain(common.DOMAIN_BLS_TO_EXECUTION_CHANGE, s.Spec.GENESIS_FORK_VERSION, s.GenesisValidatorsRoot)
+	return SignRoot(sk, common.ComputeSigningRoot(change.HashTreeRoot(tree.GetHashFn()), dom))
+}
+
+// SignDepositData signs the deposit data with the fork-agnostic deposit domain, as proof of possession.
+func (s *Signer) SignDepositData(sk *blsu.SecretKey, data *common.DepositData) common.BLSSignature {
+	dom := common.ComputeDomain(common.DOMAIN_DEPOSIT, s.Spec.GENESIS_FORK_VERSION, common.Root{})
+	return SignRoot(sk, common.ComputeSigningRoot(data.MessageRoot(), dom))
+}
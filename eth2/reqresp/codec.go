@@ -0,0 +1,123 @@
+package reqresp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/protolambda/ztyp/codec"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// snappy framing: a stream identifier chunk, and a 4 byte header and 4 byte checksum per chunk
+const (
+	snappyStreamIdentifierLen = 10
+	snappyChunkOverhead       = 4 + 4
+	snappyMaxBlockSize        = 1 << 16
+)
+
+// MaxCompressedLen is the maximum size of the framed snappy encoding of n bytes.
+// Each chunk of at most 64 KiB compresses to at most 32 + len + len/6 bytes.
+func MaxCompressedLen(n uint64) uint64 {
+	chunks := (n + snappyMaxBlockSize - 1) / snappyMaxBlockSize
+	return snappyStreamIdentifierLen + chunks*(snappyChunkOverhead+32) + n + n/6
+}
+
+// EncodePayload writes the uvarint length prefix of the SSZ encoding of obj,
+// followed by the SSZ encoding itself, compressed with framed snappy.
+func EncodePayload(w io.Writer, spec *common.Spec, obj codec.Serializable) error {
+	n := obj.ByteLength()
+	if n > uint64(spec.MAX_PAYLOAD_SIZE) {
+		return fmt.Errorf("payload of %d bytes exceeds MAX_PAYLOAD_SIZE %d", n, spec.MAX_PAYLOAD_SIZE)
+	}
+	var prefix [binary.MaxVarintLen64]byte
+	if _, err := w.Write(prefix[:binary.PutUvarint(prefix[:], n)]); err != nil {
+		return fmt.Errorf("failed to write length prefix: %w", err)
+	}
+	if n == 0 {
+		return nil
+	}
+	sw := snappy.NewBufferedWriter(w)
+	ew := codec.NewEncodingWriter(sw)
+	if err := obj.Serialize(ew); err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	if written := uint64(ew.Written()); written != n {
+		return fmt.Errorf("payload encoding has %d bytes, but declared length is %d", written, n)
+	}
+	// Close flushes the last snappy frame, it does not close w.
+	return sw.Close()
+}
+
+// DecodePayload reads a length-prefixed framed snappy payload into dst.
+// The declared length must not exceed maxLen or MAX_PAYLOAD_SIZE, and must match the size of fixed-length types.
+// No more is read from r than the payload itself, so subsequent response chunks can be read from the same stream.
+func DecodePayload(r io.Reader, spec *common.Spec, dst codec.Deserializable, maxLen uint64) error {
+	n, err := readUvarint(r)
+	if err != nil {
+		return fmt.Errorf("failed to read length prefix: %w", err)
+	}
+	if n > uint64(spec.MAX_PAYLOAD_SIZE) {
+		return fmt.Errorf("declared payload length %d exceeds MAX_PAYLOAD_SIZE %d", n, spec.MAX_PAYLOAD_SIZE)
+	}
+	if n > maxLen {
+		return fmt.Errorf("declared payload length %d exceeds limit %d", n, maxLen)
+	}
+	if fixed := dst.FixedLength(); fixed != 0 && fixed != n {
+		return fmt.Errorf("declared payload length %d does not match fixed length %d", n, fixed)
+	}
+	if n == 0 {
+		return dst.Deserialize(codec.NewDecodingReader(bytes.NewReader(nil), 0))
+	}
+	sr := &countingReader{r: snappy.NewReader(io.LimitReader(r, int64(MaxCompressedLen(n))))}
+	dr := codec.NewDecodingReader(sr, n)
+	if err := dst.Deserialize(dr); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("failed to decode payload: %w", err)
+	}
+	if sr.n != n {
+		return fmt.Errorf("decoded %d bytes, but declared length is %d", sr.n, n)
+	}
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
+
+// readUvarint reads a uvarint byte by byte, to not consume any of the payload after it.
+func readUvarint(r io.Reader) (uint64, error) {
+	var x uint64
+	var s uint
+	var b [1]byte
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		if b[0] < 0x80 {
+			if i == binary.MaxVarintLen64-1 && b[0] > 1 {
+				return 0, errors.New("uvarint overflows 64 bits")
+			}
+			return x | uint64(b[0])<<s, nil
+		}
+		x |= uint64(b[0]&0x7f) << s
+		s += 7
+	}
+	return 0, errors.New("uvarint overflows 64 bits")
+}
@@ -0,0 +1,244 @@
+package reqresp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+var spec = configs.Mainnet
+
+// defaultObj decodes the SSZ default of typ into dst, to get correctly sized bitfields and vectors.
+func defaultObj(t *testing.T, typ *view.ContainerTypeDef, dst common.SpecObj) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := typ.New().Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Deserialize(spec, codec.NewDecodingReader(&buf, uint64(buf.Len()))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatusRoundTrip(t *testing.T) {
+	req := common.Status{
+		ForkDigest:     common.ForkDigest{1, 2, 3, 4},
+		FinalizedRoot:  common.Root{0xaa},
+		FinalizedEpoch: 123,
+		HeadRoot:       common.Root{0xbb},
+		HeadSlot:       4567,
+	}
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- EncodePayload(client, spec, &req)
+	}()
+	var got common.Status
+	if err := DecodePayload(server, spec, &got, common.StatusByteLen); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if got != req {
+		t.Fatalf("got %s, expected %s", &got, &req)
+	}
+
+	// response with the same type, over the same stream
+	go func() {
+		errs <- WriteResponseChunk(server, spec, &req)
+	}()
+	var resp common.Status
+	if err := ReadResponseChunk(client, spec, &resp, common.StatusByteLen); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if resp != req {
+		t.Fatalf("got %s, expected %s", &resp, &req)
+	}
+}
+
+func TestBlocksByRootRequestRoundTrip(t *testing.T) {
+	req := common.BeaconBlocksByRootRequest{{1}, {2}, {3}}
+	var buf bytes.Buffer
+	if err := EncodePayload(&buf, spec, spec.Wrap(&req)); err != nil {
+		t.Fatal(err)
+	}
+	var got common.BeaconBlocksByRootRequest
+	if err := DecodePayload(&buf, spec, spec.Wrap(&got), uint64(spec.MAX_REQUEST_BLOCKS)*32); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(req) || got[2] != req[2] {
+		t.Fatalf("unexpected roots: %v", got)
+	}
+}
+
+func TestBlockResponseChunks(t *testing.T) {
+	d := beacon.NewForkDecoder(spec, common.Root{0x42})
+	phase0Block := new(phase0.SignedBeaconBlock)
+	defaultObj(t, phase0.SignedBeaconBlockType(spec), phase0Block)
+	phase0Block.Message.Slot = 1
+	altairBlock := new(altair.SignedBeaconBlock)
+	defaultObj(t, altair.SignedBeaconBlockType(spec), altairBlock)
+	altairBlock.Message.Slot = 2
+	denebBlock := new(deneb.SignedBeaconBlock)
+	defaultObj(t, deneb.SignedBeaconBlockType(spec), denebBlock)
+	denebBlock.Message.Slot = 3
+	chunks := []struct {
+		digest common.ForkDigest
+		block  beacon.OpaqueBlock
+	}{
+		{d.Genesis, phase0Block},
+		{d.Altair, altairBlock},
+		{d.Deneb, denebBlock},
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		for _, c := range chunks {
+			if err := WriteContextResponseChunk(w, spec, c.digest, spec.Wrap(c.block)); err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+		w.Close()
+	}()
+	for i, c := range chunks {
+		digest, block, err := ReadBlockResponseChunk(r, d)
+		if err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		if digest != c.digest {
+			t.Fatalf("chunk %d: got digest %s, expected %s", i, digest, c.digest)
+		}
+		if got, expected := block.HashTreeRoot(spec, tree.GetHashFn()), c.block.HashTreeRoot(spec, tree.GetHashFn()); got != expected {
+			t.Fatalf("chunk %d: got block %T with root %s, expected %T with root %s", i, block, got, c.block, expected)
+		}
+	}
+	if _, _, err := ReadBlockResponseChunk(r, d); err != io.EOF {
+		t.Fatalf("expected end of stream, got: %v", err)
+	}
+
+	// unknown context bytes
+	var buf bytes.Buffer
+	if err := WriteContextResponseChunk(&buf, spec, common.ForkDigest{0xff}, spec.Wrap(phase0Block)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadBlockResponseChunk(&buf, d); err == nil {
+		t.Fatal("expected error for unknown fork digest")
+	}
+}
+
+func TestBlobSidecarResponseChunk(t *testing.T) {
+	d := beacon.NewForkDecoder(spec, common.Root{0x42})
+	sidecar := new(deneb.BlobSidecar)
+	defaultObj(t, deneb.BlobSidecarType(spec), sidecar)
+	sidecar.Index = 2
+	sidecar.Blob[123] = 0x55
+	sidecar.KZGCommitmentInclusionProof[16] = common.Root{0x66}
+
+	var buf bytes.Buffer
+	if err := WriteContextResponseChunk(&buf, spec, d.Deneb, spec.Wrap(sidecar)); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	_, got, err := ReadBlobSidecarResponseChunk(bytes.NewReader(encoded), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.HashTreeRoot(spec, tree.GetHashFn()) != sidecar.HashTreeRoot(spec, tree.GetHashFn()) {
+		t.Fatal("blob sidecar changed in roundtrip")
+	}
+
+	// blob sidecars do not exist before deneb
+	copy(encoded[1:5], d.Capella[:])
+	if _, _, err := ReadBlobSidecarResponseChunk(bytes.NewReader(encoded), d); err == nil {
+		t.Fatal("expected error for pre-deneb fork digest")
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	var buf bytes.Buffer
+	longMsg := strings.Repeat("x", 300)
+	if err := WriteErrorResponse(&buf, spec, ResourceUnavailable, longMsg); err != nil {
+		t.Fatal(err)
+	}
+	var dst common.Status
+	err := ReadResponseChunk(&buf, spec, &dst, common.StatusByteLen)
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		t.Fatalf("expected response error, got: %v", err)
+	}
+	if respErr.Code != ResourceUnavailable || respErr.Message != longMsg[:MAX_ERROR_MESSAGE_LENGTH] {
+		t.Fatalf("unexpected response error: %v", respErr)
+	}
+	if err := WriteErrorResponse(&buf, spec, Success, "ok"); err == nil {
+		t.Fatal("expected error for error response with success code")
+	}
+}
+
+func TestPayloadLimits(t *testing.T) {
+	var status common.Status
+	// declared length above MAX_PAYLOAD_SIZE, without any payload
+	if err := DecodePayload(bytes.NewReader([]byte{0x81, 0x80, 0x80, 0x05}), spec, &status, 1<<30); err == nil {
+		t.Fatal("expected error for payload exceeding MAX_PAYLOAD_SIZE")
+	}
+	// declared length that does not match the fixed length
+	if err := DecodePayload(bytes.NewReader([]byte{10}), spec, &status, 1000); err == nil {
+		t.Fatal("expected error for wrong fixed length")
+	}
+	// declared length above the given limit
+	req := common.BeaconBlocksByRootRequest{{1}, {2}, {3}}
+	var buf bytes.Buffer
+	if err := EncodePayload(&buf, spec, spec.Wrap(&req)); err != nil {
+		t.Fatal(err)
+	}
+	var got common.BeaconBlocksByRootRequest
+	if err := DecodePayload(&buf, spec, spec.Wrap(&got), 2*32); err == nil {
+		t.Fatal("expected error for payload exceeding limit")
+	}
+	// varint overflow
+	if err := DecodePayload(bytes.NewReader(bytes.Repeat([]byte{0xff}, 11)), spec, &status, 1000); err == nil {
+		t.Fatal("expected error for varint overflow")
+	}
+}
+
+func TestTruncatedPayload(t *testing.T) {
+	req := common.Status{HeadSlot: 123, FinalizedRoot: common.Root{1, 2, 3}}
+	var buf bytes.Buffer
+	if err := EncodePayload(&buf, spec, &req); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	for size := 0; size < len(encoded); size++ {
+		var got common.Status
+		if err := DecodePayload(bytes.NewReader(encoded[:size]), spec, &got, common.StatusByteLen); err == nil {
+			t.Fatalf("expected error for payload truncated to %d of %d bytes", size, len(encoded))
+		}
+	}
+	// corrupted snappy data
+	corrupted := append([]byte(nil), encoded...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	var got common.Status
+	if err := DecodePayload(bytes.NewReader(corrupted), spec, &got, common.StatusByteLen); err == nil {
+		t.Fatal("expected error for corrupted payload")
+	}
+}
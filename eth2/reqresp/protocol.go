@@ -0,0 +1,32 @@
+package reqresp
+
+import "fmt"
+
+// Protocol describes a req/resp method, as registered on the p2p host.
+type Protocol struct {
+	// Name of the method, e.g. "beacon_blocks_by_range"
+	Name string
+	// Version of the method schema
+	Version uint
+	// ContextBytes is true if response chunks are prefixed with the fork digest of the chunk contents.
+	ContextBytes bool
+}
+
+// ID returns the libp2p protocol ID, with the ssz_snappy encoding.
+func (p Protocol) ID() string {
+	return fmt.Sprintf("/eth2/beacon_chain/req/%s/%d/ssz_snappy", p.Name, p.Version)
+}
+
+func (p Protocol) String() string {
+	return p.ID()
+}
+
+var (
+	StatusV1              = Protocol{Name: "status", Version: 1}
+	GoodbyeV1             = Protocol{Name: "goodbye", Version: 1}
+	PingV1                = Protocol{Name: "ping", Version: 1}
+	MetaDataV2            = Protocol{Name: "metadata", Version: 2}
+	BeaconBlocksByRangeV2 = Protocol{Name: "beacon_blocks_by_range", Version: 2, ContextBytes: true}
+	BeaconBlocksByRootV2  = Protocol{Name: "beacon_blocks_by_root", Version: 2, ContextBytes: true}
+	BlobSidecarsByRangeV1 = Protocol{Name: "blob_sidecars_by_range", Version: 1, ContextBytes: true}
+)
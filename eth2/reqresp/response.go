@@ -0,0 +1,201 @@
+package reqresp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+)
+
+type ResponseCode byte
+
+const (
+	Success             ResponseCode = 0
+	InvalidRequest      ResponseCode = 1
+	ServerError         ResponseCode = 2
+	ResourceUnavailable ResponseCode = 3
+)
+
+func (c ResponseCode) String() string {
+	switch c {
+	case Success:
+		return "success"
+	case InvalidRequest:
+		return "invalid request"
+	case ServerError:
+		return "server error"
+	case ResourceUnavailable:
+		return "resource unavailable"
+	default:
+		return fmt.Sprintf("unknown response code %d", byte(c))
+	}
+}
+
+const MAX_ERROR_MESSAGE_LENGTH = 256
+
+// ErrorMessage is the payload of a non-success response chunk, a List[byte, 256].
+type ErrorMessage []byte
+
+func (m *ErrorMessage) Deserialize(dr *codec.DecodingReader) error {
+	return dr.ByteList((*[]byte)(m), MAX_ERROR_MESSAGE_LENGTH)
+}
+
+func (m ErrorMessage) Serialize(w *codec.EncodingWriter) error {
+	return w.Write(m)
+}
+
+func (m ErrorMessage) ByteLength() uint64 {
+	return uint64(len(m))
+}
+
+func (m *ErrorMessage) FixedLength() uint64 {
+	return 0
+}
+
+func (m ErrorMessage) HashTreeRoot(hFn tree.HashFn) common.Root {
+	return hFn.ByteListHTR(m, MAX_ERROR_MESSAGE_LENGTH)
+}
+
+// ResponseError is a response chunk with a non-success response code.
+type ResponseError struct {
+	Code    ResponseCode
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// WriteResponseChunk writes a success response chunk, for protocols without context bytes.
+func WriteResponseChunk(w io.Writer, spec *common.Spec, obj codec.Serializable) error {
+	if _, err := w.Write([]byte{byte(Success)}); err != nil {
+		return fmt.Errorf("failed to write response code: %w", err)
+	}
+	return EncodePayload(w, spec, obj)
+}
+
+// WriteContextResponseChunk writes a success response chunk, prefixed with the fork digest of obj as context bytes.
+func WriteContextResponseChunk(w io.Writer, spec *common.Spec, digest common.ForkDigest, obj codec.Serializable) error {
+	var prefix [1 + 4]byte
+	prefix[0] = byte(Success)
+	copy(prefix[1:], digest[:])
+	if _, err := w.Write(prefix[:]); err != nil {
+		return fmt.Errorf("failed to write response code and context bytes: %w", err)
+	}
+	return EncodePayload(w, spec, obj)
+}
+
+// WriteErrorResponse writes an error response chunk. Messages are truncated to MAX_ERROR_MESSAGE_LENGTH bytes.
+func WriteErrorResponse(w io.Writer, spec *common.Spec, code ResponseCode, msg string) error {
+	if code == Success {
+		return errors.New("error response cannot have success response code")
+	}
+	if len(msg) > MAX_ERROR_MESSAGE_LENGTH {
+		msg = msg[:MAX_ERROR_MESSAGE_LENGTH]
+	}
+	if _, err := w.Write([]byte{byte(code)}); err != nil {
+		return fmt.Errorf("failed to write response code: %w", err)
+	}
+	m := ErrorMessage(msg)
+	return EncodePayload(w, spec, &m)
+}
+
+// readResponseCode returns io.EOF if the stream ended cleanly before the next response chunk.
+// Non-success codes are read with their error message, and returned as *ResponseError.
+func readResponseCode(r io.Reader, spec *common.Spec) error {
+	var code [1]byte
+	if _, err := io.ReadFull(r, code[:]); err != nil {
+		return err
+	}
+	if ResponseCode(code[0]) == Success {
+		return nil
+	}
+	var msg ErrorMessage
+	if err := DecodePayload(r, spec, &msg, MAX_ERROR_MESSAGE_LENGTH); err != nil {
+		return fmt.Errorf("failed to read error message of response with code %d: %w", code[0], err)
+	}
+	return &ResponseError{Code: ResponseCode(code[0]), Message: string(msg)}
+}
+
+// ReadResponseChunk reads a response chunk into dst, for protocols without context bytes.
+// It returns io.EOF when there are no more response chunks,
+// and a *ResponseError if the chunk has a non-success response code.
+func ReadResponseChunk(r io.Reader, spec *common.Spec, dst codec.Deserializable, maxLen uint64) error {
+	if err := readResponseCode(r, spec); err != nil {
+		return err
+	}
+	return DecodePayload(r, spec, dst, maxLen)
+}
+
+// ReadContextResponseChunk reads a response chunk of a protocol with context bytes.
+// The alloc function provides the type to decode into, based on the fork digest in the context bytes.
+// It returns io.EOF when there are no more response chunks,
+// and a *ResponseError if the chunk has a non-success response code.
+func ReadContextResponseChunk(r io.Reader, spec *common.Spec, maxLen uint64,
+	alloc func(digest common.ForkDigest) (codec.Deserializable, error)) (common.ForkDigest, codec.Deserializable, error) {
+	var digest common.ForkDigest
+	if err := readResponseCode(r, spec); err != nil {
+		return digest, nil, err
+	}
+	if _, err := io.ReadFull(r, digest[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return digest, nil, fmt.Errorf("failed to read context bytes: %w", err)
+	}
+	dst, err := alloc(digest)
+	if err != nil {
+		return digest, nil, err
+	}
+	if err := DecodePayload(r, spec, dst, maxLen); err != nil {
+		return digest, nil, err
+	}
+	return digest, dst, nil
+}
+
+// ReadBlockResponseChunk reads a BeaconBlocksByRange or BeaconBlocksByRoot response chunk,
+// decoding the block as the type of the fork in the context bytes.
+func ReadBlockResponseChunk(r io.Reader, d *beacon.ForkDecoder) (common.ForkDigest, beacon.OpaqueBlock, error) {
+	var block beacon.OpaqueBlock
+	digest, _, err := ReadContextResponseChunk(r, d.Spec, uint64(d.Spec.MAX_PAYLOAD_SIZE),
+		func(digest common.ForkDigest) (codec.Deserializable, error) {
+			alloc, err := d.BlockAllocator(digest)
+			if err != nil {
+				return nil, err
+			}
+			block = alloc()
+			return d.Spec.Wrap(block), nil
+		})
+	if err != nil {
+		return digest, nil, err
+	}
+	return digest, block, nil
+}
+
+// ReadBlobSidecarResponseChunk reads a BlobSidecarsByRange response chunk.
+func ReadBlobSidecarResponseChunk(r io.Reader, d *beacon.ForkDecoder) (common.ForkDigest, *deneb.BlobSidecar, error) {
+	var sidecar common.SpecObj
+	digest, _, err := ReadContextResponseChunk(r, d.Spec, uint64(d.Spec.MAX_PAYLOAD_SIZE),
+		func(digest common.ForkDigest) (codec.Deserializable, error) {
+			alloc, err := d.BlobSidecarAllocator(digest)
+			if err != nil {
+				return nil, err
+			}
+			sidecar = alloc()
+			return d.Spec.Wrap(sidecar), nil
+		})
+	if err != nil {
+		return digest, nil, err
+	}
+	out, ok := sidecar.(*deneb.BlobSidecar)
+	if !ok {
+		return digest, nil, fmt.Errorf("unexpected blob sidecar type %T", sidecar)
+	}
+	return digest, out, nil
+}
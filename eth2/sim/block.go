@@ -0,0 +1,502 @@
+package sim
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+	. "github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// GAS_LIMIT of the simulated execution payloads.
+const GAS_LIMIT = 30_000_000
+
+// operations of a block, shared by all forks
+type operations struct {
+	proposerSlashings phase0.ProposerSlashings
+	attesterSlashings phase0.AttesterSlashings
+	attestations      phase0.Attestations
+	deposits          phase0.Deposits
+	exits             phase0.VoluntaryExits
+	// roots of the included attestations
+	attestationRoots []common.Root
+}
+
+// proposeBlock builds, processes and signs a block on top of the parent block.
+// Returns nil if the proposer is slashed, since its blocks are invalid.
+func (sim *Simulator) proposeBlock(ctx context.Context, parent *Block, slot common.Slot, graffiti common.Root) (*Block, error) {
+	state, epc, err := sim.stateAt(ctx, parent, slot)
+	if err != nil {
+		return nil, err
+	}
+	proposer, err := epc.GetBeaconProposer(slot)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(proposer) >= uint64(len(sim.keys)) {
+		return nil, fmt.Errorf("no key for proposer %d", proposer)
+	}
+	if v, err := state.Validators(); err != nil {
+		return nil, err
+	} else if val, err := v.Validator(proposer); err != nil {
+		return nil, err
+	} else if slashed, err := val.Slashed(); err != nil {
+		return nil, err
+	} else if slashed {
+		return nil, nil
+	}
+	sk := sim.keys[proposer].Secret
+	epoch := sim.spec.SlotToEpoch(slot)
+	randaoReveal, err := sim.signer.SignRandaoReveal(sk, epoch)
+	if err != nil {
+		return nil, err
+	}
+	eth1Vote := sim.eth1Vote()
+	ops, err := sim.selectOperations(ctx, parent, state, epc, eth1Vote)
+	if err != nil {
+		return nil, err
+	}
+	body, err := sim.buildBody(state, epc, randaoReveal, eth1Vote, graffiti, ops)
+	if err != nil {
+		return nil, err
+	}
+	hFn := tree.GetHashFn()
+	benv := &common.BeaconBlockEnvelope{
+		ForkDigest: sim.forks.ForkDigest(epoch),
+		BeaconBlockHeader: common.BeaconBlockHeader{
+			Slot:          slot,
+			ProposerIndex: proposer,
+			ParentRoot:    parent.Root,
+			BodyRoot:      body.HashTreeRoot(sim.spec, hFn),
+		},
+		Body: body,
+	}
+	if err := common.PostSlotTransition(ctx, sim.spec, epc, state, benv, false); err != nil {
+		return nil, fmt.Errorf("failed to process block: %w", err)
+	}
+	benv.StateRoot = state.HashTreeRoot(hFn)
+	benv.BlockRoot = benv.BeaconBlockHeader.HashTreeRoot(hFn)
+	benv.Signature, err = sim.signer.SignBlock(sk, slot, benv.BlockRoot)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := beacon.EnvelopeToSignedBeaconBlock(benv)
+	if err != nil {
+		return nil, err
+	}
+	return &Block{
+		Root:         benv.BlockRoot,
+		Slot:         slot,
+		ParentRoot:   parent.Root,
+		Signed:       signed.(beacon.OpaqueBlock),
+		Envelope:     benv,
+		State:        state,
+		Epc:          epc,
+		attestations: ops.attestationRoots,
+	}, nil
+}
+
+// eth1Vote is the eth1 data of the mock deposit contract with all deposits.
+func (sim *Simulator) eth1Vote() common.Eth1Data {
+	count := uint64(len(sim.deposits))
+	return common.Eth1Data{
+		DepositRoot:  sim.depositTree(count).Root(),
+		DepositCount: common.DepositIndex(count),
+		BlockHash:    eth1BlockHash(count),
+	}
+}
+
+func (sim *Simulator) depositTree(count uint64) *common.DepositTree {
+	t := common.NewDepositTree()
+	for i := uint64(0); i < count; i++ {
+		// the deposit count is limited by the tree depth, far beyond what is simulated
+		_ = t.PushDeposit(&sim.deposits[i])
+	}
+	return t
+}
+
+// selectOperations selects the pooled operations that are valid to include in a block on the pre-state,
+// by processing them on a copy of the state. The pending deposits are required, and always included.
+func (sim *Simulator) selectOperations(ctx context.Context, parent *Block, state common.BeaconState, epc *common.EpochsContext, eth1Vote common.Eth1Data) (*operations, error) {
+	trial, err := state.CopyState()
+	if err != nil {
+		return nil, err
+	}
+	trialEpc := epc.Clone()
+	if err := phase0.ProcessEth1Vote(ctx, sim.spec, trialEpc, trial, eth1Vote); err != nil {
+		return nil, err
+	}
+	ops := new(operations)
+	for i := range sim.proposerSlashings {
+		if uint64(len(ops.proposerSlashings)) >= uint64(sim.spec.MAX_PROPOSER_SLASHINGS) {
+			break
+		}
+		if err := phase0.ProcessProposerSlashing(ctx, sim.spec, trialEpc, trial, &sim.proposerSlashings[i]); err == nil {
+			ops.proposerSlashings = append(ops.proposerSlashings, sim.proposerSlashings[i])
+		}
+	}
+	for i := range sim.attesterSlashings {
+		if uint64(len(ops.attesterSlashings)) >= uint64(sim.spec.MAX_ATTESTER_SLASHINGS) {
+			break
+		}
+		if err := phase0.ProcessAttesterSlashing(ctx, sim.spec, trialEpc, trial, &sim.attesterSlashings[i]); err == nil {
+			ops.attesterSlashings = append(ops.attesterSlashings, sim.attesterSlashings[i])
+		}
+	}
+
+	slot, err := trial.Slot()
+	if err != nil {
+		return nil, err
+	}
+	included := sim.includedAttestations(parent, slot)
+	for _, a := range sim.attestations {
+		if uint64(len(ops.attestations)) >= uint64(sim.spec.MAX_ATTESTATIONS) {
+			break
+		}
+		if _, ok := included[a.root]; ok || a.att.Data.Slot+sim.spec.MIN_ATTESTATION_INCLUSION_DELAY > slot {
+			continue
+		}
+		if err := processAttestation(ctx, sim.spec, trialEpc, trial, a.att); err == nil {
+			ops.attestations = append(ops.attestations, *a.att)
+			ops.attestationRoots = append(ops.attestationRoots, a.root)
+		}
+	}
+
+	eth1Data, err := trial.Eth1Data()
+	if err != nil {
+		return nil, err
+	}
+	depIndex, err := trial.Eth1DepositIndex()
+	if err != nil {
+		return nil, err
+	}
+	if depIndex < eth1Data.DepositCount {
+		count := uint64(eth1Data.DepositCount)
+		if count > uint64(len(sim.deposits)) {
+			return nil, fmt.Errorf("eth1 data deposit count %d is more than the %d known deposits", count, len(sim.deposits))
+		}
+		t := sim.depositTree(count)
+		for i := uint64(depIndex); i < count && uint64(len(ops.deposits)) < uint64(sim.spec.MAX_DEPOSITS); i++ {
+			_, proof, err := t.Proof(common.DepositIndex(i))
+			if err != nil {
+				return nil, err
+			}
+			dep := common.Deposit{Proof: proof, Data: sim.deposits[i]}
+			if err := phase0.ProcessDeposit(sim.spec, trialEpc, trial, &dep, false); err != nil {
+				return nil, fmt.Errorf("failed to process deposit %d: %w", i, err)
+			}
+			ops.deposits = append(ops.deposits, dep)
+		}
+	}
+
+	for i := range sim.exits {
+		if uint64(len(ops.exits)) >= uint64(sim.spec.MAX_VOLUNTARY_EXITS) {
+			break
+		}
+		var err error
+		if _, ok := trial.(*deneb.BeaconStateView); ok {
+			err = deneb.ProcessVoluntaryExit(ctx, sim.spec, trialEpc, trial, &sim.exits[i])
+		} else {
+			err = phase0.ProcessVoluntaryExit(ctx, sim.spec, trialEpc, trial, &sim.exits[i])
+		}
+		if err == nil {
+			ops.exits = append(ops.exits, sim.exits[i])
+		}
+	}
+	return ops, nil
+}
+
+// includedAttestations collects the roots of the attestations included in the block and its recent ancestors.
+func (sim *Simulator) includedAttestations(b *Block, slot common.Slot) map[common.Root]struct{} {
+	out := make(map[common.Root]struct{})
+	for ; b != nil && b.Slot+2*sim.spec.SLOTS_PER_EPOCH >= slot; b = sim.blocks[b.ParentRoot] {
+		for _, r := range b.attestations {
+			out[r] = struct{}{}
+		}
+		if b == sim.genesis {
+			break
+		}
+	}
+	return out
+}
+
+func processAttestation(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, att *phase0.Attestation) error {
+	switch s := state.(type) {
+	case *phase0.BeaconStateView:
+		return phase0.ProcessAttestation(ctx, spec, epc, s, att)
+	case *deneb.BeaconStateView:
+		return deneb.ProcessAttestation(ctx, spec, epc, s, att)
+	case altair.AltairLikeBeaconState:
+		return altair.ProcessAttestation(ctx, spec, epc, s, att)
+	default:
+		return fmt.Errorf("unsupported state type %T", state)
+	}
+}
+
+// syncAggregate signs the block root of the previous slot with the current sync committee.
+func (sim *Simulator) syncAggregate(state common.BeaconState, epc *common.EpochsContext) (*altair.SyncAggregate, error) {
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	agg := &altair.SyncAggregate{
+		SyncCommitteeBits: make(altair.SyncCommitteeBits, sim.spec.SYNC_COMMITTEE_SIZE/8),
+	}
+	if slot == 0 || epc.CurrentSyncCommittee == nil {
+		agg.SyncCommitteeSignature[0] = 0xc0 // G2 point at infinity
+		return agg, nil
+	}
+	prevSlot := slot - 1
+	blockRoot, err := common.GetBlockRootAtSlot(sim.spec, state, prevSlot)
+	if err != nil {
+		return nil, err
+	}
+	dom, err := sim.signer.Domain(common.DOMAIN_SYNC_COMMITTEE, sim.spec.SlotToEpoch(prevSlot))
+	if err != nil {
+		return nil, err
+	}
+	signingRoot := common.ComputeSigningRoot(blockRoot, dom)
+	var sigs []*blsu.Signature
+	for i, index := range epc.CurrentSyncCommittee.Indices {
+		if sim.rng.Float64() >= sim.cfg.SyncParticipation {
+			continue
+		}
+		agg.SyncCommitteeBits.SetBit(uint64(i), true)
+		sigs = append(sigs, blsu.Sign(sim.keys[index].Secret, signingRoot[:]))
+	}
+	if len(sigs) == 0 {
+		agg.SyncCommitteeSignature[0] = 0xc0 // G2 point at infinity
+		return agg, nil
+	}
+	sig, err := blsu.Aggregate(sigs)
+	if err != nil {
+		return nil, err
+	}
+	agg.SyncCommitteeSignature = sig.Serialize()
+	return agg, nil
+}
+
+// payload is the fork-agnostic part of a mock execution payload, chained to the latest execution payload header.
+type payload struct {
+	parentHash  common.Hash32
+	prevRandao  common.Bytes32
+	blockNumber uint64
+	timestamp   common.Timestamp
+	blockHash   common.Hash32
+}
+
+func (sim *Simulator) nextPayload(state common.BeaconState, parentHash common.Hash32, parentNumber uint64) (*payload, error) {
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	mixes, err := state.RandaoMixes()
+	if err != nil {
+		return nil, err
+	}
+	mix, err := mixes.GetRandomMix(sim.spec.SlotToEpoch(slot))
+	if err != nil {
+		return nil, err
+	}
+	genesisTime, err := state.GenesisTime()
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := sim.spec.TimeAtSlot(slot, genesisTime)
+	if err != nil {
+		return nil, err
+	}
+	number := parentNumber + 1
+	if parentHash == (common.Hash32{}) {
+		// merge transition block, the first payload
+		number = 0
+	}
+	var in [16]byte
+	binary.LittleEndian.PutUint64(in[:8], number)
+	binary.LittleEndian.PutUint64(in[8:], uint64(timestamp))
+	h := sha256.New()
+	h.Write(parentHash[:])
+	h.Write(in[:])
+	var blockHash common.Hash32
+	copy(blockHash[:], h.Sum(nil))
+	return &payload{
+		parentHash:  parentHash,
+		prevRandao:  common.Bytes32(mix),
+		blockNumber: number,
+		timestamp:   timestamp,
+		blockHash:   blockHash,
+	}, nil
+}
+
+func (sim *Simulator) buildBody(state common.BeaconState, epc *common.EpochsContext,
+	randaoReveal common.BLSSignature, eth1Vote common.Eth1Data, graffiti common.Root, ops *operations) (common.SpecObj, error) {
+	if _, ok := state.(*phase0.BeaconStateView); ok {
+		return &phase0.BeaconBlockBody{
+			RandaoReveal:      randaoReveal,
+			Eth1Data:          eth1Vote,
+			Graffiti:          graffiti,
+			ProposerSlashings: ops.proposerSlashings,
+			AttesterSlashings: ops.attesterSlashings,
+			Attestations:      ops.attestations,
+			Deposits:          ops.deposits,
+			VoluntaryExits:    ops.exits,
+		}, nil
+	}
+	syncAgg, err := sim.syncAggregate(state, epc)
+	if err != nil {
+		return nil, err
+	}
+	switch s := state.(type) {
+	case *altair.BeaconStateView:
+		return &altair.BeaconBlockBody{
+			RandaoReveal:      randaoReveal,
+			Eth1Data:          eth1Vote,
+			Graffiti:          graffiti,
+			ProposerSlashings: ops.proposerSlashings,
+			AttesterSlashings: ops.attesterSlashings,
+			Attestations:      ops.attestations,
+			Deposits:          ops.deposits,
+			VoluntaryExits:    ops.exits,
+			SyncAggregate:     *syncAgg,
+		}, nil
+	case *bellatrix.BeaconStateView:
+		hv, err := s.LatestExecutionPayloadHeader()
+		if err != nil {
+			return nil, err
+		}
+		header, err := hv.Raw()
+		if err != nil {
+			return nil, err
+		}
+		p, err := sim.nextPayload(state, header.BlockHash, uint64(header.BlockNumber))
+		if err != nil {
+			return nil, err
+		}
+		return &bellatrix.BeaconBlockBody{
+			RandaoReveal:      randaoReveal,
+			Eth1Data:          eth1Vote,
+			Graffiti:          graffiti,
+			ProposerSlashings: ops.proposerSlashings,
+			AttesterSlashings: ops.attesterSlashings,
+			Attestations:      ops.attestations,
+			Deposits:          ops.deposits,
+			VoluntaryExits:    ops.exits,
+			SyncAggregate:     *syncAgg,
+			ExecutionPayload: bellatrix.ExecutionPayload{
+				ParentHash:  p.parentHash,
+				PrevRandao:  p.prevRandao,
+				BlockNumber: Uint64View(p.blockNumber),
+				GasLimit:    GAS_LIMIT,
+				Timestamp:   p.timestamp,
+				BlockHash:   p.blockHash,
+			},
+		}, nil
+	case *capella.BeaconStateView:
+		hv, err := s.LatestExecutionPayloadHeader()
+		if err != nil {
+			return nil, err
+		}
+		header, err := hv.Raw()
+		if err != nil {
+			return nil, err
+		}
+		p, err := sim.nextPayload(state, header.BlockHash, uint64(header.BlockNumber))
+		if err != nil {
+			return nil, err
+		}
+		withdrawals, err := capella.GetExpectedWithdrawals(s, sim.spec)
+		if err != nil {
+			return nil, err
+		}
+		return &capella.BeaconBlockBody{
+			RandaoReveal:      randaoReveal,
+			Eth1Data:          eth1Vote,
+			Graffiti:          graffiti,
+			ProposerSlashings: ops.proposerSlashings,
+			AttesterSlashings: ops.attesterSlashings,
+			Attestations:      ops.attestations,
+			Deposits:          ops.deposits,
+			VoluntaryExits:    ops.exits,
+			SyncAggregate:     *syncAgg,
+			ExecutionPayload: capella.ExecutionPayload{
+				ParentHash:  p.parentHash,
+				PrevRandao:  p.prevRandao,
+				BlockNumber: Uint64View(p.blockNumber),
+				GasLimit:    GAS_LIMIT,
+				Timestamp:   p.timestamp,
+				BlockHash:   p.blockHash,
+				Withdrawals: withdrawals,
+			},
+		}, nil
+	case *deneb.BeaconStateView:
+		hv, err := s.LatestExecutionPayloadHeader()
+		if err != nil {
+			return nil, err
+		}
+		header, err := hv.Raw()
+		if err != nil {
+			return nil, err
+		}
+		p, err := sim.nextPayload(state, header.BlockHash, uint64(header.BlockNumber))
+		if err != nil {
+			return nil, err
+		}
+		withdrawals, err := capella.GetExpectedWithdrawals(s, sim.spec)
+		if err != nil {
+			return nil, err
+		}
+		return &deneb.BeaconBlockBody{
+			RandaoReveal:      randaoReveal,
+			Eth1Data:          eth1Vote,
+			Graffiti:          graffiti,
+			ProposerSlashings: ops.proposerSlashings,
+			AttesterSlashings: ops.attesterSlashings,
+			Attestations:      ops.attestations,
+			Deposits:          ops.deposits,
+			VoluntaryExits:    ops.exits,
+			SyncAggregate:     *syncAgg,
+			ExecutionPayload: deneb.ExecutionPayload{
+				ParentHash:  p.parentHash,
+				PrevRandao:  p.prevRandao,
+				BlockNumber: Uint64View(p.blockNumber),
+				GasLimit:    GAS_LIMIT,
+				Timestamp:   p.timestamp,
+				BlockHash:   p.blockHash,
+				Withdrawals: withdrawals,
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported state type %T", state)
+	}
+}
+
+// emptyBody is the body of the genesis block of the fork of the state.
+func (sim *Simulator) emptyBody(state common.BeaconState) (common.SpecObj, error) {
+	// the genesis body has a zero sync aggregate, not an empty one with the G2 point at infinity
+	syncAgg := altair.SyncAggregate{SyncCommitteeBits: make(altair.SyncCommitteeBits, sim.spec.SYNC_COMMITTEE_SIZE/8)}
+	switch state.(type) {
+	case *phase0.BeaconStateView:
+		return new(phase0.BeaconBlockBody), nil
+	case *altair.BeaconStateView:
+		return &altair.BeaconBlockBody{SyncAggregate: syncAgg}, nil
+	case *bellatrix.BeaconStateView:
+		return &bellatrix.BeaconBlockBody{SyncAggregate: syncAgg}, nil
+	case *capella.BeaconStateView:
+		return &capella.BeaconBlockBody{SyncAggregate: syncAgg}, nil
+	case *deneb.BeaconStateView:
+		return &deneb.BeaconBlockBody{SyncAggregate: syncAgg}, nil
+	default:
+		return nil, fmt.Errorf("unsupported state type %T", state)
+	}
+}
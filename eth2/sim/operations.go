@@ -0,0 +1,185 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/interop"
+)
+
+// attest produces the aggregate attestations of the committees of the slot, voting for the head block.
+func (sim *Simulator) attest(ctx context.Context, head *Block, slot common.Slot) ([]*phase0.Attestation, error) {
+	state, epc, err := sim.stateAt(ctx, head, slot)
+	if err != nil {
+		return nil, err
+	}
+	epoch := sim.spec.SlotToEpoch(slot)
+	start, err := sim.spec.EpochStartSlot(epoch)
+	if err != nil {
+		return nil, err
+	}
+	target := common.Checkpoint{Epoch: epoch, Root: head.Root}
+	if start != slot {
+		target.Root, err = common.GetBlockRootAtSlot(sim.spec, state, start)
+		if err != nil {
+			return nil, err
+		}
+	}
+	source, err := state.CurrentJustifiedCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	dom, err := sim.signer.Domain(common.DOMAIN_BEACON_ATTESTER, epoch)
+	if err != nil {
+		return nil, err
+	}
+	count, err := epc.GetCommitteeCountPerSlot(epoch)
+	if err != nil {
+		return nil, err
+	}
+	hFn := tree.GetHashFn()
+	var out []*phase0.Attestation
+	for i := uint64(0); i < count; i++ {
+		committee, err := epc.GetBeaconCommittee(slot, common.CommitteeIndex(i))
+		if err != nil {
+			return nil, err
+		}
+		data := phase0.AttestationData{
+			Slot:            slot,
+			Index:           common.CommitteeIndex(i),
+			BeaconBlockRoot: head.Root,
+			Source:          source,
+			Target:          target,
+		}
+		signingRoot := common.ComputeSigningRoot(data.HashTreeRoot(hFn), dom)
+		bitLen := uint64(len(committee))
+		bits := make(phase0.AttestationBits, bitLen/8+1)
+		bits.SetBit(bitLen, true) // bitlist delimiter
+		var sigs []*blsu.Signature
+		for j, index := range committee {
+			if sim.rng.Float64() >= sim.cfg.Participation {
+				continue
+			}
+			bits.SetBit(uint64(j), true)
+			sigs = append(sigs, blsu.Sign(sim.keys[index].Secret, signingRoot[:]))
+		}
+		if len(sigs) == 0 {
+			continue
+		}
+		sig, err := blsu.Aggregate(sigs)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &phase0.Attestation{
+			AggregationBits: bits,
+			Data:            data,
+			Signature:       sig.Serialize(),
+		})
+	}
+	return out, nil
+}
+
+// AddDeposit makes a deposit of the given amount for the next interop key,
+// and returns the key. The deposit is included after the eth1 votes for it reach a majority.
+func (sim *Simulator) AddDeposit(amount common.Gwei) (*interop.Key, error) {
+	index := uint64(len(sim.keys))
+	sk, err := interop.SecretKey(index)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := blsu.SkToPk(sk)
+	if err != nil {
+		return nil, err
+	}
+	key := interop.Key{Secret: sk, Pubkey: pub.Serialize()}
+	data := common.DepositData{
+		Pubkey:                key.Pubkey,
+		WithdrawalCredentials: interop.BLSWithdrawalCredentials(key.Pubkey),
+		Amount:                amount,
+	}
+	data.Signature = sim.signer.SignDepositData(sk, &data)
+	sim.keys = append(sim.keys, key)
+	sim.deposits = append(sim.deposits, data)
+	return &key, nil
+}
+
+// AddVoluntaryExit adds a voluntary exit of the validator, in the epoch of the last simulated slot, to the pool.
+func (sim *Simulator) AddVoluntaryExit(index common.ValidatorIndex) error {
+	if uint64(index) >= uint64(len(sim.keys)) {
+		return fmt.Errorf("unknown validator %d", index)
+	}
+	exit := phase0.VoluntaryExit{
+		Epoch:          sim.spec.SlotToEpoch(sim.slot),
+		ValidatorIndex: index,
+	}
+	sig, err := sim.signer.SignVoluntaryExit(sim.keys[index].Secret, &exit)
+	if err != nil {
+		return err
+	}
+	sim.exits = append(sim.exits, phase0.SignedVoluntaryExit{Message: exit, Signature: sig})
+	return nil
+}
+
+// AddAttesterSlashing makes the validators double vote, in the epoch of the last simulated slot,
+// and adds the attester slashing of the two votes to the pool.
+func (sim *Simulator) AddAttesterSlashing(indices []common.ValidatorIndex) error {
+	if len(indices) == 0 {
+		return fmt.Errorf("no validators to slash")
+	}
+	sorted := append(common.CommitteeIndices(nil), indices...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for i, index := range sorted {
+		if uint64(index) >= uint64(len(sim.keys)) {
+			return fmt.Errorf("unknown validator %d", index)
+		}
+		if i > 0 && sorted[i-1] == index {
+			return fmt.Errorf("duplicate validator %d", index)
+		}
+	}
+	source, err := sim.head.State.CurrentJustifiedCheckpoint()
+	if err != nil {
+		return err
+	}
+	epoch := sim.spec.SlotToEpoch(sim.slot)
+	vote := func(root common.Root) (*phase0.IndexedAttestation, error) {
+		data := phase0.AttestationData{
+			Slot:            sim.slot,
+			BeaconBlockRoot: root,
+			Source:          source,
+			Target:          common.Checkpoint{Epoch: epoch, Root: root},
+		}
+		var sigs []*blsu.Signature
+		for _, index := range sorted {
+			sig, err := sim.signer.SignAttestationData(sim.keys[index].Secret, &data)
+			if err != nil {
+				return nil, err
+			}
+			s, err := sig.Signature()
+			if err != nil {
+				return nil, err
+			}
+			sigs = append(sigs, s)
+		}
+		sig, err := blsu.Aggregate(sigs)
+		if err != nil {
+			return nil, err
+		}
+		return &phase0.IndexedAttestation{AttestingIndices: sorted, Data: data, Signature: sig.Serialize()}, nil
+	}
+	a, err := vote(common.Root{0: 0xaa})
+	if err != nil {
+		return err
+	}
+	b, err := vote(common.Root{0: 0xbb})
+	if err != nil {
+		return err
+	}
+	sim.attesterSlashings = append(sim.attesterSlashings, phase0.AttesterSlashing{Attestation1: *a, Attestation2: *b})
+	return nil
+}
@@ -0,0 +1,397 @@
+package sim
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/execution"
+	"github.com/protolambda/zrnt/eth2/interop"
+)
+
+type Config struct {
+	// Spec, with the fork schedule to simulate. Forks up to Deneb are supported:
+	// the Electra state transition is not implemented yet, see electra.BeaconStateView.ProcessBlock.
+	// The spec is copied, and gets a no-op execution engine if it has none.
+	Spec *common.Spec
+	// ValidatorCount is the number of interop validators at genesis.
+	ValidatorCount uint64
+	GenesisTime    common.Timestamp
+	// Participation is the probability, in [0, 1], that a validator attests in its slot.
+	Participation float64
+	// SyncParticipation is the probability, in [0, 1], that a sync committee member signs the previous block root.
+	SyncParticipation float64
+	// Seed of the participation randomness. Runs with the same config and inputs produce the same chain.
+	Seed int64
+}
+
+// Block is a simulated block, with the post-state of the block.
+type Block struct {
+	Root       common.Root
+	Slot       common.Slot
+	ParentRoot common.Root
+	// Signed is the fork-specific SignedBeaconBlock
+	Signed   beacon.OpaqueBlock
+	Envelope *common.BeaconBlockEnvelope
+	// State is the post-state of the block. It must not be modified.
+	State common.BeaconState
+	Epc   *common.EpochsContext
+	// Late is true if the block was published after the attestation deadline of its slot.
+	Late bool
+	// roots of the included attestations, to not include them again in descendants
+	attestations []common.Root
+}
+
+type EventType uint8
+
+const (
+	BlockEvent EventType = iota
+	AttestationEvent
+)
+
+// Event is a block or aggregate attestation, at the time it is published.
+type Event struct {
+	Type        EventType
+	Time        common.Timestamp
+	Block       *Block
+	Attestation *phase0.Attestation
+}
+
+// SlotOptions inject faults into the next slot. The zero value is a regular slot.
+type SlotOptions struct {
+	// Skip leaves the slot empty, as if the proposer missed it.
+	Skip bool
+	// Late publishes the block after the attestation deadline:
+	// the attesters of the slot vote for the previous head. The block still becomes the head after the slot.
+	Late bool
+	// Parent, if not nil, is the root of the block to build on instead of the head, to create a fork.
+	Parent *common.Root
+	// Equivocate makes the proposer publish a second block for the slot, with the same parent.
+	// A proposer slashing of the two blocks is added to the operation pool.
+	Equivocate bool
+}
+
+type poolAttestation struct {
+	root common.Root
+	att  *phase0.Attestation
+}
+
+// Simulator builds a chain of the interop validators, slot by slot, without network.
+// The validators follow the simulator head: the latest block that was built, unless the block was late.
+// Fork choice is not run: forks and equivocations are left to the consumers of the blocks and attestations.
+type Simulator struct {
+	cfg    Config
+	spec   *common.Spec
+	keys   interop.Keys
+	signer *interop.Signer
+	forks  *beacon.ForkDecoder
+	rng    *rand.Rand
+
+	genesis *Block
+	blocks  map[common.Root]*Block
+	head    *Block
+	// the last simulated slot
+	slot   common.Slot
+	events []Event
+
+	attestations      []poolAttestation
+	proposerSlashings []phase0.ProposerSlashing
+	attesterSlashings []phase0.AttesterSlashing
+	exits             []phase0.SignedVoluntaryExit
+	// all deposits, including the genesis deposits
+	deposits []common.DepositData
+}
+
+// eth1BlockHash is the mock eth1 block hash of the deposit contract with the given deposit count.
+func eth1BlockHash(depositCount uint64) (out common.Root) {
+	var in [8]byte
+	binary.LittleEndian.PutUint64(in[:], depositCount)
+	h := sha256.New()
+	h.Write([]byte("eth1 block"))
+	h.Write(in[:])
+	copy(out[:], h.Sum(nil))
+	return
+}
+
+// New creates a simulator, with a genesis state at the fork of epoch 0.
+func New(cfg Config) (*Simulator, error) {
+	specCopy := *cfg.Spec
+	spec := &specCopy
+	if spec.ExecutionEngine == nil {
+		spec.ExecutionEngine = execution.NoOpExecutionEngine{}
+	}
+	cfg.Spec = spec
+	if spec.ELECTRA_FORK_EPOCH == 0 {
+		return nil, errors.New("simulating Electra is not supported, electra state transitions are not implemented")
+	}
+	keys, err := interop.NewKeys(cfg.ValidatorCount)
+	if err != nil {
+		return nil, err
+	}
+	validators := keys.KickstartValidators(spec.MAX_EFFECTIVE_BALANCE)
+	eth1Hash := eth1BlockHash(cfg.ValidatorCount)
+
+	var state common.BeaconState
+	var epc *common.EpochsContext
+	switch {
+	case spec.DENEB_FORK_EPOCH == 0:
+		state, epc, err = deneb.KickStartState(spec, eth1Hash, cfg.GenesisTime, validators, nil)
+	case spec.CAPELLA_FORK_EPOCH == 0:
+		state, epc, err = capella.KickStartState(spec, eth1Hash, cfg.GenesisTime, validators, nil)
+	case spec.BELLATRIX_FORK_EPOCH == 0:
+		state, epc, err = bellatrix.KickStartState(spec, eth1Hash, cfg.GenesisTime, validators, nil)
+	case spec.ALTAIR_FORK_EPOCH == 0:
+		state, epc, err = altair.KickStartState(spec, eth1Hash, cfg.GenesisTime, validators)
+	default:
+		state, epc, err = phase0.KickStartState(spec, eth1Hash, cfg.GenesisTime, validators)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genesis state: %w", err)
+	}
+	gvr, err := state.GenesisValidatorsRoot()
+	if err != nil {
+		return nil, err
+	}
+	sim := &Simulator{
+		cfg:    cfg,
+		spec:   spec,
+		keys:   keys,
+		signer: &interop.Signer{Spec: spec, GenesisValidatorsRoot: gvr},
+		forks:  beacon.NewForkDecoder(spec, gvr),
+		rng:    rand.New(rand.NewSource(cfg.Seed)),
+		blocks: make(map[common.Root]*Block),
+	}
+	for _, d := range phase0.KickstartDeposits(validators) {
+		sim.deposits = append(sim.deposits, d.Data)
+	}
+	genesis, err := sim.genesisBlock(state, epc)
+	if err != nil {
+		return nil, err
+	}
+	sim.genesis = genesis
+	sim.head = genesis
+	sim.blocks[genesis.Root] = genesis
+	return sim, nil
+}
+
+func (sim *Simulator) genesisBlock(state common.BeaconState, epc *common.EpochsContext) (*Block, error) {
+	hFn := tree.GetHashFn()
+	header, err := state.LatestBlockHeader()
+	if err != nil {
+		return nil, err
+	}
+	header.StateRoot = state.HashTreeRoot(hFn)
+	body, err := sim.emptyBody(state)
+	if err != nil {
+		return nil, err
+	}
+	benv := &common.BeaconBlockEnvelope{
+		ForkDigest:        sim.forks.ForkDigest(0),
+		BeaconBlockHeader: *header,
+		Body:              body,
+		BlockRoot:         header.HashTreeRoot(hFn),
+	}
+	signed, err := beacon.EnvelopeToSignedBeaconBlock(benv)
+	if err != nil {
+		return nil, err
+	}
+	return &Block{
+		Root:     benv.BlockRoot,
+		Signed:   signed.(beacon.OpaqueBlock),
+		Envelope: benv,
+		State:    state,
+		Epc:      epc,
+	}, nil
+}
+
+// Spec returns the spec of the simulation, with its execution engine.
+func (sim *Simulator) Spec() *common.Spec {
+	return sim.spec
+}
+
+// Keys returns the interop keys of all validators, including those of deposits.
+func (sim *Simulator) Keys() interop.Keys {
+	return sim.keys
+}
+
+// Signer returns the signer of validator messages, for the genesis validators root of the simulation.
+func (sim *Simulator) Signer() *interop.Signer {
+	return sim.signer
+}
+
+func (sim *Simulator) Genesis() *Block {
+	return sim.genesis
+}
+
+func (sim *Simulator) Head() *Block {
+	return sim.head
+}
+
+// Slot returns the last simulated slot.
+func (sim *Simulator) Slot() common.Slot {
+	return sim.slot
+}
+
+// Block returns the simulated block with the given root, or nil if unknown.
+func (sim *Simulator) Block(root common.Root) *Block {
+	return sim.blocks[root]
+}
+
+// Events returns all blocks and aggregate attestations, ordered by publication time.
+func (sim *Simulator) Events() []Event {
+	return sim.events
+}
+
+// Chain returns the blocks from genesis to the head, in slot order.
+func (sim *Simulator) Chain() []*Block {
+	var out []*Block
+	for b := sim.head; b != nil; b = sim.blocks[b.ParentRoot] {
+		out = append(out, b)
+		if b == sim.genesis {
+			break
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// Run simulates the given number of regular slots.
+func (sim *Simulator) Run(ctx context.Context, slots uint64) error {
+	for i := uint64(0); i < slots; i++ {
+		if _, err := sim.Step(ctx, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Step simulates the next slot: the proposer publishes a block, and the committees of the slot attest.
+// The aggregate attestations are published at the start of the next slot, when fork choice accepts them.
+// Returns the block of the slot, or nil if the slot was skipped or the proposer is slashed.
+func (sim *Simulator) Step(ctx context.Context, opts *SlotOptions) (*Block, error) {
+	if opts == nil {
+		opts = new(SlotOptions)
+	}
+	slot := sim.slot + 1
+	if epoch := sim.spec.SlotToEpoch(slot); epoch >= sim.spec.ELECTRA_FORK_EPOCH {
+		return nil, fmt.Errorf("cannot simulate slot %d: simulating Electra is not supported, electra state transitions are not implemented", slot)
+	}
+	slotTime, err := sim.spec.TimeAtSlot(slot, sim.cfg.GenesisTime)
+	if err != nil {
+		return nil, err
+	}
+	parent := sim.head
+	if opts.Parent != nil {
+		parent = sim.blocks[*opts.Parent]
+		if parent == nil {
+			return nil, fmt.Errorf("unknown parent block %s", *opts.Parent)
+		}
+	}
+	sim.pruneAttestations(slot)
+
+	var block *Block
+	if !opts.Skip {
+		block, err = sim.proposeBlock(ctx, parent, slot, common.Root{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to propose block at slot %d: %w", slot, err)
+		}
+	}
+	if block != nil {
+		publishTime := slotTime
+		if opts.Late {
+			block.Late = true
+			publishTime += common.Timestamp(sim.spec.SECONDS_PER_SLOT * 2 / 3)
+		}
+		sim.addBlock(block, publishTime)
+		if opts.Equivocate {
+			graffiti := common.Root{0: 1}
+			other, err := sim.proposeBlock(ctx, parent, slot, graffiti)
+			if err != nil {
+				return nil, fmt.Errorf("failed to propose equivocating block at slot %d: %w", slot, err)
+			}
+			other.Late = block.Late
+			sim.addBlock(other, publishTime)
+			sim.proposerSlashings = append(sim.proposerSlashings, phase0.ProposerSlashing{
+				SignedHeader1: common.SignedBeaconBlockHeader{
+					Message:   block.Envelope.BeaconBlockHeader,
+					Signature: block.Envelope.Signature,
+				},
+				SignedHeader2: common.SignedBeaconBlockHeader{
+					Message:   other.Envelope.BeaconBlockHeader,
+					Signature: other.Envelope.Signature,
+				},
+			})
+		}
+	}
+
+	voteHead := sim.head
+	if block != nil && !block.Late {
+		voteHead = block
+	}
+	atts, err := sim.attest(ctx, voteHead, slot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attest at slot %d: %w", slot, err)
+	}
+	hFn := tree.GetHashFn()
+	for _, att := range atts {
+		sim.attestations = append(sim.attestations, poolAttestation{root: att.HashTreeRoot(sim.spec, hFn), att: att})
+		sim.events = append(sim.events, Event{
+			Type:        AttestationEvent,
+			Time:        slotTime + common.Timestamp(sim.spec.SECONDS_PER_SLOT),
+			Attestation: att,
+		})
+	}
+	if block != nil {
+		sim.head = block
+	}
+	sim.slot = slot
+	return block, nil
+}
+
+func (sim *Simulator) addBlock(b *Block, publishTime common.Timestamp) {
+	sim.blocks[b.Root] = b
+	sim.events = append(sim.events, Event{Type: BlockEvent, Time: publishTime, Block: b})
+}
+
+// pruneAttestations removes the attestations that cannot be included anymore at the given slot.
+func (sim *Simulator) pruneAttestations(slot common.Slot) {
+	epoch := sim.spec.SlotToEpoch(slot)
+	out := sim.attestations[:0]
+	for _, a := range sim.attestations {
+		if a.att.Data.Target.Epoch+1 >= epoch {
+			out = append(out, a)
+		}
+	}
+	sim.attestations = out
+}
+
+// stateAt returns a copy of the post-state of the block, processed up to the given slot.
+func (sim *Simulator) stateAt(ctx context.Context, b *Block, slot common.Slot) (common.BeaconState, *common.EpochsContext, error) {
+	state, err := b.State.CopyState()
+	if err != nil {
+		return nil, nil, err
+	}
+	epc := b.Epc.Clone()
+	if slot > b.Slot {
+		up := &beacon.StandardUpgradeableBeaconState{BeaconState: state}
+		if err := common.ProcessSlots(ctx, sim.spec, epc, up, slot); err != nil {
+			return nil, nil, err
+		}
+		state = up.BeaconState
+	}
+	return state, epc, nil
+}
@@ -0,0 +1,188 @@
+package sim
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func testConfig() Config {
+	specCopy := *configs.Minimal
+	specCopy.ALTAIR_FORK_EPOCH = 1
+	specCopy.BELLATRIX_FORK_EPOCH = 2
+	specCopy.CAPELLA_FORK_EPOCH = 3
+	specCopy.DENEB_FORK_EPOCH = 4
+	specCopy.SHARD_COMMITTEE_PERIOD = 0
+	return Config{
+		Spec:              &specCopy,
+		ValidatorCount:    64,
+		GenesisTime:       1_000_000,
+		Participation:     0.9,
+		SyncParticipation: 0.8,
+		Seed:              42,
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	ctx := context.Background()
+	sim, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := sim.Spec()
+	step := func(opts *SlotOptions) *Block {
+		b, err := sim.Step(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	step(nil)
+	if _, err := sim.AddDeposit(spec.MAX_EFFECTIVE_BALANCE); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.AddVoluntaryExit(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.AddAttesterSlashing([]common.ValidatorIndex{8, 7}); err != nil {
+		t.Fatal(err)
+	}
+	step(nil)
+	step(&SlotOptions{Skip: true})
+	step(&SlotOptions{Late: true})
+	equivocating := step(&SlotOptions{Equivocate: true})
+	step(nil)
+	forkParent := equivocating.ParentRoot
+	step(&SlotOptions{Parent: &forkParent})
+	for sim.Slot() < 5*spec.SLOTS_PER_EPOCH+2 {
+		step(nil)
+	}
+
+	head := sim.Head()
+	if _, ok := head.State.(*deneb.BeaconStateView); !ok {
+		t.Fatalf("expected deneb head state, got %T", head.State)
+	}
+	vals, err := head.State.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, err := vals.ValidatorCount(); err != nil {
+		t.Fatal(err)
+	} else if count != 65 {
+		t.Fatalf("expected deposit to be included, got %d validators", count)
+	}
+	isSlashed := func(index common.ValidatorIndex) bool {
+		v, err := vals.Validator(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slashed, err := v.Slashed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return slashed
+	}
+	if !isSlashed(7) || !isSlashed(8) {
+		t.Fatal("expected attester slashing to be included")
+	}
+	if !isSlashed(equivocating.Envelope.ProposerIndex) {
+		t.Fatal("expected proposer slashing to be included")
+	}
+	if v, err := vals.Validator(5); err != nil {
+		t.Fatal(err)
+	} else if exitEpoch, err := v.ExitEpoch(); err != nil {
+		t.Fatal(err)
+	} else if exitEpoch == common.FAR_FUTURE_EPOCH {
+		t.Fatal("expected voluntary exit to be included")
+	}
+	if fin, err := head.State.FinalizedCheckpoint(); err != nil {
+		t.Fatal(err)
+	} else if fin.Epoch == 0 {
+		t.Fatal("expected the chain to finalize")
+	}
+
+	// replay the chain, with all checks of the state transition
+	chain := sim.Chain()
+	state, err := sim.Genesis().State.CopyState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	epc := sim.Genesis().Epc.Clone()
+	up := &beacon.StandardUpgradeableBeaconState{BeaconState: state}
+	for _, b := range chain[1:] {
+		if err := common.StateTransition(ctx, spec, epc, up, b.Envelope, true); err != nil {
+			t.Fatalf("failed to replay block %s at slot %d: %v", b.Root, b.Slot, err)
+		}
+	}
+	if up.HashTreeRoot(tree.GetHashFn()) != head.State.HashTreeRoot(tree.GetHashFn()) {
+		t.Fatal("replayed state does not match head state")
+	}
+
+	if unsignedBlock(sim.Genesis().Envelope).HashTreeRoot(spec, tree.GetHashFn()) != sim.Genesis().Root {
+		t.Fatal("anchor block does not match genesis block root")
+	}
+
+	dir := t.TempDir()
+	if err := sim.WriteBlocksTest(filepath.Join(dir, "all"), 0); err == nil {
+		t.Fatal("expected error for blocks across multiple forks")
+	}
+	if err := sim.WriteBlocksTest(filepath.Join(dir, "deneb"), 4*spec.SLOTS_PER_EPOCH-3); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := os.ReadFile(filepath.Join(dir, "deneb", "meta.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(meta, []byte("post_fork: deneb")) {
+		t.Fatalf("expected transition into deneb, got meta:\n%s", meta)
+	}
+	if err := sim.WriteForkChoiceTest(filepath.Join(dir, "fork_choice")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"anchor_state.ssz_snappy", "anchor_block.ssz_snappy", "steps.yaml", "block_" + equivocating.Root.String() + ".ssz_snappy"} {
+		if _, err := os.Stat(filepath.Join(dir, "fork_choice", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSimulatorDeterministic(t *testing.T) {
+	run := func() common.Root {
+		sim, err := New(testConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sim.Run(context.Background(), 10); err != nil {
+			t.Fatal(err)
+		}
+		return sim.Head().Root
+	}
+	if a, b := run(), run(); a != b {
+		t.Fatalf("different heads: %s <> %s", a, b)
+	}
+}
+
+// TestSimulatorElectra checks that the simulator stops at the Electra fork,
+// until the electra state transition is implemented.
+func TestSimulatorElectra(t *testing.T) {
+	cfg := testConfig()
+	specCopy := *cfg.Spec
+	specCopy.ELECTRA_FORK_EPOCH = 1
+	cfg.Spec = &specCopy
+	sim, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.Run(context.Background(), 2*uint64(specCopy.SLOTS_PER_EPOCH)); err == nil {
+		t.Fatal("expected error when reaching electra")
+	}
+}
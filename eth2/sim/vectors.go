@@ -0,0 +1,175 @@
+package sim
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/snappy"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// BlocksMeta is the meta.yaml of a blocks test.
+// The fork fields are only set when the blocks cross a fork, like in the transition spec tests.
+type BlocksMeta struct {
+	PostFork    string  `yaml:"post_fork,omitempty"`
+	ForkEpoch   *uint64 `yaml:"fork_epoch,omitempty"`
+	ForkBlock   *uint64 `yaml:"fork_block,omitempty"`
+	BlocksCount uint64  `yaml:"blocks_count"`
+}
+
+// ForkChoiceStep is a step of steps.yaml in a fork choice test.
+type ForkChoiceStep struct {
+	Tick        *uint64 `yaml:"tick,omitempty"`
+	Block       string  `yaml:"block,omitempty"`
+	Attestation string  `yaml:"attestation,omitempty"`
+}
+
+func writeSnappy(path string, encode func(w *codec.EncodingWriter) error) error {
+	var buf bytes.Buffer
+	if err := encode(codec.NewEncodingWriter(&buf)); err != nil {
+		return err
+	}
+	return os.WriteFile(path, snappy.Encode(nil, buf.Bytes()), 0o644)
+}
+
+func (sim *Simulator) writeSpecObj(dir string, name string, obj interface {
+	Serialize(spec *common.Spec, w *codec.EncodingWriter) error
+}) error {
+	return writeSnappy(filepath.Join(dir, name+".ssz_snappy"), func(w *codec.EncodingWriter) error {
+		return obj.Serialize(sim.spec, w)
+	})
+}
+
+func writeState(dir string, name string, state common.BeaconState) error {
+	return writeSnappy(filepath.Join(dir, name+".ssz_snappy"), state.Serialize)
+}
+
+func writeYaml(dir string, name string, v interface{}) error {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), data, 0o644)
+}
+
+// WriteBlocksTest writes the blocks of the chain of the head after the given slot as a test case:
+// pre.ssz_snappy is the post-state of the last block at or before the slot,
+// blocks_<i>.ssz_snappy are the signed blocks, post.ssz_snappy is the head state, and meta.yaml the BlocksMeta.
+// This is the format of the sanity/blocks spec tests, or of the transition spec tests if the blocks cross a fork.
+// The blocks can cross at most one fork: the spec tests run with the fork epochs of their pre- and post-fork only.
+func (sim *Simulator) WriteBlocksTest(dir string, after common.Slot) error {
+	chain := sim.Chain()
+	i := 0
+	for i+1 < len(chain) && chain[i+1].Slot <= after {
+		i++
+	}
+	pre, blocks := chain[i], chain[i+1:]
+	preFork := sim.spec.ForkName(sim.spec.SlotToEpoch(pre.Slot))
+	var meta BlocksMeta
+	for j, b := range blocks {
+		name := sim.spec.ForkName(sim.spec.SlotToEpoch(b.Slot))
+		if name == preFork {
+			continue
+		}
+		if meta.PostFork == "" {
+			meta.PostFork = name
+			if j > 0 {
+				forkBlock := uint64(j - 1)
+				meta.ForkBlock = &forkBlock
+			}
+		} else if name != meta.PostFork {
+			return fmt.Errorf("blocks cross more than one fork: %s, %s and %s", preFork, meta.PostFork, name)
+		}
+	}
+	if meta.PostFork != "" {
+		// the first block of the fork may be later than the upgrade, after empty slots
+		epoch := sim.spec.SlotToEpoch(pre.Slot) + 1
+		for sim.spec.ForkName(epoch) != meta.PostFork {
+			epoch++
+		}
+		forkEpoch := uint64(epoch)
+		meta.ForkEpoch = &forkEpoch
+	}
+	meta.BlocksCount = uint64(len(blocks))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := writeState(dir, "pre", pre.State); err != nil {
+		return err
+	}
+	for j, b := range blocks {
+		if err := sim.writeSpecObj(dir, fmt.Sprintf("blocks_%d", j), b.Signed); err != nil {
+			return err
+		}
+	}
+	if err := writeState(dir, "post", sim.head.State); err != nil {
+		return err
+	}
+	return writeYaml(dir, "meta.yaml", &meta)
+}
+
+// WriteForkChoiceTest writes all simulated blocks and attestations, including those of forks and equivocations,
+// as a test case in the format of the fork_choice spec tests: anchor_state.ssz_snappy and anchor_block.ssz_snappy
+// are the genesis state and block, and steps.yaml lists the blocks and attestations by their publication time.
+// Only the steps are written, not the checks: the simulator does not run fork choice.
+func (sim *Simulator) WriteForkChoiceTest(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := writeState(dir, "anchor_state", sim.genesis.State); err != nil {
+		return err
+	}
+	if err := sim.writeSpecObj(dir, "anchor_block", unsignedBlock(sim.genesis.Envelope)); err != nil {
+		return err
+	}
+	hFn := tree.GetHashFn()
+	now := sim.cfg.GenesisTime
+	var steps []ForkChoiceStep
+	for _, ev := range sim.events {
+		if ev.Time > now {
+			t := uint64(ev.Time)
+			steps = append(steps, ForkChoiceStep{Tick: &t})
+			now = ev.Time
+		}
+		switch ev.Type {
+		case BlockEvent:
+			name := "block_" + ev.Block.Root.String()
+			if err := sim.writeSpecObj(dir, name, ev.Block.Signed); err != nil {
+				return err
+			}
+			steps = append(steps, ForkChoiceStep{Block: name})
+		case AttestationEvent:
+			name := "attestation_" + ev.Attestation.HashTreeRoot(sim.spec, hFn).String()
+			if err := sim.writeSpecObj(dir, name, ev.Attestation); err != nil {
+				return err
+			}
+			steps = append(steps, ForkChoiceStep{Attestation: name})
+		}
+	}
+	return writeYaml(dir, "steps.yaml", steps)
+}
+
+// beaconBlock is the BeaconBlock of any fork: the fields of the header, with the body instead of the body root.
+type beaconBlock struct {
+	common.BeaconBlockHeader
+	Body common.SpecObj
+}
+
+// unsignedBlock returns the BeaconBlock of the envelope.
+func unsignedBlock(benv *common.BeaconBlockEnvelope) *beaconBlock {
+	return &beaconBlock{BeaconBlockHeader: benv.BeaconBlockHeader, Body: benv.Body}
+}
+
+func (b *beaconBlock) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	return w.Container(&b.Slot, &b.ProposerIndex, &b.ParentRoot, &b.StateRoot, spec.Wrap(b.Body))
+}
+
+func (b *beaconBlock) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	return hFn.HashTreeRoot(b.Slot, b.ProposerIndex, b.ParentRoot, b.StateRoot, spec.Wrap(b.Body))
+}
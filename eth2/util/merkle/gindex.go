@@ -0,0 +1,150 @@
+package merkle
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+// LengthPathElement selects the length mix-in of a list, like in the spec get_generalized_index.
+const LengthPathElement = "__len__"
+
+// ParsePath splits a path like "validators[123].effective_balance" into its elements:
+// field names, indices of list and vector elements, and LengthPathElement for the length of a list.
+// Field names are separated by dots, indices are written in brackets.
+func ParsePath(path string) ([]interface{}, error) {
+	var out []interface{}
+	if path == "" {
+		return out, nil
+	}
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			return nil, fmt.Errorf("empty element in path %q", path)
+		}
+		name := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+		}
+		if name != "" {
+			out = append(out, name)
+		}
+		for rest := part[len(name):]; rest != ""; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid index %q in path %q", rest, path)
+			}
+			index, err := strconv.ParseUint(rest[1:end], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q in path %q: %w", rest[1:end], path, err)
+			}
+			out = append(out, index)
+			rest = rest[end+1:]
+		}
+	}
+	return out, nil
+}
+
+// GetGeneralizedIndex computes the generalized index of the node at the given path in the SSZ type,
+// see ParsePath for the path format. The type of the node is returned too.
+// Indices of basic elements point to the chunk the element is packed in, like in the spec.
+// Byte lists and byte vectors, like transactions and roots, are indexed by byte.
+func GetGeneralizedIndex(typ view.TypeDef, path string) (tree.Gindex64, view.TypeDef, error) {
+	elements, err := ParsePath(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	return GetGeneralizedIndexOf(typ, elements...)
+}
+
+// GetGeneralizedIndexOf computes the generalized index of the node at the path of parsed elements.
+// An element is either a field name (string) or an index (uint64) of a list or vector element.
+func GetGeneralizedIndexOf(typ view.TypeDef, elements ...interface{}) (tree.Gindex64, view.TypeDef, error) {
+	root := uint64(1)
+	for _, el := range elements {
+		if el == LengthPathElement {
+			if !isList(typ) {
+				return 0, nil, fmt.Errorf("type %s has no length", typ)
+			}
+			if bits.Len64(root) >= 64 {
+				return 0, nil, fmt.Errorf("path is too deep for a 64 bit gindex")
+			}
+			root = root*2 + 1
+			typ = view.Uint64Type
+			continue
+		}
+		pos, chunks, elemType, err := itemPosition(typ, el)
+		if err != nil {
+			return 0, nil, err
+		}
+		depth := uint(tree.CoverDepth(chunks))
+		if isList(typ) {
+			depth += 1
+		}
+		if uint(bits.Len64(root))+depth > 63 {
+			return 0, nil, fmt.Errorf("path is too deep for a 64 bit gindex")
+		}
+		root = root<<depth + pos
+		typ = elemType
+	}
+	return tree.Gindex64(root), typ, nil
+}
+
+func isList(typ view.TypeDef) bool {
+	switch typ.(type) {
+	case *view.BasicListTypeDef, *view.ComplexListTypeDef, *view.BitListTypeDef:
+		return true
+	default:
+		return false
+	}
+}
+
+// itemPosition returns the position of the chunk of the element in the type,
+// the number of chunks of the type, and the type of the element.
+func itemPosition(typ view.TypeDef, el interface{}) (pos uint64, chunks uint64, elemType view.TypeDef, err error) {
+	if t, ok := typ.(*view.ContainerTypeDef); ok {
+		name, ok := el.(string)
+		if !ok {
+			return 0, 0, nil, fmt.Errorf("container %s needs a field name, got %v", t.ContainerName, el)
+		}
+		for i, f := range t.Fields {
+			if f.Name == name {
+				return uint64(i), t.FieldCount(), f.Type, nil
+			}
+		}
+		return 0, 0, nil, fmt.Errorf("container %s has no field %q", t.ContainerName, name)
+	}
+	index, ok := el.(uint64)
+	if !ok {
+		return 0, 0, nil, fmt.Errorf("type %s needs an index, got %v", typ, el)
+	}
+	checkIndex := func(length uint64) error {
+		if index >= length {
+			return fmt.Errorf("index %d out of range for %s", index, typ)
+		}
+		return nil
+	}
+	switch t := typ.(type) {
+	case *view.ComplexVectorTypeDef:
+		return index, t.VectorLength, t.ElemType, checkIndex(t.VectorLength)
+	case *view.ComplexListTypeDef:
+		return index, t.ListLimit, t.ElemType, checkIndex(t.ListLimit)
+	case *view.BasicVectorTypeDef:
+		return index / t.ElementsPerBottomNode(), t.BottomNodeLength(), t.ElemType, checkIndex(t.VectorLength)
+	case *view.BasicListTypeDef:
+		return index / t.ElementsPerBottomNode(), t.BottomNodeLimit(), t.ElemType, checkIndex(t.ListLimit)
+	case *view.BitVectorTypeDef:
+		return index / 256, t.BottomNodeLength(), view.BoolType, checkIndex(t.BitLength)
+	case *view.BitListTypeDef:
+		return index / 256, t.BottomNodeLimit(), view.BoolType, checkIndex(t.BitLimit)
+	case view.SmallByteVecMeta:
+		return 0, 1, view.ByteType, checkIndex(uint64(t))
+	case view.RootMeta:
+		return 0, 1, view.ByteType, checkIndex(32)
+	default:
+		return 0, 0, nil, fmt.Errorf("cannot index into type %s", typ)
+	}
+}
@@ -0,0 +1,119 @@
+package merkle_test
+
+import (
+	"testing"
+
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/interop"
+	"github.com/protolambda/zrnt/eth2/util/merkle"
+)
+
+func TestGeneralizedIndex(t *testing.T) {
+	spec := configs.Mainnet
+	cases := []struct {
+		typ      view.TypeDef
+		path     string
+		expected tree.Gindex64
+	}{
+		{altair.BeaconStateType(spec), "finalized_checkpoint.root", altair.FINALIZED_ROOT_INDEX},
+		{altair.BeaconStateType(spec), "next_sync_committee", altair.NEXT_SYNC_COMMITTEE_INDEX},
+		{electra.BeaconStateType(spec), "finalized_checkpoint.root", 169},
+		{electra.BeaconStateType(spec), "current_sync_committee", 86},
+		{electra.BeaconStateType(spec), "next_sync_committee", 87},
+		// KZG_COMMITMENT_INCLUSION_PROOF_DEPTH is 17
+		{deneb.BeaconBlockBodyType(spec), "blob_kzg_commitments[0]", 27 << 13},
+		{deneb.BeaconBlockBodyType(spec), "blob_kzg_commitments.__len__", 27<<1 | 1},
+		{deneb.BeaconBlockBodyType(spec), "graffiti[3]", 18},
+		// byte lists are indexed by byte, 32 bytes per chunk
+		{deneb.BeaconBlockBodyType(spec), "execution_payload.extra_data[5]", 810 << 1},
+		{deneb.BeaconBlockBodyType(spec), "execution_payload.extra_data.__len__", 810<<1 | 1},
+		{deneb.BeaconBlockBodyType(spec), "execution_payload.transactions[2][100]", ((813<<21 | 2) << 26) | 3},
+		{deneb.BeaconBlockBodyType(spec), "execution_payload.transactions[2].__len__", (813<<21|2)<<1 | 1},
+		{phase0.BeaconStateType(spec), "", 1},
+		{phase0.BeaconStateType(spec), "balances[5]", (44 << 39) | 1},
+		{phase0.BeaconStateType(spec), "validators[123].effective_balance", (((43 << 41) | 123) << 3) | 2},
+	}
+	for _, c := range cases {
+		gindex, _, err := merkle.GetGeneralizedIndex(c.typ, c.path)
+		if err != nil {
+			t.Fatalf("%q: %v", c.path, err)
+		}
+		if gindex != c.expected {
+			t.Errorf("%q: expected gindex %d, got %d", c.path, c.expected, gindex)
+		}
+	}
+	for _, path := range []string{"validators[123].foo", "validators.effective_balance", "slot[1]",
+		"slot.__len__", "validators[1099511627776]", "genesis_validators_root[32]", "validators[1", "validators..slot"} {
+		if _, _, err := merkle.GetGeneralizedIndex(phase0.BeaconStateType(spec), path); err == nil {
+			t.Errorf("%q: expected error", path)
+		}
+	}
+}
+
+func TestMerkleProofs(t *testing.T) {
+	spec := configs.Mainnet
+	keys, err := interop.NewKeys(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _, err := phase0.KickStartState(spec, common.Root{123}, 1564000000, keys.KickstartValidators(spec.MAX_EFFECTIVE_BALANCE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hFn := tree.GetHashFn()
+	root := state.HashTreeRoot(hFn)
+	typ := phase0.BeaconStateType(spec)
+
+	var indices []tree.Gindex64
+	for _, path := range []string{"validators[3].effective_balance", "validators[4].pubkey", "balances[10]",
+		"validators.__len__", "genesis_validators_root", "fork.current_version"} {
+		gindex, _, err := merkle.GetGeneralizedIndex(typ, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		indices = append(indices, gindex)
+
+		leafNode, err := state.Backing().Getter(gindex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		branch, err := merkle.ProveBranch(state.Backing(), gindex, hFn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		depth := uint64(gindex.Depth())
+		index := uint64(gindex) ^ (uint64(1) << depth)
+		if !merkle.VerifyMerkleBranch(leafNode.MerkleRoot(hFn), branch, depth, index, root) {
+			t.Fatalf("%q: invalid branch", path)
+		}
+		if merkle.VerifyMerkleBranch(common.Root{1}, branch, depth, index, root) {
+			t.Fatalf("%q: expected invalid branch for other leaf", path)
+		}
+	}
+
+	leaves, proof, err := merkle.ProveMultiproof(state.Backing(), indices, hFn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proof) != len(merkle.GetHelperIndices(indices)) {
+		t.Fatalf("unexpected proof length %d", len(proof))
+	}
+	if !merkle.VerifyMultiproof(leaves, proof, indices, root) {
+		t.Fatal("invalid multiproof")
+	}
+	leaves[2] = common.Root{1}
+	if merkle.VerifyMultiproof(leaves, proof, indices, root) {
+		t.Fatal("expected invalid multiproof for other leaf")
+	}
+	if merkle.VerifyMultiproof(leaves[:2], proof, indices, root) {
+		t.Fatal("expected invalid multiproof for missing leaf")
+	}
+}
@@ -0,0 +1,117 @@
+package merkle
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/protolambda/ztyp/tree"
+)
+
+// GetHelperIndices returns the generalized indices of the nodes that are needed to prove the nodes
+// at the given indices, in the order of the proof of a multiproof: descending.
+func GetHelperIndices(indices []tree.Gindex64) []tree.Gindex64 {
+	helpers := make(map[tree.Gindex64]struct{})
+	paths := make(map[tree.Gindex64]struct{})
+	for _, index := range indices {
+		for i := index; i > 1; i >>= 1 {
+			helpers[i^1] = struct{}{}
+			paths[i] = struct{}{}
+		}
+	}
+	out := make([]tree.Gindex64, 0, len(helpers))
+	for index := range helpers {
+		if _, ok := paths[index]; !ok {
+			out = append(out, index)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i] > out[j]
+	})
+	return out
+}
+
+// ProveMultiproof collects the leaves at the given generalized indices of the tree,
+// and the roots of the helper nodes, as ordered by GetHelperIndices, to prove them all at once.
+func ProveMultiproof(node tree.Node, indices []tree.Gindex64, hFn tree.HashFn) (leaves []tree.Root, proof []tree.Root, err error) {
+	leaves = make([]tree.Root, len(indices))
+	for i, index := range indices {
+		if index == 0 {
+			return nil, nil, fmt.Errorf("invalid gindex 0")
+		}
+		leaf, err := node.Getter(index)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot get leaf at gindex %d: %w", index, err)
+		}
+		leaves[i] = leaf.MerkleRoot(hFn)
+	}
+	helpers := GetHelperIndices(indices)
+	proof = make([]tree.Root, len(helpers))
+	for i, index := range helpers {
+		helper, err := node.Getter(index)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot get helper node at gindex %d: %w", index, err)
+		}
+		proof[i] = helper.MerkleRoot(hFn)
+	}
+	return leaves, proof, nil
+}
+
+// CalculateMultiMerkleRoot computes the root of the tree with the given leaves,
+// and the multiproof helper roots, as ordered by GetHelperIndices.
+func CalculateMultiMerkleRoot(leaves []tree.Root, proof []tree.Root, indices []tree.Gindex64, hFn tree.HashFn) (tree.Root, error) {
+	if len(leaves) != len(indices) {
+		return tree.Root{}, fmt.Errorf("got %d leaves for %d indices", len(leaves), len(indices))
+	}
+	helpers := GetHelperIndices(indices)
+	if len(proof) != len(helpers) {
+		return tree.Root{}, fmt.Errorf("expected %d proof nodes, got %d", len(helpers), len(proof))
+	}
+	objects := make(map[tree.Gindex64]tree.Root, len(leaves)+len(proof))
+	for i, index := range indices {
+		if index == 0 {
+			return tree.Root{}, fmt.Errorf("invalid gindex 0")
+		}
+		objects[index] = leaves[i]
+	}
+	for i, index := range helpers {
+		objects[index] = proof[i]
+	}
+	keys := make([]tree.Gindex64, 0, len(objects))
+	for index := range objects {
+		keys = append(keys, index)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] > keys[j]
+	})
+	// merge siblings into their parent, processing new parents after the initial keys
+	for pos := 0; pos < len(keys); pos++ {
+		k := keys[pos]
+		if k <= 1 {
+			continue
+		}
+		if _, ok := objects[k>>1]; ok {
+			continue
+		}
+		sibling, ok := objects[k^1]
+		if !ok {
+			continue
+		}
+		if k&1 == 1 {
+			objects[k>>1] = hFn(sibling, objects[k])
+		} else {
+			objects[k>>1] = hFn(objects[k], sibling)
+		}
+		keys = append(keys, k>>1)
+	}
+	root, ok := objects[1]
+	if !ok {
+		return tree.Root{}, fmt.Errorf("multiproof is incomplete, cannot compute root")
+	}
+	return root, nil
+}
+
+// VerifyMultiproof verifies that the leaves at the given generalized indices are part of the tree with the given root.
+func VerifyMultiproof(leaves []tree.Root, proof []tree.Root, indices []tree.Gindex64, root tree.Root) bool {
+	computed, err := CalculateMultiMerkleRoot(leaves, proof, indices, tree.GetHashFn())
+	return err == nil && computed == root
+}
@@ -0,0 +1,40 @@
+package merkle
+
+import (
+	"fmt"
+
+	"github.com/protolambda/ztyp/tree"
+)
+
+// ProveBranch collects the merkle branch of the node at the given generalized index in the tree.
+// The branch is ordered from the bottom up, as expected by VerifyMerkleBranch,
+// with the index at that depth being the gindex without its leading bit.
+func ProveBranch(node tree.Node, gindex tree.Gindex64, hFn tree.HashFn) ([]tree.Root, error) {
+	if gindex == 0 {
+		return nil, fmt.Errorf("invalid gindex 0")
+	}
+	iter, depth := gindex.BitIter()
+	branch := make([]tree.Root, depth)
+	for i := int(depth) - 1; i >= 0; i-- {
+		right, ok := iter.Next()
+		if !ok {
+			return nil, fmt.Errorf("gindex %d ended early", gindex)
+		}
+		left, err := node.Left()
+		if err != nil {
+			return nil, fmt.Errorf("cannot descend into gindex %d: %w", gindex, err)
+		}
+		rightNode, err := node.Right()
+		if err != nil {
+			return nil, fmt.Errorf("cannot descend into gindex %d: %w", gindex, err)
+		}
+		if right {
+			branch[i] = left.MerkleRoot(hFn)
+			node = rightNode
+		} else {
+			branch[i] = rightNode.MerkleRoot(hFn)
+			node = left
+		}
+	}
+	return branch, nil
+}
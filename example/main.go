@@ -1,33 +1,27 @@
 package main
 
 import (
-	"encoding/binary"
 	"fmt"
 
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/interop"
 	"github.com/protolambda/ztyp/tree"
 )
 
-func CreateTestValidators(count uint64, balance common.Gwei) []phase0.KickstartValidatorData {
-	out := make([]phase0.KickstartValidatorData, 0, count)
-	for i := uint64(0); i < count; i++ {
-		pubkey := common.BLSPubkey{0xaa}
-		binary.LittleEndian.PutUint64(pubkey[1:], i)
-		withdrawalCred := common.Root{0xbb}
-		binary.LittleEndian.PutUint64(withdrawalCred[1:], i)
-		out = append(out, phase0.KickstartValidatorData{
-			Pubkey:                pubkey,
-			WithdrawalCredentials: withdrawalCred,
-			Balance:               balance,
-		})
+// CreateTestValidators creates validators with the deterministic interop keys, which can be used to sign messages.
+func CreateTestValidators(count uint64, balance common.Gwei) (interop.Keys, []phase0.KickstartValidatorData) {
+	keys, err := interop.NewKeys(count)
+	if err != nil {
+		panic(err)
 	}
-	return out
+	return keys, keys.KickstartValidators(balance)
 }
 
 func CreateTestState(spec *common.Spec, validatorCount uint64, balance common.Gwei) (*phase0.BeaconStateView, *common.EpochsContext) {
-	out, epc, err := phase0.KickStartState(spec, common.Root{123}, 1564000000, CreateTestValidators(validatorCount, balance))
+	_, validators := CreateTestValidators(validatorCount, balance)
+	out, epc, err := phase0.KickStartState(spec, common.Root{123}, 1564000000, validators)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,152 @@
+package benches
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+var epochValidatorFill = flag.Uint64("epoch-validators", stateValidatorFill,
+	"number of validators in the epoch processing benchmarks, use 1048576 to measure a mainnet-size registry")
+
+// CreateTestEpochState creates a phase0 state at the last slot of epoch 2, ready for an epoch transition.
+func CreateTestEpochState(validatorCount uint64) (*phase0.BeaconStateView, *common.EpochsContext) {
+	state, _ := CreateTestState(validatorCount, MAX_EFFECTIVE_BALANCE)
+	if err := state.SetSlot(spec.SLOTS_PER_EPOCH*3 - 1); err != nil {
+		panic(err)
+	}
+	epc, err := common.NewEpochsContext(spec, state)
+	if err != nil {
+		panic(err)
+	}
+	return state, epc
+}
+
+// CreateTestAltairEpochState creates an altair state at the last slot of epoch 2, ready for an epoch transition,
+// with a mix of participation flags to reward and penalize.
+func CreateTestAltairEpochState(validatorCount uint64) (*altair.BeaconStateView, *common.EpochsContext) {
+	pre, epc := CreateTestEpochState(validatorCount)
+	state, err := altair.UpgradeToAltair(spec, epc, pre)
+	if err != nil {
+		panic(err)
+	}
+	prevParticipation, err := state.PreviousEpochParticipation()
+	if err != nil {
+		panic(err)
+	}
+	for i := common.ValidatorIndex(0); i < common.ValidatorIndex(validatorCount); i++ {
+		flags := altair.ParticipationFlags(i % 8)
+		if flags == 0 {
+			continue
+		}
+		if err := prevParticipation.SetFlags(i, flags); err != nil {
+			panic(err)
+		}
+	}
+	epc, err = common.NewEpochsContext(spec, state)
+	if err != nil {
+		panic(err)
+	}
+	return state, epc
+}
+
+// benchWorkers runs the benchmark with a single worker, and with a worker per CPU if there are multiple.
+func benchWorkers(b *testing.B, fn func(b *testing.B, spec *common.Spec)) {
+	workerCounts := []int{1}
+	if procs := runtime.GOMAXPROCS(0); procs > 1 {
+		workerCounts = append(workerCounts, procs)
+	}
+	for _, workers := range workerCounts {
+		b.Run(fmt.Sprintf("workers_%d", workers), func(b *testing.B) {
+			specCopy := *spec
+			specCopy.Parallelism = common.Parallelism{MaxWorkers: workers}
+			fn(b, &specCopy)
+		})
+	}
+}
+
+func BenchmarkFlattenValidators(b *testing.B) {
+	state, _ := CreateTestEpochState(*epochValidatorFill)
+	vals, err := state.Validators()
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchWorkers(b, func(b *testing.B, spec *common.Spec) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := common.FlattenValidatorsContext(context.Background(), spec.Parallelism, vals); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkAltairAttesterDataAndRewards(b *testing.B) {
+	state, epc := CreateTestAltairEpochState(*epochValidatorFill)
+	vals, err := state.Validators()
+	if err != nil {
+		b.Fatal(err)
+	}
+	flats, err := common.FlattenValidators(vals)
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchWorkers(b, func(b *testing.B, spec *common.Spec) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			attesterData, err := altair.ComputeEpochAttesterData(context.Background(), spec, epc, flats, state)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := altair.AttestationRewardsAndPenalties(context.Background(), spec, epc, attesterData, state); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkAltairProcessEpoch(b *testing.B) {
+	state, epc := CreateTestAltairEpochState(*epochValidatorFill)
+	benchWorkers(b, func(b *testing.B, spec *common.Spec) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			s, err := state.CopyState()
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+			if err := s.ProcessEpoch(context.Background(), spec, epc.Clone()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPhase0ProcessEpoch(b *testing.B) {
+	state, epc := CreateTestEpochState(*epochValidatorFill)
+	benchWorkers(b, func(b *testing.B, spec *common.Spec) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			s, err := state.CopyState()
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+			if err := s.ProcessEpoch(context.Background(), spec, epc.Clone()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
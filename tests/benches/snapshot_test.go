@@ -0,0 +1,35 @@
+package benches
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+func BenchmarkNewEpochsContext(b *testing.B) {
+	state, _ := CreateTestAltairEpochState(*epochValidatorFill)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := common.NewEpochsContext(spec, state); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadEpochsContextSnapshot(b *testing.B) {
+	state, epc := CreateTestAltairEpochState(*epochValidatorFill)
+	var buf bytes.Buffer
+	if err := epc.WriteSnapshot(&buf); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportMetric(float64(buf.Len()), "snapshot-bytes")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := common.LoadEpochsContextSnapshot(spec, state, epc.ValidatorPubkeyCache, bytes.NewReader(buf.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
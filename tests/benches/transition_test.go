@@ -0,0 +1,63 @@
+package benches
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/sim"
+)
+
+type transitionFn func(ctx context.Context, spec *common.Spec, epc *common.EpochsContext,
+	state common.UpgradeableBeaconState, benv *common.BeaconBlockEnvelope, validateResult bool) error
+
+// benchTransition processes the last block of a simulated Deneb chain,
+// which includes the attestations of the previous slot, on a copy of the parent state.
+func benchTransition(b *testing.B, transition transitionFn) {
+	ctx := context.Background()
+	specCopy := *configs.Minimal
+	specCopy.ALTAIR_FORK_EPOCH = 0
+	specCopy.BELLATRIX_FORK_EPOCH = 0
+	specCopy.CAPELLA_FORK_EPOCH = 0
+	specCopy.DENEB_FORK_EPOCH = 0
+	s, err := sim.New(sim.Config{
+		Spec:              &specCopy,
+		ValidatorCount:    256,
+		GenesisTime:       1_000_000,
+		Participation:     1,
+		SyncParticipation: 1,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := s.Run(ctx, 6); err != nil {
+		b.Fatal(err)
+	}
+	head := s.Head()
+	parent := s.Block(head.ParentRoot)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		state, err := parent.State.CopyState()
+		if err != nil {
+			b.Fatal(err)
+		}
+		epc := parent.Epc.Clone()
+		up := &beacon.StandardUpgradeableBeaconState{BeaconState: state}
+		b.StartTimer()
+		if err := transition(ctx, s.Spec(), epc, up, head.Envelope, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStateTransition(b *testing.B) {
+	benchTransition(b, common.StateTransition)
+}
+
+func BenchmarkStateTransitionBatchVerified(b *testing.B) {
+	benchTransition(b, common.StateTransitionBatchVerified)
+}
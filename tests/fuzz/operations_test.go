@@ -0,0 +1,434 @@
+package fuzz
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/interop"
+	"github.com/protolambda/zrnt/eth2/sim"
+)
+
+// the forks of the seed chains. Electra is not fuzzed here:
+// this tree has no Electra block and operation processing, see electra.BeaconStateView.ProcessBlock.
+var forks = []string{"phase0", "altair", "bellatrix", "capella", "deneb"}
+
+type opKind uint8
+
+const (
+	depositOp opKind = iota
+	attestationOp
+	proposerSlashingOp
+	attesterSlashingOp
+	voluntaryExitOp
+	syncAggregateOp
+	blsToExecutionChangeOp
+	withdrawalsOp
+	opKindCount
+)
+
+// preState is the state of a seed chain at the slot of a block, before the block is processed.
+type preState struct {
+	state common.BeaconState
+	epc   *common.EpochsContext
+}
+
+// seedChain is a simulated chain of a single fork, with operations in its blocks.
+type seedChain struct {
+	spec   *common.Spec
+	keys   interop.Keys
+	signer *interop.Signer
+	digest common.ForkDigest
+	blocks []*sim.Block
+	// parents[i] is the post-state of the parent of blocks[i]
+	parents []preState
+	// pres[i] is the parent state of blocks[i], processed to the slot of blocks[i]
+	pres []preState
+}
+
+var (
+	seedsOnce  sync.Once
+	seedChains []*seedChain
+	seedsErr   error
+)
+
+func seeds(tb testing.TB) []*seedChain {
+	seedsOnce.Do(func() {
+		for i := range forks {
+			c, err := newSeedChain(i)
+			if err != nil {
+				seedsErr = fmt.Errorf("failed to simulate %s seed chain: %v", forks[i], err)
+				return
+			}
+			seedChains = append(seedChains, c)
+		}
+	})
+	if seedsErr != nil {
+		tb.Fatal(seedsErr)
+	}
+	return seedChains
+}
+
+// newSeedChain simulates a chain that starts at the given fork,
+// and includes a voluntary exit, an attester slashing and a proposer slashing.
+func newSeedChain(fork int) (*seedChain, error) {
+	ctx := context.Background()
+	specCopy := *configs.Minimal
+	epochs := []*common.Epoch{&specCopy.ALTAIR_FORK_EPOCH, &specCopy.BELLATRIX_FORK_EPOCH,
+		&specCopy.CAPELLA_FORK_EPOCH, &specCopy.DENEB_FORK_EPOCH}
+	for i, e := range epochs {
+		if i < fork {
+			*e = 0
+		} else {
+			*e = common.FAR_FUTURE_EPOCH
+		}
+	}
+	specCopy.SHARD_COMMITTEE_PERIOD = 0
+	s, err := sim.New(sim.Config{
+		Spec:              &specCopy,
+		ValidatorCount:    64,
+		GenesisTime:       1_000_000,
+		Participation:     0.9,
+		SyncParticipation: 0.8,
+		Seed:              int64(fork),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Run(ctx, 2); err != nil {
+		return nil, err
+	}
+	if err := s.AddVoluntaryExit(5); err != nil {
+		return nil, err
+	}
+	if err := s.AddAttesterSlashing([]common.ValidatorIndex{7, 8}); err != nil {
+		return nil, err
+	}
+	if _, err := s.Step(ctx, &sim.SlotOptions{Equivocate: true}); err != nil {
+		return nil, err
+	}
+	if err := s.Run(ctx, 3); err != nil {
+		return nil, err
+	}
+	c := &seedChain{spec: s.Spec(), keys: s.Keys(), signer: s.Signer(), blocks: s.Chain()[1:]}
+	for _, b := range c.blocks {
+		parent := s.Block(b.ParentRoot)
+		state, err := parent.State.CopyState()
+		if err != nil {
+			return nil, err
+		}
+		epc := parent.Epc.Clone()
+		c.parents = append(c.parents, preState{state: state, epc: epc})
+		state, err = parent.State.CopyState()
+		if err != nil {
+			return nil, err
+		}
+		epc = parent.Epc.Clone()
+		up := &beacon.StandardUpgradeableBeaconState{BeaconState: state}
+		if err := common.ProcessSlots(ctx, s.Spec(), epc, up, b.Slot); err != nil {
+			return nil, err
+		}
+		c.pres = append(c.pres, preState{state: up.BeaconState, epc: epc})
+		c.digest = b.Envelope.ForkDigest
+	}
+	return c, nil
+}
+
+// blockOperations are the operations of a block body, of any of the forks of the seed chains.
+type blockOperations struct {
+	ProposerSlashings     phase0.ProposerSlashings
+	AttesterSlashings     phase0.AttesterSlashings
+	Attestations          phase0.Attestations
+	Deposits              phase0.Deposits
+	VoluntaryExits        phase0.VoluntaryExits
+	SyncAggregate         *altair.SyncAggregate
+	BLSToExecutionChanges common.SignedBLSToExecutionChanges
+	Withdrawals           common.Withdrawals
+}
+
+func operationsOf(signed beacon.OpaqueBlock) *blockOperations {
+	switch b := signed.(type) {
+	case *phase0.SignedBeaconBlock:
+		body := &b.Message.Body
+		return &blockOperations{body.ProposerSlashings, body.AttesterSlashings, body.Attestations,
+			body.Deposits, body.VoluntaryExits, nil, nil, nil}
+	case *altair.SignedBeaconBlock:
+		body := &b.Message.Body
+		return &blockOperations{body.ProposerSlashings, body.AttesterSlashings, body.Attestations,
+			body.Deposits, body.VoluntaryExits, &body.SyncAggregate, nil, nil}
+	case *bellatrix.SignedBeaconBlock:
+		body := &b.Message.Body
+		return &blockOperations{body.ProposerSlashings, body.AttesterSlashings, body.Attestations,
+			body.Deposits, body.VoluntaryExits, &body.SyncAggregate, nil, nil}
+	case *capella.SignedBeaconBlock:
+		body := &b.Message.Body
+		return &blockOperations{body.ProposerSlashings, body.AttesterSlashings, body.Attestations,
+			body.Deposits, body.VoluntaryExits, &body.SyncAggregate, body.BLSToExecutionChanges,
+			body.ExecutionPayload.Withdrawals}
+	case *deneb.SignedBeaconBlock:
+		body := &b.Message.Body
+		return &blockOperations{body.ProposerSlashings, body.AttesterSlashings, body.Attestations,
+			body.Deposits, body.VoluntaryExits, &body.SyncAggregate, body.BLSToExecutionChanges,
+			body.ExecutionPayload.Withdrawals}
+	default:
+		panic(fmt.Errorf("unsupported block type %T", signed))
+	}
+}
+
+// newOperation allocates the operation of the given kind, or returns nil if the fork does not have it.
+func newOperation(fork int, kind opKind) interface{} {
+	switch kind {
+	case depositOp:
+		return new(common.Deposit)
+	case attestationOp:
+		return new(phase0.Attestation)
+	case proposerSlashingOp:
+		return new(phase0.ProposerSlashing)
+	case attesterSlashingOp:
+		return new(phase0.AttesterSlashing)
+	case voluntaryExitOp:
+		return new(phase0.SignedVoluntaryExit)
+	case syncAggregateOp:
+		if fork >= 1 {
+			return new(altair.SyncAggregate)
+		}
+	case blsToExecutionChangeOp:
+		if fork >= 3 {
+			return new(common.SignedBLSToExecutionChange)
+		}
+	case withdrawalsOp:
+		if fork >= 3 {
+			return new(common.Withdrawals)
+		}
+	}
+	return nil
+}
+
+// withdrawalsPayload is the part of the execution payload that is processed by capella.ProcessWithdrawals.
+type withdrawalsPayload common.Withdrawals
+
+func (w withdrawalsPayload) GetWitdrawals() []common.Withdrawal {
+	return w
+}
+
+func processOperation(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, op interface{}) error {
+	ctx := context.Background()
+	switch x := op.(type) {
+	case *common.Deposit:
+		return phase0.ProcessDeposit(spec, epc, state, x, false)
+	case *phase0.Attestation:
+		switch s := state.(type) {
+		case *phase0.BeaconStateView:
+			return phase0.ProcessAttestation(ctx, spec, epc, s, x)
+		case *deneb.BeaconStateView:
+			return deneb.ProcessAttestation(ctx, spec, epc, s, x)
+		case altair.AltairLikeBeaconState:
+			return altair.ProcessAttestation(ctx, spec, epc, s, x)
+		}
+	case *phase0.ProposerSlashing:
+		return phase0.ProcessProposerSlashing(ctx, spec, epc, state, x)
+	case *phase0.AttesterSlashing:
+		return phase0.ProcessAttesterSlashing(ctx, spec, epc, state, x)
+	case *phase0.SignedVoluntaryExit:
+		if _, ok := state.(*deneb.BeaconStateView); ok {
+			return deneb.ProcessVoluntaryExit(ctx, spec, epc, state, x)
+		}
+		return phase0.ProcessVoluntaryExit(ctx, spec, epc, state, x)
+	case *altair.SyncAggregate:
+		return altair.ProcessSyncAggregate(ctx, spec, epc, state, x)
+	case *common.SignedBLSToExecutionChange:
+		return capella.ProcessBLSToExecutionChange(ctx, spec, epc, state, x)
+	case *common.Withdrawals:
+		if s, ok := state.(capella.BeaconStateWithWithdrawals); ok {
+			return capella.ProcessWithdrawals(ctx, spec, s, withdrawalsPayload(*x))
+		}
+	}
+	return fmt.Errorf("unsupported operation %T for state %T", op, state)
+}
+
+// proveDeposit puts the deposit at the next deposit index of the state, in a deposit tree with empty leaves
+// for the deposits before it, and updates the eth1 data of the state to it.
+// The fuzzer cannot forge merkle proofs: this makes it reach the processing of the deposit data.
+func proveDeposit(state common.BeaconState, dep *common.Deposit) error {
+	index, err := state.Eth1DepositIndex()
+	if err != nil {
+		return err
+	}
+	eth1Data, err := state.Eth1Data()
+	if err != nil {
+		return err
+	}
+	t := common.NewDepositTree()
+	for i := common.DepositIndex(0); i < index; i++ {
+		if err := t.PushLeaf(common.Root{}); err != nil {
+			return err
+		}
+	}
+	if err := t.PushDeposit(&dep.Data); err != nil {
+		return err
+	}
+	if _, dep.Proof, err = t.Proof(index); err != nil {
+		return err
+	}
+	eth1Data.DepositRoot = t.Root()
+	eth1Data.DepositCount = t.DepositCount()
+	return state.SetEth1Data(eth1Data)
+}
+
+// checkState checks that the state of the fork round-trips through SSZ,
+// and that the hash-tree-root of the struct form agrees with the state.
+func checkState(t *testing.T, fork int, state common.BeaconState) {
+	var buf bytes.Buffer
+	if err := state.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatalf("failed to encode state: %v", err)
+	}
+	typ := &stateTypes[fork]
+	obj := typ.Alloc()
+	if err := deserialize(obj, buf.Bytes()); err != nil {
+		t.Fatalf("%s: cannot decode encoded state: %v", typ.Name, err)
+	}
+	if a, b := hashTreeRoot(obj), state.HashTreeRoot(tree.GetHashFn()); a != b {
+		t.Fatalf("%s: struct hash-tree-root %s does not match state hash-tree-root %s", typ.Name, a, b)
+	}
+	checkSSZ(t, typ, buf.Bytes())
+}
+
+func FuzzProcessOperation(f *testing.F) {
+	chains := seeds(f)
+	for fork, c := range chains {
+		addOp := func(block int, kind opKind, op interface{}) {
+			data, err := serialize(op)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(uint8(fork), uint8(block), uint8(kind), data)
+		}
+		for i, b := range c.blocks {
+			ops := operationsOf(b.Signed)
+			for j := range ops.ProposerSlashings {
+				addOp(i, proposerSlashingOp, &ops.ProposerSlashings[j])
+			}
+			for j := range ops.AttesterSlashings {
+				addOp(i, attesterSlashingOp, &ops.AttesterSlashings[j])
+			}
+			for j := range ops.Attestations {
+				addOp(i, attestationOp, &ops.Attestations[j])
+			}
+			for j := range ops.VoluntaryExits {
+				addOp(i, voluntaryExitOp, &ops.VoluntaryExits[j])
+			}
+			if ops.SyncAggregate != nil {
+				addOp(i, syncAggregateOp, ops.SyncAggregate)
+			}
+			if fork >= 3 {
+				addOp(i, withdrawalsOp, &ops.Withdrawals)
+			}
+		}
+		// a deposit of a new validator, and a BLS to execution change of an existing validator
+		sk, err := interop.SecretKey(uint64(len(c.keys)))
+		if err != nil {
+			f.Fatal(err)
+		}
+		pub, err := blsu.SkToPk(sk)
+		if err != nil {
+			f.Fatal(err)
+		}
+		dep := common.Deposit{Data: common.DepositData{
+			Pubkey:                pub.Serialize(),
+			WithdrawalCredentials: interop.BLSWithdrawalCredentials(pub.Serialize()),
+			Amount:                c.spec.MAX_EFFECTIVE_BALANCE,
+		}}
+		dep.Data.Signature = c.signer.SignDepositData(sk, &dep.Data)
+		addOp(0, depositOp, &dep)
+		if fork >= 3 {
+			change := common.BLSToExecutionChange{
+				ValidatorIndex:     0,
+				FromBLSPubKey:      c.keys[0].Pubkey,
+				ToExecutionAddress: common.Eth1Address{0: 0xaa},
+			}
+			addOp(0, blsToExecutionChangeOp, &common.SignedBLSToExecutionChange{
+				BLSToExecutionChange: change,
+				Signature:            c.signer.SignBLSToExecutionChange(c.keys[0].Secret, &change),
+			})
+		}
+	}
+	f.Fuzz(func(t *testing.T, forkIndex uint8, block uint8, kind uint8, data []byte) {
+		fork := int(forkIndex) % len(chains)
+		c := chains[fork]
+		pre := c.pres[int(block)%len(c.pres)]
+		op := newOperation(fork, opKind(kind%uint8(opKindCount)))
+		if op == nil {
+			return
+		}
+		if err := deserialize(op, data); err != nil {
+			return
+		}
+		state, err := pre.state.CopyState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		epc := pre.epc.Clone()
+		if dep, ok := op.(*common.Deposit); ok {
+			if err := proveDeposit(state, dep); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// invalid operations are expected, the state must still be consistent after processing
+		_ = processOperation(c.spec, epc, state, op)
+		checkState(t, fork, state)
+	})
+}
+
+func FuzzStateTransition(f *testing.F) {
+	chains := seeds(f)
+	for fork, c := range chains {
+		for i, b := range c.blocks {
+			data, err := serialize(b.Signed)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(uint8(fork), uint8(i), data)
+		}
+	}
+	f.Fuzz(func(t *testing.T, forkIndex uint8, block uint8, data []byte) {
+		fork := int(forkIndex) % len(chains)
+		c := chains[fork]
+		parent := c.parents[int(block)%len(c.parents)]
+		signed := blockTypes[3*fork].Alloc().(beacon.OpaqueBlock)
+		if err := deserialize(signed, data); err != nil {
+			return
+		}
+		benv := signed.Envelope(c.spec, c.digest)
+		parentSlot, err := parent.state.Slot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// don't spend the fuzzing time on processing empty slots
+		if benv.Slot > parentSlot+2*c.spec.SLOTS_PER_EPOCH {
+			return
+		}
+		state, err := parent.state.CopyState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		epc := parent.epc.Clone()
+		up := &beacon.StandardUpgradeableBeaconState{BeaconState: state}
+		// the block signature and state root are not validated, for modified blocks to reach the block processing
+		_ = common.StateTransition(context.Background(), c.spec, epc, up, benv, false)
+		checkState(t, fork, up.BeaconState)
+	})
+}
@@ -0,0 +1,213 @@
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+var spec = configs.Minimal
+
+// sszType is a type with both a struct form and a view form
+type sszType struct {
+	Name  string
+	Alloc func() interface{}
+	Type  view.TypeDef
+}
+
+var blockTypes = []sszType{
+	{"phase0.SignedBeaconBlock", func() interface{} { return new(phase0.SignedBeaconBlock) }, phase0.SignedBeaconBlockType(spec)},
+	{"phase0.BeaconBlock", func() interface{} { return new(phase0.BeaconBlock) }, phase0.BeaconBlockType(spec)},
+	{"phase0.BeaconBlockBody", func() interface{} { return new(phase0.BeaconBlockBody) }, phase0.BeaconBlockBodyType(spec)},
+	{"altair.SignedBeaconBlock", func() interface{} { return new(altair.SignedBeaconBlock) }, altair.SignedBeaconBlockType(spec)},
+	{"altair.BeaconBlock", func() interface{} { return new(altair.BeaconBlock) }, altair.BeaconBlockType(spec)},
+	{"altair.BeaconBlockBody", func() interface{} { return new(altair.BeaconBlockBody) }, altair.BeaconBlockBodyType(spec)},
+	{"bellatrix.SignedBeaconBlock", func() interface{} { return new(bellatrix.SignedBeaconBlock) }, bellatrix.SignedBeaconBlockType(spec)},
+	{"bellatrix.BeaconBlock", func() interface{} { return new(bellatrix.BeaconBlock) }, bellatrix.BeaconBlockType(spec)},
+	{"bellatrix.BeaconBlockBody", func() interface{} { return new(bellatrix.BeaconBlockBody) }, bellatrix.BeaconBlockBodyType(spec)},
+	{"capella.SignedBeaconBlock", func() interface{} { return new(capella.SignedBeaconBlock) }, capella.SignedBeaconBlockType(spec)},
+	{"capella.BeaconBlock", func() interface{} { return new(capella.BeaconBlock) }, capella.BeaconBlockType(spec)},
+	{"capella.BeaconBlockBody", func() interface{} { return new(capella.BeaconBlockBody) }, capella.BeaconBlockBodyType(spec)},
+	{"deneb.SignedBeaconBlock", func() interface{} { return new(deneb.SignedBeaconBlock) }, deneb.SignedBeaconBlockType(spec)},
+	{"deneb.BeaconBlock", func() interface{} { return new(deneb.BeaconBlock) }, deneb.BeaconBlockType(spec)},
+	{"deneb.BeaconBlockBody", func() interface{} { return new(deneb.BeaconBlockBody) }, deneb.BeaconBlockBodyType(spec)},
+	{"electra.SignedBeaconBlock", func() interface{} { return new(electra.SignedBeaconBlock) }, electra.SignedBeaconBlockType(spec)},
+	{"electra.BeaconBlock", func() interface{} { return new(electra.BeaconBlock) }, electra.BeaconBlockType(spec)},
+	{"electra.BeaconBlockBody", func() interface{} { return new(electra.BeaconBlockBody) }, electra.BeaconBlockBodyType(spec)},
+}
+
+var stateTypes = []sszType{
+	{"phase0.BeaconState", func() interface{} { return new(phase0.BeaconState) }, phase0.BeaconStateType(spec)},
+	{"altair.BeaconState", func() interface{} { return new(altair.BeaconState) }, altair.BeaconStateType(spec)},
+	{"bellatrix.BeaconState", func() interface{} { return new(bellatrix.BeaconState) }, bellatrix.BeaconStateType(spec)},
+	{"capella.BeaconState", func() interface{} { return new(capella.BeaconState) }, capella.BeaconStateType(spec)},
+	{"deneb.BeaconState", func() interface{} { return new(deneb.BeaconState) }, deneb.BeaconStateType(spec)},
+	{"electra.BeaconState", func() interface{} { return new(electra.BeaconState) }, electra.BeaconStateType(spec)},
+}
+
+var operationTypes = []sszType{
+	{"Deposit", func() interface{} { return new(common.Deposit) }, common.DepositType},
+	{"phase0.Attestation", func() interface{} { return new(phase0.Attestation) }, phase0.AttestationType(spec)},
+	{"ProposerSlashing", func() interface{} { return new(phase0.ProposerSlashing) }, phase0.ProposerSlashingType},
+	{"phase0.AttesterSlashing", func() interface{} { return new(phase0.AttesterSlashing) }, phase0.AttesterSlashingType(spec)},
+	{"SignedVoluntaryExit", func() interface{} { return new(phase0.SignedVoluntaryExit) }, phase0.SignedVoluntaryExitType},
+	{"SignedBLSToExecutionChange", func() interface{} { return new(common.SignedBLSToExecutionChange) }, common.SignedBLSToExecutionChangeType},
+	{"Withdrawal", func() interface{} { return new(common.Withdrawal) }, common.WithdrawalType},
+	{"Withdrawals", func() interface{} { return new(common.Withdrawals) }, common.WithdrawalsType(spec)},
+	{"SyncAggregate", func() interface{} { return new(altair.SyncAggregate) }, altair.SyncAggregateType(spec)},
+	{"bellatrix.ExecutionPayload", func() interface{} { return new(bellatrix.ExecutionPayload) }, bellatrix.ExecutionPayloadType(spec)},
+	{"capella.ExecutionPayload", func() interface{} { return new(capella.ExecutionPayload) }, capella.ExecutionPayloadType(spec)},
+	{"deneb.ExecutionPayload", func() interface{} { return new(deneb.ExecutionPayload) }, deneb.ExecutionPayloadType(spec)},
+	{"electra.Attestation", func() interface{} { return new(electra.Attestation) }, electra.AttestationType(spec)},
+	{"electra.SingleAttestation", func() interface{} { return new(electra.SingleAttestation) }, electra.SingleAttestationType},
+	{"electra.AttesterSlashing", func() interface{} { return new(electra.AttesterSlashing) }, electra.AttesterSlashingType(spec)},
+	{"electra.ExecutionRequests", func() interface{} { return new(electra.ExecutionRequests) }, electra.ExecutionRequestsType(spec)},
+}
+
+func deserialize(obj interface{}, data []byte) error {
+	r := bytes.NewReader(data)
+	dr := codec.NewDecodingReader(r, uint64(len(data)))
+	var err error
+	switch x := obj.(type) {
+	case common.SpecObj:
+		err = x.Deserialize(spec, dr)
+	case codec.Deserializable:
+		err = x.Deserialize(dr)
+	default:
+		panic("type cannot be deserialized")
+	}
+	if err != nil {
+		return err
+	}
+	// fixed-size types read just their own size, the caller has to reject any trailing bytes
+	if r.Len() != 0 {
+		return fmt.Errorf("%d trailing bytes", r.Len())
+	}
+	return nil
+}
+
+func serialize(obj interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	w := codec.NewEncodingWriter(&buf)
+	var err error
+	switch x := obj.(type) {
+	case common.SpecObj:
+		err = x.Serialize(spec, w)
+	case codec.Serializable:
+		err = x.Serialize(w)
+	case view.View:
+		err = x.Serialize(w)
+	default:
+		panic("type cannot be serialized")
+	}
+	return buf.Bytes(), err
+}
+
+func hashTreeRoot(obj interface{}) common.Root {
+	hFn := tree.GetHashFn()
+	switch x := obj.(type) {
+	case common.SpecObj:
+		return x.HashTreeRoot(spec, hFn)
+	case tree.HTR:
+		return x.HashTreeRoot(hFn)
+	default:
+		panic("type has no hash-tree-root")
+	}
+}
+
+// checkSSZ decodes the data into the struct form of the type, and if valid, checks that it re-encodes to the same data,
+// and that the view form decodes the same data, re-encodes it, and has the same hash-tree-root.
+// The struct form is the reference: the view form is more lenient, e.g. with unused bytes between offsets.
+func checkSSZ(t *testing.T, typ *sszType, data []byte) {
+	obj := typ.Alloc()
+	if err := deserialize(obj, data); err != nil {
+		return
+	}
+	enc, err := serialize(obj)
+	if err != nil {
+		t.Fatalf("%s: failed to encode decoded struct: %v", typ.Name, err)
+	}
+	if !bytes.Equal(enc, data) {
+		t.Fatalf("%s: struct round-trip mismatch:\ninput:  %x\noutput: %x", typ.Name, data, enc)
+	}
+	v, err := typ.Type.Deserialize(codec.NewDecodingReader(bytes.NewReader(data), uint64(len(data))))
+	if err != nil {
+		t.Fatalf("%s: view cannot decode valid struct encoding: %v", typ.Name, err)
+	}
+	enc, err = serialize(v)
+	if err != nil {
+		t.Fatalf("%s: failed to encode decoded view: %v", typ.Name, err)
+	}
+	if !bytes.Equal(enc, data) {
+		t.Fatalf("%s: view round-trip mismatch:\ninput:  %x\noutput: %x", typ.Name, data, enc)
+	}
+	if a, b := hashTreeRoot(obj), v.HashTreeRoot(tree.GetHashFn()); a != b {
+		t.Fatalf("%s: struct hash-tree-root %s does not match view hash-tree-root %s", typ.Name, a, b)
+	}
+}
+
+// addDefaults seeds the corpus with the encoding of the default value of each type.
+func addDefaults(f *testing.F, types []sszType) {
+	for i := range types {
+		data, err := serialize(types[i].Type.Default(nil))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(uint8(i), data)
+	}
+}
+
+func fuzzTypes(f *testing.F, types []sszType) {
+	f.Fuzz(func(t *testing.T, typ uint8, data []byte) {
+		checkSSZ(t, &types[int(typ)%len(types)], data)
+	})
+}
+
+func FuzzDecodeBlock(f *testing.F) {
+	addDefaults(f, blockTypes)
+	for fork, c := range seeds(f) {
+		for _, b := range c.blocks {
+			data, err := serialize(b.Signed)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(uint8(3*fork), data)
+		}
+	}
+	fuzzTypes(f, blockTypes)
+}
+
+func FuzzDecodeState(f *testing.F) {
+	addDefaults(f, stateTypes)
+	for fork, c := range seeds(f) {
+		var buf bytes.Buffer
+		if err := c.pres[len(c.pres)-1].state.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(uint8(fork), buf.Bytes())
+	}
+	f.Fuzz(func(t *testing.T, typ uint8, data []byte) {
+		checkSSZ(t, &stateTypes[int(typ)%len(stateTypes)], data)
+		// the fork of the state is decoded from the slot, like when loading states of any fork
+		_, _ = beacon.DecodeBeaconState(spec, data)
+	})
+}
+
+func FuzzDecodeOperation(f *testing.F) {
+	addDefaults(f, operationTypes)
+	fuzzTypes(f, operationTypes)
+}
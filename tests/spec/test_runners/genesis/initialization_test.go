@@ -7,7 +7,12 @@ import (
 	"testing"
 
 	"github.com/golang/snappy"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 	"github.com/protolambda/zrnt/tests/spec/test_util"
 	"github.com/protolambda/ztyp/codec"
@@ -16,15 +21,19 @@ import (
 
 type InitializationTestCase struct {
 	Spec          *common.Spec
-	GenesisState  *phase0.BeaconStateView
-	ExpectedState *phase0.BeaconStateView
+	Fork          test_util.ForkName
+	GenesisState  common.BeaconState
+	ExpectedState common.BeaconState
 	Eth1Timestamp common.Timestamp
 	Eth1BlockHash common.Root
 	Deposits      []common.Deposit
+	// Encoded execution payload header, empty if the genesis state starts without one.
+	ExecutionPayloadHeader []byte
 }
 
 type DepositsCountMeta struct {
-	DepositsCount uint64 `yaml:"deposits_count"`
+	DepositsCount          uint64 `yaml:"deposits_count"`
+	ExecutionPayloadHeader bool   `yaml:"execution_payload_header"`
 }
 
 type Eth1InitData struct {
@@ -33,28 +42,10 @@ type Eth1InitData struct {
 }
 
 func (c *InitializationTestCase) Load(t *testing.T, forkName test_util.ForkName, readPart test_util.TestPartReader) {
-	if forkName != "phase0" {
-		t.Fatalf("genesis initialization not supported for fork %s", forkName)
-	}
 	c.Spec = readPart.Spec()
-	{
-		p := readPart.Part("state.ssz_snappy")
-		if p.Exists() {
-			data, err := ioutil.ReadAll(p)
-			test_util.Check(t, err)
-			test_util.Check(t, p.Close())
-			uncompressed, err := snappy.Decode(nil, data)
-			test_util.Check(t, err)
-			state, err := phase0.AsBeaconStateView(
-				phase0.BeaconStateType(c.Spec).Deserialize(
-					codec.NewDecodingReader(bytes.NewReader(uncompressed), uint64(len(uncompressed)))))
-			test_util.Check(t, err)
-			c.ExpectedState = state
-		} else {
-			// expecting a failed genesis
-			c.ExpectedState = nil
-		}
-	}
+	c.Fork = forkName
+	// a missing state means we are expecting a failed genesis
+	c.ExpectedState = test_util.LoadState(t, forkName, "state", readPart)
 	{
 		p := readPart.Part("eth1.yaml")
 		dec := yaml.NewDecoder(p)
@@ -78,10 +69,59 @@ func (c *InitializationTestCase) Load(t *testing.T, forkName test_util.ForkName,
 			c.Deposits = append(c.Deposits, dep)
 		}
 	}
+	if m.ExecutionPayloadHeader {
+		p := readPart.Part("execution_payload_header.ssz_snappy")
+		data, err := ioutil.ReadAll(p)
+		test_util.Check(t, err)
+		test_util.Check(t, p.Close())
+		c.ExecutionPayloadHeader, err = snappy.Decode(nil, data)
+		test_util.Check(t, err)
+	}
+}
+
+func (c *InitializationTestCase) decodeHeader(dst codec.Deserializable) error {
+	// without header the genesis state starts with an empty header, like the spec default
+	if len(c.ExecutionPayloadHeader) == 0 {
+		return nil
+	}
+	return dst.Deserialize(codec.NewDecodingReader(bytes.NewReader(c.ExecutionPayloadHeader), uint64(len(c.ExecutionPayloadHeader))))
 }
 
 func (c *InitializationTestCase) Run() error {
-	res, _, err := phase0.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, false)
+	var res common.BeaconState
+	var err error
+	switch c.Fork {
+	case "phase0":
+		res, _, err = phase0.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, false)
+	case "altair":
+		res, _, err = altair.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, false)
+	case "bellatrix":
+		var header bellatrix.ExecutionPayloadHeader
+		if err := c.decodeHeader(&header); err != nil {
+			return err
+		}
+		res, _, err = bellatrix.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, false, &header)
+	case "capella":
+		var header capella.ExecutionPayloadHeader
+		if err := c.decodeHeader(&header); err != nil {
+			return err
+		}
+		res, _, err = capella.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, false, &header)
+	case "deneb":
+		var header deneb.ExecutionPayloadHeader
+		if err := c.decodeHeader(&header); err != nil {
+			return err
+		}
+		res, _, err = deneb.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, false, &header)
+	case "electra":
+		var header deneb.ExecutionPayloadHeader
+		if err := c.decodeHeader(&header); err != nil {
+			return err
+		}
+		res, _, err = electra.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, false, &header)
+	default:
+		return fmt.Errorf("unrecognized fork name: %s", c.Fork)
+	}
 	if err != nil {
 		return err
 	}
@@ -108,7 +148,6 @@ func (c *InitializationTestCase) Check(t *testing.T) {
 }
 
 func TestInitialization(t *testing.T) {
-	// TODO: support initialization for all forks, not just phase0
-	test_util.RunTransitionTest(t, []test_util.ForkName{"phase0"}, "genesis", "initialization",
+	test_util.RunTransitionTest(t, append(test_util.AllForks, "electra"), "genesis", "initialization",
 		func() test_util.TransitionTest { return new(InitializationTestCase) })
 }
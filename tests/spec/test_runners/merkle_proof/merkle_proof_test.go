@@ -0,0 +1,142 @@
+package merkle_proof
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/util/merkle"
+	"github.com/protolambda/zrnt/tests/spec/test_util"
+)
+
+type MerkleProof struct {
+	Leaf      common.Root   `yaml:"leaf"`
+	LeafIndex uint64        `yaml:"leaf_index"`
+	Branch    []common.Root `yaml:"branch"`
+}
+
+type SingleMerkleProofTestCase struct {
+	Object view.View
+	Proof  MerkleProof
+}
+
+func (c *SingleMerkleProofTestCase) Load(t *testing.T, typ view.TypeDef, readPart test_util.TestPartReader) {
+	{
+		p := readPart.Part("object.ssz_snappy")
+		data, err := ioutil.ReadAll(p)
+		test_util.Check(t, err)
+		test_util.Check(t, p.Close())
+		uncompressed, err := snappy.Decode(nil, data)
+		test_util.Check(t, err)
+		obj, err := typ.Deserialize(codec.NewDecodingReader(bytes.NewReader(uncompressed), uint64(len(uncompressed))))
+		test_util.Check(t, err)
+		c.Object = obj
+	}
+	{
+		p := readPart.Part("proof.yaml")
+		dec := yaml.NewDecoder(p)
+		test_util.Check(t, dec.Decode(&c.Proof))
+		test_util.Check(t, p.Close())
+	}
+}
+
+func (c *SingleMerkleProofTestCase) Run(t *testing.T) {
+	hFn := tree.GetHashFn()
+	root := c.Object.HashTreeRoot(hFn)
+	gindex := tree.Gindex64(c.Proof.LeafIndex)
+	depth := uint64(gindex.Depth())
+	index := c.Proof.LeafIndex ^ (uint64(1) << depth)
+	if !merkle.VerifyMerkleBranch(c.Proof.Leaf, c.Proof.Branch, depth, index, root) {
+		t.Fatalf("expected proof is not valid")
+	}
+
+	leaf, err := c.Object.Backing().Getter(gindex)
+	test_util.Check(t, err)
+	if got := leaf.MerkleRoot(hFn); got != c.Proof.Leaf {
+		t.Fatalf("leaf %s does not match expected leaf %s", got, c.Proof.Leaf)
+	}
+	branch, err := merkle.ProveBranch(c.Object.Backing(), gindex, hFn)
+	test_util.Check(t, err)
+	checkRoots(t, "branch", branch, c.Proof.Branch)
+
+	// a multiproof of a single leaf is the same as its branch
+	indices := []tree.Gindex64{gindex}
+	leaves, proof, err := merkle.ProveMultiproof(c.Object.Backing(), indices, hFn)
+	test_util.Check(t, err)
+	checkRoots(t, "multiproof", proof, c.Proof.Branch)
+	if !merkle.VerifyMultiproof(leaves, proof, indices, root) {
+		t.Fatalf("multiproof is not valid")
+	}
+}
+
+func checkRoots(t *testing.T, name string, got []tree.Root, expected []common.Root) {
+	if len(got) != len(expected) {
+		t.Fatalf("%s has %d roots, expected %d", name, len(got), len(expected))
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("%s root %d: got %s, expected %s", name, i, got[i], expected[i])
+		}
+	}
+}
+
+type typeFn func(spec *common.Spec) view.TypeDef
+
+// containers are the types that the light_client and merkle_proof suites prove fields of, by fork.
+var containers = map[test_util.ForkName]map[string]typeFn{
+	"altair": {
+		"BeaconState":     func(spec *common.Spec) view.TypeDef { return altair.BeaconStateType(spec) },
+		"BeaconBlockBody": func(spec *common.Spec) view.TypeDef { return altair.BeaconBlockBodyType(spec) },
+	},
+	"bellatrix": {
+		"BeaconState":     func(spec *common.Spec) view.TypeDef { return bellatrix.BeaconStateType(spec) },
+		"BeaconBlockBody": func(spec *common.Spec) view.TypeDef { return bellatrix.BeaconBlockBodyType(spec) },
+	},
+	"capella": {
+		"BeaconState":     func(spec *common.Spec) view.TypeDef { return capella.BeaconStateType(spec) },
+		"BeaconBlockBody": func(spec *common.Spec) view.TypeDef { return capella.BeaconBlockBodyType(spec) },
+	},
+	"deneb": {
+		"BeaconState":     func(spec *common.Spec) view.TypeDef { return deneb.BeaconStateType(spec) },
+		"BeaconBlockBody": func(spec *common.Spec) view.TypeDef { return deneb.BeaconBlockBodyType(spec) },
+	},
+	"electra": {
+		"BeaconState":     func(spec *common.Spec) view.TypeDef { return electra.BeaconStateType(spec) },
+		"BeaconBlockBody": func(spec *common.Spec) view.TypeDef { return electra.BeaconBlockBodyType(spec) },
+	},
+}
+
+func TestSingleMerkleProof(t *testing.T) {
+	for _, spec := range []*common.Spec{configs.Minimal, configs.Mainnet} {
+		for fork, types := range containers {
+			types := types
+			suiteRunner := func(suite string) test_util.CaseRunner {
+				return func(t *testing.T, forkName test_util.ForkName, readPart test_util.TestPartReader) {
+					typ, ok := types[suite]
+					if !ok {
+						t.Fatalf("unknown %s container %q", forkName, suite)
+					}
+					c := new(SingleMerkleProofTestCase)
+					c.Load(t, typ(readPart.Spec()), readPart)
+					c.Run(t)
+				}
+			}
+			for _, handler := range []string{"light_client/single_merkle_proof", "merkle_proof/single_merkle_proof"} {
+				test_util.RunHandlerSuites(t, handler, suiteRunner, spec, fork)
+			}
+		}
+	}
+}
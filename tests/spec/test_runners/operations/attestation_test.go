@@ -1,6 +1,7 @@
 package operations
 
 import (
+	"context"
 	"fmt"
 	"github.com/protolambda/zrnt/eth2/beacon/deneb"
 	"testing"
@@ -27,13 +28,13 @@ func (c *AttestationTestCase) Run() error {
 		return err
 	}
 	if s, ok := c.Pre.(phase0.Phase0PendingAttestationsBeaconState); ok {
-		return phase0.ProcessAttestation(c.Spec, epc, s, &c.Attestation)
+		return phase0.ProcessAttestation(context.Background(), c.Spec, epc, s, &c.Attestation)
 	} else if s, ok := c.Pre.(altair.AltairLikeBeaconState); ok {
 		switch c.Fork {
 		case "altair", "bellatrix", "capella":
-			return altair.ProcessAttestation(c.Spec, epc, s, &c.Attestation)
+			return altair.ProcessAttestation(context.Background(), c.Spec, epc, s, &c.Attestation)
 		case "deneb":
-			return deneb.ProcessAttestation(c.Spec, epc, s, &c.Attestation)
+			return deneb.ProcessAttestation(context.Background(), c.Spec, epc, s, &c.Attestation)
 		default:
 			return fmt.Errorf("unrecognized fork: %s", c.Fork)
 		}
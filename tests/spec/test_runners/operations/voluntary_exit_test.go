@@ -1,6 +1,7 @@
 package operations
 
 import (
+	"context"
 	"github.com/protolambda/zrnt/eth2/beacon/deneb"
 	"testing"
 
@@ -25,9 +26,9 @@ func (c *VoluntaryExitTestCase) Run() error {
 		return err
 	}
 	if c.Fork == "deneb" {
-		return deneb.ProcessVoluntaryExit(c.Spec, epc, c.Pre, &c.VoluntaryExit)
+		return deneb.ProcessVoluntaryExit(context.Background(), c.Spec, epc, c.Pre, &c.VoluntaryExit)
 	} else {
-		return phase0.ProcessVoluntaryExit(c.Spec, epc, c.Pre, &c.VoluntaryExit)
+		return phase0.ProcessVoluntaryExit(context.Background(), c.Spec, epc, c.Pre, &c.VoluntaryExit)
 	}
 }
 
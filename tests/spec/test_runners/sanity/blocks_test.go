@@ -10,3 +10,8 @@ func TestBlocks(t *testing.T) {
 	test_util.RunTransitionTest(t, test_util.AllForks, "sanity", "blocks",
 		func() test_util.TransitionTest { return new(test_util.BlocksTestCase) })
 }
+
+func TestBlocksBatchVerified(t *testing.T) {
+	test_util.RunTransitionTest(t, test_util.AllForks, "sanity", "blocks",
+		func() test_util.TransitionTest { return &test_util.BlocksTestCase{BatchVerify: true} })
+}
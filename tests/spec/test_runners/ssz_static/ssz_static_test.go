@@ -185,6 +185,7 @@ func init() {
 	objs["deneb"]["SignedBeaconBlock"] = func() interface{} { return new(deneb.SignedBeaconBlock) }
 	objs["deneb"]["ExecutionPayload"] = func() interface{} { return new(deneb.ExecutionPayload) }
 	objs["deneb"]["ExecutionPayloadHeader"] = func() interface{} { return new(deneb.ExecutionPayloadHeader) }
+	objs["deneb"]["BlobSidecar"] = func() interface{} { return new(deneb.BlobSidecar) }
 
 	objs["electra"]["BeaconBlockBody"] = func() interface{} { return new(electra.BeaconBlockBody) }
 	objs["electra"]["BeaconBlock"] = func() interface{} { return new(electra.BeaconBlock) }
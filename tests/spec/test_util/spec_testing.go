@@ -67,6 +67,14 @@ func (s *partAndSpec) Spec() *common.Spec {
 }
 
 func RunHandler(t *testing.T, handlerPath string, caseRunner CaseRunner, spec *common.Spec, fork ForkName) {
+	RunHandlerSuites(t, handlerPath, func(suite string) CaseRunner {
+		return caseRunner
+	}, spec, fork)
+}
+
+// RunHandlerSuites is like RunHandler, but selects the case runner by the name of the suite,
+// for handlers that group their cases by the tested type.
+func RunHandlerSuites(t *testing.T, handlerPath string, suiteRunner func(suite string) CaseRunner, spec *common.Spec, fork ForkName) {
 	// get the current path, go to the root, and get the tests path
 	_, filename, _, _ := runtime.Caller(0)
 	basepath := filepath.Dir(filepath.Dir(filename))
@@ -90,7 +98,7 @@ func RunHandler(t *testing.T, handlerPath string, caseRunner CaseRunner, spec *c
 		}
 	}
 
-	runTest := func(t *testing.T, path string) {
+	runTest := func(t *testing.T, path string, caseRunner CaseRunner) {
 		//t.Parallel()
 		partReader := func(name string) TestPart {
 			partPath := filepath.Join(path, name)
@@ -107,7 +115,10 @@ func RunHandler(t *testing.T, handlerPath string, caseRunner CaseRunner, spec *c
 
 	runSuite := func(t *testing.T, path string) {
 		//t.Parallel()
-		forEachDir(t, path, runTest)
+		caseRunner := suiteRunner(filepath.Base(path))
+		forEachDir(t, path, func(t *testing.T, path string) {
+			runTest(t, path, caseRunner)
+		})
 	}
 
 	t.Run(handlerPath, func(t *testing.T) {
@@ -20,6 +20,7 @@ import (
 	"github.com/protolambda/zrnt/eth2/beacon/capella"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 	"github.com/protolambda/zrnt/eth2/configs"
 )
@@ -62,6 +63,8 @@ func LoadState(t *testing.T, fork ForkName, name string, readPart TestPartReader
 			state, err = capella.AsBeaconStateView(capella.BeaconStateType(spec).Deserialize(decodingReader))
 		case "deneb":
 			state, err = deneb.AsBeaconStateView(deneb.BeaconStateType(spec).Deserialize(decodingReader))
+		case "electra":
+			state, err = electra.AsBeaconStateView(electra.BeaconStateType(spec).Deserialize(decodingReader))
 		default:
 			t.Fatalf("unrecognized fork name: %s", fork)
 			return nil
@@ -104,6 +107,8 @@ func (c *BaseTransitionTest) Check(t *testing.T) {
 type BlocksTestCase struct {
 	BaseTransitionTest
 	Blocks []*common.BeaconBlockEnvelope
+	// BatchVerify defers the block signature checks to a batch verification per block
+	BatchVerify bool
 }
 
 type BlocksCountMeta struct {
@@ -168,8 +173,14 @@ func (c *BlocksTestCase) Run() error {
 		c.Pre = state.BeaconState
 	}()
 	for _, b := range c.Blocks {
-		if err := common.StateTransition(context.Background(), c.Spec, epc, state, b, true); err != nil {
-			return err
+		if c.BatchVerify {
+			if err := common.StateTransitionBatchVerified(context.Background(), c.Spec, epc, state, b, true); err != nil {
+				return err
+			}
+		} else {
+			if err := common.StateTransition(context.Background(), c.Spec, epc, state, b, true); err != nil {
+				return err
+			}
 		}
 	}
 	return nil